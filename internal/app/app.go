@@ -8,7 +8,11 @@ func New() *cli.App {
 	return &cli.App{
 		Commands: []*cli.Command{
 			recognizeCommand,
-			voskRecognizeCommand,
+			replayCommand,
+			serveCommand,
+			compareCommand,
+			evalCommand,
+			fakeServerCommand,
 			recognizerCreateCommand,
 			recognizerDeleteCommand,
 			recognizerListCommand,
@@ -0,0 +1,572 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	myspeech "github.com/hekt/voice-recognition/internal/interfaces/speech"
+	"github.com/hekt/voice-recognition/internal/punctuator/mecab"
+	"github.com/hekt/voice-recognition/internal/recognizer/backend"
+	"github.com/hekt/voice-recognition/internal/recognizer/failover"
+	"github.com/hekt/voice-recognition/internal/recognizer/google"
+	"github.com/hekt/voice-recognition/internal/recognizer/hybrid"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/recognizer/multichannel"
+	voskrecognizer "github.com/hekt/voice-recognition/internal/recognizer/vosk"
+	"github.com/hekt/voice-recognition/internal/recognizer/whisper"
+	"github.com/hekt/voice-recognition/internal/telemetry"
+	vosk "github.com/hekt/vosk-api/go"
+	mecablib "github.com/shogo82148/go-mecab"
+	"github.com/urfave/cli/v2"
+)
+
+// backendOption binds a backend in the registry to the command line.
+// To add a backend, register its factory in newBackendRegistry and add its option to backendOptions.
+type backendOption struct {
+	name  string
+	flags []cli.Flag
+	// punctuates is true if the backend punctuates the results by itself.
+	punctuates bool
+	// config builds the config of the backend from the command line.
+	// cleanup releases the resources held by the config.
+	config func(cCtx *cli.Context, build *backendBuild) (config any, cleanup func(), err error)
+}
+
+var backendOptions = []*backendOption{
+	googleBackendOption,
+	voskBackendOption,
+	hybridBackendOption,
+	failoverBackendOption,
+	whisperBackendOption,
+	multichannelBackendOption,
+}
+
+func newBackendRegistry() (*backend.Registry, error) {
+	registry := backend.NewRegistry()
+	if err := backend.Register(registry, googleBackendOption.name, google.New); err != nil {
+		return nil, err
+	}
+	if err := backend.Register(registry, voskBackendOption.name, voskrecognizer.New); err != nil {
+		return nil, err
+	}
+	if err := backend.Register(registry, hybridBackendOption.name, hybrid.New); err != nil {
+		return nil, err
+	}
+	if err := backend.Register(registry, failoverBackendOption.name, failover.New); err != nil {
+		return nil, err
+	}
+	if err := backend.Register(registry, whisperBackendOption.name, whisper.New); err != nil {
+		return nil, err
+	}
+	if err := backend.Register(registry, multichannelBackendOption.name, multichannel.New); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+func findBackendOption(name string) (*backendOption, error) {
+	for _, option := range backendOptions {
+		if option.name == name {
+			return option, nil
+		}
+	}
+	names := make([]string, 0, len(backendOptions))
+	for _, option := range backendOptions {
+		names = append(names, option.name)
+	}
+	return nil, fmt.Errorf("unknown backend %q, available backends: %s", name, strings.Join(names, ", "))
+}
+
+// buildBackendFactory configures the backend by the command line and returns its factory.
+// cleanup must be called after the recognizer is stopped.
+func buildBackendFactory(
+	cCtx *cli.Context,
+	registry *backend.Registry,
+	name string,
+) (newCore model.RecognizerCoreFactory, cleanup func(), err error) {
+	build := &backendBuild{registry: registry}
+	return build.factory(cCtx, name)
+}
+
+// backendBuild builds a backend with the backends inside it, e.g. the ones of
+// the channels of the multichannel backend, which share the Google meter so
+// that the budget and the cost report cover all of them.
+type backendBuild struct {
+	registry *backend.Registry
+	// meter is created by the first Google backend of the build.
+	meter *google.Meter
+}
+
+// factory configures the backend of the build and returns its factory.
+// cleanup must be called after the recognizer is stopped.
+func (b *backendBuild) factory(
+	cCtx *cli.Context,
+	name string,
+) (newCore model.RecognizerCoreFactory, cleanup func(), err error) {
+	option, err := findBackendOption(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, cleanup, err := option.config(cCtx, b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to configure backend %s: %w", name, err)
+	}
+	newCore, err = b.registry.Factory(option.name, config)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to get backend %s: %w", name, err)
+	}
+	return newCore, cleanup, nil
+}
+
+// googleMeter returns the meter of Google shared by the backends of the build.
+// It is created on the first call, whose release reports the cost when the
+// backends are cleaned up. The release of the other calls does nothing.
+func (b *backendBuild) googleMeter(cCtx *cli.Context) (meter *google.Meter, release func(), err error) {
+	if b.meter != nil {
+		return b.meter, func() {}, nil
+	}
+
+	meter, err = google.NewMeter(cCtx.Float64(budgetFlag.Name))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create meter: %w", err)
+	}
+	removeCost := telemetry.GoogleEstimatedCost.Add(func() float64 {
+		return meter.Usage().Cost()
+	})
+	startedAt := time.Now()
+	ledger := cCtx.String(ledgerFlag.Name)
+	b.meter = meter
+
+	return meter, func() {
+		removeCost()
+		reportCost(meter, startedAt, ledger)
+	}, nil
+}
+
+// backendFlags returns the flags of all backends without duplicates.
+func backendFlags() []cli.Flag {
+	seen := map[string]bool{}
+	flags := []cli.Flag{}
+	for _, option := range backendOptions {
+		for _, flag := range option.flags {
+			name := flag.Names()[0]
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+var googleBackendOption = &backendOption{
+	name: "google",
+	flags: []cli.Flag{
+		projectFlag,
+		recognizerFlag,
+		intervalFlag,
+		endpointFlag,
+		insecureFlag,
+		budgetFlag,
+		ledgerFlag,
+		minSpeakersFlag,
+		maxSpeakersFlag,
+		speakerNamesFlag,
+	},
+	config: func(cCtx *cli.Context, build *backendBuild) (any, func(), error) {
+		if interval := cCtx.Duration(intervalFlag.Name); interval < time.Minute {
+			return nil, nil, fmt.Errorf("interval must be greater than or equal to 1 minute: %s", interval)
+		}
+		endpoint := cCtx.String(endpointFlag.Name)
+		insecure := cCtx.Bool(insecureFlag.Name)
+		diarization, err := buildDiarization(cCtx)
+		if err != nil {
+			return nil, nil, err
+		}
+		meter, releaseMeter, err := build.googleMeter(cCtx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return google.Config{
+			NewClient: func(ctx context.Context) (myspeech.Client, error) {
+				return newSpeechClient(ctx, endpoint, insecure)
+			},
+			ProjectID:         cCtx.String(projectFlag.Name),
+			RecognizerName:    cCtx.String(recognizerFlag.Name),
+			ReconnectInterval: cCtx.Duration(intervalFlag.Name),
+			Meter:             meter,
+			Diarization:       diarization,
+		}, releaseMeter, nil
+	},
+}
+
+// buildDiarization builds the speaker diarization of Google from the flags.
+// It is disabled if --max-speakers is not specified.
+func buildDiarization(cCtx *cli.Context) (google.Diarization, error) {
+	if cCtx.Int(maxSpeakersFlag.Name) == 0 {
+		return google.Diarization{}, nil
+	}
+	diarization := google.Diarization{
+		MinSpeakers: cCtx.Int(minSpeakersFlag.Name),
+		MaxSpeakers: cCtx.Int(maxSpeakersFlag.Name),
+	}
+	if path := cCtx.String(speakerNamesFlag.Name); path != "" {
+		names, err := google.LoadSpeakerNames(path)
+		if err != nil {
+			return google.Diarization{}, err
+		}
+		diarization.Names = names
+	}
+	if err := diarization.Validate(); err != nil {
+		return google.Diarization{}, fmt.Errorf("invalid speaker diarization: %w", err)
+	}
+	return diarization, nil
+}
+
+// reportCost prints the cost of the session and appends it to the ledger file
+// if any audio has been sent to Google.
+func reportCost(meter *google.Meter, startedAt time.Time, ledger string) {
+	usage := meter.Usage()
+	if usage.Sent == 0 {
+		return
+	}
+
+	budget := "unlimited"
+	if b := meter.Budget(); b > 0 {
+		budget = fmt.Sprintf("$%.2f", b)
+	}
+	fmt.Fprintf(
+		os.Stderr,
+		"Google: sent %s, billed %s, estimated cost $%.4f (budget %s)\n",
+		usage.Sent.Round(time.Second),
+		usage.Billed.Round(time.Second),
+		usage.Cost(),
+		budget,
+	)
+
+	if ledger == "" {
+		return
+	}
+	if err := google.AppendLedger(ledger, meter.NewLedgerEntry(startedAt, time.Now())); err != nil {
+		slog.Error(fmt.Sprintf("failed to append ledger: %v", err))
+	}
+}
+
+var voskBackendOption = &backendOption{
+	name:       "vosk",
+	punctuates: true,
+	flags: []cli.Flag{
+		voskModelFlag,
+	},
+	config: func(cCtx *cli.Context, _ *backendBuild) (any, func(), error) {
+		voskRecognizer, punctuator, cleanup, err := buildVoskRecognizer(cCtx.String(voskModelFlag.Name))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to build vosk recognizer: %w", err)
+		}
+		return voskrecognizer.Config{
+			Recognizer: voskRecognizer,
+			Punctuator: punctuator,
+		}, cleanup, nil
+	},
+}
+
+var hybridBackendOption = &backendOption{
+	name:       "hybrid",
+	punctuates: true,
+	flags: []cli.Flag{
+		projectFlag,
+		recognizerFlag,
+		intervalFlag,
+		voskModelFlag,
+		&cli.BoolFlag{
+			Name:  "gate",
+			Usage: "[hybrid] Send audio to Google only while Vosk detects speech",
+			Value: false,
+		},
+		&cli.DurationFlag{
+			Name:  "preroll",
+			Usage: "[hybrid] Duration of audio sent to Google ahead of the detected speech",
+			Value: time.Second,
+		},
+		&cli.DurationFlag{
+			Name:  "hangover",
+			Usage: "[hybrid] Duration of audio sent to Google after the last detected speech",
+			Value: 2 * time.Second,
+		},
+	},
+	config: func(cCtx *cli.Context, build *backendBuild) (any, func(), error) {
+		newRemote, newLocal, cleanup, err := googleAndVoskFactories(cCtx, build)
+		if err != nil {
+			return nil, nil, err
+		}
+		return hybrid.Config{
+			NewLocal:  newLocal,
+			NewRemote: newRemote,
+			Gate:      cCtx.Bool("gate"),
+			Preroll:   cCtx.Duration("preroll"),
+			Hangover:  cCtx.Duration("hangover"),
+		}, cleanup, nil
+	},
+}
+
+var failoverBackendOption = &backendOption{
+	name:       "failover",
+	punctuates: true,
+	flags: []cli.Flag{
+		projectFlag,
+		recognizerFlag,
+		intervalFlag,
+		voskModelFlag,
+		&cli.DurationFlag{
+			Name:  "retry-interval",
+			Usage: "[failover] Interval of retrying Google while Vosk is used",
+			Value: time.Minute,
+		},
+		&cli.DurationFlag{
+			Name:  "max-buffer",
+			Usage: "[failover] Maximum duration of audio resent to Vosk on failover",
+			Value: 30 * time.Second,
+		},
+	},
+	config: func(cCtx *cli.Context, build *backendBuild) (any, func(), error) {
+		newPrimary, newFallback, cleanup, err := googleAndVoskFactories(cCtx, build)
+		if err != nil {
+			return nil, nil, err
+		}
+		return failover.Config{
+			PrimaryName:   googleBackendOption.name,
+			NewPrimary:    newPrimary,
+			FallbackName:  voskBackendOption.name,
+			NewFallback:   newFallback,
+			RetryInterval: cCtx.Duration("retry-interval"),
+			MaxBuffer:     cCtx.Duration("max-buffer"),
+		}, cleanup, nil
+	},
+}
+
+var whisperBackendOption = &backendOption{
+	name: "whisper",
+	flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "whisper-url",
+			Usage: "[whisper] URL of the transcription endpoint of whisper.cpp server or OpenAI-compatible API",
+			Value: "http://127.0.0.1:8080/inference",
+		},
+		&cli.StringFlag{
+			Name:  "whisper-model",
+			Usage: "[whisper] Model name sent to the endpoint",
+		},
+		&cli.StringFlag{
+			Name:  "whisper-language",
+			Usage: "[whisper] Language of the audio",
+			Value: "ja",
+		},
+		&cli.DurationFlag{
+			Name:  "whisper-timeout",
+			Usage: "[whisper] Timeout of a transcription request",
+			Value: 30 * time.Second,
+		},
+		&cli.DurationFlag{
+			Name:  "whisper-step",
+			Usage: "[whisper] Duration of audio received to start the next transcription",
+			Value: time.Second,
+		},
+		&cli.DurationFlag{
+			Name:  "whisper-max-window",
+			Usage: "[whisper] Maximum duration of audio transcribed at once",
+			Value: 20 * time.Second,
+		},
+	},
+	config: func(cCtx *cli.Context, _ *backendBuild) (any, func(), error) {
+		return whisper.Config{
+			URL:       cCtx.String("whisper-url"),
+			Model:     cCtx.String("whisper-model"),
+			Language:  cCtx.String("whisper-language"),
+			Timeout:   cCtx.Duration("whisper-timeout"),
+			Step:      cCtx.Duration("whisper-step"),
+			MaxWindow: cCtx.Duration("whisper-max-window"),
+		}, func() {}, nil
+	},
+}
+
+var multichannelBackendOption = &backendOption{
+	name: "multichannel",
+	flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "channel-backend",
+			Usage: "[multichannel] Backend recognizing each channel, configured by its own flags",
+			Value: "google",
+		},
+		&cli.StringSliceFlag{
+			Name:  "channel-names",
+			Usage: "[multichannel] Speaker names of the channels in the order of the channels. The number of the names is the number of the interleaved channels of the audio",
+			Value: cli.NewStringSlice("Channel 1", "Channel 2"),
+		},
+		&cli.DurationFlag{
+			Name:  "merge-window",
+			Usage: "[multichannel] Maximum duration of audio for which a final result waits for the other channels to keep the order",
+			Value: 5 * time.Second,
+		},
+	},
+}
+
+func init() {
+	// assigned here since it refers to backendOptions through buildBackendFactory.
+	multichannelBackendOption.config = multichannelConfig
+}
+
+// multichannelConfig builds a backend for each channel.
+func multichannelConfig(cCtx *cli.Context, build *backendBuild) (any, func(), error) {
+	name := cCtx.String("channel-backend")
+	if name == multichannelBackendOption.name {
+		return nil, nil, fmt.Errorf("channel backend must not be %s", name)
+	}
+
+	// each channel has its own backend, e.g. its own Vosk recognizer, but the
+	// Google meter is shared through the build.
+	names := cCtx.StringSlice("channel-names")
+	newCores := make([]model.RecognizerCoreFactory, 0, len(names))
+	cleanups := make([]func(), 0, len(names))
+	cleanup := func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+	for range names {
+		newCore, c, err := build.factory(cCtx, name)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		newCores = append(newCores, newCore)
+		cleanups = append(cleanups, c)
+	}
+
+	return multichannel.Config{
+		Names:    names,
+		NewCores: newCores,
+		Window:   cCtx.Duration("merge-window"),
+	}, cleanup, nil
+}
+
+// googleAndVoskFactories returns the factories of the Google and Vosk backends
+// for the backends which run both of them.
+func googleAndVoskFactories(
+	cCtx *cli.Context,
+	build *backendBuild,
+) (newGoogle, newVosk model.RecognizerCoreFactory, cleanup func(), err error) {
+	voskConfig, voskCleanup, err := voskBackendOption.config(cCtx, build)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	googleConfig, googleCleanup, err := googleBackendOption.config(cCtx, build)
+	if err != nil {
+		voskCleanup()
+		return nil, nil, nil, err
+	}
+	cleanup = func() {
+		googleCleanup()
+		voskCleanup()
+	}
+
+	newVosk, err = build.registry.Factory(voskBackendOption.name, voskConfig)
+	if err != nil {
+		cleanup()
+		return nil, nil, nil, err
+	}
+	newGoogle, err = build.registry.Factory(googleBackendOption.name, googleConfig)
+	if err != nil {
+		cleanup()
+		return nil, nil, nil, err
+	}
+
+	return newGoogle, newVosk, cleanup, nil
+}
+
+// voskModels are the Vosk models loaded by the process by path. A model is
+// loaded once and shared by the recognizers, e.g. the ones of the sessions of
+// serve or the channels of multichannel, since it is large and only read by
+// them. The models are kept until the process exits.
+var voskModels = struct {
+	mu     sync.Mutex
+	models map[string]*vosk.VoskModel
+}{
+	models: map[string]*vosk.VoskModel{},
+}
+
+// loadVoskModel returns the Vosk model of the path, loading it on the first call.
+func loadVoskModel(modelPath string) (*vosk.VoskModel, error) {
+	voskModels.mu.Lock()
+	defer voskModels.mu.Unlock()
+
+	if model, ok := voskModels.models[modelPath]; ok {
+		return model, nil
+	}
+	vosk.SetLogLevel(-1)
+	model, err := vosk.NewModel(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	voskModels.models[modelPath] = model
+	return model, nil
+}
+
+// buildVoskRecognizer creates a Vosk recognizer of the model and the
+// punctuator for it. cleanup must be called after the recognizer is stopped.
+func buildVoskRecognizer(modelPath string) (
+	voskRecognizer *vosk.VoskRecognizer,
+	punctuator *mecab.MecabPunctuator,
+	cleanup func(),
+	err error,
+) {
+	model, err := loadVoskModel(modelPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load model: %w", err)
+	}
+	voskRecognizer, err = vosk.NewRecognizer(model, 16000.0)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create recognizer: %w", err)
+	}
+	punctuator, destroyMecab, err := buildMecabPunctuator()
+	if err != nil {
+		voskRecognizer.Free()
+		return nil, nil, nil, err
+	}
+
+	return voskRecognizer, punctuator, func() {
+		destroyMecab()
+		voskRecognizer.Free()
+	}, nil
+}
+
+// buildMecabPunctuator creates the punctuator by MeCab.
+// cleanup must be called after the punctuator is used.
+func buildMecabPunctuator() (punctuator *mecab.MecabPunctuator, cleanup func(), err error) {
+	mc, err := mecablib.New(map[string]string{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create mecab: %w", err)
+	}
+
+	// parse empty string to initialize the parser
+	// see https://github.com/shogo82148/go-mecab/commit/272940876bf3b127ada5381ad15595f7f8ec0d8e
+	if _, err := mc.Parse(""); err != nil {
+		mc.Destroy()
+		return nil, nil, fmt.Errorf("failed to parse empty string: %w", err)
+	}
+
+	punctuator, err = mecab.NewMecabPunctuator(&mc)
+	if err != nil {
+		mc.Destroy()
+		return nil, nil, fmt.Errorf("failed to create punctuator: %w", err)
+	}
+
+	return punctuator, mc.Destroy, nil
+}
@@ -1,41 +1,306 @@
 package app
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	speech "cloud.google.com/go/speech/apiv2"
+	"github.com/hekt/voice-recognition/internal/audio"
+	"github.com/hekt/voice-recognition/internal/clock"
+	"github.com/hekt/voice-recognition/internal/eval"
+	"github.com/hekt/voice-recognition/internal/fakespeech"
 	"github.com/hekt/voice-recognition/internal/file"
+	"github.com/hekt/voice-recognition/internal/ingest"
 	"github.com/hekt/voice-recognition/internal/logger"
-	"github.com/hekt/voice-recognition/internal/punctuator/mecab"
+	"github.com/hekt/voice-recognition/internal/postprocess"
 	"github.com/hekt/voice-recognition/internal/recognizer"
+	"github.com/hekt/voice-recognition/internal/recognizer/compare"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/replay"
 	"github.com/hekt/voice-recognition/internal/resource"
-	vosk "github.com/hekt/vosk-api/go"
+	"github.com/hekt/voice-recognition/internal/server"
+	"github.com/hekt/voice-recognition/internal/sink"
+	"github.com/hekt/voice-recognition/internal/telemetry"
+	"github.com/hekt/voice-recognition/internal/tui"
+	"github.com/hekt/voice-recognition/internal/viewer"
 	mecablib "github.com/shogo82148/go-mecab"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	grpcinsecure "google.golang.org/grpc/credentials/insecure"
 )
 
+var recognizeFlags = append([]cli.Flag{
+	backendFlag,
+	debugFlag,
+	outputFlag,
+	rotateFlag,
+	rotateGzipFlag,
+	rotateManifestFlag,
+	journalFlag,
+	fsyncIntervalFlag,
+	bufferSizeFlag,
+	timeoutFlag,
+	metricsAddrFlag,
+	httpFlag,
+	tuiFlag,
+	saveAudioFlag,
+	sinkFlag,
+	sinkRetriesFlag,
+	sinkBackoffFlag,
+	onFinalExecFlag,
+	webhookFlag,
+	webhookBatchSizeFlag,
+	webhookBatchIntervalFlag,
+	webhookQueueFlag,
+	postProcessFlag,
+	fillersFlag,
+	dictionaryFlag,
+}, backendFlags()...)
+
 var recognizeCommand = &cli.Command{
 	Name:  "recognize",
 	Usage: "recognize voice",
-	Flags: []cli.Flag{
-		requiredProjectFlag,
-		requiredRecognizerFlag,
+	Flags: append([]cli.Flag{
+		listenFlag,
+		listenTokenFlag,
+	}, recognizeFlags...),
+	Action: func(cCtx *cli.Context) error {
+		address := cCtx.String(listenFlag.Name)
+		if address == "" {
+			return recognize(cCtx, os.Stdin)
+		}
+
+		ctx, cancel := context.WithCancel(cCtx.Context)
+		defer cancel()
+		session, err := ingest.Listen(ctx, address, cCtx.String(listenTokenFlag.Name))
+		if err != nil {
+			return err
+		}
+		return recognize(cCtx, session)
+	},
+}
+
+var replayCommand = &cli.Command{
+	Name:  "replay",
+	Usage: "recognize a recorded audio file with the pacing of a live capture",
+	Flags: append([]cli.Flag{
+		inputFlag,
+		speedFlag,
+		eventsFlag,
+	}, recognizeFlags...),
+	Action: func(cCtx *cli.Context) error {
+		pcm, err := audio.LoadFile(cCtx.String(inputFlag.Name))
+		if err != nil {
+			return err
+		}
+		var events []replay.Event
+		if path := cCtx.String(eventsFlag.Name); path != "" {
+			if events, err = replay.LoadEvents(path); err != nil {
+				return err
+			}
+		}
+
+		input, err := replay.NewPacedReader(
+			cCtx.Context,
+			bytes.NewReader(pcm),
+			cCtx.Float64(speedFlag.Name),
+			events,
+			clock.Real,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create paced reader: %w", err)
+		}
+
+		return recognize(cCtx, input)
+	},
+}
+
+// recognize recognizes the audio read from input and writes the results as the flags of recognizeCommand.
+func recognize(cCtx *cli.Context, input io.Reader) error {
+	switch {
+	case cCtx.Bool(debugFlag.Name):
+		if err := setLogger(slog.LevelDebug); err != nil {
+			return fmt.Errorf("failed to set logger: %w", err)
+		}
+	case cCtx.Bool(tuiFlag.Name):
+		// the logs on stderr would break the screen.
+		if err := setLogger(slog.LevelInfo); err != nil {
+			return fmt.Errorf("failed to set logger: %w", err)
+		}
+	}
+
+	registry, err := newBackendRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to create backend registry: %w", err)
+	}
+	if addr := cCtx.String(metricsAddrFlag.Name); addr != "" {
+		registry.Wrap(telemetry.InstrumentCore)
+		stop, err := serveMetrics(addr)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	newCore, cleanup, err := buildBackendFactory(cCtx, registry, cCtx.String(backendFlag.Name))
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	postProcessOptions, cleanupPostProcess, err := buildPostProcessOptions(cCtx)
+	if err != nil {
+		return err
+	}
+	defer cleanupPostProcess()
+	if err := checkPostProcess(cCtx, cCtx.String(backendFlag.Name), cCtx.StringSlice(postProcessFlag.Name)); err != nil {
+		return err
+	}
+	chain, err := postprocess.Build(cCtx.StringSlice(postProcessFlag.Name), postProcessOptions)
+	if err != nil {
+		return err
+	}
+	newCore = chain.Wrap(newCore)
+
+	output, err := openOutput(cCtx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := output.Close(); err != nil {
+			slog.Error(fmt.Sprintf("failed to close output file: %v", err))
+		}
+	}()
+	// the files of the sinks and the segments, reopened with the output file.
+	files := file.NewGroup()
+	defer func() {
+		if err := files.Close(); err != nil {
+			slog.Error(fmt.Sprintf("failed to close files: %v", err))
+		}
+	}()
+	defer syncOutput(output)()
+	defer reopenOnHangup(output, files)()
+
+	if addr := cCtx.String(httpFlag.Name); addr != "" {
+		hub := viewer.NewHub()
+		newCore = hub.Wrap(newCore)
+		stop, err := serveViewer(addr, hub, output.Path)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	audioReader := input
+	var interimWriter io.Writer = os.Stdout
+	var monitor *tui.Monitor
+	if cCtx.Bool(tuiFlag.Name) {
+		monitor = tui.NewMonitor(clock.Real)
+		newCore = monitor.Wrap(newCore)
+		// the paused audio is not saved either.
+		audioReader = monitor.Input(audioReader)
+		interimWriter = io.Discard
+	}
+
+	var segmentWriter recognizer.SegmentWriterInterface
+	if path := cCtx.String(saveAudioFlag.Name); path != "" {
+		// the WAV file is mono.
+		if cCtx.String(backendFlag.Name) == multichannelBackendOption.name {
+			return fmt.Errorf("--%s does not support the %s backend", saveAudioFlag.Name, multichannelBackendOption.name)
+		}
+		// the WAV file is a single file which is neither split nor reopened.
+		if cCtx.String(rotateFlag.Name) != "" {
+			return fmt.Errorf("--%s cannot be used with --%s", saveAudioFlag.Name, rotateFlag.Name)
+		}
+		r, w, closeAudio, err := saveAudio(audioReader, path, files)
+		if err != nil {
+			return err
+		}
+		defer closeAudio()
+		audioReader = r
+		segmentWriter = w
+	}
+	sinks, webhook, err := openSinks(cCtx, files)
+	if err != nil {
+		return err
+	}
+	if len(sinks) > 0 {
+		if segmentWriter != nil {
+			sinks = append([]recognizer.SegmentWriterInterface{segmentWriter}, sinks...)
+		}
+		segmentWriter = sink.NewFanOut(sinks...)
+	}
+	if webhook != nil {
+		// the webhook outlives the pipeline to post the last results.
+		ctx, cancel := context.WithCancel(context.WithoutCancel(cCtx.Context))
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := webhook.Start(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				slog.Error(fmt.Sprintf("failed to run webhook: %v", err))
+			}
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+	}
+
+	recognizer, err := recognizer.NewPipeline(cCtx.Context, newCore, recognizer.PipelineConfig{
+		BufferSize:      cCtx.Int(bufferSizeFlag.Name),
+		InactiveTimeout: cCtx.Duration(timeoutFlag.Name),
+		AudioReader:     audioReader,
+		ResultWriter:    output,
+		InterimWriter:   interimWriter,
+		SegmentWriter:   segmentWriter,
+		InterimJournal:  output.Interim(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create recognizer: %w", err)
+	}
+
+	start := recognizer.Start
+	if monitor != nil {
+		start = func(ctx context.Context) error {
+			return runMonitor(ctx, monitor, recognizer)
+		}
+	}
+	if err := start(cCtx.Context); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return fmt.Errorf("failed to start recognizer: %w", err)
+	}
+
+	return nil
+}
+
+var compareCommand = &cli.Command{
+	Name:  "compare",
+	Usage: "recognize voice by two backends and report the differences",
+	Flags: append([]cli.Flag{
+		compareBackendAFlag,
+		compareBackendBFlag,
+		reportFlag,
+		alignToleranceFlag,
 		debugFlag,
 		outputFlag,
 		bufferSizeFlag,
 		timeoutFlag,
-		&cli.DurationFlag{
-			Name:  "interval",
-			Usage: "Reconnect interval duration",
-			Value: time.Minute,
-		},
-	},
+	}, backendFlags()...),
 	Action: func(cCtx *cli.Context) error {
 		if cCtx.Bool(debugFlag.Name) {
 			if err := setLogger(slog.LevelDebug); err != nil {
@@ -43,66 +308,385 @@ var recognizeCommand = &cli.Command{
 			}
 		}
 
-		// This behavior ensures the output file is created early,
-		// making it easier to use with tools like `tail -f`.
-		if err := prepareOutputFile(cCtx.String(outputFlag.Name)); err != nil {
-			return fmt.Errorf("failed to prepare output file: %w", err)
+		registry, err := newBackendRegistry()
+		if err != nil {
+			return fmt.Errorf("failed to create backend registry: %w", err)
+		}
+		nameA := cCtx.String(compareBackendAFlag.Name)
+		nameB := cCtx.String(compareBackendBFlag.Name)
+		newA, cleanupA, err := buildBackendFactory(cCtx, registry, nameA)
+		if err != nil {
+			return err
+		}
+		defer cleanupA()
+		newB, cleanupB, err := buildBackendFactory(cCtx, registry, nameB)
+		if err != nil {
+			return err
+		}
+		defer cleanupB()
+
+		output, err := openOutput(cCtx)
+		if err != nil {
+			return err
 		}
+		defer func() {
+			if err := output.Close(); err != nil {
+				slog.Error(fmt.Sprintf("failed to close output file: %v", err))
+			}
+		}()
+		defer syncOutput(output)()
+
+		var comparer *compare.Recognizer
+		newCore := func(
+			_ context.Context,
+			audioCh <-chan []byte,
+			resultCh chan<- []*model.Result,
+		) (model.RecognizerCoreInterface, error) {
+			r, err := compare.NewRecognizer(nameA, newA, nameB, newB, audioCh, resultCh)
+			if err != nil {
+				return nil, err
+			}
+			comparer = r
+			return r, nil
+		}
+
+		recognizer, err := recognizer.NewPipeline(cCtx.Context, newCore, recognizer.PipelineConfig{
+			BufferSize:      cCtx.Int(bufferSizeFlag.Name),
+			InactiveTimeout: cCtx.Duration(timeoutFlag.Name),
+			AudioReader:     os.Stdin,
+			ResultWriter:    output,
+			InterimWriter:   os.Stdout,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create recognizer: %w", err)
+		}
+
+		// the report is written even if the recognizer stopped by an error
+		// to keep the results so far.
+		startErr := recognizer.Start(cCtx.Context)
+
+		a, b := comparer.Segments()
+		report := compare.NewReport(nameA, a, nameB, b, cCtx.Duration(alignToleranceFlag.Name))
+		if err := writeReport(cCtx.String(reportFlag.Name), report); err != nil {
+			return err
+		}
+
+		if startErr != nil && !errors.Is(startErr, context.Canceled) {
+			return fmt.Errorf("failed to start recognizer: %w", startErr)
+		}
+
+		return nil
+	},
+}
+
+// saveAudio tees the audio read from r to the WAV file at path. It returns the
+// reader of the audio and the segment writer which links the final results to
+// the positions in the audio. closeAudio finalizes the WAV file.
+func saveAudio(r io.Reader, path string, files *file.Group) (
+	reader io.Reader,
+	segmentWriter recognizer.SegmentWriterInterface,
+	closeAudio func(),
+	err error,
+) {
+	ext := filepath.Ext(path)
+	if !strings.EqualFold(ext, ".wav") {
+		return nil, nil, nil, fmt.Errorf("unsupported audio file %q, only WAV is supported", path)
+	}
 
-		audioReader := os.Stdin
-		resultWriter := file.NewOpenCloseFileWriter(
-			cCtx.String(outputFlag.Name),
-			os.O_APPEND|os.O_CREATE|os.O_WRONLY,
-			os.FileMode(0o644),
+	segmentsPath := strings.TrimSuffix(path, ext) + ".segments.jsonl"
+	segments, err := files.Open(segmentsPath, os.O_TRUNC|os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to prepare segments file: %w", err)
+	}
+	archive, err := audio.CreateWAV(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	segmentWriter = recognizer.NewJSONSegmentWriter(segments, path, archive.Samples)
+	return io.TeeReader(r, archive), segmentWriter, func() {
+		if err := archive.Close(); err != nil {
+			slog.Error(fmt.Sprintf("failed to close audio file: %v", err))
+		}
+	}, nil
+}
+
+// serveViewer serves the live transcript of hub with the output file on addr.
+// The returned function stops the server.
+func serveViewer(addr string, hub *viewer.Hub, output func() string) (stop func(), err error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen viewer address: %w", err)
+	}
+	server := &http.Server{Handler: viewer.NewHandler(hub, output), ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error(fmt.Sprintf("failed to serve viewer: %v", err))
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "Viewer listening on http://%s\n", lis.Addr())
+
+	return func() {
+		// the event streams never become idle until the hub is closed.
+		hub.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Error(fmt.Sprintf("failed to shutdown viewer server: %v", err))
+		}
+	}, nil
+}
+
+// openSinks opens the additional outputs of the final results by the flags.
+// webhook is also in sinks, and must be started to post the results.
+func openSinks(cCtx *cli.Context, files *file.Group) (sinks []recognizer.SegmentWriterInterface, webhook *sink.BatchWebhook, err error) {
+	options := sink.Options{
+		Retries: cCtx.Int(sinkRetriesFlag.Name),
+		Backoff: cCtx.Duration(sinkBackoffFlag.Name),
+		Files:   files,
+	}
+	for _, spec := range cCtx.StringSlice(sinkFlag.Name) {
+		s, err := sink.Open(spec, options)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open sink: %w", err)
+		}
+		sinks = append(sinks, s)
+	}
+
+	if command := cCtx.String(onFinalExecFlag.Name); command != "" {
+		sinks = append(sinks, sink.WithPolicy(
+			"on-final-exec",
+			sink.NewExecSink(command, clock.Real),
+			sink.PolicyDrop,
+			options,
+		))
+	}
+
+	if url := cCtx.String(webhookFlag.Name); url != "" {
+		queuePath := cCtx.String(webhookQueueFlag.Name)
+		if err := os.MkdirAll(filepath.Dir(queuePath), 0o755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create webhook queue directory: %w", err)
+		}
+		webhook, err = sink.NewBatchWebhook(
+			url,
+			queuePath,
+			cCtx.Int(webhookBatchSizeFlag.Name),
+			cCtx.Duration(webhookBatchIntervalFlag.Name),
+			cCtx.Duration(sinkBackoffFlag.Name),
+			&http.Client{Timeout: 10 * time.Second},
+			clock.Real,
 		)
-		interimWriter := os.Stdout
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create webhook: %w", err)
+		}
+		sinks = append(sinks, webhook)
+	}
+
+	return sinks, webhook, nil
+}
 
-		client, err := speech.NewClient(cCtx.Context)
+// runMonitor starts the recognizer showing monitor on the terminal until the
+// recognizer stops or monitor quits.
+func runMonitor(ctx context.Context, monitor *tui.Monitor, pipeline *recognizer.Recognizer) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open terminal: %w", err)
+	}
+	defer tty.Close()
+	fd := int(tty.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to make terminal raw: %w", err)
+	}
+	defer func() {
+		if err := term.Restore(fd, state); err != nil {
+			slog.Error(fmt.Sprintf("failed to restore terminal: %v", err))
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		size := func() (int, int, error) {
+			return term.GetSize(fd)
+		}
+		status := func() tui.Status {
+			status := tui.Status{
+				InactiveDeadline: pipeline.InactiveDeadline(),
+				Cost:             telemetry.GoogleEstimatedCost.Value(),
+			}
+			if next := telemetry.GoogleNextReconnect.Value(); next > 0 {
+				status.Streaming = true
+				status.Connected = telemetry.GoogleStreamsConnected.Value() > 0
+				status.NextReconnect = time.Unix(int64(next), 0)
+			}
+			return status
+		}
+		if err := monitor.Run(ctx, tty, size, status, cancel); err != nil && !errors.Is(err, context.Canceled) {
+			slog.Error(fmt.Sprintf("failed to run monitor: %v", err))
+			cancel()
+		}
+	}()
+
+	err = pipeline.Start(ctx)
+	// the screen is restored before the error is printed.
+	cancel()
+	<-done
+	return err
+}
+
+// serveMetrics serves the metrics of telemetry.Default on /metrics of addr.
+// The returned function stops the server.
+func serveMetrics(addr string) (stop func(), err error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen metrics address: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", telemetry.Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error(fmt.Sprintf("failed to serve metrics: %v", err))
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Error(fmt.Sprintf("failed to shutdown metrics server: %v", err))
+		}
+	}, nil
+}
+
+// writeReport writes the report as HTML if the path ends with .html, and as Markdown otherwise.
+func writeReport(path string, report compare.Report) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(path, ".html") {
+		err = report.WriteHTML(f)
+	} else {
+		err = report.WriteMarkdown(f)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close report file: %w", err)
+	}
+	return nil
+}
+
+var evalCommand = &cli.Command{
+	Name:  "eval",
+	Usage: "evaluate the accuracy of a backend against reference transcripts",
+	Flags: append([]cli.Flag{
+		backendFlag,
+		manifestFlag,
+		baselineFlag,
+		saveFlag,
+		speedFlag,
+		trailingSilenceFlag,
+		settleFlag,
+		debugFlag,
+		bufferSizeFlag,
+	}, backendFlags()...),
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.Bool(debugFlag.Name) {
+			if err := setLogger(slog.LevelDebug); err != nil {
+				return fmt.Errorf("failed to set logger: %w", err)
+			}
+		}
+
+		entries, err := eval.ReadManifest(cCtx.String(manifestFlag.Name))
 		if err != nil {
-			return fmt.Errorf("failed to create speech client: %w", err)
+			return err
+		}
+		var baseline *eval.Report
+		if path := cCtx.String(baselineFlag.Name); path != "" {
+			if baseline, err = eval.ReadReport(path); err != nil {
+				return err
+			}
 		}
 
-		recognizer, err := recognizer.New(
-			cCtx.Context,
-			client,
-			cCtx.String(projectFlag.Name),
-			cCtx.String(recognizerFlag.Name),
-			cCtx.Duration("interval"),
+		registry, err := newBackendRegistry()
+		if err != nil {
+			return fmt.Errorf("failed to create backend registry: %w", err)
+		}
+		name := cCtx.String(backendFlag.Name)
+		if _, err := findBackendOption(name); err != nil {
+			return err
+		}
+
+		runner, err := eval.NewRunner(
+			func() (model.RecognizerCoreFactory, func(), error) {
+				return buildBackendFactory(cCtx, registry, name)
+			},
 			cCtx.Int(bufferSizeFlag.Name),
-			cCtx.Duration(timeoutFlag.Name),
-			audioReader,
-			resultWriter,
-			interimWriter,
+			cCtx.Float64(speedFlag.Name),
+			cCtx.Duration(trailingSilenceFlag.Name),
+			cCtx.Duration(settleFlag.Name),
 		)
 		if err != nil {
-			return fmt.Errorf("failed to create recognizer: %w", err)
+			return fmt.Errorf("failed to create runner: %w", err)
+		}
+		tokenize, destroy, err := buildMecabTokenizer()
+		if err != nil {
+			return fmt.Errorf("failed to build tokenizer: %w", err)
+		}
+		defer destroy()
+
+		report, err := eval.Evaluate(cCtx.Context, name, entries, runner, tokenize)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate: %w", err)
 		}
 
-		if err := recognizer.Start(cCtx.Context); err != nil {
-			if errors.Is(err, context.Canceled) {
-				return nil
+		if path := cCtx.String(saveFlag.Name); path != "" {
+			f, err := os.Create(path)
+			if err != nil {
+				return fmt.Errorf("failed to create report file: %w", err)
+			}
+			defer f.Close()
+			if err := report.WriteJSON(f); err != nil {
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("failed to close report file: %w", err)
 			}
-			return fmt.Errorf("failed to start recognizer: %w", err)
 		}
 
-		return nil
+		return report.WriteMarkdown(os.Stdout, baseline)
 	},
 }
 
-var voskRecognizeCommand = &cli.Command{
-	Name:  "recognize-vosk",
-	Usage: "recognize voice using Vosk",
-	Flags: []cli.Flag{
-		debugFlag,
-		outputFlag,
+var serveCommand = &cli.Command{
+	Name:  "serve",
+	Usage: "serve the recognition of multiple sessions over HTTP and WebSocket",
+	Flags: append([]cli.Flag{
+		serveAddrFlag,
+		backendFlag,
+		backendsFlag,
+		maxSessionsFlag,
+		maxSessionDurationFlag,
+		outputDirFlag,
 		bufferSizeFlag,
 		timeoutFlag,
-		&cli.StringFlag{
-			Name:  "model",
-			Usage: "path to model directory",
-			Value: "model",
-		},
-	},
+		postProcessFlag,
+		fillersFlag,
+		dictionaryFlag,
+		debugFlag,
+	}, backendFlags()...),
 	Action: func(cCtx *cli.Context) error {
 		if cCtx.Bool(debugFlag.Name) {
 			if err := setLogger(slog.LevelDebug); err != nil {
@@ -110,63 +694,128 @@ var voskRecognizeCommand = &cli.Command{
 			}
 		}
 
-		outputFile, err := os.OpenFile(
-			cCtx.String(outputFlag.Name),
-			os.O_APPEND|os.O_CREATE|os.O_WRONLY,
-			os.FileMode(0o644),
-		)
+		registry, err := newBackendRegistry()
 		if err != nil {
-			return fmt.Errorf("failed to open output file: %w", err)
+			return fmt.Errorf("failed to create backend registry: %w", err)
+		}
+		defaultBackend := cCtx.String(backendFlag.Name)
+		names := cCtx.StringSlice(backendsFlag.Name)
+		if len(names) == 0 {
+			names = []string{defaultBackend}
+		}
+		// each session builds its own backend so that the sessions do not
+		// share the state of the backend.
+		backends := map[string]model.RecognizerCoreFactoryBuilder{}
+		for _, name := range names {
+			if _, err := findBackendOption(name); err != nil {
+				return err
+			}
+			backends[name] = func() (model.RecognizerCoreFactory, func(), error) {
+				return buildBackendFactory(cCtx, registry, name)
+			}
 		}
 
-		audioReader := os.Stdin
-		resultWriter := outputFile
-		interimWriter := os.Stdout
-
-		vosk.SetLogLevel(-1)
-		model, err := vosk.NewModel(cCtx.String("model"))
+		postProcessOptions, cleanupPostProcess, err := buildPostProcessOptions(cCtx)
 		if err != nil {
-			return fmt.Errorf("failed to load model: %w", err)
+			return err
+		}
+		defer cleanupPostProcess()
+		check := func(backend string, names []string) error {
+			return checkPostProcess(cCtx, backend, names)
 		}
-		voskRecognizer, err := vosk.NewRecognizer(model, 16000.0)
+
+		s, err := server.NewServer(server.Config{
+			Backends:           backends,
+			DefaultBackend:     defaultBackend,
+			PostProcess:        postProcessOptions,
+			DefaultPostProcess: cCtx.StringSlice(postProcessFlag.Name),
+			CheckPostProcess:   check,
+			MaxSessions:        cCtx.Int(maxSessionsFlag.Name),
+			MaxDuration:        cCtx.Duration(maxSessionDurationFlag.Name),
+			OutputDir:          cCtx.String(outputDirFlag.Name),
+			BufferSize:         cCtx.Int(bufferSizeFlag.Name),
+			InactiveTimeout:    cCtx.Duration(timeoutFlag.Name),
+		})
 		if err != nil {
-			return fmt.Errorf("failed to create recognizer: %w", err)
+			return fmt.Errorf("failed to create server: %w", err)
 		}
-		mc, err := mecablib.New(map[string]string{})
+		defer s.Close()
+
+		lis, err := net.Listen("tcp", cCtx.String(serveAddrFlag.Name))
 		if err != nil {
-			return fmt.Errorf("failed to create mecab: %w", err)
+			return fmt.Errorf("failed to listen: %w", err)
 		}
-		defer mc.Destroy()
+		hs := &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+
+		ctx, stop := signal.NotifyContext(cCtx.Context, os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			// the WebSocket connections are closed by stopping the sessions.
+			hs.Close()
+		}()
+
+		fmt.Printf("Server listening on %s\n", lis.Addr())
 
-		// parse empty string to initialize the parser
-		// see https://github.com/shogo82148/go-mecab/commit/272940876bf3b127ada5381ad15595f7f8ec0d8e
-		if _, err := mc.Parse(""); err != nil {
-			return fmt.Errorf("failed to parse empty string: %w", err)
+		if err := hs.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to serve: %w", err)
 		}
+		return nil
+	},
+}
 
-		punctuator, err := mecab.NewMecabPunctuator(&mc)
-		if err != nil {
-			return fmt.Errorf("failed to create punctuator: %w", err)
+var fakeServerCommand = &cli.Command{
+	Name:  "fake-server",
+	Usage: "run fake Speech-to-Text API server recognizing audio by a script",
+	Flags: []cli.Flag{
+		addrFlag,
+		scriptFlag,
+		maxStreamDurationFlag,
+		operationDelayFlag,
+		debugFlag,
+	},
+	Action: func(cCtx *cli.Context) error {
+		if cCtx.Bool(debugFlag.Name) {
+			if err := setLogger(slog.LevelDebug); err != nil {
+				return fmt.Errorf("failed to set logger: %w", err)
+			}
 		}
 
-		recognizer, err := recognizer.NewVoskRecognizer(
-			voskRecognizer,
-			punctuator,
-			cCtx.Int(bufferSizeFlag.Name),
-			cCtx.Duration(timeoutFlag.Name),
-			audioReader,
-			resultWriter,
-			interimWriter,
+		var script []fakespeech.Utterance
+		if path := cCtx.String(scriptFlag.Name); path != "" {
+			s, err := fakespeech.LoadScript(path)
+			if err != nil {
+				return err
+			}
+			script = s
+		}
+		server, err := fakespeech.NewServer(
+			script,
+			cCtx.Duration(maxStreamDurationFlag.Name),
+			cCtx.Duration(operationDelayFlag.Name),
 		)
 		if err != nil {
-			return fmt.Errorf("failed to create recognizer: %w", err)
+			return fmt.Errorf("failed to create fake server: %w", err)
 		}
 
-		if err := recognizer.Start(cCtx.Context); err != nil {
-			if errors.Is(err, context.Canceled) {
-				return nil
-			}
-			return fmt.Errorf("failed to start recognizer: %w", err)
+		lis, err := net.Listen("tcp", cCtx.String(addrFlag.Name))
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		gs := grpc.NewServer()
+		server.Register(gs)
+
+		ctx, stop := signal.NotifyContext(cCtx.Context, os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			gs.Stop()
+		}()
+
+		fmt.Printf("Fake server listening on %s\n", lis.Addr())
+
+		if err := gs.Serve(lis); err != nil {
+			return fmt.Errorf("failed to serve: %w", err)
 		}
 
 		return nil
@@ -183,9 +832,13 @@ var recognizerCreateCommand = &cli.Command{
 		modelFlag,
 		languageCodeFlag,
 		phraseSetFlag,
+		minSpeakersFlag,
+		maxSpeakersFlag,
+		endpointFlag,
+		insecureFlag,
 	},
 	Action: func(cCtx *cli.Context) error {
-		manager, err := buildRecognizerManager(cCtx.Context)
+		manager, err := buildRecognizerManager(cCtx)
 		if err != nil {
 			return fmt.Errorf("failed to build recognizer manager: %w", err)
 		}
@@ -196,12 +849,18 @@ var recognizerCreateCommand = &cli.Command{
 			languageCode = fs[0]
 		}
 
+		diarization, err := buildDiarization(cCtx)
+		if err != nil {
+			return err
+		}
+
 		args := resource.CreateRecognizerArgs{
 			ProjectID:      cCtx.String(projectFlag.Name),
 			RecognizerName: cCtx.String(recognizerFlag.Name),
 			Model:          cCtx.String(modelFlag.Name),
 			LanguageCode:   languageCode,
 			PhraseSet:      cCtx.String(phraseSetFlag.Name),
+			Diarization:    diarization.Config(),
 		}
 		if err := manager.Create(cCtx.Context, args); err != nil {
 			return fmt.Errorf("failed to create recognizer: %w", err)
@@ -220,9 +879,11 @@ var recognizerDeleteCommand = &cli.Command{
 	Flags: []cli.Flag{
 		requiredProjectFlag,
 		requiredRecognizerFlag,
+		endpointFlag,
+		insecureFlag,
 	},
 	Action: func(cCtx *cli.Context) error {
-		manager, err := buildRecognizerManager(cCtx.Context)
+		manager, err := buildRecognizerManager(cCtx)
 		if err != nil {
 			return fmt.Errorf("failed to build recognizer manager: %w", err)
 		}
@@ -247,9 +908,11 @@ var recognizerListCommand = &cli.Command{
 	Usage:    "list recognizers for Speech-to-Text API",
 	Flags: []cli.Flag{
 		requiredProjectFlag,
+		endpointFlag,
+		insecureFlag,
 	},
 	Action: func(cCtx *cli.Context) error {
-		manager, err := buildRecognizerManager(cCtx.Context)
+		manager, err := buildRecognizerManager(cCtx)
 		if err != nil {
 			return fmt.Errorf("failed to build recognizer manager: %w", err)
 		}
@@ -280,9 +943,11 @@ var phraseSetCreateCommand = &cli.Command{
 		phraseFlag,
 		phrasesFlag,
 		boostFlag,
+		endpointFlag,
+		insecureFlag,
 	},
 	Action: func(cCtx *cli.Context) error {
-		manager, err := buildPhraseSetManager(cCtx.Context)
+		manager, err := buildPhraseSetManager(cCtx)
 		if err != nil {
 			return fmt.Errorf("failed to build phrase set manager: %w", err)
 		}
@@ -328,9 +993,11 @@ var phraseSetUpdateCommand = &cli.Command{
 		phraseFlag,
 		phrasesFlag,
 		boostFlag,
+		endpointFlag,
+		insecureFlag,
 	},
 	Action: func(cCtx *cli.Context) error {
-		manager, err := buildPhraseSetManager(cCtx.Context)
+		manager, err := buildPhraseSetManager(cCtx)
 		if err != nil {
 			return fmt.Errorf("failed to build phrase set manager: %w", err)
 		}
@@ -372,9 +1039,11 @@ var phraseSetListCommand = &cli.Command{
 	Usage:    "list phrase sets for Speech-to-Text API",
 	Flags: []cli.Flag{
 		requiredProjectFlag,
+		endpointFlag,
+		insecureFlag,
 	},
 	Action: func(cCtx *cli.Context) error {
-		manager, err := buildPhraseSetManager(cCtx.Context)
+		manager, err := buildPhraseSetManager(cCtx)
 		if err != nil {
 			return fmt.Errorf("failed to build phrase set manager: %w", err)
 		}
@@ -407,8 +1076,8 @@ func setLogger(level slog.Level) error {
 	return nil
 }
 
-func buildRecognizerManager(ctx context.Context) (resource.RecognizerManager, error) {
-	client, err := speech.NewClient(ctx)
+func buildRecognizerManager(cCtx *cli.Context) (resource.RecognizerManager, error) {
+	client, err := newSpeechClient(cCtx.Context, cCtx.String(endpointFlag.Name), cCtx.Bool(insecureFlag.Name))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create speech client: %w", err)
 	}
@@ -418,8 +1087,8 @@ func buildRecognizerManager(ctx context.Context) (resource.RecognizerManager, er
 	return manager, nil
 }
 
-func buildPhraseSetManager(ctx context.Context) (resource.PhraseSetManager, error) {
-	client, err := speech.NewClient(ctx)
+func buildPhraseSetManager(cCtx *cli.Context) (resource.PhraseSetManager, error) {
+	client, err := newSpeechClient(cCtx.Context, cCtx.String(endpointFlag.Name), cCtx.Bool(insecureFlag.Name))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create speech client: %w", err)
 	}
@@ -429,13 +1098,152 @@ func buildPhraseSetManager(ctx context.Context) (resource.PhraseSetManager, erro
 	return manager, nil
 }
 
-func prepareOutputFile(path string) error {
-	f, err := os.OpenFile(path, os.O_CREATE, os.FileMode(0o644))
+// newSpeechClient creates a client of the Speech-to-Text API.
+// The default endpoint of Google Cloud is used if endpoint is empty.
+// If insecure is true, it connects to the endpoint without TLS and credentials.
+func newSpeechClient(ctx context.Context, endpoint string, insecure bool) (*speech.Client, error) {
+	if !insecure {
+		if endpoint == "" {
+			return speech.NewClient(ctx)
+		}
+		return speech.NewClient(ctx, option.WithEndpoint(endpoint))
+	}
+
+	if endpoint == "" {
+		return nil, errors.New("endpoint must be specified when insecure")
+	}
+	// the connection is closed when the client is closed.
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(grpcinsecure.NewCredentials()))
 	if err != nil {
-		return fmt.Errorf("failed to open output file: %w", err)
+		return nil, fmt.Errorf("failed to connect to %s: %w", endpoint, err)
 	}
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("failed to close output file: %w", err)
+	return speech.NewClient(ctx, option.WithGRPCConn(conn))
+}
+
+// buildMecabTokenizer returns the tokenizer which splits a transcript into the
+// words by MeCab. destroy must be called after the tokenizer is used.
+func buildMecabTokenizer() (tokenize eval.Tokenizer, destroy func(), err error) {
+	mc, err := mecablib.New(map[string]string{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create mecab: %w", err)
+	}
+
+	// parse empty string to initialize the parser
+	// see https://github.com/shogo82148/go-mecab/commit/272940876bf3b127ada5381ad15595f7f8ec0d8e
+	if _, err := mc.Parse(""); err != nil {
+		mc.Destroy()
+		return nil, nil, fmt.Errorf("failed to parse empty string: %w", err)
+	}
+
+	tokenize = func(s string) ([]string, error) {
+		node, err := mc.ParseToNode(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse: %w", err)
+		}
+		var words []string
+		for n := node.Next(); n.Stat() == mecablib.NormalNode || n.Stat() == mecablib.UnknownNode; n = n.Next() {
+			words = append(words, n.Surface())
+		}
+		return words, nil
+	}
+
+	return tokenize, mc.Destroy, nil
+}
+
+// openOutput opens the output file of the session by the flags. The session
+// is the unix time of the start, which is the default name of the file.
+// The sessions left by a crash are recovered before it.
+func openOutput(cCtx *cli.Context) (*file.RotatingFileWriter, error) {
+	rotation, err := file.ParseRotation(cCtx.String(rotateFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := strconv.FormatInt(now.Unix(), 10)
+	// the manifest is needed only when the output is split into the parts.
+	var manifest string
+	if rotation != (file.Rotation{}) {
+		manifest = file.ExpandPath(cCtx.String(rotateManifestFlag.Name), session, now, 1)
+	}
+	var journal string
+	if template := cCtx.String(journalFlag.Name); template != "" {
+		recoveries, err := file.RecoverJournals(template)
+		for _, r := range recoveries {
+			if r.Path == "" {
+				continue
+			}
+			msg := fmt.Sprintf("Recovered %s from the crash: %d writes repaired", r.Path, r.Repaired)
+			if r.Interim != "" {
+				msg += ", the last interim result appended"
+			}
+			fmt.Fprintln(os.Stderr, msg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to recover output file: %w", err)
+		}
+		journal = file.ExpandPath(template, session, now, 1)
+	}
+
+	// This behavior ensures the output file is created early,
+	// making it easier to use with tools like `tail -f`.
+	output, err := file.NewRotatingFileWriter(
+		cCtx.String(outputFlag.Name),
+		session,
+		rotation,
+		manifest,
+		cCtx.Bool(rotateGzipFlag.Name),
+		journal,
+		cCtx.Duration(fsyncIntervalFlag.Name),
+		clock.Real,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare output file: %w", err)
+	}
+	return output, nil
+}
+
+// syncOutput syncs output by its interval in the background. The returned
+// function stops it.
+func syncOutput(output *file.RotatingFileWriter) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := output.Start(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.Error(fmt.Sprintf("failed to sync output file: %v", err))
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// reopenOnHangup reopens output and files on SIGHUP, e.g. sent by logrotate
+// after it moves them. The returned function stops it.
+func reopenOnHangup(output *file.RotatingFileWriter, files *file.Group) (stop func()) {
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			case <-hupCh:
+				if err := errors.Join(output.Reopen(), files.Reopen()); err != nil {
+					slog.Error(fmt.Sprintf("failed to reopen files: %v", err))
+					continue
+				}
+				slog.Info("files reopened on SIGHUP")
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(hupCh)
+		close(done)
+		<-stopped
 	}
-	return nil
 }
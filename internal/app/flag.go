@@ -2,8 +2,11 @@ package app
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/hekt/voice-recognition/internal/fakespeech"
+	"github.com/hekt/voice-recognition/internal/postprocess"
 	"github.com/urfave/cli/v2"
 )
 
@@ -18,6 +21,12 @@ var requiredProjectFlag = &cli.StringFlag{
 	Required: true,
 }
 
+var backendFlag = &cli.StringFlag{
+	Name:  "backend",
+	Usage: "Recognition backend name",
+	Value: "google",
+}
+
 var debugFlag = &cli.BoolFlag{
 	Name:  "debug",
 	Usage: "Enable debug log",
@@ -52,8 +61,36 @@ var languageCodeFlag = &cli.StringSliceFlag{
 
 var outputFlag = &cli.StringFlag{
 	Name:  "output",
-	Usage: "Output file path",
-	Value: fmt.Sprintf("output/%d.txt", time.Now().Unix()),
+	Usage: "Output file path. {session} is the unix time of the start, {date}, {hour} and {part} are of each part with --rotate",
+	Value: "output/{session}.txt",
+}
+
+var rotateFlag = &cli.StringFlag{
+	Name:  "rotate",
+	Usage: "Rotation of the output file: daily, hourly or size=<size> like size=10MB, possibly combined with commas",
+}
+
+var rotateGzipFlag = &cli.BoolFlag{
+	Name:  "rotate-gzip",
+	Usage: "Compress the rotated parts of the output file but the current one with gzip",
+}
+
+var rotateManifestFlag = &cli.StringFlag{
+	Name:  "rotate-manifest",
+	Usage: "Path of the manifest listing the parts of the output file with --rotate. {session} is the same as --output",
+	Value: "output/{session}.manifest.json",
+}
+
+var journalFlag = &cli.StringFlag{
+	Name:  "journal",
+	Usage: "Path of the journal to recover the output file after a crash. {session} is the same as --output. Empty to disable",
+	Value: "output/{session}.journal.jsonl",
+}
+
+var fsyncIntervalFlag = &cli.DurationFlag{
+	Name:  "fsync-interval",
+	Usage: "Interval to sync the journal, or the output file without it, to the storage. 0 to sync on each write",
+	Value: time.Second,
 }
 
 var bufferSizeFlag = &cli.IntFlag{
@@ -68,6 +105,289 @@ var timeoutFlag = &cli.DurationFlag{
 	Value: 5 * time.Minute,
 }
 
+var endpointFlag = &cli.StringFlag{
+	Name:  "endpoint",
+	Usage: "Speech-to-Text API endpoint. Default is the endpoint of Google Cloud",
+}
+
+var insecureFlag = &cli.BoolFlag{
+	Name:  "insecure",
+	Usage: "Connect to the endpoint without TLS and credentials, e.g. for fake-server",
+	Value: false,
+}
+
+var saveAudioFlag = &cli.StringFlag{
+	Name:  "save-audio",
+	Usage: "WAV file path to save the audio. The final results are linked to the audio in <path without extension>.segments.jsonl",
+}
+
+var sinkFlag = &cli.StringSliceFlag{
+	Name:  "sink",
+	Usage: "Additional output of the final results possibly multiple: <format>[+<policy>]:<target>, where format is text, jsonl, srt or webhook, and policy is fail, retry or drop",
+}
+
+var sinkRetriesFlag = &cli.IntFlag{
+	Name:  "sink-retries",
+	Usage: "Number of the retries of the sinks with the retry policy",
+	Value: 3,
+}
+
+var sinkBackoffFlag = &cli.DurationFlag{
+	Name:  "sink-backoff",
+	Usage: "First backoff of the retries of the sinks, doubled on each retry",
+	Value: time.Second,
+}
+
+var onFinalExecFlag = &cli.StringFlag{
+	Name:  "on-final-exec",
+	Usage: "Command run by sh for each final result with the JSON on stdin. The result is dropped with a warning if it fails",
+}
+
+var webhookFlag = &cli.StringFlag{
+	Name:  "webhook",
+	Usage: "URL to post the final results in batches as JSON arrays. The results are queued in --webhook-queue until posted",
+}
+
+var webhookBatchSizeFlag = &cli.IntFlag{
+	Name:  "webhook-batch-size",
+	Usage: "Maximum number of the results in a post of --webhook",
+	Value: 10,
+}
+
+var webhookBatchIntervalFlag = &cli.DurationFlag{
+	Name:  "webhook-batch-interval",
+	Usage: "Interval to post the results of --webhook which do not fill a batch",
+	Value: 5 * time.Second,
+}
+
+var webhookQueueFlag = &cli.StringFlag{
+	Name:  "webhook-queue",
+	Usage: "File to queue the results of --webhook until posted, which are posted on the next run if left",
+	Value: "output/webhook-queue.jsonl",
+}
+
+var listenFlag = &cli.StringFlag{
+	Name:  "listen",
+	Usage: "Address to receive the audio from the network instead of stdin: tcp://host:port, ws://host:port/path or rtp://host:port?pt=96",
+}
+
+var listenTokenFlag = &cli.StringFlag{
+	Name:    "listen-token",
+	Usage:   "Token the clients must send in the header with --listen. Not supported by RTP",
+	EnvVars: []string{"VOICE_RECOGNITION_LISTEN_TOKEN"},
+}
+
+var httpFlag = &cli.StringFlag{
+	Name:  "http",
+	Usage: "Address to serve the live transcript viewer for browsers, e.g. :8080. Disabled if empty",
+}
+
+var tuiFlag = &cli.BoolFlag{
+	Name:  "tui",
+	Usage: "Show the session on a full screen of the terminal. Keys: p pauses or resumes, m inserts a marker, q quits",
+}
+
+var metricsAddrFlag = &cli.StringFlag{
+	Name:  "metrics-addr",
+	Usage: "Address to serve Prometheus metrics on /metrics, e.g. 127.0.0.1:9090. Disabled if empty",
+}
+
+var budgetFlag = &cli.Float64Flag{
+	Name:  "budget",
+	Usage: "Maximum cost in dollars of Google per session. Google stops when the projected cost exceeds it, which falls back to Vosk with --backend failover. 0 means unlimited",
+	Value: 0,
+}
+
+var ledgerFlag = &cli.StringFlag{
+	Name:  "ledger",
+	Usage: "Ledger file path to append the cost of Google per session. Disabled if empty",
+	Value: "output/ledger.jsonl",
+}
+
+var minSpeakersFlag = &cli.IntFlag{
+	Name:  "min-speakers",
+	Usage: "Minimum number of the speakers told by the speaker diarization of Google. Used with --max-speakers",
+	Value: 1,
+}
+
+var maxSpeakersFlag = &cli.IntFlag{
+	Name:  "max-speakers",
+	Usage: "Maximum number of the speakers told by the speaker diarization of Google, if the model supports it. 0 disables the diarization",
+}
+
+var speakerNamesFlag = &cli.StringFlag{
+	Name:  "speaker-names",
+	Usage: `JSON file mapping the speaker labels of the diarization to the names like {"1": "Alice"}`,
+}
+
+var postProcessFlag = &cli.StringSliceFlag{
+	Name:  "postprocess",
+	Usage: "Processors applied to the results in order, from " + strings.Join(postprocess.Names, ", "),
+}
+
+var fillersFlag = &cli.StringSliceFlag{
+	Name:  "fillers",
+	Usage: "Fillers removed by the fillers processor. The built-in fillers if empty",
+}
+
+var dictionaryFlag = &cli.StringFlag{
+	Name:  "dictionary",
+	Usage: `JSON file mapping the words to the replacements like {"ぐーぐる": "Google"}, used by the dictionary processor`,
+}
+
+var intervalFlag = &cli.DurationFlag{
+	Name:  "interval",
+	Usage: "Reconnect interval duration, at least 1m",
+	Value: time.Minute,
+}
+
+var voskModelFlag = &cli.StringFlag{
+	Name:  "model",
+	Usage: "path to model directory",
+	Value: "model",
+}
+
+//
+// Compare flags
+//
+
+var compareBackendAFlag = &cli.StringFlag{
+	Name:  "backend-a",
+	Usage: "Recognition backend name used as the reference",
+	Value: "google",
+}
+
+var compareBackendBFlag = &cli.StringFlag{
+	Name:  "backend-b",
+	Usage: "Recognition backend name compared with the reference",
+	Value: "vosk",
+}
+
+var reportFlag = &cli.StringFlag{
+	Name:  "report",
+	Usage: "Report file path. HTML if it ends with .html, Markdown otherwise",
+	Value: fmt.Sprintf("output/%d-report.md", time.Now().Unix()),
+}
+
+var alignToleranceFlag = &cli.DurationFlag{
+	Name:  "align-tolerance",
+	Usage: "Maximum difference of the end of the segments regarded as the same sentence",
+	Value: 2 * time.Second,
+}
+
+//
+// Eval flags
+//
+
+var manifestFlag = &cli.StringFlag{
+	Name:     "manifest",
+	Usage:    `Manifest file path. Each line is {"audio": "a.wav", "reference": "a.txt"}`,
+	Required: true,
+}
+
+var baselineFlag = &cli.StringFlag{
+	Name:  "baseline",
+	Usage: "Report JSON file path saved by --save to compare with",
+}
+
+var saveFlag = &cli.StringFlag{
+	Name:  "save",
+	Usage: "Report JSON file path to save",
+}
+
+var speedFlag = &cli.Float64Flag{
+	Name:  "speed",
+	Usage: "Speed of sending audio relative to real time. 0 sends audio as fast as possible",
+	Value: 1,
+}
+
+var trailingSilenceFlag = &cli.DurationFlag{
+	Name:  "trailing-silence",
+	Usage: "Duration of silence sent after the audio to finalize the last sentence",
+	Value: 3 * time.Second,
+}
+
+var settleFlag = &cli.DurationFlag{
+	Name:  "settle",
+	Usage: "Duration without results to finish the transcription after the audio is sent",
+	Value: 5 * time.Second,
+}
+
+//
+// Replay flags
+//
+
+var inputFlag = &cli.StringFlag{
+	Name:     "input",
+	Usage:    "Audio file path to replay. WAV if it ends with .wav, raw LINEAR16 otherwise",
+	Required: true,
+}
+
+var eventsFlag = &cli.StringFlag{
+	Name:  "events",
+	Usage: `Events file path to inject. Each line is {"at": 10, "gap": 5} or {"at": 20, "stall": 70} in seconds of the audio`,
+}
+
+//
+// Serve flags
+//
+
+var serveAddrFlag = &cli.StringFlag{
+	Name:  "addr",
+	Usage: "Address to serve the API on",
+	Value: "127.0.0.1:8000",
+}
+
+var backendsFlag = &cli.StringSliceFlag{
+	Name:  "backends",
+	Usage: "Backend names the sessions can select. Only --backend if empty",
+}
+
+var maxSessionsFlag = &cli.IntFlag{
+	Name:  "max-sessions",
+	Usage: "Maximum number of the running sessions",
+	Value: 4,
+}
+
+var maxSessionDurationFlag = &cli.DurationFlag{
+	Name:  "max-session-duration",
+	Usage: "Maximum duration of a session. 0 means unlimited",
+	Value: 0,
+}
+
+var outputDirFlag = &cli.StringFlag{
+	Name:  "output-dir",
+	Usage: "Directory to write the final results of each session to <id>.txt",
+	Value: "output/sessions",
+}
+
+//
+// Fake server flags
+//
+
+var addrFlag = &cli.StringFlag{
+	Name:  "addr",
+	Usage: "Address to listen on",
+	Value: "127.0.0.1:50051",
+}
+
+var scriptFlag = &cli.StringFlag{
+	Name:  "script",
+	Usage: `Script file path. Each line is {"start": 0.5, "end": 2.0, "text": "..."} in seconds of the audio`,
+}
+
+var maxStreamDurationFlag = &cli.DurationFlag{
+	Name:  "max-stream-duration",
+	Usage: "Maximum duration of the audio in a stream",
+	Value: fakespeech.DefaultMaxStreamDuration,
+}
+
+var operationDelayFlag = &cli.DurationFlag{
+	Name:  "operation-delay",
+	Usage: "Duration until the long-running operations are done",
+	Value: 0,
+}
+
 //
 // Phrase set flags
 //
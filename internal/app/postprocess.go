@@ -0,0 +1,89 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/hekt/voice-recognition/internal/postprocess"
+	"github.com/hekt/voice-recognition/internal/punctuator"
+	"github.com/hekt/voice-recognition/internal/punctuator/mecab"
+	"github.com/urfave/cli/v2"
+)
+
+// buildPostProcessOptions builds the resources of the processors of the
+// results from the command line. cleanup must be called after the processors
+// are used.
+func buildPostProcessOptions(cCtx *cli.Context) (options postprocess.Options, cleanup func(), err error) {
+	var dictionary map[string]string
+	if path := cCtx.String(dictionaryFlag.Name); path != "" {
+		dictionary, err = postprocess.LoadDictionary(path)
+		if err != nil {
+			return postprocess.Options{}, nil, err
+		}
+	}
+
+	p := &lazyPunctuator{}
+	return postprocess.Options{
+		Punctuator: p,
+		Fillers:    cCtx.StringSlice(fillersFlag.Name),
+		Dictionary: dictionary,
+	}, p.close, nil
+}
+
+// checkPostProcess returns an error if the processors cannot be used with the
+// backend. The punctuation processor is rejected for the backends which
+// punctuate the results by themselves, e.g. Vosk, not to run MeCab twice.
+func checkPostProcess(cCtx *cli.Context, backend string, names []string) error {
+	if !slices.Contains(names, postprocess.NamePunctuation) {
+		return nil
+	}
+	if backend == multichannelBackendOption.name {
+		backend = cCtx.String("channel-backend")
+	}
+	option, err := findBackendOption(backend)
+	if err != nil {
+		return err
+	}
+	if option.punctuates {
+		return fmt.Errorf("%s cannot be used with the %s backend, which punctuates the results by itself", postprocess.NamePunctuation, backend)
+	}
+	return nil
+}
+
+var _ punctuator.PunctuatorInterface = (*lazyPunctuator)(nil)
+
+// lazyPunctuator creates the MeCab punctuator on the first use, so that MeCab
+// is not loaded unless the punctuation processor is used. It is safe for
+// concurrent use since the sessions of serve share it.
+type lazyPunctuator struct {
+	mu         sync.Mutex
+	punctuator *mecab.MecabPunctuator
+	cleanup    func()
+}
+
+func (p *lazyPunctuator) Punctuate(sentence string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.punctuator == nil {
+		punctuator, cleanup, err := buildMecabPunctuator()
+		if err != nil {
+			return "", err
+		}
+		p.punctuator = punctuator
+		p.cleanup = cleanup
+	}
+	return p.punctuator.Punctuate(sentence)
+}
+
+func (p *lazyPunctuator) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cleanup != nil {
+		p.cleanup()
+	}
+	p.punctuator = nil
+	p.cleanup = nil
+}
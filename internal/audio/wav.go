@@ -0,0 +1,112 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+// WAVHeaderSize is the size of the header written by WAVHeader.
+const WAVHeaderSize = 44
+
+// WAVHeader returns the header of a WAV file which contains dataSize bytes of
+// audio in the format of the pipeline.
+func WAVHeader(dataSize int) []byte {
+	h := make([]byte, 0, WAVHeaderSize)
+	h = append(h, "RIFF"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(WAVHeaderSize-8+dataSize))
+	h = append(h, "WAVE"...)
+
+	h = append(h, "fmt "...)
+	h = binary.LittleEndian.AppendUint32(h, 16) // size of fmt chunk
+	h = binary.LittleEndian.AppendUint16(h, 1)  // PCM
+	h = binary.LittleEndian.AppendUint16(h, 1)  // channels
+	h = binary.LittleEndian.AppendUint32(h, model.SampleRate)
+	h = binary.LittleEndian.AppendUint32(h, model.BytesPerSecond)
+	h = binary.LittleEndian.AppendUint16(h, model.BytesPerSample) // block align
+	h = binary.LittleEndian.AppendUint16(h, model.BytesPerSample*8)
+
+	h = append(h, "data"...)
+	h = binary.LittleEndian.AppendUint32(h, uint32(dataSize))
+
+	return h
+}
+
+// EncodeWAV returns a WAV file which contains the audio.
+func EncodeWAV(pcm []byte) []byte {
+	return append(WAVHeader(len(pcm)), pcm...)
+}
+
+// LoadFile returns the audio in the file, which is WAV if the path ends with
+// .wav and raw LINEAR16 otherwise.
+func LoadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio: %w", err)
+	}
+	if !strings.EqualFold(filepath.Ext(path), ".wav") {
+		return data, nil
+	}
+
+	pcm, err := DecodeWAV(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return pcm, nil
+}
+
+// DecodeWAV returns the audio in the WAV file.
+// The audio must be in the format of the pipeline.
+func DecodeWAV(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errors.New("not a WAV file")
+	}
+
+	formatChecked := false
+	for rest := data[12:]; len(rest) >= 8; {
+		id := string(rest[0:4])
+		size := int(binary.LittleEndian.Uint32(rest[4:8]))
+		rest = rest[8:]
+		if size > len(rest) {
+			// the size of the data chunk is not written by some streaming encoders.
+			if id != "data" {
+				return nil, fmt.Errorf("chunk %q is truncated", id)
+			}
+			size = len(rest)
+		}
+		chunk := rest[:size]
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, errors.New("fmt chunk is too short")
+			}
+			format := binary.LittleEndian.Uint16(chunk[0:2])
+			channels := binary.LittleEndian.Uint16(chunk[2:4])
+			sampleRate := binary.LittleEndian.Uint32(chunk[4:8])
+			bitsPerSample := binary.LittleEndian.Uint16(chunk[14:16])
+			if format != 1 || channels != 1 || sampleRate != model.SampleRate || bitsPerSample != model.BytesPerSample*8 {
+				return nil, fmt.Errorf(
+					"unsupported format: format=%d, channels=%d, sample rate=%d, bits per sample=%d",
+					format, channels, sampleRate, bitsPerSample,
+				)
+			}
+			formatChecked = true
+		case "data":
+			if !formatChecked {
+				return nil, errors.New("data chunk appears before fmt chunk")
+			}
+			return chunk, nil
+		}
+
+		// chunks are aligned to 2 bytes.
+		rest = rest[min(size+size%2, len(rest)):]
+	}
+
+	return nil, errors.New("data chunk not found")
+}
@@ -0,0 +1,150 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVHeader(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		got := WAVHeader(32000)
+
+		if len(got) != WAVHeaderSize {
+			t.Fatalf("len(WAVHeader()) = %d, want %d", len(got), WAVHeaderSize)
+		}
+		for _, tt := range []struct {
+			offset int
+			want   string
+		}{
+			{0, "RIFF"},
+			{8, "WAVE"},
+			{12, "fmt "},
+			{36, "data"},
+		} {
+			if g := string(got[tt.offset : tt.offset+4]); g != tt.want {
+				t.Errorf("WAVHeader()[%d:%d] = %q, want %q", tt.offset, tt.offset+4, g, tt.want)
+			}
+		}
+		for _, tt := range []struct {
+			name   string
+			offset int
+			want   uint32
+		}{
+			{"riff size", 4, 36 + 32000},
+			{"sample rate", 24, 16000},
+			{"byte rate", 28, 32000},
+			{"data size", 40, 32000},
+		} {
+			if g := binary.LittleEndian.Uint32(got[tt.offset:]); g != tt.want {
+				t.Errorf("%s = %d, want %d", tt.name, g, tt.want)
+			}
+		}
+	})
+}
+
+func TestEncodeWAV(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		pcm := []byte{1, 2, 3, 4}
+		got := EncodeWAV(pcm)
+
+		if !bytes.Equal(got[:WAVHeaderSize], WAVHeader(len(pcm))) {
+			t.Errorf("EncodeWAV() header = %v, want %v", got[:WAVHeaderSize], WAVHeader(len(pcm)))
+		}
+		if !bytes.Equal(got[WAVHeaderSize:], pcm) {
+			t.Errorf("EncodeWAV() data = %v, want %v", got[WAVHeaderSize:], pcm)
+		}
+	})
+}
+
+func TestDecodeWAV(t *testing.T) {
+	pcm := []byte{1, 2, 3, 4}
+
+	withChannels := func(channels uint16) []byte {
+		data := EncodeWAV(pcm)
+		binary.LittleEndian.PutUint16(data[22:], channels)
+		return data
+	}
+	withListChunk := func() []byte {
+		data := EncodeWAV(pcm)
+		list := append([]byte("LIST"), 3, 0, 0, 0, 'a', 'b', 'c', 0)
+		return append(append(append([]byte{}, data[:36]...), list...), data[36:]...)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			data:    EncodeWAV(pcm),
+			want:    pcm,
+			wantErr: false,
+		},
+		{
+			name:    "with other chunk",
+			data:    withListChunk(),
+			want:    pcm,
+			wantErr: false,
+		},
+		{
+			name:    "stereo",
+			data:    withChannels(2),
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "not wav",
+			data:    pcm,
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeWAV(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DecodeWAV() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("DecodeWAV() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	pcm := []byte{1, 2, 3, 4}
+	dir := t.TempDir()
+	wavPath := filepath.Join(dir, "a.WAV")
+	rawPath := filepath.Join(dir, "a.raw")
+	if err := os.WriteFile(wavPath, EncodeWAV(pcm), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rawPath, pcm, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{wavPath, rawPath} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			got, err := LoadFile(path)
+			if err != nil {
+				t.Fatalf("LoadFile() error = %v", err)
+			}
+			if !bytes.Equal(got, pcm) {
+				t.Errorf("LoadFile() = %v, want %v", got, pcm)
+			}
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		if _, err := LoadFile(filepath.Join(dir, "missing.wav")); err == nil {
+			t.Error("LoadFile() error = nil, want an error")
+		}
+	})
+}
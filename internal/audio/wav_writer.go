@@ -0,0 +1,90 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+// maxWAVDataSize is the maximum size of the audio in a WAV file,
+// since the sizes in the header are 32 bits.
+const maxWAVDataSize = math.MaxUint32 - (WAVHeaderSize - 8)
+
+var _ io.WriteCloser = (*WAVWriter)(nil)
+
+// WAVWriter writes the audio in the format of the pipeline to a WAV file as it comes.
+// The sizes in the header are written on Close. Until then they are the
+// maximum values, so the audio is readable by most players even if the
+// process is killed.
+type WAVWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	// size is the bytes of the audio written.
+	size int64
+}
+
+// CreateWAV creates the WAV file, truncating it if it exists.
+func CreateWAV(path string) (*WAVWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WAV file: %w", err)
+	}
+	if _, err := f.Write(WAVHeader(maxWAVDataSize)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write WAV header: %w", err)
+	}
+
+	return &WAVWriter{file: f}, nil
+}
+
+func (w *WAVWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, errors.New("WAV file is closed")
+	}
+	if w.size+int64(len(p)) > maxWAVDataSize {
+		return 0, errors.New("audio exceeds the maximum size of WAV file")
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err != nil {
+		return n, fmt.Errorf("failed to write WAV file: %w", err)
+	}
+	return n, nil
+}
+
+// Samples returns the number of the samples written so far.
+func (w *WAVWriter) Samples() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size / model.BytesPerSample
+}
+
+// Close writes the sizes in the header and closes the file.
+func (w *WAVWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	f := w.file
+	w.file = nil
+
+	if _, err := f.WriteAt(WAVHeader(int(w.size)), 0); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to finalize WAV header: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close WAV file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVWriter(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "audio.wav")
+		w, err := CreateWAV(path)
+		if err != nil {
+			t.Fatalf("CreateWAV() error = %v", err)
+		}
+
+		pcm := []byte{1, 2, 3, 4, 5, 6}
+		if _, err := w.Write(pcm[:4]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if _, err := w.Write(pcm[4:]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if got := w.Samples(); got != 3 {
+			t.Errorf("Samples() = %d, want 3", got)
+		}
+
+		// the audio is readable before the header is finalized.
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read WAV file: %v", err)
+		}
+		if got, err := DecodeWAV(b); err != nil || !bytes.Equal(got, pcm) {
+			t.Errorf("DecodeWAV() before Close = %v, %v, want %v", got, err, pcm)
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		b, err = os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read WAV file: %v", err)
+		}
+		if !bytes.Equal(b, EncodeWAV(pcm)) {
+			t.Errorf("WAV file = %v, want %v", b, EncodeWAV(pcm))
+		}
+
+		if _, err := w.Write(pcm); err == nil {
+			t.Error("Write() after Close error = nil, want an error")
+		}
+		if err := w.Close(); err != nil {
+			t.Errorf("second Close() error = %v", err)
+		}
+	})
+
+	t.Run("header before close", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "audio.wav")
+		w, err := CreateWAV(path)
+		if err != nil {
+			t.Fatalf("CreateWAV() error = %v", err)
+		}
+		defer w.Close()
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read WAV file: %v", err)
+		}
+		if got := binary.LittleEndian.Uint32(b[4:]); got != 0xFFFFFFFF {
+			t.Errorf("riff size = %#x, want %#x", got, 0xFFFFFFFF)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+// Package broadcast sends the events of a live transcript to the subscribers,
+// such as the browsers of the viewer and the WebSocket clients of the server.
+package broadcast
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Buffer is the number of the events kept for a slow subscriber.
+// The subscriber is disconnected when it falls behind more than this.
+const Buffer = 64
+
+// Broadcaster sends the events to the subscribers without blocking.
+type Broadcaster[E any] struct {
+	// subscriber names the subscribers in the log, e.g. "Viewer: browser".
+	subscriber string
+
+	mu          sync.Mutex
+	subscribers map[chan E]struct{}
+	closed      bool
+}
+
+func New[E any](subscriber string) *Broadcaster[E] {
+	return &Broadcaster[E]{
+		subscriber:  subscriber,
+		subscribers: map[chan E]struct{}{},
+	}
+}
+
+// Subscribe returns the channel of the following events, which is closed when
+// the broadcaster is closed or the subscriber is too slow.
+func (b *Broadcaster[E]) Subscribe() (events <-chan E, unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan E, Buffer)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Send sends the event to the subscribers. A subscriber whose buffer is full
+// is disconnected.
+func (b *Broadcaster[E]) Send(e E) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			slog.Warn(fmt.Sprintf("%s is too slow, disconnecting", b.subscriber))
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close disconnects the subscribers. The following subscribers get a closed channel.
+func (b *Broadcaster[E]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		close(ch)
+	}
+	b.subscribers = map[chan E]struct{}{}
+	b.closed = true
+}
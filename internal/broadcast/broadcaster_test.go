@@ -0,0 +1,43 @@
+package broadcast
+
+import "testing"
+
+func TestBroadcaster_Send(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		b := New[string]("test")
+		events, unsubscribe := b.Subscribe()
+		defer unsubscribe()
+
+		b.Send("a")
+		if got := <-events; got != "a" {
+			t.Errorf("event = %q, want %q", got, "a")
+		}
+
+		b.Close()
+		if _, ok := <-events; ok {
+			t.Error("events are not closed")
+		}
+		events, _ = b.Subscribe()
+		if _, ok := <-events; ok {
+			t.Error("events subscribed after close are not closed")
+		}
+	})
+
+	t.Run("slow subscriber", func(t *testing.T) {
+		b := New[string]("test")
+		events, unsubscribe := b.Subscribe()
+		defer unsubscribe()
+
+		for range Buffer + 1 {
+			b.Send("a")
+		}
+
+		n := 0
+		for range events {
+			n++
+		}
+		if n != Buffer {
+			t.Errorf("received %d events, want %d", n, Buffer)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+// Package eval evaluates the accuracy of a backend against reference transcripts.
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hekt/voice-recognition/internal/audio"
+)
+
+// Entry is a pair of an audio file and its reference transcript.
+type Entry struct {
+	// Audio is the path of the audio file. WAV if it ends with .wav,
+	// raw LINEAR16 otherwise.
+	Audio string `json:"audio"`
+	// Reference is the path of the reference transcript.
+	Reference string `json:"reference,omitempty"`
+	// Text is the reference transcript. It is used if Reference is empty.
+	Text string `json:"text,omitempty"`
+}
+
+// ReadManifest reads the manifest which has an entry in JSON for each line.
+// Relative paths in the manifest are resolved against the directory of the manifest.
+func ReadManifest(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", n, err)
+		}
+		if e.Audio == "" {
+			return nil, fmt.Errorf("audio must be specified in line %d", n)
+		}
+		if e.Reference == "" && e.Text == "" {
+			return nil, fmt.Errorf("reference or text must be specified in line %d", n)
+		}
+		e.Audio = resolve(e.Audio)
+		e.Reference = resolve(e.Reference)
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("manifest has no entries")
+	}
+
+	return entries, nil
+}
+
+// LoadAudio returns the LINEAR16 audio of the entry.
+func (e Entry) LoadAudio() ([]byte, error) {
+	return audio.LoadFile(e.Audio)
+}
+
+// LoadReference returns the reference transcript of the entry.
+func (e Entry) LoadReference() (string, error) {
+	if e.Reference == "" {
+		return e.Text, nil
+	}
+
+	data, err := os.ReadFile(e.Reference)
+	if err != nil {
+		return "", fmt.Errorf("failed to read reference: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,128 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/audio"
+)
+
+func TestReadManifest(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []Entry
+		wantErr bool
+	}{
+		{
+			name: "success",
+			content: `{"audio":"a.wav","reference":"a.txt"}
+# comment
+
+{"audio":"/abs/b.raw","text":"こんにちは"}
+`,
+			want: []Entry{
+				{Audio: filepath.Join(dir, "a.wav"), Reference: filepath.Join(dir, "a.txt")},
+				{Audio: "/abs/b.raw", Text: "こんにちは"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "no audio",
+			content: `{"text":"こんにちは"}`,
+			wantErr: true,
+		},
+		{
+			name:    "no reference",
+			content: `{"audio":"a.wav"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			content: `{"audio":`,
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			content: "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := write("manifest.jsonl", tt.content)
+
+			got, err := ReadManifest(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadManifest() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("ReadManifest() (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEntry_LoadAudio(t *testing.T) {
+	dir := t.TempDir()
+	pcm := []byte{1, 2, 3, 4}
+	if err := os.WriteFile(filepath.Join(dir, "a.wav"), audio.EncodeWAV(pcm), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.raw"), pcm, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a.wav", "a.raw"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := Entry{Audio: filepath.Join(dir, name)}.LoadAudio()
+			if err != nil {
+				t.Fatalf("Entry.LoadAudio() error = %v", err)
+			}
+			if diff := cmp.Diff(got, pcm); diff != "" {
+				t.Errorf("Entry.LoadAudio() (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEntry_LoadReference(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("ファイル"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		entry Entry
+		want  string
+	}{
+		{name: "file", entry: Entry{Reference: path, Text: "テキスト"}, want: "ファイル"},
+		{name: "text", entry: Entry{Text: "テキスト"}, want: "テキスト"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.entry.LoadReference()
+			if err != nil {
+				t.Fatalf("Entry.LoadReference() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Entry.LoadReference() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// FileResult is the result of an entry in the manifest.
+type FileResult struct {
+	Audio      string `json:"audio"`
+	Reference  string `json:"reference"`
+	Hypothesis string `json:"hypothesis"`
+	Scores     Scores `json:"scores"`
+}
+
+// Report is the result of the evaluation.
+// It is saved as JSON to be compared with later evaluations.
+type Report struct {
+	Backend string       `json:"backend"`
+	Files   []FileResult `json:"files"`
+	Total   Scores       `json:"total"`
+}
+
+// Evaluate transcribes the audio of the entries and scores them.
+func Evaluate(
+	ctx context.Context,
+	backend string,
+	entries []Entry,
+	runner RunnerInterface,
+	tokenize Tokenizer,
+) (*Report, error) {
+	report := &Report{Backend: backend, Files: make([]FileResult, 0, len(entries))}
+
+	for _, e := range entries {
+		slog.Debug("Eval: transcribing", "audio", e.Audio)
+
+		pcm, err := e.LoadAudio()
+		if err != nil {
+			return nil, err
+		}
+		reference, err := e.LoadReference()
+		if err != nil {
+			return nil, err
+		}
+		hypothesis, err := runner.Transcribe(ctx, pcm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transcribe %s: %w", e.Audio, err)
+		}
+		scores, err := NewScores(reference, hypothesis, tokenize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to score %s: %w", e.Audio, err)
+		}
+
+		report.Files = append(report.Files, FileResult{
+			Audio:      e.Audio,
+			Reference:  reference,
+			Hypothesis: hypothesis,
+			Scores:     scores,
+		})
+		report.Total = report.Total.add(scores)
+	}
+
+	return report, nil
+}
+
+// ReadReport reads the report saved by WriteJSON.
+func ReadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse report: %w", err)
+	}
+	return &report, nil
+}
+
+// WriteJSON writes the report as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// WriteMarkdown writes the rates of each file and the total as a Markdown table.
+// If baseline is not nil, the differences from it are written in percentage points.
+func (r *Report) WriteMarkdown(w io.Writer, baseline *Report) error {
+	var baselineFiles map[string]Scores
+	if baseline != nil {
+		baselineFiles = make(map[string]Scores, len(baseline.Files))
+		for _, f := range baseline.Files {
+			baselineFiles[f.Audio] = f.Scores
+		}
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Evaluation of %s\n\n", r.Backend)
+	if baseline != nil {
+		fmt.Fprintf(&b, "Compared with the baseline of %s in percentage points.\n\n", baseline.Backend)
+	}
+	b.WriteString("| File | CER | WER | CER (normalized) | WER (normalized) |\n")
+	b.WriteString("| --- | ---: | ---: | ---: | ---: |\n")
+
+	writeRow := func(name string, scores Scores, base *Scores) {
+		cell := func(s Score, pick func(Scores) Score) string {
+			rate := fmt.Sprintf("%.2f%%", s.Rate()*100)
+			if baseline == nil {
+				return rate
+			}
+			if base == nil {
+				return rate + " (new)"
+			}
+			return fmt.Sprintf("%s (%+.2f)", rate, (s.Rate()-pick(*base).Rate())*100)
+		}
+		fmt.Fprintf(
+			&b,
+			"| %s | %s | %s | %s | %s |\n",
+			strings.ReplaceAll(name, "|", `\|`),
+			cell(scores.CER, func(s Scores) Score { return s.CER }),
+			cell(scores.WER, func(s Scores) Score { return s.WER }),
+			cell(scores.NormalizedCER, func(s Scores) Score { return s.NormalizedCER }),
+			cell(scores.NormalizedWER, func(s Scores) Score { return s.NormalizedWER }),
+		)
+	}
+
+	for _, f := range r.Files {
+		var base *Scores
+		if s, ok := baselineFiles[f.Audio]; ok {
+			base = &s
+		}
+		writeRow(f.Audio, f.Scores, base)
+	}
+	var base *Scores
+	if baseline != nil {
+		base = &baseline.Total
+	}
+	writeRow("**Total**", r.Total, base)
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	writeAudio := func(name string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte{1, 2}, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	entries := []Entry{
+		{Audio: writeAudio("a.raw"), Text: "こんにちは"},
+		{Audio: writeAudio("b.raw"), Text: "世界"},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		hypotheses := []string{"こんにちわ", "世界"}
+		runner := &RunnerInterfaceMock{}
+		runner.TranscribeFunc = func(ctx context.Context, pcm []byte) (string, error) {
+			return hypotheses[len(runner.TranscribeCalls())-1], nil
+		}
+
+		got, err := Evaluate(context.Background(), "vosk", entries, runner, fields)
+		if err != nil {
+			t.Fatalf("Evaluate() error = %v", err)
+		}
+
+		want := &Report{
+			Backend: "vosk",
+			Files: []FileResult{
+				{
+					Audio:      entries[0].Audio,
+					Reference:  "こんにちは",
+					Hypothesis: "こんにちわ",
+					Scores: Scores{
+						CER:           Score{Errors: 1, Length: 5},
+						WER:           Score{Errors: 1, Length: 1},
+						NormalizedCER: Score{Errors: 1, Length: 5},
+						NormalizedWER: Score{Errors: 1, Length: 1},
+					},
+				},
+				{
+					Audio:      entries[1].Audio,
+					Reference:  "世界",
+					Hypothesis: "世界",
+					Scores: Scores{
+						CER:           Score{Errors: 0, Length: 2},
+						WER:           Score{Errors: 0, Length: 1},
+						NormalizedCER: Score{Errors: 0, Length: 2},
+						NormalizedWER: Score{Errors: 0, Length: 1},
+					},
+				},
+			},
+			Total: Scores{
+				CER:           Score{Errors: 1, Length: 7},
+				WER:           Score{Errors: 1, Length: 2},
+				NormalizedCER: Score{Errors: 1, Length: 7},
+				NormalizedWER: Score{Errors: 1, Length: 2},
+			},
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("Evaluate() (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("transcription error", func(t *testing.T) {
+		runner := &RunnerInterfaceMock{
+			TranscribeFunc: func(ctx context.Context, pcm []byte) (string, error) {
+				return "", errors.New("error")
+			},
+		}
+
+		if _, err := Evaluate(context.Background(), "vosk", entries, runner, fields); err == nil {
+			t.Error("Evaluate() error = nil, want an error")
+		}
+	})
+}
+
+func TestReport_WriteMarkdown(t *testing.T) {
+	report := &Report{
+		Backend: "vosk",
+		Files: []FileResult{
+			{Audio: "a.wav", Scores: Scores{CER: Score{Errors: 1, Length: 10}}},
+			{Audio: "b.wav", Scores: Scores{CER: Score{Errors: 1, Length: 4}}},
+		},
+		Total: Scores{CER: Score{Errors: 2, Length: 14}},
+	}
+
+	t.Run("without baseline", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.WriteMarkdown(&buf, nil); err != nil {
+			t.Fatalf("Report.WriteMarkdown() error = %v", err)
+		}
+
+		want := strings.Join([]string{
+			"# Evaluation of vosk",
+			"",
+			"| File | CER | WER | CER (normalized) | WER (normalized) |",
+			"| --- | ---: | ---: | ---: | ---: |",
+			"| a.wav | 10.00% | 0.00% | 0.00% | 0.00% |",
+			"| b.wav | 25.00% | 0.00% | 0.00% | 0.00% |",
+			"| **Total** | 14.29% | 0.00% | 0.00% | 0.00% |",
+			"",
+		}, "\n")
+		if diff := cmp.Diff(buf.String(), want); diff != "" {
+			t.Errorf("Report.WriteMarkdown() (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("with baseline", func(t *testing.T) {
+		baseline := &Report{
+			Backend: "google",
+			Files: []FileResult{
+				{Audio: "a.wav", Scores: Scores{CER: Score{Errors: 2, Length: 10}}},
+			},
+			Total: Scores{CER: Score{Errors: 2, Length: 10}},
+		}
+
+		var buf bytes.Buffer
+		if err := report.WriteMarkdown(&buf, baseline); err != nil {
+			t.Fatalf("Report.WriteMarkdown() error = %v", err)
+		}
+
+		want := strings.Join([]string{
+			"# Evaluation of vosk",
+			"",
+			"Compared with the baseline of google in percentage points.",
+			"",
+			"| File | CER | WER | CER (normalized) | WER (normalized) |",
+			"| --- | ---: | ---: | ---: | ---: |",
+			"| a.wav | 10.00% (-10.00) | 0.00% (+0.00) | 0.00% (+0.00) | 0.00% (+0.00) |",
+			"| b.wav | 25.00% (new) | 0.00% (new) | 0.00% (new) | 0.00% (new) |",
+			"| **Total** | 14.29% (-5.71) | 0.00% (+0.00) | 0.00% (+0.00) | 0.00% (+0.00) |",
+			"",
+		}, "\n")
+		if diff := cmp.Diff(buf.String(), want); diff != "" {
+			t.Errorf("Report.WriteMarkdown() (-got +want):\n%s", diff)
+		}
+	})
+}
+
+func TestReadReport(t *testing.T) {
+	report := &Report{
+		Backend: "vosk",
+		Files: []FileResult{
+			{Audio: "a.wav", Reference: "a", Hypothesis: "b", Scores: Scores{CER: Score{Errors: 1, Length: 1}}},
+		},
+		Total: Scores{CER: Score{Errors: 1, Length: 1}},
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("Report.WriteJSON() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadReport(path)
+	if err != nil {
+		t.Fatalf("ReadReport() error = %v", err)
+	}
+	if diff := cmp.Diff(got, report); diff != "" {
+		t.Errorf("ReadReport() (-got +want):\n%s", diff)
+	}
+}
@@ -0,0 +1,177 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"golang.org/x/sync/errgroup"
+)
+
+//go:generate moq -rm -out runner_mock.go . RunnerInterface
+type RunnerInterface interface {
+	// Transcribe returns the transcript of the whole audio.
+	Transcribe(ctx context.Context, pcm []byte) (string, error)
+}
+
+var _ RunnerInterface = (*Runner)(nil)
+
+// errSettled stops the runner when no result is received for a while after
+// all audio is sent.
+var errSettled = errors.New("settled")
+
+// Runner transcribes a whole audio by a backend.
+//
+// The pipeline has no end of stream, so silence is sent after the audio to
+// make the backend finalize the last sentence, and the transcription is
+// finished when no result is received for settle after that.
+//
+// The backend is built for each audio, so that the state of the backend, such
+// as the words kept by Vosk, does not leak into the transcript of the next one.
+type Runner struct {
+	newBackend model.RecognizerCoreFactoryBuilder
+	chunkSize  int
+	// speed is the speed of sending audio relative to real time.
+	// 0 means sending audio as fast as possible.
+	speed           float64
+	trailingSilence time.Duration
+	settle          time.Duration
+}
+
+func NewRunner(
+	newBackend model.RecognizerCoreFactoryBuilder,
+	chunkSize int,
+	speed float64,
+	trailingSilence time.Duration,
+	settle time.Duration,
+) (*Runner, error) {
+	if newBackend == nil {
+		return nil, errors.New("backend builder must be specified")
+	}
+	if chunkSize <= 0 {
+		return nil, errors.New("chunk size must be positive")
+	}
+	if speed < 0 {
+		return nil, errors.New("speed must not be negative")
+	}
+	if trailingSilence < 0 {
+		return nil, errors.New("trailing silence must not be negative")
+	}
+	if settle <= 0 {
+		return nil, errors.New("settle must be positive")
+	}
+
+	return &Runner{
+		newBackend:      newBackend,
+		chunkSize:       chunkSize,
+		speed:           speed,
+		trailingSilence: trailingSilence,
+		settle:          settle,
+	}, nil
+}
+
+// Transcribe returns the final results for the audio joined by newlines.
+func (r *Runner) Transcribe(ctx context.Context, pcm []byte) (string, error) {
+	audioCh := make(chan []byte, 10)
+	resultCh := make(chan []*model.Result, 10)
+	sentCh := make(chan struct{})
+
+	newCore, cleanup, err := r.newBackend()
+	if err != nil {
+		return "", fmt.Errorf("failed to build backend: %w", err)
+	}
+	defer cleanup()
+
+	core, err := newCore(ctx, audioCh, resultCh)
+	if err != nil {
+		return "", fmt.Errorf("failed to create recognizer: %w", err)
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		if err := core.Start(ctx); err != nil {
+			return fmt.Errorf("error occured in recognizer: %w", err)
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		if err := r.send(ctx, audioCh, pcm); err != nil {
+			return err
+		}
+		close(sentCh)
+		return nil
+	})
+
+	var finals []string
+	eg.Go(func() error {
+		var timer *time.Timer
+		var settled <-chan time.Time
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-sentCh:
+				timer = time.NewTimer(r.settle)
+				settled = timer.C
+				sentCh = nil
+			case results := <-resultCh:
+				for _, result := range results {
+					if result.IsFinal && result.Transcript != "" {
+						finals = append(finals, result.Transcript)
+					}
+				}
+				if timer != nil {
+					timer.Reset(r.settle)
+				}
+			case <-settled:
+				return errSettled
+			}
+		}
+	})
+
+	if err := eg.Wait(); err != nil && !errors.Is(err, errSettled) {
+		return "", err
+	}
+
+	return strings.Join(finals, "\n"), nil
+}
+
+// send sends the audio followed by the trailing silence in chunks.
+func (r *Runner) send(ctx context.Context, audioCh chan<- []byte, pcm []byte) error {
+	silence := make([]byte, model.AudioBytes(r.trailingSilence))
+	data := append(append(make([]byte, 0, len(pcm)+len(silence)), pcm...), silence...)
+
+	start := time.Now()
+	for sent := 0; sent < len(data); {
+		n := min(r.chunkSize, len(data)-sent)
+
+		if r.speed > 0 {
+			at := start.Add(time.Duration(float64(model.AudioDuration(sent)) / r.speed))
+			if d := time.Until(at); d > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(d):
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case audioCh <- data[sent : sent+n]:
+		}
+		sent += n
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package eval
+
+import (
+	"context"
+	"sync"
+)
+
+// Ensure, that RunnerInterfaceMock does implement RunnerInterface.
+// If this is not the case, regenerate this file with moq.
+var _ RunnerInterface = &RunnerInterfaceMock{}
+
+// RunnerInterfaceMock is a mock implementation of RunnerInterface.
+//
+//	func TestSomethingThatUsesRunnerInterface(t *testing.T) {
+//
+//		// make and configure a mocked RunnerInterface
+//		mockedRunnerInterface := &RunnerInterfaceMock{
+//			TranscribeFunc: func(ctx context.Context, pcm []byte) (string, error) {
+//				panic("mock out the Transcribe method")
+//			},
+//		}
+//
+//		// use mockedRunnerInterface in code that requires RunnerInterface
+//		// and then make assertions.
+//
+//	}
+type RunnerInterfaceMock struct {
+	// TranscribeFunc mocks the Transcribe method.
+	TranscribeFunc func(ctx context.Context, pcm []byte) (string, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Transcribe holds details about calls to the Transcribe method.
+		Transcribe []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Pcm is the pcm argument value.
+			Pcm []byte
+		}
+	}
+	lockTranscribe sync.RWMutex
+}
+
+// Transcribe calls TranscribeFunc.
+func (mock *RunnerInterfaceMock) Transcribe(ctx context.Context, pcm []byte) (string, error) {
+	if mock.TranscribeFunc == nil {
+		panic("RunnerInterfaceMock.TranscribeFunc: method is nil but RunnerInterface.Transcribe was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Pcm []byte
+	}{
+		Ctx: ctx,
+		Pcm: pcm,
+	}
+	mock.lockTranscribe.Lock()
+	mock.calls.Transcribe = append(mock.calls.Transcribe, callInfo)
+	mock.lockTranscribe.Unlock()
+	return mock.TranscribeFunc(ctx, pcm)
+}
+
+// TranscribeCalls gets all the calls that were made to Transcribe.
+// Check the length with:
+//
+//	len(mockedRunnerInterface.TranscribeCalls())
+func (mock *RunnerInterfaceMock) TranscribeCalls() []struct {
+	Ctx context.Context
+	Pcm []byte
+} {
+	var calls []struct {
+		Ctx context.Context
+		Pcm []byte
+	}
+	mock.lockTranscribe.RLock()
+	calls = mock.calls.Transcribe
+	mock.lockTranscribe.RUnlock()
+	return calls
+}
@@ -0,0 +1,225 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+func TestNewRunner(t *testing.T) {
+	type args struct {
+		newBackend      model.RecognizerCoreFactoryBuilder
+		chunkSize       int
+		speed           float64
+		trailingSilence time.Duration
+		settle          time.Duration
+	}
+	baseArgs := args{
+		newBackend:      staticBackend(echoFactory),
+		chunkSize:       4096,
+		speed:           1,
+		trailingSilence: 3 * time.Second,
+		settle:          5 * time.Second,
+	}
+	tests := []struct {
+		name    string
+		args    func() args
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			args:    func() args { return baseArgs },
+			wantErr: false,
+		},
+		{
+			name: "nil builder",
+			args: func() args {
+				a := baseArgs
+				a.newBackend = nil
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero chunk size",
+			args: func() args {
+				a := baseArgs
+				a.chunkSize = 0
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative speed",
+			args: func() args {
+				a := baseArgs
+				a.speed = -1
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero settle",
+			args: func() args {
+				a := baseArgs
+				a.settle = 0
+				return a
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.args()
+			got, err := NewRunner(a.newBackend, a.chunkSize, a.speed, a.trailingSilence, a.settle)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRunner() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got == nil {
+				t.Errorf("NewRunner() = nil, want non-nil")
+			}
+		})
+	}
+}
+
+// staticBackend returns a builder which always builds newCore.
+func staticBackend(newCore model.RecognizerCoreFactory) model.RecognizerCoreFactoryBuilder {
+	return func() (model.RecognizerCoreFactory, func(), error) {
+		return newCore, func() {}, nil
+	}
+}
+
+// echoFactory creates a core which passes the non-silent audio as final results.
+func echoFactory(
+	_ context.Context,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (model.RecognizerCoreInterface, error) {
+	return &model.RecognizerCoreInterfaceMock{
+		StartFunc: func(ctx context.Context) error {
+			for {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case audio := <-audioCh:
+					if audio[0] == 0 {
+						continue
+					}
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case resultCh <- []*model.Result{
+						{Transcript: "...", IsFinal: false},
+						{Transcript: string(audio), IsFinal: true},
+					}:
+					}
+				}
+			}
+		},
+	}, nil
+}
+
+func TestRunner_Transcribe(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		r := &Runner{
+			newBackend:      staticBackend(echoFactory),
+			chunkSize:       2,
+			speed:           0,
+			trailingSilence: time.Second,
+			settle:          10 * time.Millisecond,
+		}
+
+		got, err := r.Transcribe(context.Background(), []byte("aabbcc"))
+		if err != nil {
+			t.Fatalf("Runner.Transcribe() error = %v", err)
+		}
+		if want := "aa\nbb\ncc"; got != want {
+			t.Errorf("Runner.Transcribe() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("paced", func(t *testing.T) {
+		r := &Runner{
+			newBackend: staticBackend(echoFactory),
+			chunkSize:  model.AudioBytes(50 * time.Millisecond),
+			// 100ms of audio is sent in 50ms.
+			speed:  2,
+			settle: 10 * time.Millisecond,
+		}
+
+		pcm := make([]byte, model.AudioBytes(100*time.Millisecond))
+		pcm[0] = 1
+
+		start := time.Now()
+		if _, err := r.Transcribe(context.Background(), pcm); err != nil {
+			t.Fatalf("Runner.Transcribe() error = %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+			t.Errorf("Runner.Transcribe() took %v, want at least 25ms", elapsed)
+		}
+	})
+
+	t.Run("recognizer error", func(t *testing.T) {
+		r := &Runner{
+			newBackend: staticBackend(func(
+				_ context.Context,
+				_ <-chan []byte,
+				_ chan<- []*model.Result,
+			) (model.RecognizerCoreInterface, error) {
+				return &model.RecognizerCoreInterfaceMock{
+					StartFunc: func(ctx context.Context) error {
+						return errors.New("error")
+					},
+				}, nil
+			}),
+			chunkSize: 2,
+			settle:    time.Second,
+		}
+
+		if _, err := r.Transcribe(context.Background(), []byte("aabb")); err == nil {
+			t.Error("Runner.Transcribe() error = nil, want an error")
+		}
+	})
+
+	t.Run("backend for each audio", func(t *testing.T) {
+		var built, cleaned int
+		r := &Runner{
+			newBackend: func() (model.RecognizerCoreFactory, func(), error) {
+				built++
+				return echoFactory, func() { cleaned++ }, nil
+			},
+			chunkSize: 2,
+			settle:    10 * time.Millisecond,
+		}
+
+		for _, pcm := range []string{"aa", "bb"} {
+			if _, err := r.Transcribe(context.Background(), []byte(pcm)); err != nil {
+				t.Fatalf("Runner.Transcribe() error = %v", err)
+			}
+		}
+		if built != 2 || cleaned != 2 {
+			t.Errorf("backend is built %d times and cleaned up %d times, want 2 and 2", built, cleaned)
+		}
+	})
+
+	t.Run("backend build error", func(t *testing.T) {
+		r := &Runner{
+			newBackend: func() (model.RecognizerCoreFactory, func(), error) {
+				return nil, nil, errors.New("error")
+			},
+			chunkSize: 2,
+			settle:    time.Second,
+		}
+
+		if _, err := r.Transcribe(context.Background(), []byte("aabb")); err == nil {
+			t.Error("Runner.Transcribe() error = nil, want an error")
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/hekt/voice-recognition/internal/metric"
+)
+
+// Tokenizer splits a transcript into words.
+type Tokenizer func(s string) ([]string, error)
+
+// Score is the number of errors against the length of the reference.
+// Scores are summed before the rate is calculated to weight them by the length.
+type Score struct {
+	Errors int `json:"errors"`
+	Length int `json:"length"`
+}
+
+// Rate returns the error rate.
+// If the reference is empty, it returns 0 when there is no error and 1 otherwise.
+func (s Score) Rate() float64 {
+	if s.Length == 0 {
+		if s.Errors == 0 {
+			return 0
+		}
+		return 1
+	}
+	return float64(s.Errors) / float64(s.Length)
+}
+
+func (s Score) add(o Score) Score {
+	return Score{Errors: s.Errors + o.Errors, Length: s.Length + o.Length}
+}
+
+// Scores are the scores of the metrics.
+// The normalized ones ignore punctuations, symbols and the width of alphanumerics.
+type Scores struct {
+	CER           Score `json:"cer"`
+	WER           Score `json:"wer"`
+	NormalizedCER Score `json:"normalizedCer"`
+	NormalizedWER Score `json:"normalizedWer"`
+}
+
+func (s Scores) add(o Scores) Scores {
+	return Scores{
+		CER:           s.CER.add(o.CER),
+		WER:           s.WER.add(o.WER),
+		NormalizedCER: s.NormalizedCER.add(o.NormalizedCER),
+		NormalizedWER: s.NormalizedWER.add(o.NormalizedWER),
+	}
+}
+
+// NewScores calculates the scores of hyp against ref.
+func NewScores(ref, hyp string, tokenize Tokenizer) (Scores, error) {
+	cer := func(ref, hyp string) Score {
+		r, h := metric.Characters(ref), metric.Characters(hyp)
+		return Score{Errors: metric.EditDistance(r, h), Length: len(r)}
+	}
+	wer := func(ref, hyp string) (Score, error) {
+		r, err := tokenize(ref)
+		if err != nil {
+			return Score{}, fmt.Errorf("failed to tokenize reference: %w", err)
+		}
+		h, err := tokenize(hyp)
+		if err != nil {
+			return Score{}, fmt.Errorf("failed to tokenize hypothesis: %w", err)
+		}
+		return Score{Errors: metric.EditDistance(r, h), Length: len(r)}, nil
+	}
+
+	normalizedRef, normalizedHyp := metric.Normalize(ref), metric.Normalize(hyp)
+
+	w, err := wer(ref, hyp)
+	if err != nil {
+		return Scores{}, err
+	}
+	nw, err := wer(normalizedRef, normalizedHyp)
+	if err != nil {
+		return Scores{}, err
+	}
+
+	return Scores{
+		CER:           cer(ref, hyp),
+		WER:           w,
+		NormalizedCER: cer(normalizedRef, normalizedHyp),
+		NormalizedWER: nw,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package eval
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func fields(s string) ([]string, error) {
+	return strings.Fields(s), nil
+}
+
+func TestNewScores(t *testing.T) {
+	got, err := NewScores("今日 は 晴れ 。", "今日 わ 晴れ", fields)
+	if err != nil {
+		t.Fatalf("NewScores() error = %v", err)
+	}
+
+	want := Scores{
+		CER:           Score{Errors: 2, Length: 6},
+		WER:           Score{Errors: 2, Length: 4},
+		NormalizedCER: Score{Errors: 1, Length: 5},
+		NormalizedWER: Score{Errors: 1, Length: 3},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("NewScores() (-got +want):\n%s", diff)
+	}
+}
+
+func TestScore_Rate(t *testing.T) {
+	tests := []struct {
+		name  string
+		score Score
+		want  float64
+	}{
+		{name: "success", score: Score{Errors: 1, Length: 4}, want: 0.25},
+		{name: "empty reference without error", score: Score{}, want: 0},
+		{name: "empty reference with error", score: Score{Errors: 2}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.score.Rate(); got != tt.want {
+				t.Errorf("Score.Rate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
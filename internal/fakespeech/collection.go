@@ -0,0 +1,278 @@
+package fakespeech
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// the values of the state enums of Recognizer and PhraseSet.
+const (
+	stateActive  protoreflect.EnumNumber = 2
+	stateDeleted protoreflect.EnumNumber = 4
+)
+
+// expireDuration is the duration to keep the deleted resources.
+const expireDuration = 30 * 24 * time.Hour
+
+var (
+	parentPattern = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+$`)
+	idPattern     = regexp.MustCompile(`^[a-z]([a-z0-9-]{0,61}[a-z0-9])?$`)
+)
+
+// outputOnlyFields are the fields set by the server.
+var outputOnlyFields = map[protoreflect.Name]bool{
+	"name":        true,
+	"uid":         true,
+	"state":       true,
+	"create_time": true,
+	"update_time": true,
+	"delete_time": true,
+	"expire_time": true,
+	"etag":        true,
+	"reconciling": true,
+}
+
+// collection keeps the resources of a kind in memory.
+// Recognizers and phrase sets have the same lifecycle fields, so they are
+// handled through protoreflect.
+type collection struct {
+	// kind is the collection ID in the resource name, e.g. "recognizers".
+	kind  string
+	items map[string]proto.Message
+	seq   int
+}
+
+func newCollection(kind string) *collection {
+	return &collection{
+		kind:  kind,
+		items: map[string]proto.Message{},
+	}
+}
+
+func (c *collection) create(parent, id string, res proto.Message, now time.Time, validateOnly bool) (proto.Message, error) {
+	if !parentPattern.MatchString(parent) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid parent %q", parent)
+	}
+	if !idPattern.MatchString(id) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid ID %q", id)
+	}
+	if res == nil {
+		return nil, status.Error(codes.InvalidArgument, "resource must be specified")
+	}
+
+	name := fmt.Sprintf("%s/%s/%s", parent, c.kind, id)
+	if _, ok := c.items[name]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "%s already exists", name)
+	}
+
+	created := proto.Clone(res)
+	for _, f := range fields(created) {
+		if outputOnlyFields[f.Name()] {
+			created.ProtoReflect().Clear(f)
+		}
+	}
+	c.seq++
+	setString(created, "name", name)
+	setString(created, "uid", strconv.Itoa(c.seq))
+	setEnum(created, "state", stateActive)
+	setTime(created, "create_time", now)
+	setTime(created, "update_time", now)
+	setString(created, "etag", etag(now))
+
+	if !validateOnly {
+		c.items[name] = created
+	}
+	return proto.Clone(created), nil
+}
+
+func (c *collection) get(name string) (proto.Message, error) {
+	res, ok := c.items[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "%s not found", name)
+	}
+	return proto.Clone(res), nil
+}
+
+// list returns the resources under the parent sorted by name.
+// pageToken is the offset of the page.
+func (c *collection) list(parent string, showDeleted bool, pageSize int, pageToken string) ([]proto.Message, string, error) {
+	if !parentPattern.MatchString(parent) {
+		return nil, "", status.Errorf(codes.InvalidArgument, "invalid parent %q", parent)
+	}
+
+	names := make([]string, 0, len(c.items))
+	for name, res := range c.items {
+		if !strings.HasPrefix(name, parent+"/"+c.kind+"/") {
+			continue
+		}
+		if !showDeleted && getEnum(res, "state") == stateDeleted {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	offset := 0
+	if pageToken != "" {
+		var err error
+		if offset, err = strconv.Atoi(pageToken); err != nil || offset < 0 || offset > len(names) {
+			return nil, "", status.Errorf(codes.InvalidArgument, "invalid page token %q", pageToken)
+		}
+	}
+	end := len(names)
+	if pageSize > 0 {
+		end = min(offset+pageSize, len(names))
+	}
+	nextPageToken := ""
+	if end < len(names) {
+		nextPageToken = strconv.Itoa(end)
+	}
+
+	items := make([]proto.Message, 0, end-offset)
+	for _, name := range names[offset:end] {
+		items = append(items, proto.Clone(c.items[name]))
+	}
+	return items, nextPageToken, nil
+}
+
+// update updates the fields of the resource in paths. If paths is empty, the
+// fields set in res are updated.
+func (c *collection) update(res proto.Message, paths []string, now time.Time, validateOnly bool) (proto.Message, error) {
+	if res == nil {
+		return nil, status.Error(codes.InvalidArgument, "resource must be specified")
+	}
+	name := getString(res, "name")
+	current, ok := c.items[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "%s not found", name)
+	}
+	if getEnum(current, "state") == stateDeleted {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s is deleted", name)
+	}
+
+	updated := proto.Clone(current)
+	src, dst := res.ProtoReflect(), updated.ProtoReflect()
+	if len(paths) == 0 {
+		src.Range(func(f protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+			if !outputOnlyFields[f.Name()] {
+				dst.Set(f, v)
+			}
+			return true
+		})
+	}
+	for _, path := range paths {
+		f := src.Descriptor().Fields().ByName(protoreflect.Name(path))
+		if f == nil || outputOnlyFields[f.Name()] {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid update mask path %q", path)
+		}
+		if src.Has(f) {
+			dst.Set(f, src.Get(f))
+		} else {
+			dst.Clear(f)
+		}
+	}
+	setTime(updated, "update_time", now)
+	setString(updated, "etag", etag(now))
+
+	if !validateOnly {
+		c.items[name] = updated
+	}
+	return proto.Clone(updated), nil
+}
+
+// delete marks the resource as deleted. It is kept until it expires.
+func (c *collection) delete(name string, allowMissing bool, now time.Time, validateOnly bool) (proto.Message, error) {
+	current, ok := c.items[name]
+	if !ok || getEnum(current, "state") == stateDeleted {
+		if allowMissing {
+			return nil, nil
+		}
+		return nil, status.Errorf(codes.NotFound, "%s not found", name)
+	}
+
+	deleted := proto.Clone(current)
+	setEnum(deleted, "state", stateDeleted)
+	setTime(deleted, "delete_time", now)
+	setTime(deleted, "expire_time", now.Add(expireDuration))
+	setString(deleted, "etag", etag(now))
+
+	if !validateOnly {
+		c.items[name] = deleted
+	}
+	return proto.Clone(deleted), nil
+}
+
+func (c *collection) undelete(name string, now time.Time, validateOnly bool) (proto.Message, error) {
+	current, ok := c.items[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "%s not found", name)
+	}
+	if getEnum(current, "state") != stateDeleted {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s is not deleted", name)
+	}
+
+	undeleted := proto.Clone(current)
+	setEnum(undeleted, "state", stateActive)
+	undeleted.ProtoReflect().Clear(field(undeleted, "delete_time"))
+	undeleted.ProtoReflect().Clear(field(undeleted, "expire_time"))
+	setTime(undeleted, "update_time", now)
+	setString(undeleted, "etag", etag(now))
+
+	if !validateOnly {
+		c.items[name] = undeleted
+	}
+	return proto.Clone(undeleted), nil
+}
+
+// active reports whether the resource exists and is not deleted.
+func (c *collection) active(name string) bool {
+	res, ok := c.items[name]
+	return ok && getEnum(res, "state") == stateActive
+}
+
+func etag(now time.Time) string {
+	return strconv.FormatInt(now.UnixNano(), 36)
+}
+
+func fields(m proto.Message) []protoreflect.FieldDescriptor {
+	fs := m.ProtoReflect().Descriptor().Fields()
+	result := make([]protoreflect.FieldDescriptor, 0, fs.Len())
+	for i := range fs.Len() {
+		result = append(result, fs.Get(i))
+	}
+	return result
+}
+
+func field(m proto.Message, name protoreflect.Name) protoreflect.FieldDescriptor {
+	return m.ProtoReflect().Descriptor().Fields().ByName(name)
+}
+
+func getString(m proto.Message, name protoreflect.Name) string {
+	return m.ProtoReflect().Get(field(m, name)).String()
+}
+
+func setString(m proto.Message, name protoreflect.Name, v string) {
+	m.ProtoReflect().Set(field(m, name), protoreflect.ValueOfString(v))
+}
+
+func getEnum(m proto.Message, name protoreflect.Name) protoreflect.EnumNumber {
+	return m.ProtoReflect().Get(field(m, name)).Enum()
+}
+
+func setEnum(m proto.Message, name protoreflect.Name, v protoreflect.EnumNumber) {
+	m.ProtoReflect().Set(field(m, name), protoreflect.ValueOfEnum(v))
+}
+
+func setTime(m proto.Message, name protoreflect.Name, t time.Time) {
+	m.ProtoReflect().Set(field(m, name), protoreflect.ValueOfMessage(timestamppb.New(t).ProtoReflect()))
+}
@@ -0,0 +1,122 @@
+package fakespeech
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"cloud.google.com/go/speech/apiv2/speechpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// operation is a long-running operation which is done at doneAt.
+type operation struct {
+	op       *longrunningpb.Operation
+	response *anypb.Any
+	doneAt   time.Time
+}
+
+// startOperation returns a long-running operation which returns the response
+// after the operation delay.
+// The change of the resource is applied immediately, and only the completion
+// of the operation is delayed.
+// It must be called with the lock held.
+func (s *Server) startOperation(parent, method string, resource string, response proto.Message) (*longrunningpb.Operation, error) {
+	now := s.now()
+
+	metadata, err := anypb.New(&speechpb.OperationMetadata{
+		CreateTime: timestamppb.New(now),
+		UpdateTime: timestamppb.New(now),
+		Resource:   resource,
+		Method:     method,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create metadata: %v", err)
+	}
+	resp, err := anypb.New(response)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create response: %v", err)
+	}
+
+	s.operationSeq++
+	o := &operation{
+		op: &longrunningpb.Operation{
+			Name:     fmt.Sprintf("%s/operations/%d", parent, s.operationSeq),
+			Metadata: metadata,
+		},
+		response: resp,
+		doneAt:   now.Add(s.operationDelay),
+	}
+	s.operations[o.op.Name] = o
+
+	return s.operationState(o), nil
+}
+
+// operationState returns the operation at the moment.
+// It must be called with the lock held.
+func (s *Server) operationState(o *operation) *longrunningpb.Operation {
+	op := proto.Clone(o.op).(*longrunningpb.Operation)
+	if !s.now().Before(o.doneAt) {
+		op.Done = true
+		op.Result = &longrunningpb.Operation_Response{Response: o.response}
+	}
+	return op
+}
+
+var _ longrunningpb.OperationsServer = (*operationsServer)(nil)
+
+// operationsServer serves the operations of the server for the clients to wait for them.
+type operationsServer struct {
+	longrunningpb.UnimplementedOperationsServer
+
+	s *Server
+}
+
+func (o *operationsServer) GetOperation(_ context.Context, req *longrunningpb.GetOperationRequest) (*longrunningpb.Operation, error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+
+	op, ok := o.s.operations[req.GetName()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "operation %s not found", req.GetName())
+	}
+	return o.s.operationState(op), nil
+}
+
+func (o *operationsServer) ListOperations(_ context.Context, req *longrunningpb.ListOperationsRequest) (*longrunningpb.ListOperationsResponse, error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+
+	names := make([]string, 0, len(o.s.operations))
+	for name := range o.s.operations {
+		if strings.HasPrefix(name, req.GetName()) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	resp := &longrunningpb.ListOperationsResponse{}
+	for _, name := range names {
+		resp.Operations = append(resp.Operations, o.s.operationState(o.s.operations[name]))
+	}
+	return resp, nil
+}
+
+func (o *operationsServer) DeleteOperation(_ context.Context, req *longrunningpb.DeleteOperationRequest) (*emptypb.Empty, error) {
+	o.s.mu.Lock()
+	defer o.s.mu.Unlock()
+
+	if _, ok := o.s.operations[req.GetName()]; !ok {
+		return nil, status.Errorf(codes.NotFound, "operation %s not found", req.GetName())
+	}
+	delete(o.s.operations, req.GetName())
+	return &emptypb.Empty{}, nil
+}
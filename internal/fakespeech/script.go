@@ -0,0 +1,161 @@
+package fakespeech
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Utterance is a speech in the audio which the server recognizes.
+// Start and End are the offsets from the beginning of the audio.
+type Utterance struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// LoadScript reads the transcript sidecar of the audio.
+// See ParseScript for the format.
+func LoadScript(path string) ([]Utterance, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open script: %w", err)
+	}
+	defer f.Close()
+
+	return ParseScript(f)
+}
+
+// ParseScript parses the script which has an utterance in JSON for each line,
+// e.g. {"start": 1.5, "end": 3.2, "text": "こんにちは"}.
+// The offsets are in seconds and the utterances must be in order without overlaps.
+func ParseScript(r io.Reader) ([]Utterance, error) {
+	var utterances []Utterance
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var u struct {
+			Start float64 `json:"start"`
+			End   float64 `json:"end"`
+			Text  string  `json:"text"`
+		}
+		if err := json.Unmarshal([]byte(line), &u); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", n, err)
+		}
+
+		utterance := Utterance{
+			Start: time.Duration(u.Start * float64(time.Second)),
+			End:   time.Duration(u.End * float64(time.Second)),
+			Text:  u.Text,
+		}
+		if utterance.Start < 0 || utterance.End <= utterance.Start {
+			return nil, fmt.Errorf("invalid offsets in line %d", n)
+		}
+		if len(utterances) > 0 && utterance.Start < utterances[len(utterances)-1].End {
+			return nil, fmt.Errorf("utterance in line %d overlaps the previous one", n)
+		}
+		utterances = append(utterances, utterance)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+
+	return utterances, nil
+}
+
+// Event is a result of the script.
+type Event struct {
+	Text    string
+	IsFinal bool
+	// End is the offset of the end of the result from the beginning of the audio.
+	End time.Duration
+}
+
+// Player plays the script along the audio received.
+//
+// While the audio is in an utterance, the part of the text in proportion to
+// the audio heard is returned as an interim result, and the whole text is
+// returned as a final result at the end of the utterance.
+type Player struct {
+	utterances []Utterance
+	// position is the duration of the audio received.
+	position time.Duration
+	// current is the index of the utterance being heard or to be heard next.
+	current int
+	// finalized is the number of the runes of the current utterance already finalized.
+	finalized int
+	// lastInterim is the last interim text to skip the same results.
+	lastInterim string
+}
+
+func NewPlayer(script []Utterance) *Player {
+	return &Player{utterances: script}
+}
+
+// Position returns the duration of the audio received.
+func (p *Player) Position() time.Duration {
+	return p.position
+}
+
+// Advance moves the position by d and returns the results.
+func (p *Player) Advance(d time.Duration) []Event {
+	p.position += d
+
+	var events []Event
+	for p.current < len(p.utterances) && p.utterances[p.current].End <= p.position {
+		u := p.utterances[p.current]
+		if text := string([]rune(u.Text)[p.finalized:]); text != "" {
+			events = append(events, Event{Text: text, IsFinal: true, End: u.End})
+		}
+		p.current++
+		p.finalized = 0
+		p.lastInterim = ""
+	}
+
+	if text := p.heard(); text != "" && text != p.lastInterim {
+		events = append(events, Event{Text: text, IsFinal: false, End: p.position})
+		p.lastInterim = text
+	}
+
+	return events
+}
+
+// Finalize returns the part of the current utterance heard so far as a final
+// result, as the stream is closed in the middle of the utterance.
+func (p *Player) Finalize() []Event {
+	text := p.heard()
+	if text == "" {
+		return nil
+	}
+
+	p.finalized += len([]rune(text))
+	p.lastInterim = ""
+	return []Event{{Text: text, IsFinal: true, End: p.position}}
+}
+
+// heard returns the text of the current utterance heard and not finalized yet.
+func (p *Player) heard() string {
+	if p.current >= len(p.utterances) {
+		return ""
+	}
+	u := p.utterances[p.current]
+	if p.position <= u.Start {
+		return ""
+	}
+
+	runes := []rune(u.Text)
+	n := int(int64(len(runes)) * int64(p.position-u.Start) / int64(u.End-u.Start))
+	n = min(n, len(runes))
+	if n <= p.finalized {
+		return ""
+	}
+	return string(runes[p.finalized:n])
+}
@@ -0,0 +1,122 @@
+package fakespeech
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		want    []Utterance
+		wantErr bool
+	}{
+		{
+			name: "success",
+			script: `{"start": 0.5, "end": 1.5, "text": "こんにちは"}
+# comment
+
+{"start": 2, "end": 3, "text": "世界"}
+`,
+			want: []Utterance{
+				{Start: 500 * time.Millisecond, End: 1500 * time.Millisecond, Text: "こんにちは"},
+				{Start: 2 * time.Second, End: 3 * time.Second, Text: "世界"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "end before start",
+			script:  `{"start": 2, "end": 1, "text": "a"}`,
+			wantErr: true,
+		},
+		{
+			name: "overlap",
+			script: `{"start": 0, "end": 2, "text": "a"}
+{"start": 1, "end": 3, "text": "b"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			script:  `{"start": 0`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseScript(strings.NewReader(tt.script))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseScript() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("ParseScript() (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	p := NewPlayer([]Utterance{
+		{Start: 0, End: time.Second, Text: "こんにちは"},
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: "世界"},
+	})
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		// finalize calls finalize instead of advance.
+		finalize bool
+		want     []Event
+	}{
+		{
+			name:    "in the first utterance",
+			advance: 500 * time.Millisecond,
+			want:    []Event{{Text: "こん", IsFinal: false, End: 500 * time.Millisecond}},
+		},
+		{
+			name:    "same interim is skipped",
+			advance: 50 * time.Millisecond,
+			want:    nil,
+		},
+		{
+			name:    "end of the first utterance",
+			advance: 450 * time.Millisecond,
+			want:    []Event{{Text: "こんにちは", IsFinal: true, End: time.Second}},
+		},
+		{
+			name:    "in the second utterance",
+			advance: 1500 * time.Millisecond,
+			want:    []Event{{Text: "世", IsFinal: false, End: 2500 * time.Millisecond}},
+		},
+		{
+			name:     "stream closed",
+			finalize: true,
+			want:     []Event{{Text: "世", IsFinal: true, End: 2500 * time.Millisecond}},
+		},
+		{
+			name:    "rest of the second utterance",
+			advance: time.Second,
+			want:    []Event{{Text: "界", IsFinal: true, End: 3 * time.Second}},
+		},
+		{
+			name:    "after the script",
+			advance: time.Second,
+			want:    nil,
+		},
+	}
+	for _, step := range steps {
+		var got []Event
+		if step.finalize {
+			got = p.Finalize()
+		} else {
+			got = p.Advance(step.advance)
+		}
+		if diff := cmp.Diff(got, step.want); diff != "" {
+			t.Errorf("%s: (-got +want):\n%s", step.name, diff)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Package fakespeech provides a fake Speech-to-Text API server which keeps the
+// resources in memory and recognizes the audio by a script.
+// It is for end-to-end tests and demos without Google Cloud.
+package fakespeech
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"cloud.google.com/go/speech/apiv2/speechpb"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultMaxStreamDuration is the limit of the duration of a stream of the real API.
+const DefaultMaxStreamDuration = 305 * time.Second
+
+var _ speechpb.SpeechServer = (*Server)(nil)
+
+// Server is a fake of the Speech-to-Text API v2.
+//
+// The recognizers and phrase sets are kept in memory, and their changes are
+// returned as long-running operations which are done after operationDelay.
+//
+// StreamingRecognize plays the script along the audio received. The position
+// of the audio continues across the streams to emulate the reconnections of a
+// client, so the server is supposed to be used by a client at a time.
+type Server struct {
+	speechpb.UnimplementedSpeechServer
+
+	// maxStreamDuration is the limit of the duration of the audio in a stream.
+	maxStreamDuration time.Duration
+	operationDelay    time.Duration
+	now               func() time.Time
+
+	mu           sync.Mutex
+	recognizers  *collection
+	phraseSets   *collection
+	operations   map[string]*operation
+	operationSeq int
+	player       *Player
+}
+
+func NewServer(
+	script []Utterance,
+	maxStreamDuration time.Duration,
+	operationDelay time.Duration,
+) (*Server, error) {
+	if maxStreamDuration <= 0 {
+		return nil, errors.New("max stream duration must be positive")
+	}
+	if operationDelay < 0 {
+		return nil, errors.New("operation delay must not be negative")
+	}
+
+	return &Server{
+		maxStreamDuration: maxStreamDuration,
+		operationDelay:    operationDelay,
+		now:               time.Now,
+		recognizers:       newCollection("recognizers"),
+		phraseSets:        newCollection("phraseSets"),
+		operations:        map[string]*operation{},
+		player:            NewPlayer(script),
+	}, nil
+}
+
+// Register registers the speech service and the operations service of the server.
+func (s *Server) Register(gs *grpc.Server) {
+	speechpb.RegisterSpeechServer(gs, s)
+	longrunningpb.RegisterOperationsServer(gs, &operationsServer{s: s})
+}
+
+// parentOf returns the parent of the resource name.
+func parentOf(name string) string {
+	if i := strings.Index(name, "/recognizers/"); i >= 0 {
+		return name[:i]
+	}
+	if i := strings.Index(name, "/phraseSets/"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+//
+// Recognizers
+//
+
+func (s *Server) CreateRecognizer(_ context.Context, req *speechpb.CreateRecognizerRequest) (*longrunningpb.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res proto.Message
+	if req.GetRecognizer() != nil {
+		res = req.GetRecognizer()
+	}
+	created, err := s.recognizers.create(req.GetParent(), req.GetRecognizerId(), res, s.now(), req.GetValidateOnly())
+	if err != nil {
+		return nil, err
+	}
+	return s.startOperation(req.GetParent(), "CreateRecognizer", getString(created, "name"), created)
+}
+
+func (s *Server) ListRecognizers(_ context.Context, req *speechpb.ListRecognizersRequest) (*speechpb.ListRecognizersResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, next, err := s.recognizers.list(req.GetParent(), req.GetShowDeleted(), int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &speechpb.ListRecognizersResponse{NextPageToken: next}
+	for _, item := range items {
+		resp.Recognizers = append(resp.Recognizers, item.(*speechpb.Recognizer))
+	}
+	return resp, nil
+}
+
+func (s *Server) GetRecognizer(_ context.Context, req *speechpb.GetRecognizerRequest) (*speechpb.Recognizer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.recognizers.get(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return res.(*speechpb.Recognizer), nil
+}
+
+func (s *Server) UpdateRecognizer(_ context.Context, req *speechpb.UpdateRecognizerRequest) (*longrunningpb.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res proto.Message
+	if req.GetRecognizer() != nil {
+		res = req.GetRecognizer()
+	}
+	updated, err := s.recognizers.update(res, req.GetUpdateMask().GetPaths(), s.now(), req.GetValidateOnly())
+	if err != nil {
+		return nil, err
+	}
+	name := getString(updated, "name")
+	return s.startOperation(parentOf(name), "UpdateRecognizer", name, updated)
+}
+
+func (s *Server) DeleteRecognizer(_ context.Context, req *speechpb.DeleteRecognizerRequest) (*longrunningpb.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted, err := s.recognizers.delete(req.GetName(), req.GetAllowMissing(), s.now(), req.GetValidateOnly())
+	if err != nil {
+		return nil, err
+	}
+	if deleted == nil {
+		deleted = &speechpb.Recognizer{}
+	}
+	return s.startOperation(parentOf(req.GetName()), "DeleteRecognizer", req.GetName(), deleted)
+}
+
+func (s *Server) UndeleteRecognizer(_ context.Context, req *speechpb.UndeleteRecognizerRequest) (*longrunningpb.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	undeleted, err := s.recognizers.undelete(req.GetName(), s.now(), req.GetValidateOnly())
+	if err != nil {
+		return nil, err
+	}
+	return s.startOperation(parentOf(req.GetName()), "UndeleteRecognizer", req.GetName(), undeleted)
+}
+
+//
+// Phrase sets
+//
+
+func (s *Server) CreatePhraseSet(_ context.Context, req *speechpb.CreatePhraseSetRequest) (*longrunningpb.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res proto.Message
+	if req.GetPhraseSet() != nil {
+		res = req.GetPhraseSet()
+	}
+	created, err := s.phraseSets.create(req.GetParent(), req.GetPhraseSetId(), res, s.now(), req.GetValidateOnly())
+	if err != nil {
+		return nil, err
+	}
+	return s.startOperation(req.GetParent(), "CreatePhraseSet", getString(created, "name"), created)
+}
+
+func (s *Server) ListPhraseSets(_ context.Context, req *speechpb.ListPhraseSetsRequest) (*speechpb.ListPhraseSetsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, next, err := s.phraseSets.list(req.GetParent(), req.GetShowDeleted(), int(req.GetPageSize()), req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	resp := &speechpb.ListPhraseSetsResponse{NextPageToken: next}
+	for _, item := range items {
+		resp.PhraseSets = append(resp.PhraseSets, item.(*speechpb.PhraseSet))
+	}
+	return resp, nil
+}
+
+func (s *Server) GetPhraseSet(_ context.Context, req *speechpb.GetPhraseSetRequest) (*speechpb.PhraseSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.phraseSets.get(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return res.(*speechpb.PhraseSet), nil
+}
+
+func (s *Server) UpdatePhraseSet(_ context.Context, req *speechpb.UpdatePhraseSetRequest) (*longrunningpb.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res proto.Message
+	if req.GetPhraseSet() != nil {
+		res = req.GetPhraseSet()
+	}
+	updated, err := s.phraseSets.update(res, req.GetUpdateMask().GetPaths(), s.now(), req.GetValidateOnly())
+	if err != nil {
+		return nil, err
+	}
+	name := getString(updated, "name")
+	return s.startOperation(parentOf(name), "UpdatePhraseSet", name, updated)
+}
+
+func (s *Server) DeletePhraseSet(_ context.Context, req *speechpb.DeletePhraseSetRequest) (*longrunningpb.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted, err := s.phraseSets.delete(req.GetName(), req.GetAllowMissing(), s.now(), req.GetValidateOnly())
+	if err != nil {
+		return nil, err
+	}
+	if deleted == nil {
+		deleted = &speechpb.PhraseSet{}
+	}
+	return s.startOperation(parentOf(req.GetName()), "DeletePhraseSet", req.GetName(), deleted)
+}
+
+func (s *Server) UndeletePhraseSet(_ context.Context, req *speechpb.UndeletePhraseSetRequest) (*longrunningpb.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	undeleted, err := s.phraseSets.undelete(req.GetName(), s.now(), req.GetValidateOnly())
+	if err != nil {
+		return nil, err
+	}
+	return s.startOperation(parentOf(req.GetName()), "UndeletePhraseSet", req.GetName(), undeleted)
+}
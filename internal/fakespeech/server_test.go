@@ -0,0 +1,314 @@
+package fakespeech
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/longrunning/autogen/longrunningpb"
+	"cloud.google.com/go/speech/apiv2/speechpb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/resource"
+	"github.com/hekt/voice-recognition/internal/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewServer(t *testing.T) {
+	type args struct {
+		script            []Utterance
+		maxStreamDuration time.Duration
+		operationDelay    time.Duration
+	}
+	baseArgs := args{
+		script:            []Utterance{{Start: 0, End: time.Second, Text: "こんにちは"}},
+		maxStreamDuration: DefaultMaxStreamDuration,
+		operationDelay:    time.Second,
+	}
+	tests := []struct {
+		name    string
+		args    func() args
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			args:    func() args { return baseArgs },
+			wantErr: false,
+		},
+		{
+			name: "zero max stream duration",
+			args: func() args {
+				a := baseArgs
+				a.maxStreamDuration = 0
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative operation delay",
+			args: func() args {
+				a := baseArgs
+				a.operationDelay = -1
+				return a
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.args()
+			got, err := NewServer(a.script, a.maxStreamDuration, a.operationDelay)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewServer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got == nil {
+				t.Errorf("NewServer() = nil, want non-nil")
+			}
+		})
+	}
+}
+
+func TestServer_recognizers(t *testing.T) {
+	ctx := context.Background()
+	server, err := NewServer(nil, DefaultMaxStreamDuration, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := testutil.NewSpeechClient(t, ctx, server.Register)
+	manager := resource.NewRecognizerManager(client)
+
+	for _, name := range []string{"b", "a"} {
+		if err := manager.Create(ctx, resource.CreateRecognizerArgs{
+			ProjectID:      "project",
+			RecognizerName: name,
+			Model:          "long",
+			LanguageCode:   "ja-JP",
+		}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := manager.Create(ctx, resource.CreateRecognizerArgs{
+		ProjectID:      "project",
+		RecognizerName: "a",
+	}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Create() error = %v, want AlreadyExists", err)
+	}
+	if err := manager.Delete(ctx, resource.DeleteRecognizerArgs{
+		ProjectID:      "project",
+		RecognizerName: "b",
+	}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	got, err := manager.List(ctx, resource.ListRecognizerArgs{ProjectID: "project"})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	type summary struct {
+		Name  string
+		Model string
+	}
+	var gotSummaries []summary
+	for _, r := range got {
+		gotSummaries = append(gotSummaries, summary{Name: r.Name, Model: r.Model})
+	}
+	want := []summary{
+		{Name: resource.RecognizerFullname("project", "a"), Model: "long"},
+		{Name: resource.RecognizerFullname("project", "b"), Model: "long"},
+	}
+	if diff := cmp.Diff(gotSummaries, want); diff != "" {
+		t.Errorf("List() (-got +want):\n%s", diff)
+	}
+
+	deleted, err := server.GetRecognizer(ctx, &speechpb.GetRecognizerRequest{
+		Name: resource.RecognizerFullname("project", "b"),
+	})
+	if err != nil {
+		t.Fatalf("GetRecognizer() error = %v", err)
+	}
+	if deleted.State != speechpb.Recognizer_DELETED {
+		t.Errorf("state = %v, want %v", deleted.State, speechpb.Recognizer_DELETED)
+	}
+}
+
+func TestServer_phraseSets(t *testing.T) {
+	ctx := context.Background()
+	server, err := NewServer(nil, DefaultMaxStreamDuration, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := testutil.NewSpeechClient(t, ctx, server.Register)
+	manager := resource.NewPhraseSetManager(client)
+
+	if err := manager.Create(ctx, resource.CreatePhraseSetArgs{
+		ProjectID:     "project",
+		PhraseSetName: "words",
+		Phrases:       []string{"こんにちは"},
+		Boost:         10,
+	}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := manager.Update(ctx, resource.UpdatePhraseSetArgs{
+		ProjectID:     "project",
+		PhraseSetName: "words",
+		Phrases:       []string{"さようなら"},
+		Boost:         5,
+	}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	got, err := manager.List(ctx, resource.ListPhraseSetArgs{ProjectID: "project"})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("len(List()) = %d, want 1", len(got))
+	}
+	if got[0].Boost != 5 || len(got[0].Phrases) != 1 || got[0].Phrases[0].Value != "さようなら" {
+		t.Errorf("List()[0] = %+v, want the updated phrase set", got[0])
+	}
+}
+
+func TestServer_operation(t *testing.T) {
+	ctx := context.Background()
+	server, err := NewServer(nil, DefaultMaxStreamDuration, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	server.now = func() time.Time { return now }
+	operations := &operationsServer{s: server}
+
+	op, err := server.CreateRecognizer(ctx, &speechpb.CreateRecognizerRequest{
+		Parent:       resource.ParentName("project"),
+		RecognizerId: "a",
+		Recognizer:   &speechpb.Recognizer{},
+	})
+	if err != nil {
+		t.Fatalf("CreateRecognizer() error = %v", err)
+	}
+	if op.Done {
+		t.Errorf("operation is done before the delay")
+	}
+
+	now = now.Add(time.Minute)
+	got, err := operations.GetOperation(ctx, &longrunningpb.GetOperationRequest{Name: op.Name})
+	if err != nil {
+		t.Fatalf("GetOperation() error = %v", err)
+	}
+	if !got.Done {
+		t.Fatalf("operation is not done after the delay")
+	}
+	recognizer := &speechpb.Recognizer{}
+	if err := got.GetResponse().UnmarshalTo(recognizer); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if want := resource.RecognizerFullname("project", "a"); recognizer.Name != want {
+		t.Errorf("recognizer name = %q, want %q", recognizer.Name, want)
+	}
+
+	if _, err := operations.GetOperation(ctx, &longrunningpb.GetOperationRequest{Name: "unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetOperation() error = %v, want NotFound", err)
+	}
+}
+
+func Test_collection(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	parent := resource.ParentName("project")
+
+	t.Run("invalid id", func(t *testing.T) {
+		c := newCollection("recognizers")
+		if _, err := c.create(parent, "Invalid_ID", &speechpb.Recognizer{}, now, false); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("create() error = %v, want InvalidArgument", err)
+		}
+	})
+
+	t.Run("validate only", func(t *testing.T) {
+		c := newCollection("recognizers")
+		if _, err := c.create(parent, "a", &speechpb.Recognizer{}, now, true); err != nil {
+			t.Fatalf("create() error = %v", err)
+		}
+		if _, err := c.get(parent + "/recognizers/a"); status.Code(err) != codes.NotFound {
+			t.Errorf("get() error = %v, want NotFound", err)
+		}
+	})
+
+	t.Run("update with mask", func(t *testing.T) {
+		c := newCollection("recognizers")
+		if _, err := c.create(parent, "a", &speechpb.Recognizer{DisplayName: "a", Annotations: map[string]string{"k": "v"}}, now, false); err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.update(
+			&speechpb.Recognizer{Name: parent + "/recognizers/a", DisplayName: "b"},
+			[]string{"display_name", "annotations"},
+			now,
+			false,
+		)
+		if err != nil {
+			t.Fatalf("update() error = %v", err)
+		}
+		r := got.(*speechpb.Recognizer)
+		if r.DisplayName != "b" || len(r.Annotations) != 0 {
+			t.Errorf("update() = %v, want display name updated and annotations cleared", r)
+		}
+		if _, err := c.update(&speechpb.Recognizer{Name: parent + "/recognizers/a"}, []string{"uid"}, now, false); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("update() error = %v, want InvalidArgument", err)
+		}
+	})
+
+	t.Run("delete and undelete", func(t *testing.T) {
+		c := newCollection("recognizers")
+		name := parent + "/recognizers/a"
+		if _, err := c.create(parent, "a", &speechpb.Recognizer{}, now, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.delete(name, false, now, false); err != nil {
+			t.Fatalf("delete() error = %v", err)
+		}
+		if c.active(name) {
+			t.Error("active() = true after delete")
+		}
+		if _, err := c.delete(name, false, now, false); status.Code(err) != codes.NotFound {
+			t.Errorf("delete() error = %v, want NotFound", err)
+		}
+		if _, err := c.undelete(name, now, false); err != nil {
+			t.Fatalf("undelete() error = %v", err)
+		}
+		if !c.active(name) {
+			t.Error("active() = false after undelete")
+		}
+	})
+
+	t.Run("paging", func(t *testing.T) {
+		c := newCollection("recognizers")
+		for _, id := range []string{"c", "a", "b"} {
+			if _, err := c.create(parent, id, &speechpb.Recognizer{}, now, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var names []string
+		token := ""
+		for {
+			items, next, err := c.list(parent, false, 2, token)
+			if err != nil {
+				t.Fatalf("list() error = %v", err)
+			}
+			for _, item := range items {
+				names = append(names, getString(item, "name"))
+			}
+			if next == "" {
+				break
+			}
+			token = next
+		}
+		want := []string{parent + "/recognizers/a", parent + "/recognizers/b", parent + "/recognizers/c"}
+		if diff := cmp.Diff(names, want); diff != "" {
+			t.Errorf("list() (-got +want):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package fakespeech
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/speech/apiv2/speechpb"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// implicitRecognizer is the ID of the recognizer which is used without creation.
+const implicitRecognizer = "_"
+
+func (s *Server) StreamingRecognize(stream speechpb.Speech_StreamingRecognizeServer) error {
+	req, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	config := req.GetStreamingConfig()
+	if config == nil {
+		return status.Error(codes.InvalidArgument, "the first request must contain the streaming config")
+	}
+	recognizer := req.GetRecognizer()
+	s.mu.Lock()
+	found := strings.HasSuffix(recognizer, "/recognizers/"+implicitRecognizer) || s.recognizers.active(recognizer)
+	start := s.player.Position()
+	s.mu.Unlock()
+	if !found {
+		return status.Errorf(codes.NotFound, "recognizer %s not found", recognizer)
+	}
+	interimResults := config.GetStreamingFeatures().GetInterimResults()
+
+	slog.Debug("FakeSpeechServer: stream started", "recognizer", recognizer, "offset", start)
+
+	// received is the bytes of the audio received in the stream.
+	received := 0
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			// the client closed the stream in the middle of the utterance.
+			s.mu.Lock()
+			events := s.player.Finalize()
+			s.mu.Unlock()
+
+			slog.Debug("FakeSpeechServer: stream closed", "received", model.AudioDuration(received))
+			return s.send(stream, events, start, interimResults)
+		}
+		if err != nil {
+			return err
+		}
+
+		audio := req.GetAudio()
+		if audio == nil {
+			return status.Error(codes.InvalidArgument, "the requests after the first one must contain audio")
+		}
+		received += len(audio)
+		if model.AudioDuration(received) > s.maxStreamDuration {
+			return status.Errorf(
+				codes.OutOfRange,
+				"Exceeded maximum allowed stream duration of %d seconds.",
+				int(s.maxStreamDuration.Seconds()),
+			)
+		}
+
+		s.mu.Lock()
+		events := s.player.Advance(model.AudioDuration(len(audio)))
+		s.mu.Unlock()
+
+		if err := s.send(stream, events, start, interimResults); err != nil {
+			return err
+		}
+	}
+}
+
+// send sends a response for each event.
+// start is the position of the audio at the beginning of the stream because
+// the offsets in the responses are from the beginning of the stream.
+func (s *Server) send(
+	stream speechpb.Speech_StreamingRecognizeServer,
+	events []Event,
+	start time.Duration,
+	interimResults bool,
+) error {
+	for _, e := range events {
+		if !e.IsFinal && !interimResults {
+			continue
+		}
+
+		stability := float32(0)
+		if !e.IsFinal {
+			stability = 0.9
+		}
+		if err := stream.Send(&speechpb.StreamingRecognizeResponse{
+			Results: []*speechpb.StreamingRecognitionResult{{
+				Alternatives: []*speechpb.SpeechRecognitionAlternative{{
+					Transcript: e.Text,
+					Confidence: 1,
+				}},
+				IsFinal:         e.IsFinal,
+				Stability:       stability,
+				ResultEndOffset: durationpb.New(e.End - start),
+			}},
+		}); err != nil {
+			return fmt.Errorf("failed to send response: %w", err)
+		}
+	}
+	return nil
+}
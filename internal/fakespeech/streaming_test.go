@@ -0,0 +1,150 @@
+package fakespeech
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/speech/apiv2/speechpb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/resource"
+	"github.com/hekt/voice-recognition/internal/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type response struct {
+	Transcript string
+	IsFinal    bool
+	EndOffset  time.Duration
+}
+
+// stream sends the audio of the durations in a stream and returns the responses.
+func stream(
+	t *testing.T,
+	ctx context.Context,
+	server *Server,
+	recognizer string,
+	durations ...time.Duration,
+) ([]response, error) {
+	t.Helper()
+
+	client := testutil.NewSpeechClient(t, ctx, server.Register)
+	stream, err := client.StreamingRecognize(ctx)
+	if err != nil {
+		t.Fatalf("StreamingRecognize() error = %v", err)
+	}
+
+	if err := stream.Send(&speechpb.StreamingRecognizeRequest{
+		Recognizer: recognizer,
+		StreamingRequest: &speechpb.StreamingRecognizeRequest_StreamingConfig{
+			StreamingConfig: &speechpb.StreamingRecognitionConfig{
+				StreamingFeatures: &speechpb.StreamingRecognitionFeatures{
+					InterimResults: true,
+				},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("failed to send config: %v", err)
+	}
+	for _, d := range durations {
+		// the error is returned by Recv.
+		if err := stream.Send(&speechpb.StreamingRecognizeRequest{
+			StreamingRequest: &speechpb.StreamingRecognizeRequest_Audio{
+				Audio: make([]byte, model.AudioBytes(d)),
+			},
+		}); err != nil {
+			break
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("failed to close send: %v", err)
+	}
+
+	var responses []response
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return responses, nil
+		}
+		if err != nil {
+			return responses, err
+		}
+		for _, r := range resp.Results {
+			responses = append(responses, response{
+				Transcript: r.Alternatives[0].Transcript,
+				IsFinal:    r.IsFinal,
+				EndOffset:  r.ResultEndOffset.AsDuration(),
+			})
+		}
+	}
+}
+
+func TestServer_StreamingRecognize(t *testing.T) {
+	ctx := context.Background()
+	script := []Utterance{
+		{Start: 0, End: time.Second, Text: "こんにちは"},
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: "世界"},
+	}
+	recognizer := resource.RecognizerFullname("project", implicitRecognizer)
+
+	t.Run("success", func(t *testing.T) {
+		server, err := NewServer(script, DefaultMaxStreamDuration, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := stream(t, ctx, server, recognizer, 500*time.Millisecond, 500*time.Millisecond, 1500*time.Millisecond)
+		if err != nil {
+			t.Fatalf("first stream error = %v", err)
+		}
+		want := []response{
+			{Transcript: "こん", IsFinal: false, EndOffset: 500 * time.Millisecond},
+			{Transcript: "こんにちは", IsFinal: true, EndOffset: time.Second},
+			{Transcript: "世", IsFinal: false, EndOffset: 2500 * time.Millisecond},
+			{Transcript: "世", IsFinal: true, EndOffset: 2500 * time.Millisecond},
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("first stream (-got +want):\n%s", diff)
+		}
+
+		// the offsets are from the beginning of the stream.
+		got, err = stream(t, ctx, server, recognizer, 500*time.Millisecond)
+		if err != nil {
+			t.Fatalf("second stream error = %v", err)
+		}
+		want = []response{
+			{Transcript: "界", IsFinal: true, EndOffset: 500 * time.Millisecond},
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("second stream (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("exceeded max stream duration", func(t *testing.T) {
+		server, err := NewServer(script, time.Second, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = stream(t, ctx, server, recognizer, time.Second, time.Second)
+		if status.Code(err) != codes.OutOfRange {
+			t.Errorf("stream error = %v, want OutOfRange", err)
+		}
+	})
+
+	t.Run("recognizer not found", func(t *testing.T) {
+		server, err := NewServer(script, DefaultMaxStreamDuration, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = stream(t, ctx, server, resource.RecognizerFullname("project", "unknown"), time.Second)
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("stream error = %v, want NotFound", err)
+		}
+	})
+}
@@ -1,36 +1,154 @@
 package file
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sync"
 )
 
-// OpenCloseFileWrite is a impelmentation of io.Writer.
-// It opens the file and writes to it each time the Write() method is executed.
-type OpenCloseFileWriter struct {
+// FileWriter is a impelmentation of io.Writer.
+// It keeps the file open while writing, and reopens it on Reopen, e.g. after
+// the file is moved by logrotate.
+type FileWriter struct {
 	path string
 	flag int
 	perm os.FileMode
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+var _ io.WriteCloser = (*FileWriter)(nil)
+
+// OpenFileWriter opens the file at path with flag and perm. O_TRUNC in flag
+// truncates the file only on this open, not on Reopen.
+func OpenFileWriter(path string, flag int, perm os.FileMode) (*FileWriter, error) {
+	file, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return &FileWriter{
+		path: path,
+		flag: flag &^ os.O_TRUNC,
+		perm: perm,
+		file: file,
+	}, nil
+}
+
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	n, err := w.file.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to write to file: %w", err)
+	}
+	return n, nil
 }
 
-var _ io.Writer = (*OpenCloseFileWriter)(nil)
+// Sync commits the written contents to the storage.
+func (w *FileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-func NewOpenCloseFileWriter(path string, flag int, perm os.FileMode) *OpenCloseFileWriter {
-	return &OpenCloseFileWriter{path, flag, perm}
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	return nil
 }
 
-func (w *OpenCloseFileWriter) Write(p []byte) (int, error) {
+// Reopen closes the file and opens the file at the same path again.
+func (w *FileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
 	file, err := os.OpenFile(w.path, w.flag, w.perm)
 	if err != nil {
-		return 0, fmt.Errorf("failed to open file: %w", err)
+		w.file = nil
+		return fmt.Errorf("failed to reopen file: %w", err)
 	}
-	defer file.Close()
+	w.file = file
+	return nil
+}
 
-	n, err := file.Write(p)
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
 	if err != nil {
-		return n, fmt.Errorf("failed to write to file: %w", err)
+		return fmt.Errorf("failed to close file: %w", err)
 	}
+	return nil
+}
 
-	return n, nil
+// Group holds the files opened by it to reopen or close them at once.
+type Group struct {
+	mu      sync.Mutex
+	writers []*FileWriter
+}
+
+func NewGroup() *Group {
+	return &Group{}
+}
+
+// Open opens the file as OpenFileWriter does and adds it to the group.
+func (g *Group) Open(path string, flag int, perm os.FileMode) (*FileWriter, error) {
+	w, err := OpenFileWriter(path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writers = append(g.writers, w)
+	return w, nil
+}
+
+// Reopen reopens all the files even if some of them fail, and returns their
+// errors joined.
+func (g *Group) Reopen() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var errs []error
+	for _, w := range g.writers {
+		if err := w.Reopen(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", w.path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes all the files even if some of them fail, and returns their
+// errors joined.
+func (g *Group) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var errs []error
+	for _, w := range g.writers {
+		if err := w.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", w.path, err))
+		}
+	}
+	g.writers = nil
+	return errors.Join(errs...)
 }
@@ -1,37 +1,45 @@
 package file
 
 import (
+	"errors"
 	"os"
-	"reflect"
+	"path/filepath"
 	"testing"
-
-	"github.com/google/go-cmp/cmp"
 )
 
-func TestNewOpenCloseFileWriter(t *testing.T) {
+func TestOpenFileWriter(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
-		path := "test.txt"
-		flag := os.O_CREATE
-		perm := os.FileMode(0o644)
+		path := filepath.Join(t.TempDir(), "test.txt")
+		w, err := OpenFileWriter(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.FileMode(0o644))
+		if err != nil {
+			t.Fatalf("OpenFileWriter() error = %v", err)
+		}
+		defer w.Close()
+
+		// the file is created early.
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("file is not created: %v", err)
+		}
+	})
 
-		want := &OpenCloseFileWriter{path, flag, perm}
-		got := NewOpenCloseFileWriter(path, flag, perm)
-		if !reflect.DeepEqual(got, want) {
-			t.Errorf("NewFileWriter() = %v, want %v", got, want)
+	t.Run("unexisting file with no O_CREATE flag", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.txt")
+		if _, err := OpenFileWriter(path, os.O_APPEND|os.O_WRONLY, os.FileMode(0o644)); err == nil {
+			t.Error("OpenFileWriter() error = nil, want an error")
 		}
 	})
 }
 
-func TestOpenCloseFileWriter_Write(t *testing.T) {
+func TestFileWriter_Write(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
-		dir := t.TempDir()
-		path := dir + "/test.txt"
-		flag := os.O_APPEND | os.O_CREATE | os.O_WRONLY
-		perm := os.FileMode(0o644)
+		path := filepath.Join(t.TempDir(), "test.txt")
+		w, err := OpenFileWriter(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.FileMode(0o644))
+		if err != nil {
+			t.Fatalf("OpenFileWriter() error = %v", err)
+		}
+		defer w.Close()
 
-		w := &OpenCloseFileWriter{path, flag, perm}
 		p := []byte("test")
-
 		got, err := w.Write(p)
 		if err != nil {
 			t.Errorf("FileWriter.Write() error = %v", err)
@@ -39,49 +47,72 @@ func TestOpenCloseFileWriter_Write(t *testing.T) {
 		if got != len(p) {
 			t.Errorf("FileWriter.Write() = %v, want %v", got, len(p))
 		}
+		assertFile(t, path, "test")
+	})
 
-		file, err := os.Open(path)
+	t.Run("with O_RONLY flag", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.txt")
+		w, err := OpenFileWriter(path, os.O_CREATE|os.O_RDONLY, os.FileMode(0o644))
 		if err != nil {
-			t.Errorf("failed to open file: %v", err)
+			t.Fatalf("OpenFileWriter() error = %v", err)
 		}
-		defer file.Close()
+		defer w.Close()
 
-		b, err := os.ReadFile(path)
-		if err != nil {
-			t.Errorf("failed to read file: %v", err)
-		}
-		if diff := cmp.Diff(p, b); diff != "" {
-			t.Errorf("mismatch (-want +got):\n%s", diff)
+		if _, err := w.Write([]byte("test")); err == nil {
+			t.Error("FileWriter.Write() error = nil, want an error")
 		}
 	})
 
-	t.Run("unexisting file with no O_CREATE flag", func(t *testing.T) {
-		dir := t.TempDir()
-		path := dir + "/test.txt"
-		flag := os.O_APPEND
-		perm := os.FileMode(0o644)
-
-		w := &OpenCloseFileWriter{path, flag, perm}
-		p := []byte("test")
+	t.Run("closed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.txt")
+		w, err := OpenFileWriter(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.FileMode(0o644))
+		if err != nil {
+			t.Fatalf("OpenFileWriter() error = %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("FileWriter.Close() error = %v", err)
+		}
 
-		_, err := w.Write(p)
-		if err == nil {
-			t.Error("FileWriter.Write() error = nil, want an error")
+		if _, err := w.Write([]byte("test")); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("FileWriter.Write() error = %v, want %v", err, os.ErrClosed)
 		}
 	})
+}
 
-	t.Run("with O_RONLY flag", func(t *testing.T) {
+func TestGroup_Reopen(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
 		dir := t.TempDir()
-		path := dir + "/test.txt"
-		flag := os.O_CREATE | os.O_RDONLY
-		perm := os.FileMode(0o644)
-
-		w := &OpenCloseFileWriter{path, flag, perm}
-		p := []byte("test")
+		path := filepath.Join(dir, "test.txt")
+		if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		g := NewGroup()
+		w, err := g.Open(path, os.O_TRUNC|os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.FileMode(0o644))
+		if err != nil {
+			t.Fatalf("Group.Open() error = %v", err)
+		}
+		write(t, w, "a")
 
-		_, err := w.Write(p)
-		if err == nil {
-			t.Error("FileWriter.Write() error = nil, want an error")
+		// the file is moved away as logrotate does.
+		rotated := filepath.Join(dir, "test.txt.1")
+		if err := os.Rename(path, rotated); err != nil {
+			t.Fatalf("failed to rename file: %v", err)
 		}
+		write(t, w, "b")
+		if err := g.Reopen(); err != nil {
+			t.Fatalf("Group.Reopen() error = %v", err)
+		}
+		write(t, w, "c")
+		if err := g.Reopen(); err != nil {
+			t.Fatalf("Group.Reopen() error = %v", err)
+		}
+		write(t, w, "d")
+		if err := g.Close(); err != nil {
+			t.Fatalf("Group.Close() error = %v", err)
+		}
+
+		assertFile(t, rotated, "ab")
+		// O_TRUNC is not applied on the reopen.
+		assertFile(t, path, "cd")
 	})
 }
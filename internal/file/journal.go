@@ -0,0 +1,299 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// journalCheckpointSize is the size of the journal which triggers the
+// checkpoint of RotatingFileWriter.
+const journalCheckpointSize = 1 << 20
+
+const (
+	// entryPart tells the part which the following writes go to.
+	entryPart = "part"
+	// entryWrite is a write to the part at the offset.
+	entryWrite = "write"
+	// entryInterim is the interim result which has not been written to the part.
+	entryInterim = "interim"
+)
+
+type journalEntry struct {
+	Type   string `json:"type"`
+	Path   string `json:"path,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+	Data   string `json:"data,omitempty"`
+}
+
+// journal is the write-ahead log of RotatingFileWriter. Each write to the
+// part is recorded before it is written, and so is each interim result, so
+// that Recover restores the text which had not reached the storage on a
+// crash. It is truncated on each checkpoint, when the part is synced, and
+// removed on the clean shutdown.
+//
+// The file is locked while it is open, so that Recover does not touch the
+// journal of a running process.
+type journal struct {
+	path string
+	file *os.File
+	size int64
+}
+
+func openJournal(path string) (*journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.FileMode(0o644))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock journal: %w", err)
+	}
+	return &journal{path: path, file: file}, nil
+}
+
+func (j *journal) append(entry journalEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+	n, err := j.file.Write(append(b, '\n'))
+	j.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write to journal: %w", err)
+	}
+	return nil
+}
+
+// reset truncates the journal and starts it with the part at path and the
+// interim result if any. The journal is synced.
+func (j *journal) reset(path, interim string) error {
+	if err := j.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate journal: %w", err)
+	}
+	j.size = 0
+	if err := j.append(journalEntry{Type: entryPart, Path: path}); err != nil {
+		return err
+	}
+	if interim != "" {
+		if err := j.append(journalEntry{Type: entryInterim, Data: interim}); err != nil {
+			return err
+		}
+	}
+	return j.sync()
+}
+
+func (j *journal) sync() error {
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+	return nil
+}
+
+// remove removes the journal since everything in it has reached the part.
+func (j *journal) remove() error {
+	if err := j.file.Close(); err != nil {
+		return fmt.Errorf("failed to close journal: %w", err)
+	}
+	if err := os.Remove(j.path); err != nil {
+		return fmt.Errorf("failed to remove journal: %w", err)
+	}
+	return nil
+}
+
+// Recovery is the result of Recover.
+type Recovery struct {
+	// Journal is the path of the journal recovered.
+	Journal string
+	// Path is the part recovered. Empty if the journal has no part.
+	Path string
+	// Repaired is the number of the writes which had not reached the part.
+	Repaired int
+	// Interim is the interim result appended to the part as a final one.
+	// Empty if there was none.
+	Interim string
+}
+
+// RecoverJournals recovers the journals left by the processes which did not
+// shut down cleanly. The placeholders in the template of the journals match
+// any session. The journals of the running processes are skipped.
+func RecoverJournals(template string) ([]Recovery, error) {
+	pattern := strings.NewReplacer(
+		"{session}", "*",
+		"{date}", "*",
+		"{hour}", "*",
+		"{part}", "*",
+	).Replace(template)
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find journals: %w", err)
+	}
+
+	var recoveries []Recovery
+	for _, path := range paths {
+		recovery, err := Recover(path)
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			slog.Debug(fmt.Sprintf("RecoverJournals: %s is in use", path))
+			continue
+		}
+		if err != nil {
+			return recoveries, fmt.Errorf("failed to recover %s: %w", path, err)
+		}
+		recoveries = append(recoveries, recovery)
+	}
+	return recoveries, nil
+}
+
+// Recover reconciles the part with the journal at path, and removes the
+// journal. The writes which had not reached the part are written again, and
+// the last interim result is appended as the final one as it would have been
+// on the clean shutdown. A broken line, which is left by a crash in the
+// middle of a write, is skipped.
+func Recover(path string) (Recovery, error) {
+	jf, err := os.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return Recovery{}, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer jf.Close()
+	if err := syscall.Flock(int(jf.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		return Recovery{}, fmt.Errorf("failed to lock journal: %w", err)
+	}
+
+	recovery := Recovery{Journal: path}
+	var writes []journalEntry
+	scanner := bufio.NewScanner(jf)
+	scanner.Buffer(nil, journalCheckpointSize*2)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			slog.Warn(fmt.Sprintf("Recover: broken line in %s is skipped", path))
+			continue
+		}
+		switch entry.Type {
+		case entryPart:
+			recovery.Path = entry.Path
+			writes = nil
+		case entryWrite:
+			writes = append(writes, entry)
+			recovery.Interim = ""
+		case entryInterim:
+			recovery.Interim = entry.Data
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Recovery{}, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	if recovery.Path != "" {
+		repaired, err := replay(recovery.Path, writes, recovery.Interim)
+		if err != nil {
+			return Recovery{}, err
+		}
+		recovery.Repaired = repaired
+	}
+
+	if err := os.Remove(path); err != nil {
+		return Recovery{}, fmt.Errorf("failed to remove journal: %w", err)
+	}
+	return recovery, nil
+}
+
+// replay writes the writes which had not reached the part at path again,
+// and appends the interim result. It returns the number of the writes.
+func replay(path string, writes []journalEntry, interim string) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, os.FileMode(0o644))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	repaired := 0
+	// shift is the size of the lost contents before the writes, which are
+	// not recorded and cannot be filled.
+	var shift int64
+	for _, entry := range writes {
+		info, err := f.Stat()
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat file: %w", err)
+		}
+		offset := entry.Offset - shift
+		if size := info.Size(); size < offset {
+			shift += offset - size
+			offset = size
+		}
+
+		data := []byte(entry.Data)
+		got := make([]byte, len(data))
+		n, err := f.ReadAt(got, offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("failed to read file: %w", err)
+		}
+		if n == len(data) && bytes.Equal(got, data) {
+			continue
+		}
+		if _, err := f.WriteAt(data, offset); err != nil {
+			return 0, fmt.Errorf("failed to write to file: %w", err)
+		}
+		repaired++
+	}
+
+	// the crash may leave the part extended with zeros after the last write.
+	if n := len(writes); n > 0 {
+		end := writes[n-1].Offset - shift + int64(len(writes[n-1].Data))
+		if err := trimZeros(f, end); err != nil {
+			return 0, err
+		}
+	}
+
+	if interim != "" {
+		info, err := f.Stat()
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat file: %w", err)
+		}
+		// the same as the interim result written on the clean shutdown.
+		if _, err := f.WriteAt([]byte("\n"+interim), info.Size()); err != nil {
+			return 0, fmt.Errorf("failed to write to file: %w", err)
+		}
+	}
+
+	if err := f.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync file: %w", err)
+	}
+	return repaired, nil
+}
+
+// trimZeros truncates f to end if it has only zeros after end.
+func trimZeros(f *os.File, end int64) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.Size() <= end {
+		return nil
+	}
+	tail, err := io.ReadAll(io.NewSectionReader(f, end, info.Size()-end))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(bytes.Trim(tail, "\x00")) > 0 {
+		return nil
+	}
+	if err := f.Truncate(end); err != nil {
+		return fmt.Errorf("failed to truncate file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+// crash leaves the journal of w as a killed process does.
+func crash(t *testing.T, w *RotatingFileWriter) {
+	t.Helper()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.file.Close()
+	w.journal.file.Close()
+}
+
+func TestRotatingFileWriter_journal(t *testing.T) {
+	t.Run("clean shutdown", func(t *testing.T) {
+		dir := t.TempDir()
+		journal := filepath.Join(dir, "s.journal.jsonl")
+		w, err := NewRotatingFileWriter(filepath.Join(dir, "{session}.txt"), "s", Rotation{}, "", false, journal, 0, testutil.NewFakeClock(time.Now()))
+		if err != nil {
+			t.Fatalf("NewRotatingFileWriter() error = %v", err)
+		}
+		write(t, w, "\na")
+		write(t, w.Interim(), "b")
+		if err := w.Close(); err != nil {
+			t.Fatalf("RotatingFileWriter.Close() error = %v", err)
+		}
+
+		if _, err := os.Stat(journal); !os.IsNotExist(err) {
+			t.Errorf("journal is not removed: %v", err)
+		}
+	})
+
+	t.Run("crash", func(t *testing.T) {
+		dir := t.TempDir()
+		journal := filepath.Join(dir, "s.journal.jsonl")
+		path := filepath.Join(dir, "s.txt")
+		w, err := NewRotatingFileWriter(filepath.Join(dir, "{session}.txt"), "s", Rotation{}, "", false, journal, time.Second, testutil.NewFakeClock(time.Now()))
+		if err != nil {
+			t.Fatalf("NewRotatingFileWriter() error = %v", err)
+		}
+		write(t, w, "\na")
+		write(t, w, "\nb")
+		write(t, w.Interim(), "c")
+		crash(t, w)
+		// the last write has not reached the storage.
+		if err := os.Truncate(path, 2); err != nil {
+			t.Fatalf("failed to truncate file: %v", err)
+		}
+
+		got, err := Recover(journal)
+		if err != nil {
+			t.Fatalf("Recover() error = %v", err)
+		}
+		want := Recovery{Journal: journal, Path: path, Repaired: 1, Interim: "c"}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("Recover() (-got +want):\n%s", diff)
+		}
+		assertFile(t, path, "\na\nb\nc")
+		if _, err := os.Stat(journal); !os.IsNotExist(err) {
+			t.Errorf("journal is not removed: %v", err)
+		}
+	})
+
+	t.Run("checkpoint", func(t *testing.T) {
+		dir := t.TempDir()
+		journal := filepath.Join(dir, "s.journal.jsonl")
+		w, err := NewRotatingFileWriter(filepath.Join(dir, "{session}.txt"), "s", Rotation{}, "", false, journal, time.Second, testutil.NewFakeClock(time.Now()))
+		if err != nil {
+			t.Fatalf("NewRotatingFileWriter() error = %v", err)
+		}
+		defer w.Close()
+
+		large := strings.Repeat("a", journalCheckpointSize)
+		write(t, w, "\n"+large)
+		write(t, w.Interim(), "b")
+
+		// the journal is reset to the part and the interim result.
+		info, err := os.Stat(journal)
+		if err != nil {
+			t.Fatalf("failed to stat journal: %v", err)
+		}
+		if info.Size() > 1024 {
+			t.Errorf("journal size = %d, want it reset", info.Size())
+		}
+	})
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("zeros after the last write", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "s.txt")
+		journal := filepath.Join(dir, "s.journal.jsonl")
+		if err := os.WriteFile(path, []byte("\na\x00\x00\x00"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		lines := `{"type":"part","path":"` + path + `"}` + "\n" +
+			`{"type":"write","path":"` + path + `","data":"\na"}` + "\n" +
+			`{"type":"write","path":"` + path + `","offset":2,"data":"\nb"}` + "\n" +
+			`{"type":"interim","da`
+		if err := os.WriteFile(journal, []byte(lines), 0o644); err != nil {
+			t.Fatalf("failed to write journal: %v", err)
+		}
+
+		got, err := Recover(journal)
+		if err != nil {
+			t.Fatalf("Recover() error = %v", err)
+		}
+		// the broken line is skipped.
+		if want := (Recovery{Journal: journal, Path: path, Repaired: 1}); got != want {
+			t.Errorf("Recover() = %+v, want %+v", got, want)
+		}
+		assertFile(t, path, "\na\nb")
+	})
+
+	t.Run("lost contents before the writes", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "s.txt")
+		journal := filepath.Join(dir, "s.journal.jsonl")
+		lines := `{"type":"part","path":"` + path + `"}` + "\n" +
+			`{"type":"write","path":"` + path + `","offset":10,"data":"\na"}` + "\n" +
+			`{"type":"write","path":"` + path + `","offset":12,"data":"\nb"}` + "\n"
+		if err := os.WriteFile(journal, []byte(lines), 0o644); err != nil {
+			t.Fatalf("failed to write journal: %v", err)
+		}
+
+		if _, err := Recover(journal); err != nil {
+			t.Fatalf("Recover() error = %v", err)
+		}
+		// the gap cannot be filled, and the writes follow what is left.
+		assertFile(t, path, "\na\nb")
+	})
+}
+
+func TestRecoverJournals(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dir := t.TempDir()
+		template := filepath.Join(dir, "{session}.journal.jsonl")
+
+		// the journal of the running process is skipped.
+		running, err := NewRotatingFileWriter(filepath.Join(dir, "{session}.txt"), "running", Rotation{}, "", false, filepath.Join(dir, "running.journal.jsonl"), 0, testutil.NewFakeClock(time.Now()))
+		if err != nil {
+			t.Fatalf("NewRotatingFileWriter() error = %v", err)
+		}
+		defer running.Close()
+
+		crashed, err := NewRotatingFileWriter(filepath.Join(dir, "{session}.txt"), "crashed", Rotation{}, "", false, filepath.Join(dir, "crashed.journal.jsonl"), 0, testutil.NewFakeClock(time.Now()))
+		if err != nil {
+			t.Fatalf("NewRotatingFileWriter() error = %v", err)
+		}
+		write(t, crashed.Interim(), "a")
+		crash(t, crashed)
+
+		got, err := RecoverJournals(template)
+		if err != nil {
+			t.Fatalf("RecoverJournals() error = %v", err)
+		}
+		want := []Recovery{{
+			Journal: filepath.Join(dir, "crashed.journal.jsonl"),
+			Path:    filepath.Join(dir, "crashed.txt"),
+			Interim: "a",
+		}}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("RecoverJournals() (-got +want):\n%s", diff)
+		}
+		assertFile(t, filepath.Join(dir, "crashed.txt"), "\na")
+	})
+}
@@ -0,0 +1,554 @@
+package file
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/clock"
+)
+
+const (
+	PeriodDaily  = "daily"
+	PeriodHourly = "hourly"
+)
+
+// Rotation tells when RotatingFileWriter switches to the next part.
+type Rotation struct {
+	// Period is PeriodDaily or PeriodHourly. Empty if the parts are not rotated by time.
+	Period string
+	// Size is the maximum size of a part in bytes. 0 if the parts are not rotated by size.
+	Size int64
+}
+
+// ParseRotation parses the comma separated rotations, e.g. "daily",
+// "size=10MB" or "hourly,size=1GB". The empty string means no rotation.
+func ParseRotation(s string) (Rotation, error) {
+	var r Rotation
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+		case item == PeriodDaily || item == PeriodHourly:
+			r.Period = item
+		case strings.HasPrefix(item, "size="):
+			size, err := parseSize(strings.TrimPrefix(item, "size="))
+			if err != nil {
+				return Rotation{}, err
+			}
+			r.Size = size
+		default:
+			return Rotation{}, fmt.Errorf("unknown rotation %q, daily, hourly or size=<size> is expected", item)
+		}
+	}
+	return r, nil
+}
+
+// parseSize parses the size with the unit B, KB, MB or GB in 1024.
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+	upper := strings.ToUpper(s)
+	for _, unit := range units {
+		if n, ok := strings.CutSuffix(upper, unit.suffix); ok {
+			v, err := strconv.ParseInt(n, 10, 64)
+			if err != nil || v <= 0 {
+				return 0, fmt.Errorf("invalid size %q", s)
+			}
+			return v * unit.size, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid size %q, the unit B, KB, MB or GB is expected", s)
+}
+
+// ExpandPath expands the placeholders in the template: {session} to the
+// session, {date} to the date of t as 2006-01-02, {hour} to the hour of t
+// as 15, and {part} to the part number from 1.
+func ExpandPath(template, session string, t time.Time, part int) string {
+	return strings.NewReplacer(
+		"{session}", session,
+		"{date}", t.Format("2006-01-02"),
+		"{hour}", t.Format("15"),
+		"{part}", strconv.Itoa(part),
+	).Replace(template)
+}
+
+// Manifest lists the parts of a session written by RotatingFileWriter.
+type Manifest struct {
+	Session string `json:"session"`
+	Parts   []Part `json:"parts"`
+}
+
+type Part struct {
+	Path      string    `json:"path"`
+	StartedAt time.Time `json:"started_at"`
+	// Bytes is the size before the compression. It is updated on the rotation and Close.
+	Bytes      int64 `json:"bytes"`
+	Compressed bool  `json:"compressed,omitempty"`
+}
+
+var _ io.WriteCloser = (*RotatingFileWriter)(nil)
+
+// RotatingFileWriter writes to the parts of a session, switching to the next
+// part by the rotation. Each Write goes to a single part, so a final result
+// written at once is never split across the parts. The current part is kept
+// open, and reopened by Reopen.
+//
+// With the journal, each write is recorded in it before it is written to the
+// part, and the interim result written to Interim is recorded as well, so
+// that Recover restores them after a crash. The journal is synced by the sync
+// interval and the part on each checkpoint: the rotation, the reopen, and
+// when the journal grows. Without the journal, the part is synced by the sync
+// interval instead.
+type RotatingFileWriter struct {
+	template string
+	session  string
+	rotation Rotation
+	// manifestPath is the path of the manifest. Empty if it is not written.
+	manifestPath string
+	// compress compresses the parts but the current one with gzip.
+	compress bool
+	// syncInterval is the interval of the syncs. 0 syncs on each write.
+	syncInterval time.Duration
+	clock        clock.Clock
+
+	mu       sync.Mutex
+	manifest Manifest
+	// file is the current part. nil after Close.
+	file *os.File
+	// journal is nil if the writes are not journaled.
+	journal *journal
+	// interim is the interim result recorded since the last write.
+	interim string
+	// dirty tells that something is written since the last sync.
+	dirty bool
+	// period is the period of the current part.
+	period string
+	// size is the size of the current part.
+	size int64
+	// compressing waits for the compressions.
+	compressing sync.WaitGroup
+}
+
+// NewRotatingFileWriter creates the first part of the session at the path
+// expanded from the template. See ExpandPath. journalPath is the path of the
+// journal, which is empty if the writes are not journaled.
+func NewRotatingFileWriter(
+	template string,
+	session string,
+	rotation Rotation,
+	manifestPath string,
+	compress bool,
+	journalPath string,
+	syncInterval time.Duration,
+	clock clock.Clock,
+) (*RotatingFileWriter, error) {
+	if template == "" {
+		return nil, errors.New("path template must be specified")
+	}
+	if session == "" {
+		return nil, errors.New("session must be specified")
+	}
+	if rotation.Size < 0 {
+		return nil, errors.New("rotation size must not be negative")
+	}
+	switch rotation.Period {
+	case "", PeriodDaily, PeriodHourly:
+	default:
+		return nil, fmt.Errorf("unknown rotation period %q", rotation.Period)
+	}
+	if syncInterval < 0 {
+		return nil, errors.New("sync interval must not be negative")
+	}
+
+	w := &RotatingFileWriter{
+		template:     template,
+		session:      session,
+		rotation:     rotation,
+		manifestPath: manifestPath,
+		compress:     compress,
+		syncInterval: syncInterval,
+		clock:        clock,
+		manifest:     Manifest{Session: session},
+	}
+	if journalPath != "" {
+		j, err := openJournal(journalPath)
+		if err != nil {
+			return nil, err
+		}
+		w.journal = j
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.rotate(clock.Now()); err != nil {
+		if w.journal != nil {
+			w.journal.file.Close()
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+// Path returns the path of the current part.
+func (w *RotatingFileWriter) Path() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.manifest.Parts[len(w.manifest.Parts)-1].Path
+}
+
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	now := w.clock.Now()
+	// an empty part is not rotated by size even if p is larger than the size.
+	if w.periodOf(now) != w.period || (w.rotation.Size > 0 && w.size > 0 && w.size+int64(len(p)) > w.rotation.Size) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	part := &w.manifest.Parts[len(w.manifest.Parts)-1]
+	if w.journal != nil {
+		if err := w.journal.append(journalEntry{Type: entryWrite, Path: part.Path, Offset: w.size, Data: string(p)}); err != nil {
+			return 0, err
+		}
+		w.interim = ""
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	part.Bytes = w.size
+	if err != nil {
+		return n, fmt.Errorf("failed to write to file: %w", err)
+	}
+	if err := w.written(); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// Interim returns the writer recording the interim result in the journal,
+// which is written to the part on the clean shutdown but lost on a crash
+// otherwise. It does nothing without the journal.
+func (w *RotatingFileWriter) Interim() io.Writer {
+	return interimWriter{w}
+}
+
+type interimWriter struct {
+	w *RotatingFileWriter
+}
+
+func (i interimWriter) Write(p []byte) (int, error) {
+	w := i.w
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.journal == nil || w.file == nil || string(p) == w.interim {
+		return len(p), nil
+	}
+	if err := w.journal.append(journalEntry{Type: entryInterim, Data: string(p)}); err != nil {
+		return 0, err
+	}
+	w.interim = string(p)
+	if err := w.written(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// written syncs or marks dirty after a write. The caller must hold mu.
+func (w *RotatingFileWriter) written() error {
+	if w.journal != nil && w.journal.size > journalCheckpointSize {
+		return w.checkpoint()
+	}
+	if w.syncInterval == 0 {
+		return w.sync()
+	}
+	w.dirty = true
+	return nil
+}
+
+// sync syncs the journal, or the part without the journal. The caller must hold mu.
+func (w *RotatingFileWriter) sync() error {
+	w.dirty = false
+	if w.journal != nil {
+		return w.journal.sync()
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	return nil
+}
+
+// checkpoint syncs the part and resets the journal, since the writes in it
+// have reached the storage. The caller must hold mu.
+func (w *RotatingFileWriter) checkpoint() error {
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	w.dirty = false
+	if w.journal == nil {
+		return nil
+	}
+	return w.journal.reset(w.file.Name(), w.interim)
+}
+
+// Start syncs by the sync interval until ctx is done.
+func (w *RotatingFileWriter) Start(ctx context.Context) error {
+	if w.syncInterval == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	timer := w.clock.NewTimer(w.syncInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C():
+			w.mu.Lock()
+			if w.dirty && w.file != nil {
+				if err := w.sync(); err != nil {
+					slog.Error(fmt.Sprintf("RotatingFileWriter: %v", err))
+				}
+			}
+			w.mu.Unlock()
+			timer.Reset(w.syncInterval)
+		}
+	}
+}
+
+// Reopen closes the current part and opens the file at the same path again,
+// e.g. after it is moved by logrotate.
+func (w *RotatingFileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	path := w.file.Name()
+	if err := w.checkpoint(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.FileMode(0o644))
+	if err != nil {
+		w.file = nil
+		return fmt.Errorf("failed to reopen file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		w.file = nil
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	w.file = file
+	w.size = info.Size()
+	slog.Info(fmt.Sprintf("RotatingFileWriter: reopened %s", path))
+	return nil
+}
+
+// Close syncs and closes the current part, removes the journal since
+// everything has reached the part, waits for the compressions, and writes
+// the manifest.
+func (w *RotatingFileWriter) Close() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	w.compressing.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writeManifest()
+}
+
+func (w *RotatingFileWriter) closeFile() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	w.file = nil
+	if w.journal != nil {
+		return w.journal.remove()
+	}
+	return nil
+}
+
+func (w *RotatingFileWriter) periodOf(t time.Time) string {
+	switch w.rotation.Period {
+	case PeriodDaily:
+		return t.Format("2006-01-02")
+	case PeriodHourly:
+		return t.Format("2006-01-02T15")
+	default:
+		return ""
+	}
+}
+
+// rotate creates the next part. The caller must hold mu.
+func (w *RotatingFileWriter) rotate(now time.Time) error {
+	number := len(w.manifest.Parts) + 1
+	path := ExpandPath(w.template, w.session, now, number)
+	// the part number is added if the template does not tell the parts apart.
+	for _, part := range w.manifest.Parts {
+		if strings.TrimSuffix(part.Path, ".gz") == path {
+			ext := filepath.Ext(path)
+			path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), number, ext)
+			break
+		}
+	}
+
+	// the file is created early, making it easier to use with tools like `tail -f`.
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.FileMode(0o644))
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// the previous part is complete before it is compressed.
+	if w.file != nil {
+		if err := w.file.Sync(); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to sync file: %w", err)
+		}
+		if err := w.file.Close(); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to close file: %w", err)
+		}
+	}
+	w.file = file
+	w.dirty = false
+	if w.journal != nil {
+		if err := w.journal.reset(path, w.interim); err != nil {
+			return err
+		}
+	}
+
+	if n := len(w.manifest.Parts); n > 0 && w.compress {
+		w.compressing.Add(1)
+		go w.compressPart(n - 1)
+	}
+	w.manifest.Parts = append(w.manifest.Parts, Part{Path: path, StartedAt: now, Bytes: info.Size()})
+	w.period = w.periodOf(now)
+	w.size = info.Size()
+	if number > 1 {
+		slog.Info(fmt.Sprintf("RotatingFileWriter: rotated to %s", path))
+	}
+
+	// the failure of the manifest does not stop the transcript.
+	if err := w.writeManifest(); err != nil {
+		slog.Error(err.Error())
+	}
+	return nil
+}
+
+// compressPart compresses the part with gzip and removes the original.
+func (w *RotatingFileWriter) compressPart(index int) {
+	defer w.compressing.Done()
+
+	w.mu.Lock()
+	path := w.manifest.Parts[index].Path
+	w.mu.Unlock()
+
+	if err := gzipFile(path); err != nil {
+		slog.Error(fmt.Sprintf("RotatingFileWriter: failed to compress %s: %v", path, err))
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.manifest.Parts[index].Path = path + ".gz"
+	w.manifest.Parts[index].Compressed = true
+	if err := w.writeManifest(); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer dst.Close()
+
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(path)
+	if _, err := io.Copy(zw, src); err != nil {
+		return fmt.Errorf("failed to compress: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	return os.Remove(path)
+}
+
+// writeManifest writes the manifest at once. The caller must hold mu.
+func (w *RotatingFileWriter) writeManifest() error {
+	if w.manifestPath == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(w.manifestPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	tmp := w.manifestPath + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), os.FileMode(0o644)); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, w.manifestPath); err != nil {
+		return fmt.Errorf("failed to replace manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package file
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+func TestParseRotation(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    Rotation
+		wantErr bool
+	}{
+		{name: "none", s: "", want: Rotation{}},
+		{name: "daily", s: "daily", want: Rotation{Period: PeriodDaily}},
+		{name: "hourly", s: "hourly", want: Rotation{Period: PeriodHourly}},
+		{name: "size", s: "size=10MB", want: Rotation{Size: 10 << 20}},
+		{name: "combined", s: "daily, size=2kb", want: Rotation{Period: PeriodDaily, Size: 2 << 10}},
+		{name: "bytes", s: "size=100B", want: Rotation{Size: 100}},
+		{name: "no unit", s: "size=100", wantErr: true},
+		{name: "zero", s: "size=0MB", wantErr: true},
+		{name: "unknown", s: "weekly", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRotation(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRotation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRotation() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	got := ExpandPath("output/{date}/{session}-{hour}-{part}.txt", "1704207845", time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), 3)
+	if want := "output/2024-01-02/1704207845-15-3.txt"; got != want {
+		t.Errorf("ExpandPath() = %q, want %q", got, want)
+	}
+}
+
+func TestRotatingFileWriter(t *testing.T) {
+	start := time.Date(2024, 1, 2, 23, 59, 0, 0, time.Local)
+
+	t.Run("daily", func(t *testing.T) {
+		dir := t.TempDir()
+		clock := testutil.NewFakeClock(start)
+		manifest := filepath.Join(dir, "s.manifest.json")
+		w, err := NewRotatingFileWriter(filepath.Join(dir, "{date}", "{session}.txt"), "s", Rotation{Period: PeriodDaily}, manifest, false, "", 0, clock)
+		if err != nil {
+			t.Fatalf("NewRotatingFileWriter() error = %v", err)
+		}
+
+		// the first part is created early.
+		first := filepath.Join(dir, "2024-01-02", "s.txt")
+		if _, err := os.Stat(first); err != nil {
+			t.Errorf("first part is not created: %v", err)
+		}
+
+		write(t, w, "\na")
+		clock.Advance(time.Minute)
+		write(t, w, "\nb")
+		second := filepath.Join(dir, "2024-01-03", "s.txt")
+		if got := w.Path(); got != second {
+			t.Errorf("RotatingFileWriter.Path() = %q, want %q", got, second)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("RotatingFileWriter.Close() error = %v", err)
+		}
+
+		assertFile(t, first, "\na")
+		assertFile(t, second, "\nb")
+		want := Manifest{
+			Session: "s",
+			Parts: []Part{
+				{Path: first, StartedAt: start, Bytes: 2},
+				{Path: second, StartedAt: start.Add(time.Minute), Bytes: 2},
+			},
+		}
+		if diff := cmp.Diff(readManifest(t, manifest), want); diff != "" {
+			t.Errorf("manifest (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("size with compression", func(t *testing.T) {
+		dir := t.TempDir()
+		clock := testutil.NewFakeClock(start)
+		manifest := filepath.Join(dir, "s.manifest.json")
+		w, err := NewRotatingFileWriter(filepath.Join(dir, "{session}.txt"), "s", Rotation{Size: 4}, manifest, true, "", 0, clock)
+		if err != nil {
+			t.Fatalf("NewRotatingFileWriter() error = %v", err)
+		}
+
+		write(t, w, "\na")
+		write(t, w, "\nb")
+		// the write larger than the size is not split.
+		write(t, w, "\ncccc")
+		write(t, w, "\nd")
+		if err := w.Close(); err != nil {
+			t.Fatalf("RotatingFileWriter.Close() error = %v", err)
+		}
+
+		// the part number is added since the template does not tell the parts apart.
+		paths := []string{
+			filepath.Join(dir, "s.txt"),
+			filepath.Join(dir, "s-2.txt"),
+			filepath.Join(dir, "s-3.txt"),
+		}
+		assertGzipFile(t, paths[0]+".gz", "\na\nb")
+		assertGzipFile(t, paths[1]+".gz", "\ncccc")
+		assertFile(t, paths[2], "\nd")
+		if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+			t.Errorf("compressed part is not removed: %v", err)
+		}
+
+		got := readManifest(t, manifest)
+		if len(got.Parts) != 3 {
+			t.Fatalf("manifest has %d parts, want 3", len(got.Parts))
+		}
+		for i, part := range got.Parts[:2] {
+			if part.Path != paths[i]+".gz" || !part.Compressed {
+				t.Errorf("part %d = %+v, want compressed", i+1, part)
+			}
+		}
+		if part := got.Parts[2]; part.Path != paths[2] || part.Compressed || part.Bytes != 2 {
+			t.Errorf("part 3 = %+v, want the current part", part)
+		}
+	})
+}
+
+func TestRotatingFileWriter_Reopen(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dir := t.TempDir()
+		w, err := NewRotatingFileWriter(filepath.Join(dir, "{session}.txt"), "s", Rotation{}, "", false, "", 0, testutil.NewFakeClock(time.Now()))
+		if err != nil {
+			t.Fatalf("NewRotatingFileWriter() error = %v", err)
+		}
+
+		write(t, w, "\na")
+		// the part is moved away as logrotate does.
+		path := filepath.Join(dir, "s.txt")
+		rotated := filepath.Join(dir, "s.txt.1")
+		if err := os.Rename(path, rotated); err != nil {
+			t.Fatalf("failed to rename file: %v", err)
+		}
+		if err := w.Reopen(); err != nil {
+			t.Fatalf("RotatingFileWriter.Reopen() error = %v", err)
+		}
+		write(t, w, "\nb")
+		if err := w.Close(); err != nil {
+			t.Fatalf("RotatingFileWriter.Close() error = %v", err)
+		}
+
+		assertFile(t, rotated, "\na")
+		assertFile(t, path, "\nb")
+		if _, err := w.Write([]byte("\nc")); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("RotatingFileWriter.Write() after Close error = %v, want %v", err, os.ErrClosed)
+		}
+	})
+}
+
+func write(t *testing.T, w io.Writer, s string) {
+	t.Helper()
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(b) != want {
+		t.Errorf("%s = %q, want %q", path, b, want)
+	}
+}
+
+func assertGzipFile(t *testing.T, path, want string) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read gzip: %v", err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to read gzip: %v", err)
+	}
+	if string(b) != want {
+		t.Errorf("%s = %q, want %q", path, b, want)
+	}
+}
+
+func readManifest(t *testing.T, path string) Manifest {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("failed to unmarshal manifest: %v", err)
+	}
+	return m
+}
@@ -0,0 +1,58 @@
+package ingest
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+// Format is the only audio format accepted, which is the format of the pipeline.
+const Format = "S16LE"
+
+// Header is the handshake sent by the client before the audio on TCP and WebSocket,
+// e.g. {"token": "secret", "format": "S16LE", "rate": 16000, "channels": 1}.
+// The format fields can be omitted, and are checked if given since the audio is not converted.
+type Header struct {
+	Token    string `json:"token,omitempty"`
+	Format   string `json:"format,omitempty"`
+	Rate     int    `json:"rate,omitempty"`
+	Channels int    `json:"channels,omitempty"`
+}
+
+// HeaderReply is the reply to the header. Error is empty if the header is accepted.
+type HeaderReply struct {
+	Format   string `json:"format,omitempty"`
+	Rate     int    `json:"rate,omitempty"`
+	Channels int    `json:"channels,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+var errUnauthorized = errors.New("invalid token")
+
+// negotiate checks the header sent by the client and returns the reply.
+// token is the token the client must send. Any client is accepted if it is empty.
+func negotiate(data []byte, token string) (HeaderReply, error) {
+	var h Header
+	if err := json.Unmarshal(data, &h); err != nil {
+		err = fmt.Errorf("invalid header: %w", err)
+		return HeaderReply{Error: err.Error()}, err
+	}
+
+	if token != "" && subtle.ConstantTimeCompare([]byte(h.Token), []byte(token)) != 1 {
+		return HeaderReply{Error: errUnauthorized.Error()}, errUnauthorized
+	}
+	if (h.Format != "" && h.Format != Format) ||
+		(h.Rate != 0 && h.Rate != model.SampleRate) ||
+		(h.Channels != 0 && h.Channels != 1) {
+		err := fmt.Errorf(
+			"unsupported format %s/%d/%d, only %s/%d/1 is supported",
+			h.Format, h.Rate, h.Channels, Format, model.SampleRate,
+		)
+		return HeaderReply{Error: err.Error()}, err
+	}
+
+	return HeaderReply{Format: Format, Rate: model.SampleRate, Channels: 1}, nil
+}
@@ -0,0 +1,65 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_negotiate(t *testing.T) {
+	accepted := HeaderReply{Format: "S16LE", Rate: 16000, Channels: 1}
+	tests := []struct {
+		name    string
+		header  string
+		token   string
+		want    HeaderReply
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			header:  `{"token": "secret", "format": "S16LE", "rate": 16000, "channels": 1}`,
+			token:   "secret",
+			want:    accepted,
+			wantErr: false,
+		},
+		{
+			name:    "format omitted without token",
+			header:  `{}`,
+			token:   "",
+			want:    accepted,
+			wantErr: false,
+		},
+		{
+			name:    "invalid token",
+			header:  `{"token": "wrong"}`,
+			token:   "secret",
+			want:    HeaderReply{Error: "invalid token"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported rate",
+			header:  `{"rate": 44100}`,
+			token:   "",
+			want:    HeaderReply{Error: "unsupported format /44100/0, only S16LE/16000/1 is supported"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			header:  `{`,
+			token:   "",
+			want:    HeaderReply{Error: "invalid header: unexpected end of JSON input"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := negotiate([]byte(tt.header), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("negotiate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("negotiate() (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// Listen listens on the address and returns the session of the audio received.
+// The address is one of:
+//
+//   - tcp://host:port
+//   - ws://host:port/path
+//   - rtp://host:port?pt=96
+//
+// token is the token the clients must send in the header. RTP does not support it.
+// When the server fails, Read of the session returns the error.
+func Listen(ctx context.Context, address string, token string) (*Session, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address: %w", err)
+	}
+
+	var serve func(ctx context.Context, session *Session) error
+	switch u.Scheme {
+	case "tcp":
+		lis, err := net.Listen("tcp", u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		serve = func(ctx context.Context, session *Session) error {
+			return ServeTCP(ctx, lis, session, token)
+		}
+	case "ws":
+		path := u.Path
+		if path == "" {
+			path = "/"
+		}
+		lis, err := net.Listen("tcp", u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		serve = func(ctx context.Context, session *Session) error {
+			return ServeWebSocket(ctx, lis, path, session, token)
+		}
+	case "rtp":
+		if token != "" {
+			return nil, errors.New("token is not supported by RTP")
+		}
+		payloadType := DefaultRTPPayloadType
+		if pt := u.Query().Get("pt"); pt != "" {
+			if payloadType, err = strconv.Atoi(pt); err != nil {
+				return nil, fmt.Errorf("invalid payload type: %w", err)
+			}
+		}
+		conn, err := net.ListenPacket("udp", u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		serve = func(ctx context.Context, session *Session) error {
+			return ServeRTP(ctx, conn, session, payloadType)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported listen scheme %q", u.Scheme)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	session := NewSession(ctx)
+	go func() {
+		if err := serve(ctx, session); err != nil {
+			cancel(err)
+			return
+		}
+		cancel(nil)
+	}()
+	slog.Info(fmt.Sprintf("Ingest: listening on %s", address))
+
+	return session, nil
+}
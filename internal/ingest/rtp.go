@@ -0,0 +1,151 @@
+package ingest
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+)
+
+// DefaultRTPPayloadType is the dynamic payload type of L16 at 16000Hz mono by default.
+const DefaultRTPPayloadType = 96
+
+// maxRTPPacketSize is the maximum size of a UDP datagram.
+const maxRTPPacketSize = 65536
+
+// ServeRTP receives the RTP packets of L16 audio at 16000Hz mono on conn and
+// attaches the stream to the session. The packets of the other payload
+// types, out of order or duplicated are dropped. A new SSRC is taken as a
+// reconnecting client. RTP has no handshake, so the format is configured by
+// payloadType and the client is not authenticated. It returns nil when ctx is done.
+func ServeRTP(ctx context.Context, conn net.PacketConn, session *Session, payloadType int) error {
+	if payloadType < 0 || payloadType > 127 {
+		return fmt.Errorf("invalid payload type %d", payloadType)
+	}
+	context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+
+	session.Attach(ctx, &rtpReader{conn: conn, payloadType: uint8(payloadType)}, conn.LocalAddr().String())
+	return nil
+}
+
+var _ io.ReadCloser = (*rtpReader)(nil)
+
+// rtpReader reads the audio in the RTP packets as LINEAR16.
+type rtpReader struct {
+	conn        net.PacketConn
+	payloadType uint8
+
+	buf []byte
+	// pending is the audio of the last packet left to read.
+	pending []byte
+
+	ssrc    uint32
+	lastSeq uint16
+	// started is true once a packet of ssrc is accepted.
+	started bool
+}
+
+func (r *rtpReader) Read(p []byte) (int, error) {
+	if r.buf == nil {
+		r.buf = make([]byte, maxRTPPacketSize)
+	}
+
+	for len(r.pending) == 0 {
+		n, addr, err := r.conn.ReadFrom(r.buf)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read packet: %w", err)
+		}
+
+		pkt, err := parseRTP(r.buf[:n])
+		if err != nil {
+			slog.Debug(fmt.Sprintf("Ingest: packet from %s dropped: %v", addr, err))
+			continue
+		}
+		if pkt.payloadType != r.payloadType {
+			slog.Debug(fmt.Sprintf("Ingest: packet from %s dropped: payload type %d", addr, pkt.payloadType))
+			continue
+		}
+		if !r.accept(pkt) {
+			continue
+		}
+		if !r.started || pkt.ssrc != r.ssrc {
+			slog.Info(fmt.Sprintf("Ingest: RTP stream %08x from %s started", pkt.ssrc, addr))
+		}
+
+		// L16 is big-endian in RTP.
+		payload := pkt.payload[:len(pkt.payload)-len(pkt.payload)%2]
+		for i := 0; i < len(payload); i += 2 {
+			payload[i], payload[i+1] = payload[i+1], payload[i]
+		}
+		r.pending = payload
+		r.ssrc = pkt.ssrc
+		r.lastSeq = pkt.seq
+		r.started = true
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// accept reports whether the packet is newer than the last one of the stream.
+func (r *rtpReader) accept(pkt rtpPacket) bool {
+	if !r.started || pkt.ssrc != r.ssrc {
+		return true
+	}
+	// the sequence number wraps around.
+	return int16(pkt.seq-r.lastSeq) > 0
+}
+
+func (r *rtpReader) Close() error {
+	return r.conn.Close()
+}
+
+type rtpPacket struct {
+	payloadType uint8
+	seq         uint16
+	ssrc        uint32
+	payload     []byte
+}
+
+// parseRTP parses the packet in the format of RFC 3550.
+func parseRTP(b []byte) (rtpPacket, error) {
+	if len(b) < 12 {
+		return rtpPacket{}, errors.New("packet is too short")
+	}
+	if version := b[0] >> 6; version != 2 {
+		return rtpPacket{}, fmt.Errorf("unsupported version %d", version)
+	}
+	padding := b[0]&0x20 != 0
+	extension := b[0]&0x10 != 0
+	csrcCount := int(b[0] & 0x0f)
+
+	pkt := rtpPacket{
+		payloadType: b[1] & 0x7f,
+		seq:         binary.BigEndian.Uint16(b[2:4]),
+		ssrc:        binary.BigEndian.Uint32(b[8:12]),
+	}
+
+	offset := 12 + 4*csrcCount
+	if extension {
+		if len(b) < offset+4 {
+			return rtpPacket{}, errors.New("extension header is truncated")
+		}
+		offset += 4 + 4*int(binary.BigEndian.Uint16(b[offset+2:offset+4]))
+	}
+	end := len(b)
+	if padding && end > 0 {
+		end -= int(b[end-1])
+	}
+	if offset > end {
+		return rtpPacket{}, errors.New("packet is truncated")
+	}
+
+	pkt.payload = b[offset:end]
+	return pkt, nil
+}
@@ -0,0 +1,131 @@
+package ingest
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// rtpPacketBytes builds an RTP packet without the optional fields.
+func rtpPacketBytes(payloadType uint8, seq uint16, ssrc uint32, payload []byte) []byte {
+	b := make([]byte, 12, 12+len(payload))
+	b[0] = 2 << 6
+	b[1] = payloadType
+	binary.BigEndian.PutUint16(b[2:4], seq)
+	binary.BigEndian.PutUint32(b[8:12], ssrc)
+	return append(b, payload...)
+}
+
+func TestServeRTP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	session := NewSession(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ServeRTP(ctx, conn, session, DefaultRTPPayloadType)
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	packets := [][]byte{
+		rtpPacketBytes(96, 10, 1, []byte{0x01, 0x02}),
+		// the other payload type.
+		rtpPacketBytes(0, 11, 1, []byte{0xff, 0xff}),
+		// out of order.
+		rtpPacketBytes(96, 9, 1, []byte{0xff, 0xff}),
+		rtpPacketBytes(96, 11, 1, []byte{0x03, 0x04}),
+		// a reconnecting client starts a new sequence.
+		rtpPacketBytes(96, 0, 2, []byte{0x05, 0x06}),
+	}
+	for _, p := range packets {
+		if _, err := client.Write(p); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+
+	// the samples are converted to little-endian.
+	want := string([]byte{0x02, 0x01, 0x04, 0x03, 0x06, 0x05})
+	if got := readN(t, session, len(want)); got != want {
+		t.Errorf("audio = %x, want %x", got, want)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("ServeRTP() error = %v", err)
+	}
+}
+
+func Test_parseRTP(t *testing.T) {
+	tests := []struct {
+		name    string
+		packet  []byte
+		want    rtpPacket
+		wantErr bool
+	}{
+		{
+			name:   "success",
+			packet: rtpPacketBytes(96, 1, 2, []byte{0x01, 0x02}),
+			want:   rtpPacket{payloadType: 96, seq: 1, ssrc: 2, payload: []byte{0x01, 0x02}},
+		},
+		{
+			name: "csrc, extension and padding",
+			packet: func() []byte {
+				b := rtpPacketBytes(96, 1, 2, nil)
+				b[0] |= 0x20 | 0x10 | 1
+				b = append(b, 0, 0, 0, 3)    // csrc
+				b = append(b, 0, 0, 0, 1)    // extension header of a word
+				b = append(b, 0, 0, 0, 0)    // extension
+				b = append(b, 0x01, 0x02)    // payload
+				return append(b, 0, 0, 0, 4) // padding
+			}(),
+			want: rtpPacket{payloadType: 96, seq: 1, ssrc: 2, payload: []byte{0x01, 0x02}},
+		},
+		{
+			name:    "too short",
+			packet:  []byte{0x80, 96},
+			wantErr: true,
+		},
+		{
+			name: "unsupported version",
+			packet: func() []byte {
+				b := rtpPacketBytes(96, 1, 2, nil)
+				b[0] = 1 << 6
+				return b
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "truncated",
+			packet: func() []byte {
+				b := rtpPacketBytes(96, 1, 2, nil)
+				b[0] |= 2
+				return b
+			}(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRTP(tt.packet)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseRTP() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want, cmp.AllowUnexported(rtpPacket{})); diff != "" {
+				t.Errorf("parseRTP() (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
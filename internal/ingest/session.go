@@ -0,0 +1,145 @@
+// Package ingest receives the audio from the clients over the network.
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+var _ io.Reader = (*Session)(nil)
+
+// Session is the reader of the audio of a session, which is the audio of the
+// connections concatenated. When a connection ends, Read waits for the next
+// one, so that a reconnecting client resumes the same session. A new
+// connection replaces the current one, since the client reconnecting after
+// a network failure may find the old connection not closed yet.
+type Session struct {
+	ctx    context.Context
+	connCh chan *conn
+
+	mu sync.Mutex
+	// active is the latest connection attached.
+	active *conn
+
+	// cur is the connection read by Read.
+	cur *conn
+	// partial is the byte of the incomplete sample at the end of the audio
+	// read from cur, which is returned with the rest of the sample. It is
+	// dropped when cur ends, so that the audio of the next connection starts
+	// on the boundary of the samples.
+	partial []byte
+}
+
+// conn is a connection attached to the session.
+type conn struct {
+	io.ReadCloser
+	remote string
+
+	once sync.Once
+	// done is closed when the session stops reading the connection.
+	done chan struct{}
+}
+
+func (c *conn) close() {
+	c.once.Do(func() {
+		if err := c.Close(); err != nil {
+			slog.Debug(fmt.Sprintf("Session: failed to close connection from %s: %v", c.remote, err))
+		}
+		close(c.done)
+	})
+}
+
+// NewSession creates a session. Read returns the cause of ctx when it is done.
+func NewSession(ctx context.Context) *Session {
+	s := &Session{
+		ctx:    ctx,
+		connCh: make(chan *conn),
+	}
+	// unblocks Read on the current connection.
+	context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.active != nil {
+			s.active.close()
+		}
+	})
+	return s
+}
+
+// Attach makes the session read the audio from r, replacing the current connection.
+// It blocks until the session stops reading r or ctx is done, and closes r.
+func (s *Session) Attach(ctx context.Context, r io.ReadCloser, remote string) {
+	c := &conn{ReadCloser: r, remote: remote, done: make(chan struct{})}
+	defer c.close()
+
+	s.mu.Lock()
+	if s.active != nil {
+		slog.Info(fmt.Sprintf("Session: connection from %s is replaced by %s", s.active.remote, remote))
+		// unblocks Read on the old connection.
+		s.active.close()
+	}
+	s.active = c
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-s.ctx.Done():
+		return
+	case <-c.done:
+		// replaced before the session reads it.
+		return
+	case s.connCh <- c:
+	}
+	slog.Info(fmt.Sprintf("Session: connection from %s attached", remote))
+
+	select {
+	case <-ctx.Done():
+	case <-s.ctx.Done():
+	case <-c.done:
+	}
+}
+
+// Read reads the audio in whole samples. p must have room for a sample.
+func (s *Session) Read(p []byte) (int, error) {
+	if len(p) < model.BytesPerSample {
+		return 0, io.ErrShortBuffer
+	}
+
+	for {
+		if s.cur == nil {
+			select {
+			case <-s.ctx.Done():
+				return 0, context.Cause(s.ctx)
+			case c := <-s.connCh:
+				s.cur = c
+			}
+		}
+
+		k := copy(p, s.partial)
+		n, err := s.cur.Read(p[k:])
+		n += k
+		whole := n - n%model.BytesPerSample
+		s.partial = append(s.partial[:0], p[whole:n]...)
+		if err != nil {
+			if err != io.EOF {
+				slog.Debug(fmt.Sprintf("Session: failed to read from %s: %v", s.cur.remote, err))
+			}
+			if len(s.partial) > 0 {
+				slog.Debug(fmt.Sprintf("Session: incomplete sample from %s dropped", s.cur.remote))
+				s.partial = s.partial[:0]
+			}
+			slog.Info(fmt.Sprintf("Session: connection from %s detached", s.cur.remote))
+			s.cur.close()
+			s.cur = nil
+		}
+		if whole > 0 {
+			return whole, nil
+		}
+	}
+}
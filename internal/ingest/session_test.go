@@ -0,0 +1,148 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSession_Read(t *testing.T) {
+	t.Run("resume", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s := NewSession(ctx)
+
+		// the second connection is attached after the first one ends.
+		attached := make(chan struct{})
+		go func() {
+			s.Attach(ctx, io.NopCloser(strings.NewReader("ab")), "first")
+			close(attached)
+			s.Attach(ctx, io.NopCloser(strings.NewReader("cd")), "second")
+		}()
+
+		got := readN(t, s, 4)
+		if got != "abcd" {
+			t.Errorf("Read() = %q, want %q", got, "abcd")
+		}
+		<-attached
+	})
+
+	t.Run("incomplete sample before reconnect", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s := NewSession(ctx)
+
+		// the first connection ends in the middle of the second sample, which
+		// is dropped so that the next connection starts on a sample.
+		attached := make(chan struct{})
+		go func() {
+			s.Attach(ctx, io.NopCloser(strings.NewReader("abc")), "first")
+			close(attached)
+			s.Attach(ctx, io.NopCloser(strings.NewReader("de")), "second")
+		}()
+
+		got := readN(t, s, 4)
+		if got != "abde" {
+			t.Errorf("Read() = %q, want %q", got, "abde")
+		}
+		<-attached
+	})
+
+	t.Run("incomplete sample completed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s := NewSession(ctx)
+
+		// the sample split between the reads is returned as a whole.
+		r, w := io.Pipe()
+		go s.Attach(ctx, r, "first")
+		go func() {
+			w.Write([]byte("abc"))
+			w.Write([]byte("d"))
+		}()
+
+		buf := make([]byte, 4)
+		n, err := s.Read(buf)
+		if err != nil || string(buf[:n]) != "ab" {
+			t.Errorf("Read() = %q, %v, want %q", buf[:n], err, "ab")
+		}
+		n, err = s.Read(buf)
+		if err != nil || string(buf[:n]) != "cd" {
+			t.Errorf("Read() = %q, %v, want %q", buf[:n], err, "cd")
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s := NewSession(ctx)
+
+		// the first connection never ends until it is closed.
+		r1, w1 := io.Pipe()
+		go s.Attach(ctx, r1, "first")
+		go func() {
+			w1.Write([]byte("ab"))
+		}()
+		if got := readN(t, s, 2); got != "ab" {
+			t.Errorf("Read() = %q, want %q", got, "ab")
+		}
+
+		go s.Attach(ctx, io.NopCloser(strings.NewReader("cd")), "second")
+		if got := readN(t, s, 2); got != "cd" {
+			t.Errorf("Read() = %q, want %q", got, "cd")
+		}
+		if _, err := w1.Write([]byte("x")); err == nil {
+			t.Error("first connection is not closed")
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := NewSession(ctx)
+
+		r, _ := io.Pipe()
+		go s.Attach(context.Background(), r, "first")
+
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := s.Read(make([]byte, 2))
+			errCh <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		select {
+		case err := <-errCh:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Read() error = %v, want %v", err, context.Canceled)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Read() is not unblocked by cancellation")
+		}
+	})
+}
+
+// readN reads n bytes from r or fails after a while.
+func readN(t *testing.T, r io.Reader, n int) string {
+	t.Helper()
+
+	ch := make(chan string, 1)
+	go func() {
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			t.Errorf("failed to read: %v", err)
+		}
+		ch <- string(buf)
+	}()
+
+	select {
+	case s := <-ch:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out reading the session")
+		return ""
+	}
+}
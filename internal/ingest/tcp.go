@@ -0,0 +1,92 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// handshakeTimeout is the time limit for the client to send the header.
+const handshakeTimeout = 10 * time.Second
+
+// maxHeaderSize is the maximum size of the header line on TCP.
+const maxHeaderSize = 4096
+
+// ServeTCP accepts the connections on lis and attaches them to the session.
+// The client sends the header in a JSON line followed by the audio, and the
+// server replies to the header in a JSON line. It returns nil when ctx is done.
+func ServeTCP(ctx context.Context, lis net.Listener, session *Session, token string) error {
+	context.AfterFunc(ctx, func() {
+		lis.Close()
+	})
+
+	for {
+		c, err := lis.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+		go handleTCP(ctx, c, session, token)
+	}
+}
+
+func handleTCP(ctx context.Context, c net.Conn, session *Session, token string) {
+	remote := c.RemoteAddr().String()
+
+	r, err := handshakeTCP(c, token)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Ingest: handshake with %s failed: %v", remote, err))
+		c.Close()
+		return
+	}
+
+	session.Attach(ctx, r, remote)
+}
+
+// handshakeTCP reads the header and replies to it.
+// It returns the reader of the audio following the header.
+func handshakeTCP(c net.Conn, token string) (io.ReadCloser, error) {
+	if err := c.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return nil, fmt.Errorf("failed to set deadline: %w", err)
+	}
+
+	br := bufio.NewReaderSize(c, maxHeaderSize)
+	line, err := br.ReadSlice('\n')
+	if err != nil {
+		if errors.Is(err, bufio.ErrBufferFull) {
+			err = errors.New("header is too long")
+		}
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	reply, negotiateErr := negotiate(line, token)
+	b, err := json.Marshal(reply)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reply: %w", err)
+	}
+	if _, err := c.Write(append(b, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write reply: %w", err)
+	}
+	if negotiateErr != nil {
+		return nil, negotiateErr
+	}
+
+	if err := c.SetDeadline(time.Time{}); err != nil {
+		return nil, fmt.Errorf("failed to clear deadline: %w", err)
+	}
+	return &bufferedConn{Reader: br, Closer: c}, nil
+}
+
+// bufferedConn reads the connection through the buffer which may hold the audio read with the header.
+type bufferedConn struct {
+	io.Reader
+	io.Closer
+}
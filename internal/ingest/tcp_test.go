@@ -0,0 +1,81 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestServeTCP(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		wantReply HeaderReply
+		wantAudio string
+	}{
+		{
+			name:      "success",
+			header:    `{"token": "secret", "format": "S16LE", "rate": 16000, "channels": 1}`,
+			wantReply: HeaderReply{Format: "S16LE", Rate: 16000, Channels: 1},
+			wantAudio: "sample",
+		},
+		{
+			name:      "invalid token",
+			header:    `{"token": "wrong"}`,
+			wantReply: HeaderReply{Error: "invalid token"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+			session := NewSession(ctx)
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- ServeTCP(ctx, lis, session, "secret")
+			}()
+
+			c, err := net.Dial("tcp", lis.Addr().String())
+			if err != nil {
+				t.Fatalf("failed to dial: %v", err)
+			}
+			defer c.Close()
+			// the audio is sent with the header to check that it is not lost.
+			if _, err := c.Write([]byte(tt.header + "\n" + tt.wantAudio)); err != nil {
+				t.Fatalf("failed to write: %v", err)
+			}
+
+			line, err := bufio.NewReader(c).ReadBytes('\n')
+			if err != nil {
+				t.Fatalf("failed to read reply: %v", err)
+			}
+			var reply HeaderReply
+			if err := json.Unmarshal(line, &reply); err != nil {
+				t.Fatalf("failed to unmarshal reply: %v", err)
+			}
+			if diff := cmp.Diff(reply, tt.wantReply); diff != "" {
+				t.Errorf("reply (-got +want):\n%s", diff)
+			}
+
+			if tt.wantAudio != "" {
+				if got := readN(t, session, len(tt.wantAudio)); got != tt.wantAudio {
+					t.Errorf("audio = %q, want %q", got, tt.wantAudio)
+				}
+			}
+
+			cancel()
+			if err := <-errCh; err != nil {
+				t.Errorf("ServeTCP() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// ServeWebSocket serves the WebSocket endpoint at path on lis and attaches
+// the connections to the session. The client sends the header as a text
+// message, and the server replies to it as a text message. The audio follows
+// in binary messages. It returns nil when ctx is done.
+func ServeWebSocket(ctx context.Context, lis net.Listener, path string, session *Session, token string) error {
+	mux := http.NewServeMux()
+	mux.Handle(path, websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			handleWebSocket(ctx, ws, session, token)
+		},
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: handshakeTimeout}
+
+	context.AfterFunc(ctx, func() {
+		// the handlers end by ctx since the connections are hijacked.
+		server.Close()
+	})
+
+	if err := server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve WebSocket: %w", err)
+	}
+	return nil
+}
+
+func handleWebSocket(ctx context.Context, ws *websocket.Conn, session *Session, token string) {
+	remote := ws.Request().RemoteAddr
+
+	if err := handshakeWebSocket(ws, token); err != nil {
+		slog.Warn(fmt.Sprintf("Ingest: handshake with %s failed: %v", remote, err))
+		ws.Close()
+		return
+	}
+
+	ws.PayloadType = websocket.BinaryFrame
+	session.Attach(ctx, ws, remote)
+}
+
+func handshakeWebSocket(ws *websocket.Conn, token string) error {
+	if err := ws.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return fmt.Errorf("failed to set deadline: %w", err)
+	}
+
+	var header string
+	if err := websocket.Message.Receive(ws, &header); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	reply, negotiateErr := negotiate([]byte(header), token)
+	if err := websocket.JSON.Send(ws, reply); err != nil {
+		return fmt.Errorf("failed to write reply: %w", err)
+	}
+	if negotiateErr != nil {
+		return negotiateErr
+	}
+
+	if err := ws.SetDeadline(time.Time{}); err != nil {
+		return fmt.Errorf("failed to clear deadline: %w", err)
+	}
+	return nil
+}
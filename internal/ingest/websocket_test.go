@@ -0,0 +1,78 @@
+package ingest
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/websocket"
+)
+
+func TestServeWebSocket(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		wantReply HeaderReply
+		wantAudio string
+	}{
+		{
+			name:      "success",
+			header:    `{"token": "secret"}`,
+			wantReply: HeaderReply{Format: "S16LE", Rate: 16000, Channels: 1},
+			wantAudio: "sample",
+		},
+		{
+			name:      "unsupported format",
+			header:    `{"token": "secret", "channels": 2}`,
+			wantReply: HeaderReply{Error: "unsupported format /0/2, only S16LE/16000/1 is supported"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+			session := NewSession(ctx)
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- ServeWebSocket(ctx, lis, "/audio", session, "secret")
+			}()
+
+			ws, err := websocket.Dial("ws://"+lis.Addr().String()+"/audio", "", "http://localhost/")
+			if err != nil {
+				t.Fatalf("failed to dial: %v", err)
+			}
+			defer ws.Close()
+
+			if err := websocket.Message.Send(ws, tt.header); err != nil {
+				t.Fatalf("failed to send header: %v", err)
+			}
+			var reply HeaderReply
+			if err := websocket.JSON.Receive(ws, &reply); err != nil {
+				t.Fatalf("failed to receive reply: %v", err)
+			}
+			if diff := cmp.Diff(reply, tt.wantReply); diff != "" {
+				t.Errorf("reply (-got +want):\n%s", diff)
+			}
+
+			if tt.wantAudio != "" {
+				if err := websocket.Message.Send(ws, []byte(tt.wantAudio)); err != nil {
+					t.Fatalf("failed to send audio: %v", err)
+				}
+				if got := readN(t, session, len(tt.wantAudio)); got != tt.wantAudio {
+					t.Errorf("audio = %q, want %q", got, tt.wantAudio)
+				}
+			}
+
+			cancel()
+			if err := <-errCh; err != nil {
+				t.Errorf("ServeWebSocket() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Package metric provides the metrics to evaluate transcripts.
+package metric
+
+import (
+	"strings"
+	"unicode"
+)
+
+// EditDistance returns the Levenshtein distance between ref and hyp.
+func EditDistance[T comparable](ref, hyp []T) int {
+	prev := make([]int, len(hyp)+1)
+	cur := make([]int, len(hyp)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ref); i++ {
+		cur[0] = i
+		for j := 1; j <= len(hyp); j++ {
+			cost := 1
+			if ref[i-1] == hyp[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(hyp)]
+}
+
+// Characters returns the characters of s without whitespaces.
+func Characters(s string) []rune {
+	return []rune(strings.Join(strings.FieldsFunc(s, unicode.IsSpace), ""))
+}
+
+// ErrorRate returns the edit distance divided by the length of ref.
+// If ref is empty, it returns 0 when hyp is also empty and 1 otherwise.
+func ErrorRate[T comparable](ref, hyp []T) float64 {
+	if len(ref) == 0 {
+		if len(hyp) == 0 {
+			return 0
+		}
+		return 1
+	}
+	return float64(EditDistance(ref, hyp)) / float64(len(ref))
+}
+
+// CER returns the character error rate of hyp against ref ignoring whitespaces.
+func CER(ref, hyp string) float64 {
+	return ErrorRate(Characters(ref), Characters(hyp))
+}
+
+// WER returns the word error rate of the words of hyp against those of ref.
+func WER(ref, hyp []string) float64 {
+	return ErrorRate(ref, hyp)
+}
+
+// Normalize removes the punctuations and symbols from s, and folds the
+// full-width alphanumerics into the half-width lower-case ones so that the
+// differences of the notation are not counted as errors.
+// Whitespaces are kept to keep the word boundaries.
+func Normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return -1
+		}
+		// U+FF01..U+FF5E are the full-width forms of U+0021..U+007E.
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFF01 - 0x21
+		}
+		if r == '\u3000' {
+			r = ' '
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
@@ -0,0 +1,92 @@
+package metric
+
+import (
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  string
+		hyp  string
+		want int
+	}{
+		{name: "same", ref: "こんにちは", hyp: "こんにちは", want: 0},
+		{name: "substitution", ref: "こんにちは", hyp: "こんにちわ", want: 1},
+		{name: "insertion", ref: "今日は", hyp: "今日はね", want: 1},
+		{name: "deletion", ref: "今日は晴れ", hyp: "今日晴れ", want: 1},
+		{name: "empty ref", ref: "", hyp: "abc", want: 3},
+		{name: "empty hyp", ref: "abc", hyp: "", want: 3},
+		{name: "kitten", ref: "kitten", hyp: "sitting", want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EditDistance([]rune(tt.ref), []rune(tt.hyp)); got != tt.want {
+				t.Errorf("EditDistance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCER(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  string
+		hyp  string
+		want float64
+	}{
+		{name: "same", ref: "こんにちは", hyp: "こんにちは", want: 0},
+		{name: "whitespaces are ignored", ref: "こんにちは 世界", hyp: "こんにちは世界", want: 0},
+		{name: "substitution", ref: "こんにちは", hyp: "こんにちわ", want: 0.2},
+		{name: "both empty", ref: "", hyp: " ", want: 0},
+		{name: "empty ref", ref: "", hyp: "あ", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CER(tt.ref, tt.hyp); got != tt.want {
+				t.Errorf("CER() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWER(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  []string
+		hyp  []string
+		want float64
+	}{
+		{name: "same", ref: []string{"今日", "は", "晴れ"}, hyp: []string{"今日", "は", "晴れ"}, want: 0},
+		{name: "substitution", ref: []string{"今日", "は", "晴れ", "です"}, hyp: []string{"今日", "わ", "晴れ", "です"}, want: 0.25},
+		{name: "empty ref", ref: nil, hyp: []string{"a"}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WER(tt.ref, tt.hyp); got != tt.want {
+				t.Errorf("WER() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "japanese punctuations", s: "こんにちは、世界。「はい」！", want: "こんにちは世界はい"},
+		{name: "ascii punctuations", s: "Hello, world!", want: "hello world"},
+		{name: "full-width alphanumerics", s: "ＡＢＣ１２３", want: "abc123"},
+		{name: "ideographic space", s: "今日は　晴れ", want: "今日は 晴れ"},
+		{name: "symbols", s: "100%＋α", want: "100α"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.s); got != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package postprocess
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hekt/voice-recognition/internal/punctuator"
+)
+
+// The names of the built-in processors.
+const (
+	NamePunctuation = "punctuation"
+	NameFillers     = "fillers"
+	NameNumbers     = "numbers"
+	NameDictionary  = "dictionary"
+	NameWhitespace  = "whitespace"
+)
+
+// Names are the names of the built-in processors.
+var Names = []string{NamePunctuation, NameFillers, NameNumbers, NameDictionary, NameWhitespace}
+
+var ErrUnknownProcessor = errors.New("unknown processor")
+
+// Options are the resources of the built-in processors.
+type Options struct {
+	// Punctuator is used by the punctuation processor.
+	Punctuator punctuator.PunctuatorInterface
+	// Fillers are removed by the fillers processor. DefaultFillers if empty.
+	Fillers []string
+	// Dictionary is used by the dictionary processor.
+	Dictionary map[string]string
+}
+
+// Build builds the chain of the built-in processors in the order of names.
+func Build(names []string, options Options) (*Chain, error) {
+	processors := make([]Processor, 0, len(names))
+	for _, name := range names {
+		p, err := build(name, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build processor %s: %w", name, err)
+		}
+		processors = append(processors, p)
+	}
+	return NewChain(processors...), nil
+}
+
+func build(name string, options Options) (Processor, error) {
+	switch name {
+	case NamePunctuation:
+		return NewPunctuation(options.Punctuator)
+	case NameFillers:
+		fillers := options.Fillers
+		if len(fillers) == 0 {
+			fillers = DefaultFillers
+		}
+		return NewFillerRemover(fillers)
+	case NameNumbers:
+		return NewNumberNormalizer(), nil
+	case NameDictionary:
+		return NewDictionary(options.Dictionary)
+	case NameWhitespace:
+		return NewWhitespaceCleaner(), nil
+	default:
+		return nil, fmt.Errorf("%w, available processors: %s", ErrUnknownProcessor, strings.Join(Names, ", "))
+	}
+}
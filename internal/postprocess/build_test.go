@@ -0,0 +1,34 @@
+package postprocess
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		chain, err := Build(
+			[]string{NameFillers, NameNumbers, NameDictionary, NameWhitespace},
+			Options{Dictionary: map[string]string{"ぐーぐる": "Google"}},
+		)
+		if err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+
+		if got, want := chain.Process("えーと、 ぐーぐる で 二十 件"), "Googleで20件"; got != want {
+			t.Errorf("Chain.Process() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("unknown processor", func(t *testing.T) {
+		if _, err := Build([]string{"unknown"}, Options{}); !errors.Is(err, ErrUnknownProcessor) {
+			t.Errorf("Build() error = %v, want %v", err, ErrUnknownProcessor)
+		}
+	})
+
+	t.Run("missing resource", func(t *testing.T) {
+		if _, err := Build([]string{NamePunctuation}, Options{}); err == nil {
+			t.Error("Build() error = nil, want an error")
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package postprocess
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+var _ Processor = (*Dictionary)(nil)
+
+// Dictionary replaces the words by the dictionary, e.g. the misrecognized
+// names. The longer word is replaced first if the words overlap.
+type Dictionary struct {
+	replacer *strings.Replacer
+}
+
+func NewDictionary(dictionary map[string]string) (*Dictionary, error) {
+	if len(dictionary) == 0 {
+		return nil, errors.New("dictionary must not be empty")
+	}
+	words := make([]string, 0, len(dictionary))
+	for word := range dictionary {
+		if word == "" {
+			return nil, errors.New("word must not be empty")
+		}
+		words = append(words, word)
+	}
+	// strings.Replacer tries the pairs in order at each position.
+	slices.SortFunc(words, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+	pairs := make([]string, 0, len(words)*2)
+	for _, word := range words {
+		pairs = append(pairs, word, dictionary[word])
+	}
+	return &Dictionary{replacer: strings.NewReplacer(pairs...)}, nil
+}
+
+func (d *Dictionary) Process(text string) (string, error) {
+	return d.replacer.Replace(text), nil
+}
+
+// LoadDictionary loads the JSON object mapping the words to their
+// replacements, e.g. {"ぐーぐる": "Google"}.
+func LoadDictionary(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %w", err)
+	}
+	var dictionary map[string]string
+	if err := json.Unmarshal(b, &dictionary); err != nil {
+		return nil, fmt.Errorf("failed to parse dictionary: %w", err)
+	}
+	return dictionary, nil
+}
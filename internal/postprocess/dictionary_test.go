@@ -0,0 +1,89 @@
+package postprocess
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewDictionary(t *testing.T) {
+	tests := []struct {
+		name       string
+		dictionary map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "success",
+			dictionary: map[string]string{"ぐーぐる": "Google"},
+		},
+		{
+			name:    "empty dictionary",
+			wantErr: true,
+		},
+		{
+			name:       "empty word",
+			dictionary: map[string]string{"": "Google"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDictionary(tt.dictionary)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewDictionary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDictionary_Process(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		d, err := NewDictionary(map[string]string{
+			"ぐーぐる":     "Google",
+			"ぐーぐるくらうど": "Google Cloud",
+			"ぼすく":      "Vosk",
+		})
+		if err != nil {
+			t.Fatalf("NewDictionary() error = %v", err)
+		}
+
+		got, err := d.Process("ぐーぐるくらうどとぐーぐるとぼすく")
+		if err != nil {
+			t.Fatalf("Dictionary.Process() error = %v", err)
+		}
+		// the longer word is replaced first.
+		if want := "Google CloudとGoogleとVosk"; got != want {
+			t.Errorf("Dictionary.Process() = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestLoadDictionary(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "dictionary.json")
+		if err := os.WriteFile(path, []byte(`{"ぐーぐる": "Google"}`), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		got, err := LoadDictionary(path)
+		if err != nil {
+			t.Fatalf("LoadDictionary() error = %v", err)
+		}
+		if diff := cmp.Diff(got, map[string]string{"ぐーぐる": "Google"}); diff != "" {
+			t.Errorf("LoadDictionary() (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "dictionary.json")
+		if err := os.WriteFile(path, []byte(`["ぐーぐる"]`), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		if _, err := LoadDictionary(path); err == nil {
+			t.Error("LoadDictionary() error = nil, want an error")
+		}
+	})
+}
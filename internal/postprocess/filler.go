@@ -0,0 +1,91 @@
+package postprocess
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultFillers are the fillers removed by FillerRemover by default. The
+// words which are also used as other than fillers, e.g. "あの" in "あの人",
+// are not included.
+var DefaultFillers = []string{
+	"えー", "えーと", "えっと", "えーっと", "ええと",
+	"あのー", "あのう", "そのー", "うーん", "んー", "まー",
+	"uh", "um", "uhm", "erm",
+}
+
+var _ Processor = (*FillerRemover)(nil)
+
+// FillerRemover removes the fillers, and the comma and the spaces following
+// them. A filler is removed only if it is a separate word, i.e. it is between
+// the start, the end, the spaces or the punctuations, so that "um" in
+// "umbrella" is kept. The long vowel marks after a filler, e.g. "えーー", are
+// removed together. The fillers are matched case-insensitively.
+type FillerRemover struct {
+	pattern *regexp.Regexp
+}
+
+func NewFillerRemover(fillers []string) (*FillerRemover, error) {
+	if len(fillers) == 0 {
+		return nil, errors.New("fillers must be specified")
+	}
+	// the longer filler is matched first, e.g. "えーと" before "えー".
+	sorted := slices.Clone(fillers)
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		return len(b) - len(a)
+	})
+	quoted := make([]string, 0, len(sorted))
+	for _, f := range sorted {
+		if f == "" {
+			return nil, errors.New("filler must not be empty")
+		}
+		quoted = append(quoted, regexp.QuoteMeta(f))
+	}
+	pattern, err := regexp.Compile(`(?i)((?:` + strings.Join(quoted, "|") + `)[ー〜~]*)([、，,]?\s*)`)
+	if err != nil {
+		return nil, err
+	}
+	return &FillerRemover{pattern: pattern}, nil
+}
+
+func (r *FillerRemover) Process(text string) (string, error) {
+	var b strings.Builder
+	last := 0
+	for _, m := range r.pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end, wordEnd := m[0], m[1], m[3]
+		if !isWordStart(text, start) || !isWordEnd(text, wordEnd, end) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String(), nil
+}
+
+// isWordStart tells whether a word starts at i of text.
+func isWordStart(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return isBoundary(r)
+}
+
+// isWordEnd tells whether the word ending at wordEnd of text is followed by a
+// comma or spaces up to end, or by a boundary.
+func isWordEnd(text string, wordEnd, end int) bool {
+	if end > wordEnd || end == len(text) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(text[end:])
+	return isBoundary(r)
+}
+
+func isBoundary(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r)
+}
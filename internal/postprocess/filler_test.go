@@ -0,0 +1,93 @@
+package postprocess
+
+import "testing"
+
+func TestNewFillerRemover(t *testing.T) {
+	tests := []struct {
+		name    string
+		fillers []string
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			fillers: DefaultFillers,
+		},
+		{
+			name:    "no fillers",
+			wantErr: true,
+		},
+		{
+			name:    "empty filler",
+			fillers: []string{"えー", ""},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFillerRemover(tt.fillers)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFillerRemover() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFillerRemover_Process(t *testing.T) {
+	r, err := NewFillerRemover(DefaultFillers)
+	if err != nil {
+		t.Fatalf("NewFillerRemover() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "with comma",
+			text: "えーと、今日は晴れです",
+			want: "今日は晴れです",
+		},
+		{
+			name: "separated by spaces",
+			text: "今日は えー えーー 晴れです",
+			want: "今日は 晴れです",
+		},
+		{
+			name: "after punctuation",
+			text: "はい。あのー、そうです",
+			want: "はい。そうです",
+		},
+		{
+			name: "whole text",
+			text: "うーん",
+			want: "",
+		},
+		{
+			name: "part of word",
+			text: "えーと言っていた",
+			want: "えーと言っていた",
+		},
+		{
+			name: "case-insensitive",
+			text: "Um, I think so",
+			want: "I think so",
+		},
+		{
+			name: "prefix of English word",
+			text: "um umbrella",
+			want: "umbrella",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Process(tt.text)
+			if err != nil {
+				t.Fatalf("FillerRemover.Process() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("FillerRemover.Process() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package postprocess
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var _ Processor = (*NumberNormalizer)(nil)
+
+// kanjiNumbers matches the sequences of the kanji numerals.
+var kanjiNumbers = regexp.MustCompile(`[〇零一二三四五六七八九十百千万億兆]+`)
+
+var (
+	kanjiDigits = map[rune]int64{
+		'〇': 0, '零': 0, '一': 1, '二': 2, '三': 3, '四': 4,
+		'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+	}
+	// kanjiUnits multiply the digit before them in a section of 4 digits.
+	kanjiUnits = map[rune]int64{'十': 10, '百': 100, '千': 1000}
+	// kanjiSections multiply the section before them.
+	kanjiSections = map[rune]int64{'万': 1e4, '億': 1e8, '兆': 1e12}
+)
+
+// NumberNormalizer writes the numbers in the half-width Arabic numerals, e.g.
+// "１２" as "12" and "二十四" as "24". A single kanji numeral is kept, since
+// it is often a part of a word, e.g. "一緒" or "十分", and so is the sequence
+// starting with "万", "億" or "兆", e.g. "万一". The approximate numbers, e.g.
+// "四五人" and "二十四五歳", are kept as well since they are not a single number.
+type NumberNormalizer struct{}
+
+func NewNumberNormalizer() *NumberNormalizer {
+	return &NumberNormalizer{}
+}
+
+func (n *NumberNormalizer) Process(text string) (string, error) {
+	text = strings.Map(func(r rune) rune {
+		if r >= '０' && r <= '９' {
+			return r - '０' + '0'
+		}
+		return r
+	}, text)
+
+	return kanjiNumbers.ReplaceAllStringFunc(text, func(s string) string {
+		runes := []rune(s)
+		if len(runes) < 2 {
+			return s
+		}
+		if _, ok := kanjiSections[runes[0]]; ok {
+			return s
+		}
+		if isApproximate(runes) {
+			return s
+		}
+		return strconv.FormatInt(parseKanjiNumber(runes), 10)
+	}), nil
+}
+
+// isApproximate tells whether the kanji numerals contain exactly two digits in
+// a row which are consecutive, e.g. "四五" meaning 4 or 5, rather than
+// positional digits such as "二〇二四".
+func isApproximate(runes []rune) bool {
+	var run []int64
+	for i, r := range runes {
+		if d, ok := kanjiDigits[r]; ok {
+			run = append(run, d)
+			if i < len(runes)-1 {
+				continue
+			}
+		}
+		if len(run) == 2 && run[0] != 0 && run[1] == run[0]+1 {
+			return true
+		}
+		run = run[:0]
+	}
+	return false
+}
+
+// parseKanjiNumber parses the kanji numerals, e.g. "二千二十四" and "二〇二四"
+// both as 2024.
+func parseKanjiNumber(runes []rune) int64 {
+	var total, section, digits int64
+	// hasDigits tells whether digits is pending, to tell "十" from "一十".
+	hasDigits := false
+	for _, r := range runes {
+		if d, ok := kanjiDigits[r]; ok {
+			// the digits in a row are positional, e.g. "二〇".
+			digits = digits*10 + d
+			hasDigits = true
+			continue
+		}
+		if u, ok := kanjiUnits[r]; ok {
+			if !hasDigits {
+				digits = 1
+			}
+			section += digits * u
+			digits, hasDigits = 0, false
+			continue
+		}
+		if s, ok := kanjiSections[r]; ok {
+			section += digits
+			if section == 0 {
+				section = 1
+			}
+			total += section * s
+			section, digits, hasDigits = 0, 0, false
+		}
+	}
+	return total + section + digits
+}
@@ -0,0 +1,74 @@
+package postprocess
+
+import "testing"
+
+func TestNumberNormalizer_Process(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "full-width digits",
+			text: "会議は１５時から",
+			want: "会議は15時から",
+		},
+		{
+			name: "kanji with units",
+			text: "二千二十四年に三百五十人",
+			want: "2024年に350人",
+		},
+		{
+			name: "kanji with sections",
+			text: "一億二千万円",
+			want: "120000000円",
+		},
+		{
+			name: "positional kanji",
+			text: "二〇二四年",
+			want: "2024年",
+		},
+		{
+			name: "unit without digit",
+			text: "十五分",
+			want: "15分",
+		},
+		{
+			name: "positional kanji without zero",
+			text: "一九九九年",
+			want: "1999年",
+		},
+		{
+			name: "approximate",
+			text: "四五人と二十四五歳と二三日",
+			want: "四五人と二十四五歳と二三日",
+		},
+		{
+			name: "not consecutive digits",
+			text: "二五番",
+			want: "25番",
+		},
+		{
+			name: "single kanji",
+			text: "一緒に十分話した",
+			want: "一緒に十分話した",
+		},
+		{
+			name: "starting with section",
+			text: "万一の場合",
+			want: "万一の場合",
+		},
+	}
+	n := NewNumberNormalizer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := n.Process(tt.text)
+			if err != nil {
+				t.Fatalf("NumberNormalizer.Process() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NumberNormalizer.Process() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package postprocess processes the text of the results of any backend, e.g.
+// removes the fillers, before they are written.
+package postprocess
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+//go:generate moq -rm -out processor_mock.go . Processor
+type Processor interface {
+	Process(text string) (string, error)
+}
+
+// Chain applies the processors in order.
+type Chain struct {
+	processors []Processor
+}
+
+func NewChain(processors ...Processor) *Chain {
+	return &Chain{processors: processors}
+}
+
+// Process applies the processors to text in order. A processor which fails is
+// skipped, so that the text is written even if it is not fully processed.
+func (c *Chain) Process(text string) string {
+	for _, p := range c.processors {
+		processed, err := p.Process(text)
+		if err != nil {
+			slog.Warn(fmt.Sprintf("Chain: processor %T failed: %v", p, err))
+			continue
+		}
+		text = processed
+	}
+	return text
+}
+
+// Wrap returns the factory of the cores whose results are processed by the
+// chain. The interim results are processed as well, since the last one is
+// written as the final one on the shutdown. A final result which becomes
+// empty, e.g. only of the fillers, is dropped.
+func (c *Chain) Wrap(newCore model.RecognizerCoreFactory) model.RecognizerCoreFactory {
+	return model.Transform(newCore, c.processResults)
+}
+
+func (c *Chain) processResults(results []*model.Result) []*model.Result {
+	processed := make([]*model.Result, 0, len(results))
+	for _, result := range results {
+		p := *result
+		p.Transcript = c.Process(result.Transcript)
+		if p.IsFinal && p.Transcript == "" {
+			continue
+		}
+		processed = append(processed, &p)
+	}
+	return processed
+}
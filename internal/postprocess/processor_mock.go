@@ -0,0 +1,74 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package postprocess
+
+import (
+	"sync"
+)
+
+// Ensure, that ProcessorMock does implement Processor.
+// If this is not the case, regenerate this file with moq.
+var _ Processor = &ProcessorMock{}
+
+// ProcessorMock is a mock implementation of Processor.
+//
+//	func TestSomethingThatUsesProcessor(t *testing.T) {
+//
+//		// make and configure a mocked Processor
+//		mockedProcessor := &ProcessorMock{
+//			ProcessFunc: func(text string) (string, error) {
+//				panic("mock out the Process method")
+//			},
+//		}
+//
+//		// use mockedProcessor in code that requires Processor
+//		// and then make assertions.
+//
+//	}
+type ProcessorMock struct {
+	// ProcessFunc mocks the Process method.
+	ProcessFunc func(text string) (string, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Process holds details about calls to the Process method.
+		Process []struct {
+			// Text is the text argument value.
+			Text string
+		}
+	}
+	lockProcess sync.RWMutex
+}
+
+// Process calls ProcessFunc.
+func (mock *ProcessorMock) Process(text string) (string, error) {
+	if mock.ProcessFunc == nil {
+		panic("ProcessorMock.ProcessFunc: method is nil but Processor.Process was just called")
+	}
+	callInfo := struct {
+		Text string
+	}{
+		Text: text,
+	}
+	mock.lockProcess.Lock()
+	mock.calls.Process = append(mock.calls.Process, callInfo)
+	mock.lockProcess.Unlock()
+	return mock.ProcessFunc(text)
+}
+
+// ProcessCalls gets all the calls that were made to Process.
+// Check the length with:
+//
+//	len(mockedProcessor.ProcessCalls())
+func (mock *ProcessorMock) ProcessCalls() []struct {
+	Text string
+} {
+	var calls []struct {
+		Text string
+	}
+	mock.lockProcess.RLock()
+	calls = mock.calls.Process
+	mock.lockProcess.RUnlock()
+	return calls
+}
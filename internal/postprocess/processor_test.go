@@ -0,0 +1,115 @@
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+func TestChain_Process(t *testing.T) {
+	upper := &ProcessorMock{
+		ProcessFunc: func(text string) (string, error) {
+			return strings.ToUpper(text), nil
+		},
+	}
+	exclaim := &ProcessorMock{
+		ProcessFunc: func(text string) (string, error) {
+			return text + "!", nil
+		},
+	}
+	broken := &ProcessorMock{
+		ProcessFunc: func(string) (string, error) {
+			return "", errors.New("broken")
+		},
+	}
+
+	tests := []struct {
+		name       string
+		processors []Processor
+		want       string
+	}{
+		{
+			name:       "in order",
+			processors: []Processor{exclaim, upper},
+			want:       "HELLO!",
+		},
+		{
+			name:       "failed processor is skipped",
+			processors: []Processor{upper, broken, exclaim},
+			want:       "HELLO!",
+		},
+		{
+			name: "no processor",
+			want: "hello",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewChain(tt.processors...).Process("hello"); got != tt.want {
+				t.Errorf("Chain.Process() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChain_Wrap(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		chain := NewChain(&ProcessorMock{
+			ProcessFunc: func(text string) (string, error) {
+				return strings.ReplaceAll(text, "えー", ""), nil
+			},
+		})
+		inner := []*model.Result{
+			{Transcript: "えー"},
+			{Transcript: "えー", IsFinal: true},
+			{Transcript: "えーはい", IsFinal: true, Backend: "google"},
+		}
+		newCore := func(
+			_ context.Context,
+			_ <-chan []byte,
+			resultCh chan<- []*model.Result,
+		) (model.RecognizerCoreInterface, error) {
+			return &model.RecognizerCoreInterfaceMock{
+				StartFunc: func(ctx context.Context) error {
+					resultCh <- inner
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			}, nil
+		}
+
+		resultCh := make(chan []*model.Result)
+		core, err := chain.Wrap(newCore)(ctx, make(chan []byte), resultCh)
+		if err != nil {
+			t.Fatalf("Wrap() error = %v", err)
+		}
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- core.Start(ctx)
+		}()
+
+		// the empty final result is dropped, and the empty interim result is kept.
+		want := []*model.Result{
+			{Transcript: ""},
+			{Transcript: "はい", IsFinal: true, Backend: "google"},
+		}
+		if diff := cmp.Diff(<-resultCh, want); diff != "" {
+			t.Errorf("results (-got +want):\n%s", diff)
+		}
+		if inner[2].Transcript != "えーはい" {
+			t.Errorf("inner result is modified: %q", inner[2].Transcript)
+		}
+
+		cancel()
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("Start() error = %v, want %v", err, context.Canceled)
+		}
+	})
+}
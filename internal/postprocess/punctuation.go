@@ -0,0 +1,35 @@
+package postprocess
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hekt/voice-recognition/internal/punctuator"
+)
+
+var _ Processor = (*Punctuation)(nil)
+
+// Punctuation punctuates the text separated by spaces, e.g. the results of
+// Whisper, by the punctuator. The punctuator must be safe for concurrent use
+// if it is shared by the chains.
+type Punctuation struct {
+	punctuator punctuator.PunctuatorInterface
+}
+
+func NewPunctuation(punctuator punctuator.PunctuatorInterface) (*Punctuation, error) {
+	if punctuator == nil {
+		return nil, errors.New("punctuator must be specified")
+	}
+	return &Punctuation{punctuator: punctuator}, nil
+}
+
+func (p *Punctuation) Process(text string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	punctuated, err := p.punctuator.Punctuate(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to punctuate: %w", err)
+	}
+	return punctuated, nil
+}
@@ -0,0 +1,66 @@
+package postprocess
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hekt/voice-recognition/internal/punctuator"
+)
+
+func TestPunctuation_Process(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		p, err := NewPunctuation(&punctuator.PunctuatorInterfaceMock{
+			PunctuateFunc: func(sentence string) (string, error) {
+				return sentence + "。", nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewPunctuation() error = %v", err)
+		}
+
+		got, err := p.Process("はい")
+		if err != nil {
+			t.Fatalf("Punctuation.Process() error = %v", err)
+		}
+		if got != "はい。" {
+			t.Errorf("Punctuation.Process() = %q, want %q", got, "はい。")
+		}
+	})
+
+	t.Run("empty text", func(t *testing.T) {
+		mock := &punctuator.PunctuatorInterfaceMock{}
+		p, err := NewPunctuation(mock)
+		if err != nil {
+			t.Fatalf("NewPunctuation() error = %v", err)
+		}
+
+		got, err := p.Process("")
+		if err != nil || got != "" {
+			t.Errorf("Punctuation.Process() = %q, %v, want empty", got, err)
+		}
+		if len(mock.PunctuateCalls()) != 0 {
+			t.Error("punctuator is called for the empty text")
+		}
+	})
+
+	t.Run("punctuator fails", func(t *testing.T) {
+		p, err := NewPunctuation(&punctuator.PunctuatorInterfaceMock{
+			PunctuateFunc: func(string) (string, error) {
+				return "", errors.New("broken")
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewPunctuation() error = %v", err)
+		}
+
+		if _, err := p.Process("はい"); err == nil {
+			t.Error("Punctuation.Process() error = nil, want an error")
+		}
+	})
+
+	t.Run("nil punctuator", func(t *testing.T) {
+		if _, err := NewPunctuation(nil); err == nil {
+			t.Error("NewPunctuation() error = nil, want an error")
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package postprocess
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var _ Processor = (*WhitespaceCleaner)(nil)
+
+// WhitespaceCleaner trims the text and collapses the spaces into one. The
+// spaces next to Japanese, which is written without spaces, are removed, e.g.
+// "今日 は Google で" becomes "今日はGoogleで", and so are the spaces before
+// the punctuations, e.g. "hello , world" becomes "hello, world".
+type WhitespaceCleaner struct{}
+
+func NewWhitespaceCleaner() *WhitespaceCleaner {
+	return &WhitespaceCleaner{}
+}
+
+func (c *WhitespaceCleaner) Process(text string) (string, error) {
+	var b strings.Builder
+	for i, field := range strings.Fields(text) {
+		if i > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(b.String())
+			next, _ := utf8.DecodeRuneInString(field)
+			if !isJapanese(prev) && !isJapanese(next) && !strings.ContainsRune(",.!?;:", next) {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(field)
+	}
+	return b.String(), nil
+}
+
+func isJapanese(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		// the punctuations and the long vowel mark.
+		(r >= 0x3000 && r <= 0x303f) || r == 'ー' ||
+		// the full-width forms.
+		(r >= 0xff00 && r <= 0xffef)
+}
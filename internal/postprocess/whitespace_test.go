@@ -0,0 +1,44 @@
+package postprocess
+
+import "testing"
+
+func TestWhitespaceCleaner_Process(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "Japanese",
+			text: "今日 は Google で 検索 した 。",
+			want: "今日はGoogleで検索した。",
+		},
+		{
+			name: "English",
+			text: "  hello ,\t world  ! ",
+			want: "hello, world!",
+		},
+		{
+			name: "full-width space",
+			text: "はい　そうです",
+			want: "はいそうです",
+		},
+		{
+			name: "empty",
+			text: "   ",
+			want: "",
+		},
+	}
+	c := NewWhitespaceCleaner()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Process(tt.text)
+			if err != nil {
+				t.Fatalf("WhitespaceCleaner.Process() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("WhitespaceCleaner.Process() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+
+	"github.com/hekt/voice-recognition/internal/telemetry"
 )
 
 //go:generate moq -rm -out audio_reader_mock.go . AudioReaderInterface
@@ -53,6 +55,7 @@ func (r *AudioReader) Start(ctx context.Context) error {
 			if n == 0 {
 				continue
 			}
+			telemetry.AudioReadBytes.Add(float64(n))
 
 			// Send copied buffer to audio channel.
 			r.audioCh <- append(make([]byte, 0, n), buf[:n]...)
@@ -0,0 +1,143 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+// Factory builds a recognizer core of a backend from its typed config.
+type Factory[C any] func(
+	ctx context.Context,
+	config C,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (model.RecognizerCoreInterface, error)
+
+// Registry holds the backends by name.
+type Registry struct {
+	mu       sync.RWMutex
+	backends map[string]entry
+	wrap     Wrapper
+}
+
+// Wrapper wraps the factory of the backend, e.g. to instrument the cores.
+type Wrapper func(name string, newCore model.RecognizerCoreFactory) model.RecognizerCoreFactory
+
+// entry is a type-erased Factory.
+type entry struct {
+	build func(
+		ctx context.Context,
+		config any,
+		audioCh <-chan []byte,
+		resultCh chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		backends: map[string]entry{},
+	}
+}
+
+// Register registers the factory of the backend with the name.
+// It is not a method because methods cannot have type parameters.
+func Register[C any](r *Registry, name string, factory Factory[C]) error {
+	if name == "" {
+		return errors.New("backend name must be specified")
+	}
+	if factory == nil {
+		return errors.New("backend factory must be specified")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.backends[name]; ok {
+		return fmt.Errorf("backend %q is already registered", name)
+	}
+
+	r.backends[name] = entry{
+		build: func(
+			ctx context.Context,
+			config any,
+			audioCh <-chan []byte,
+			resultCh chan<- []*model.Result,
+		) (model.RecognizerCoreInterface, error) {
+			c, ok := config.(C)
+			if !ok {
+				var want C
+				return nil, fmt.Errorf("config of backend %q must be %T, got %T", name, want, config)
+			}
+			return factory(ctx, c, audioCh, resultCh)
+		},
+	}
+
+	return nil
+}
+
+// Names returns the names of the registered backends in sorted order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.backends))
+	for name := range r.backends {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// New builds a recognizer core of the backend.
+func (r *Registry) New(
+	ctx context.Context,
+	name string,
+	config any,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (model.RecognizerCoreInterface, error) {
+	factory, err := r.Factory(name, config)
+	if err != nil {
+		return nil, err
+	}
+	return factory(ctx, audioCh, resultCh)
+}
+
+// Wrap sets the wrapper applied to the factories returned by Factory.
+// Since the cores that run other cores get the factories from the registry,
+// the inner cores are wrapped as well.
+func (r *Registry) Wrap(wrap Wrapper) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.wrap = wrap
+}
+
+// Factory returns the factory of the backend bound to the config.
+// It can be passed to the cores that run other cores internally.
+func (r *Registry) Factory(name string, config any) (model.RecognizerCoreFactory, error) {
+	r.mu.RLock()
+	e, ok := r.backends[name]
+	wrap := r.wrap
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("backend %q is not registered", name)
+	}
+
+	var factory model.RecognizerCoreFactory = func(
+		ctx context.Context,
+		audioCh <-chan []byte,
+		resultCh chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		return e.build(ctx, config, audioCh, resultCh)
+	}
+	if wrap != nil {
+		factory = wrap(name, factory)
+	}
+	return factory, nil
+}
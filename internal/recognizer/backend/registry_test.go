@@ -0,0 +1,163 @@
+package backend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+type testConfig struct {
+	Name string
+}
+
+func TestRegister(t *testing.T) {
+	factory := func(
+		context.Context,
+		testConfig,
+		<-chan []byte,
+		chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		return &model.RecognizerCoreInterfaceMock{}, nil
+	}
+
+	t.Run("success", func(t *testing.T) {
+		r := NewRegistry()
+		if err := Register(r, "b", factory); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+		if err := Register(r, "a", factory); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+		if diff := cmp.Diff(r.Names(), []string{"a", "b"}); diff != "" {
+			t.Errorf("Names() (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("duplicated name", func(t *testing.T) {
+		r := NewRegistry()
+		if err := Register(r, "a", factory); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+		if err := Register(r, "a", factory); err == nil {
+			t.Error("Register() error = nil, want an error")
+		}
+	})
+
+	t.Run("empty name", func(t *testing.T) {
+		if err := Register(NewRegistry(), "", factory); err == nil {
+			t.Error("Register() error = nil, want an error")
+		}
+	})
+
+	t.Run("nil factory", func(t *testing.T) {
+		if err := Register[testConfig](NewRegistry(), "a", nil); err == nil {
+			t.Error("Register() error = nil, want an error")
+		}
+	})
+}
+
+func TestRegistry_New(t *testing.T) {
+	var gotConfig testConfig
+	var gotAudioCh <-chan []byte
+	core := &model.RecognizerCoreInterfaceMock{}
+	r := NewRegistry()
+	if err := Register(r, "test", func(
+		_ context.Context,
+		config testConfig,
+		audioCh <-chan []byte,
+		_ chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		gotConfig = config
+		gotAudioCh = audioCh
+		return core, nil
+	}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		audioCh := make(chan []byte)
+		got, err := r.New(
+			context.Background(),
+			"test",
+			testConfig{Name: "config"},
+			audioCh,
+			make(chan []*model.Result),
+		)
+		if err != nil {
+			t.Fatalf("Registry.New() error = %v", err)
+		}
+		if got != core {
+			t.Errorf("Registry.New() = %v, want %v", got, core)
+		}
+		if gotConfig.Name != "config" {
+			t.Errorf("config = %v, want %v", gotConfig, testConfig{Name: "config"})
+		}
+		if gotAudioCh != audioCh {
+			t.Error("audio channel is not passed to the factory")
+		}
+	})
+
+	t.Run("unknown backend", func(t *testing.T) {
+		if _, err := r.New(
+			context.Background(),
+			"unknown",
+			testConfig{},
+			make(chan []byte),
+			make(chan []*model.Result),
+		); err == nil {
+			t.Error("Registry.New() error = nil, want an error")
+		}
+	})
+
+	t.Run("config type mismatch", func(t *testing.T) {
+		if _, err := r.New(
+			context.Background(),
+			"test",
+			&testConfig{},
+			make(chan []byte),
+			make(chan []*model.Result),
+		); err == nil {
+			t.Error("Registry.New() error = nil, want an error")
+		}
+	})
+}
+
+func TestRegistry_Wrap(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		core := &model.RecognizerCoreInterfaceMock{}
+		r := NewRegistry()
+		if err := Register(r, "test", func(
+			context.Context,
+			testConfig,
+			<-chan []byte,
+			chan<- []*model.Result,
+		) (model.RecognizerCoreInterface, error) {
+			return core, nil
+		}); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+
+		var wrapped []string
+		r.Wrap(func(name string, newCore model.RecognizerCoreFactory) model.RecognizerCoreFactory {
+			wrapped = append(wrapped, name)
+			return newCore
+		})
+
+		factory, err := r.Factory("test", testConfig{})
+		if err != nil {
+			t.Fatalf("Registry.Factory() error = %v", err)
+		}
+		got, err := factory(context.Background(), make(chan []byte), make(chan []*model.Result))
+		if err != nil {
+			t.Fatalf("factory error = %v", err)
+		}
+		if got != core {
+			t.Errorf("factory() = %v, want %v", got, core)
+		}
+		if diff := cmp.Diff(wrapped, []string{"test"}); diff != "" {
+			t.Errorf("wrapped backends (-got +want):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package compare
+
+import (
+	"strings"
+	"time"
+)
+
+// Row is a pair of the parts of the transcripts spoken at the same time.
+type Row struct {
+	Start time.Duration
+	End   time.Duration
+	A     string
+	B     string
+}
+
+// Align pairs the segments of two backends by time.
+//
+// Segments are accumulated in the order of the end until both backends have a
+// segment ending within tolerance of each other, which is considered the same
+// sentence boundary.
+// The tolerance absorbs the difference of the latencies of the backends.
+func Align(a, b []Segment, tolerance time.Duration) []Row {
+	var rows []Row
+	var row *Row
+	var textA, textB []string
+	var endA, endB time.Duration
+
+	extend := func(s Segment) {
+		if row == nil {
+			row = &Row{Start: s.Start, End: s.End}
+		}
+		row.Start = min(row.Start, s.Start)
+		row.End = max(row.End, s.End)
+	}
+	flush := func() {
+		if row == nil {
+			return
+		}
+		row.A = strings.Join(textA, " ")
+		row.B = strings.Join(textB, " ")
+		rows = append(rows, *row)
+		row = nil
+		textA, textB = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j >= len(b) || (i < len(a) && a[i].End <= b[j].End):
+			extend(a[i])
+			textA = append(textA, a[i].Text)
+			endA = a[i].End
+			i++
+		default:
+			extend(b[j])
+			textB = append(textB, b[j].Text)
+			endB = b[j].End
+			j++
+		}
+
+		if len(textA) > 0 && len(textB) > 0 && (endA-endB).Abs() <= tolerance {
+			flush()
+		}
+	}
+	flush()
+
+	return rows
+}
@@ -0,0 +1,63 @@
+package compare
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAlign(t *testing.T) {
+	s := func(start, end int, text string) Segment {
+		return Segment{Start: time.Duration(start) * time.Second, End: time.Duration(end) * time.Second, Text: text}
+	}
+	tolerance := time.Second
+
+	tests := []struct {
+		name string
+		a    []Segment
+		b    []Segment
+		want []Row
+	}{
+		{
+			name: "same boundaries",
+			a:    []Segment{s(0, 3, "a1"), s(3, 6, "a2")},
+			b:    []Segment{s(0, 3, "b1"), s(3, 7, "b2")},
+			want: []Row{
+				{Start: 0, End: 3 * time.Second, A: "a1", B: "b1"},
+				{Start: 3 * time.Second, End: 7 * time.Second, A: "a2", B: "b2"},
+			},
+		},
+		{
+			name: "split segments",
+			a:    []Segment{s(0, 6, "a1")},
+			b:    []Segment{s(0, 3, "b1"), s(3, 6, "b2")},
+			want: []Row{
+				{Start: 0, End: 6 * time.Second, A: "a1", B: "b1 b2"},
+			},
+		},
+		{
+			name: "missing in one side",
+			a:    []Segment{s(0, 3, "a1"), s(10, 12, "a2")},
+			b:    []Segment{s(0, 4, "b1")},
+			want: []Row{
+				{Start: 0, End: 4 * time.Second, A: "a1", B: "b1"},
+				{Start: 10 * time.Second, End: 12 * time.Second, A: "a2", B: ""},
+			},
+		},
+		{
+			name: "empty",
+			a:    nil,
+			b:    nil,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Align(tt.a, tt.b, tolerance)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Align() (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Package compare runs two backends on the same audio and reports the differences.
+package compare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"golang.org/x/sync/errgroup"
+)
+
+var _ model.RecognizerCoreInterface = (*Recognizer)(nil)
+
+// Segment is a final result with its position in the audio.
+// The position is the audio received when the results are received, so it
+// includes the latency of the backend.
+type Segment struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+type backend struct {
+	name    string
+	newCore model.RecognizerCoreFactory
+}
+
+// Recognizer passes the same audio to two backends and records their final
+// results as segments.
+// The final results are also passed to the result channel marked with the backend.
+type Recognizer struct {
+	backends [2]backend
+
+	audioCh  <-chan []byte
+	resultCh chan<- []*model.Result
+
+	// position is the bytes of audio passed to the backends.
+	position atomic.Int64
+
+	mu       sync.Mutex
+	segments [2][]Segment
+}
+
+func NewRecognizer(
+	nameA string,
+	newA model.RecognizerCoreFactory,
+	nameB string,
+	newB model.RecognizerCoreFactory,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (*Recognizer, error) {
+	if nameA == "" || nameB == "" {
+		return nil, errors.New("backend names must be specified")
+	}
+	if nameA == nameB {
+		return nil, errors.New("backend names must be different")
+	}
+	if newA == nil || newB == nil {
+		return nil, errors.New("recognizer factories must be specified")
+	}
+	if audioCh == nil {
+		return nil, errors.New("audio channel must be specified")
+	}
+	if resultCh == nil {
+		return nil, errors.New("result channel must be specified")
+	}
+
+	return &Recognizer{
+		backends: [2]backend{
+			{name: nameA, newCore: newA},
+			{name: nameB, newCore: newB},
+		},
+		audioCh:  audioCh,
+		resultCh: resultCh,
+	}, nil
+}
+
+func (r *Recognizer) Start(ctx context.Context) error {
+	var audioChs [2]chan []byte
+	var resultChs [2]chan []*model.Result
+	var cores [2]model.RecognizerCoreInterface
+	for i, b := range r.backends {
+		audioChs[i] = make(chan []byte, 10)
+		resultChs[i] = make(chan []*model.Result, 10)
+		core, err := b.newCore(ctx, audioChs[i], resultChs[i])
+		if err != nil {
+			return fmt.Errorf("failed to create %s recognizer: %w", b.name, err)
+		}
+		cores[i] = core
+	}
+	defer func() {
+		for i := range r.backends {
+			close(audioChs[i])
+			close(resultChs[i])
+		}
+	}()
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	for i, b := range r.backends {
+		eg.Go(func() error {
+			if err := cores[i].Start(ctx); err != nil {
+				return fmt.Errorf("error occured in %s recognizer: %w", b.name, err)
+			}
+			return nil
+		})
+		eg.Go(func() error {
+			return r.collect(ctx, i, resultChs[i])
+		})
+	}
+	eg.Go(func() error {
+		return r.tee(ctx, audioChs)
+	})
+
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Segments returns the segments recorded for each backend.
+func (r *Recognizer) Segments() (a, b []Segment) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Segment(nil), r.segments[0]...), append([]Segment(nil), r.segments[1]...)
+}
+
+// Names returns the names of the backends.
+func (r *Recognizer) Names() (a, b string) {
+	return r.backends[0].name, r.backends[1].name
+}
+
+func (r *Recognizer) tee(ctx context.Context, audioChs [2]chan []byte) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case audio, ok := <-r.audioCh:
+			if !ok {
+				return errors.New("audio channel closed")
+			}
+
+			// count the audio before passing it so that the results for it
+			// are stamped after it.
+			r.position.Add(int64(len(audio)))
+			for _, ch := range audioChs {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case ch <- audio:
+				}
+			}
+		}
+	}
+}
+
+func (r *Recognizer) collect(ctx context.Context, i int, resultCh <-chan []*model.Result) error {
+	name := r.backends[i].name
+	s := &stamper{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case results, ok := <-resultCh:
+			if !ok {
+				return fmt.Errorf("%s result channel closed", name)
+			}
+
+			segments := s.stamp(results, model.AudioDuration(int(r.position.Load())))
+			if len(segments) == 0 {
+				continue
+			}
+
+			r.mu.Lock()
+			r.segments[i] = append(r.segments[i], segments...)
+			r.mu.Unlock()
+
+			finals := make([]*model.Result, 0, len(segments))
+			for _, segment := range segments {
+				finals = append(finals, &model.Result{Transcript: segment.Text, IsFinal: true, Backend: name})
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case r.resultCh <- finals:
+			}
+		}
+	}
+}
+
+// stamper converts the final results of a backend into segments.
+// A segment starts at the first interim result after the last final result,
+// or at the last final result if there is no interim result.
+type stamper struct {
+	speaking bool
+	start    time.Duration
+	lastEnd  time.Duration
+}
+
+// stamp returns the segments of the final results received at pos.
+func (s *stamper) stamp(results []*model.Result, pos time.Duration) []Segment {
+	var segments []Segment
+	for _, result := range results {
+		if !result.IsFinal {
+			if !s.speaking {
+				s.start = pos
+				s.speaking = true
+			}
+			continue
+		}
+
+		if !s.speaking {
+			s.start = s.lastEnd
+		}
+		s.speaking = false
+		s.lastEnd = pos
+		if result.Transcript == "" {
+			continue
+		}
+		segments = append(segments, Segment{Start: s.start, End: pos, Text: result.Transcript})
+	}
+	return segments
+}
@@ -0,0 +1,255 @@
+package compare
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+func TestNewRecognizer(t *testing.T) {
+	type args struct {
+		nameA    string
+		newA     model.RecognizerCoreFactory
+		nameB    string
+		newB     model.RecognizerCoreFactory
+		audioCh  <-chan []byte
+		resultCh chan<- []*model.Result
+	}
+	baseArgs := args{
+		nameA:    "google",
+		newA:     scriptedFactory(nil),
+		nameB:    "vosk",
+		newB:     scriptedFactory(nil),
+		audioCh:  make(chan []byte),
+		resultCh: make(chan []*model.Result),
+	}
+	tests := []struct {
+		name    string
+		args    func() args
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			args:    func() args { return baseArgs },
+			wantErr: false,
+		},
+		{
+			name: "empty name",
+			args: func() args {
+				a := baseArgs
+				a.nameB = ""
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "same names",
+			args: func() args {
+				a := baseArgs
+				a.nameB = a.nameA
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "nil factory",
+			args: func() args {
+				a := baseArgs
+				a.newA = nil
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "nil audio channel",
+			args: func() args {
+				a := baseArgs
+				a.audioCh = nil
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "nil result channel",
+			args: func() args {
+				a := baseArgs
+				a.resultCh = nil
+				return a
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.args()
+			got, err := NewRecognizer(a.nameA, a.newA, a.nameB, a.newB, a.audioCh, a.resultCh)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRecognizer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got == nil {
+				t.Errorf("NewRecognizer() = nil, want non-nil")
+			}
+		})
+	}
+}
+
+// scriptedFactory returns a factory of a core which passes the results in
+// script for each audio received.
+func scriptedFactory(script [][]*model.Result) model.RecognizerCoreFactory {
+	return func(
+		_ context.Context,
+		audioCh <-chan []byte,
+		resultCh chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		return &model.RecognizerCoreInterfaceMock{
+			StartFunc: func(ctx context.Context) error {
+				for n := 0; ; n++ {
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-audioCh:
+					}
+					if n >= len(script) || script[n] == nil {
+						continue
+					}
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case resultCh <- script[n]:
+					}
+				}
+			},
+		}, nil
+	}
+}
+
+func TestRecognizer_Start(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		audioCh := make(chan []byte)
+		resultCh := make(chan []*model.Result)
+		r, err := NewRecognizer(
+			"a",
+			scriptedFactory([][]*model.Result{
+				{{Transcript: "こんにちは", IsFinal: true}},
+				{{Transcript: "世界", IsFinal: true}},
+			}),
+			"b",
+			scriptedFactory([][]*model.Result{
+				{{Transcript: "こんにちわ", IsFinal: true}},
+				{{Transcript: "せか", IsFinal: false}, {Transcript: "世界", IsFinal: true}},
+			}),
+			audioCh,
+			resultCh,
+		)
+		if err != nil {
+			t.Fatalf("NewRecognizer() error = %v", err)
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var got error
+		go func() {
+			defer wg.Done()
+			got = r.Start(ctx)
+		}()
+
+		second := make([]byte, model.AudioBytes(time.Second))
+		var gotResults [][]*model.Result
+		for range 2 {
+			audioCh <- second
+			gotResults = append(gotResults, <-resultCh, <-resultCh)
+		}
+
+		cancel()
+		wg.Wait()
+
+		if !errors.Is(got, context.Canceled) {
+			t.Errorf("Recognizer.Start() error = %v, want %v", got, context.Canceled)
+		}
+
+		// the order of the backends is not deterministic.
+		for i := 0; i < len(gotResults); i += 2 {
+			if gotResults[i][0].Backend > gotResults[i+1][0].Backend {
+				gotResults[i], gotResults[i+1] = gotResults[i+1], gotResults[i]
+			}
+		}
+		wantResults := [][]*model.Result{
+			{{Transcript: "こんにちは", IsFinal: true, Backend: "a"}},
+			{{Transcript: "こんにちわ", IsFinal: true, Backend: "b"}},
+			{{Transcript: "世界", IsFinal: true, Backend: "a"}},
+			{{Transcript: "世界", IsFinal: true, Backend: "b"}},
+		}
+		if diff := cmp.Diff(gotResults, wantResults); diff != "" {
+			t.Errorf("unexpected results (-got +want):\n%s", diff)
+		}
+
+		gotA, gotB := r.Segments()
+		wantA := []Segment{
+			{Start: 0, End: time.Second, Text: "こんにちは"},
+			{Start: time.Second, End: 2 * time.Second, Text: "世界"},
+		}
+		wantB := []Segment{
+			{Start: 0, End: time.Second, Text: "こんにちわ"},
+			{Start: 2 * time.Second, End: 2 * time.Second, Text: "世界"},
+		}
+		if diff := cmp.Diff(gotA, wantA); diff != "" {
+			t.Errorf("unexpected segments of a (-got +want):\n%s", diff)
+		}
+		if diff := cmp.Diff(gotB, wantB); diff != "" {
+			t.Errorf("unexpected segments of b (-got +want):\n%s", diff)
+		}
+	})
+}
+
+func Test_stamper_stamp(t *testing.T) {
+	s := &stamper{}
+
+	steps := []struct {
+		results []*model.Result
+		pos     time.Duration
+		want    []Segment
+	}{
+		{
+			results: []*model.Result{{Transcript: "こん", IsFinal: false}},
+			pos:     time.Second,
+			want:    nil,
+		},
+		{
+			results: []*model.Result{{Transcript: "こんにち", IsFinal: false}},
+			pos:     2 * time.Second,
+			want:    nil,
+		},
+		{
+			results: []*model.Result{{Transcript: "こんにちは", IsFinal: true}},
+			pos:     3 * time.Second,
+			want:    []Segment{{Start: time.Second, End: 3 * time.Second, Text: "こんにちは"}},
+		},
+		{
+			results: []*model.Result{{Transcript: "世界", IsFinal: true}},
+			pos:     5 * time.Second,
+			want:    []Segment{{Start: 3 * time.Second, End: 5 * time.Second, Text: "世界"}},
+		},
+		{
+			results: []*model.Result{{Transcript: "", IsFinal: true}},
+			pos:     6 * time.Second,
+			want:    nil,
+		},
+	}
+	for i, step := range steps {
+		got := s.stamp(step.results, step.pos)
+		if diff := cmp.Diff(got, step.want); diff != "" {
+			t.Errorf("step %d: unexpected segments (-got +want):\n%s", i, diff)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package compare
+
+import (
+	_ "embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/metric"
+)
+
+// Report is the side-by-side comparison of the transcripts of two backends.
+// B is evaluated against A.
+type Report struct {
+	NameA string
+	NameB string
+	Rows  []ReportRow
+	// CER is the character error rate of B against A over all rows.
+	CER float64
+}
+
+type ReportRow struct {
+	Row
+	CER   float64
+	DiffA []Span
+	DiffB []Span
+}
+
+// Span is a part of a transcript. Changed is true if it is not in the other transcript.
+type Span struct {
+	Text    string
+	Changed bool
+}
+
+// NewReport aligns the segments and compares them.
+func NewReport(nameA string, a []Segment, nameB string, b []Segment, tolerance time.Duration) Report {
+	report := Report{NameA: nameA, NameB: nameB}
+
+	distance, length := 0, 0
+	for _, row := range Align(a, b, tolerance) {
+		ref, hyp := metric.Characters(row.A), metric.Characters(row.B)
+		distance += metric.EditDistance(ref, hyp)
+		length += len(ref)
+
+		diffA, diffB := diff(row.A, row.B)
+		report.Rows = append(report.Rows, ReportRow{
+			Row:   row,
+			CER:   metric.ErrorRate(ref, hyp),
+			DiffA: diffA,
+			DiffB: diffB,
+		})
+	}
+	if length > 0 {
+		report.CER = float64(distance) / float64(length)
+	}
+
+	return report
+}
+
+// diff splits the texts into the spans which are in the longest common
+// subsequence and the others.
+func diff(a, b string) (spansA, spansB []Span) {
+	ra, rb := []rune(a), []rune(b)
+
+	// lcs[i][j] is the length of the LCS of ra[i:] and rb[j:].
+	lcs := make([][]int, len(ra)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(rb)+1)
+	}
+	for i := len(ra) - 1; i >= 0; i-- {
+		for j := len(rb) - 1; j >= 0; j-- {
+			if ra[i] == rb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	appendRune := func(spans []Span, r rune, changed bool) []Span {
+		if n := len(spans); n > 0 && spans[n-1].Changed == changed {
+			spans[n-1].Text += string(r)
+			return spans
+		}
+		return append(spans, Span{Text: string(r), Changed: changed})
+	}
+
+	i, j := 0, 0
+	for i < len(ra) || j < len(rb) {
+		switch {
+		case i < len(ra) && j < len(rb) && ra[i] == rb[j]:
+			spansA = appendRune(spansA, ra[i], false)
+			spansB = appendRune(spansB, rb[j], false)
+			i++
+			j++
+		case j >= len(rb) || (i < len(ra) && lcs[i+1][j] >= lcs[i][j+1]):
+			spansA = appendRune(spansA, ra[i], true)
+			i++
+		default:
+			spansB = appendRune(spansB, rb[j], true)
+			j++
+		}
+	}
+
+	return spansA, spansB
+}
+
+// WriteMarkdown writes the report as a Markdown table.
+// The differences are marked with <del> in A and <ins> in B.
+func (r Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s vs %s\n\n", r.NameA, r.NameB)
+	fmt.Fprintf(&b, "- CER of %s against %s: %s\n", r.NameB, r.NameA, percent(r.CER))
+	fmt.Fprintf(&b, "- Rows: %d\n\n", len(r.Rows))
+	fmt.Fprintf(&b, "| Time | %s | %s | CER |\n", r.NameA, r.NameB)
+	b.WriteString("| --- | --- | --- | ---: |\n")
+	for _, row := range r.Rows {
+		fmt.Fprintf(
+			&b,
+			"| %s - %s | %s | %s | %s |\n",
+			timestamp(row.Start),
+			timestamp(row.End),
+			markdownCell(row.DiffA, "del"),
+			markdownCell(row.DiffB, "ins"),
+			percent(row.CER),
+		)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+func markdownCell(spans []Span, tag string) string {
+	var b strings.Builder
+	for _, s := range spans {
+		text := strings.ReplaceAll(html.EscapeString(s.Text), "|", `\|`)
+		text = strings.ReplaceAll(text, "\n", " ")
+		if s.Changed {
+			fmt.Fprintf(&b, "<%s>%s</%s>", tag, text, tag)
+		} else {
+			b.WriteString(text)
+		}
+	}
+	return b.String()
+}
+
+//go:embed report.html.tmpl
+var htmlTemplateText string
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent":   percent,
+	"timestamp": timestamp,
+}).Parse(htmlTemplateText))
+
+// WriteHTML writes the report as an HTML page.
+func (r Report) WriteHTML(w io.Writer) error {
+	if err := htmlTemplate.Execute(w, r); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+func percent(f float64) string {
+	return fmt.Sprintf("%.2f%%", f*100)
+}
+
+func timestamp(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
@@ -0,0 +1,142 @@
+package compare
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_diff(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     string
+		b     string
+		wantA []Span
+		wantB []Span
+	}{
+		{
+			name:  "same",
+			a:     "こんにちは",
+			b:     "こんにちは",
+			wantA: []Span{{Text: "こんにちは"}},
+			wantB: []Span{{Text: "こんにちは"}},
+		},
+		{
+			name:  "substitution",
+			a:     "こんにちは。",
+			b:     "こんにちわ",
+			wantA: []Span{{Text: "こんにち"}, {Text: "は。", Changed: true}},
+			wantB: []Span{{Text: "こんにち"}, {Text: "わ", Changed: true}},
+		},
+		{
+			name:  "one side empty",
+			a:     "",
+			b:     "あ",
+			wantA: nil,
+			wantB: []Span{{Text: "あ", Changed: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotA, gotB := diff(tt.a, tt.b)
+			if d := cmp.Diff(gotA, tt.wantA); d != "" {
+				t.Errorf("diff() a (-got +want):\n%s", d)
+			}
+			if d := cmp.Diff(gotB, tt.wantB); d != "" {
+				t.Errorf("diff() b (-got +want):\n%s", d)
+			}
+		})
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	a := []Segment{
+		{Start: 0, End: 2 * time.Second, Text: "こんにちは"},
+		{Start: 2 * time.Second, End: 4 * time.Second, Text: "世界"},
+	}
+	b := []Segment{
+		{Start: 0, End: 2 * time.Second, Text: "こんにちわ"},
+		{Start: 2 * time.Second, End: 4 * time.Second, Text: "世界"},
+	}
+
+	got := NewReport("google", a, "vosk", b, time.Second)
+
+	if len(got.Rows) != 2 {
+		t.Fatalf("len(Rows) = %d, want 2", len(got.Rows))
+	}
+	if got.Rows[0].CER != 0.2 {
+		t.Errorf("Rows[0].CER = %v, want 0.2", got.Rows[0].CER)
+	}
+	if got.Rows[1].CER != 0 {
+		t.Errorf("Rows[1].CER = %v, want 0", got.Rows[1].CER)
+	}
+	// 1 error in 7 characters
+	if want := 1.0 / 7; got.CER != want {
+		t.Errorf("CER = %v, want %v", got.CER, want)
+	}
+}
+
+func TestReport_WriteMarkdown(t *testing.T) {
+	report := Report{
+		NameA: "google",
+		NameB: "vosk",
+		CER:   0.5,
+		Rows: []ReportRow{
+			{
+				Row:   Row{Start: time.Second, End: 61 * time.Second, A: "a|b", B: "a<b"},
+				CER:   0.5,
+				DiffA: []Span{{Text: "a"}, {Text: "|", Changed: true}, {Text: "b"}},
+				DiffB: []Span{{Text: "a"}, {Text: "<", Changed: true}, {Text: "b"}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("Report.WriteMarkdown() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"# google vs vosk",
+		"",
+		"- CER of vosk against google: 50.00%",
+		"- Rows: 1",
+		"",
+		"| Time | google | vosk | CER |",
+		"| --- | --- | --- | ---: |",
+		`| 00:00:01 - 00:01:01 | a<del>\|</del>b | a<ins>&lt;</ins>b | 50.00% |`,
+		"",
+	}, "\n")
+	if diff := cmp.Diff(buf.String(), want); diff != "" {
+		t.Errorf("Report.WriteMarkdown() (-got +want):\n%s", diff)
+	}
+}
+
+func TestReport_WriteHTML(t *testing.T) {
+	report := Report{
+		NameA: "google",
+		NameB: "vosk",
+		Rows: []ReportRow{
+			{
+				Row:   Row{A: "a", B: "<script>"},
+				DiffA: []Span{{Text: "a", Changed: true}},
+				DiffB: []Span{{Text: "<script>", Changed: true}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteHTML(&buf); err != nil {
+		t.Fatalf("Report.WriteHTML() error = %v", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{"<del>a</del>", "<ins>&lt;script&gt;</ins>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Report.WriteHTML() does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+var _ model.RecognizerCoreInterface = (*Recognizer)(nil)
+
+// Recognizer supervises a primary core and a fallback core.
+// Audio is sent to the primary core while it is running. When the primary core
+// fails, the audio which is not yet finalized by the primary core is resent to
+// the fallback core, and the following audio is sent to the fallback core.
+// While the fallback core is active, a new primary core is started at regular
+// intervals. Once it returns a result, it becomes active again at the next
+// pause, where neither core has an interim result, so that no utterance is
+// lost or duplicated across the switch.
+//
+// Each result is marked with the name of the backend which produced it.
+type Recognizer struct {
+	primaryName  string
+	fallbackName string
+	newPrimary   model.RecognizerCoreFactory
+	newFallback  model.RecognizerCoreFactory
+
+	// retryInterval is the interval of restarting the primary core.
+	retryInterval time.Duration
+	// maxBuffer is the maximum bytes of audio kept to be resent.
+	maxBuffer int
+
+	audioCh  <-chan []byte
+	resultCh chan<- []*model.Result
+}
+
+// Config is the config of the failover backend.
+type Config struct {
+	PrimaryName   string
+	NewPrimary    model.RecognizerCoreFactory
+	FallbackName  string
+	NewFallback   model.RecognizerCoreFactory
+	RetryInterval time.Duration
+	MaxBuffer     time.Duration
+}
+
+// New creates a recognizer from the config.
+func New(
+	_ context.Context,
+	config Config,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (model.RecognizerCoreInterface, error) {
+	recognizer, err := NewRecognizer(
+		config.PrimaryName,
+		config.NewPrimary,
+		config.FallbackName,
+		config.NewFallback,
+		audioCh,
+		resultCh,
+		config.RetryInterval,
+		config.MaxBuffer,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return recognizer, nil
+}
+
+func NewRecognizer(
+	primaryName string,
+	newPrimary model.RecognizerCoreFactory,
+	fallbackName string,
+	newFallback model.RecognizerCoreFactory,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+	retryInterval time.Duration,
+	maxBuffer time.Duration,
+) (*Recognizer, error) {
+	if primaryName == "" || fallbackName == "" {
+		return nil, errors.New("backend names must be specified")
+	}
+	if newPrimary == nil {
+		return nil, errors.New("primary recognizer factory must be specified")
+	}
+	if newFallback == nil {
+		return nil, errors.New("fallback recognizer factory must be specified")
+	}
+	if audioCh == nil {
+		return nil, errors.New("audio channel must be specified")
+	}
+	if resultCh == nil {
+		return nil, errors.New("result channel must be specified")
+	}
+	if retryInterval <= 0 {
+		return nil, errors.New("retry interval must be positive")
+	}
+	if maxBuffer < 0 {
+		return nil, errors.New("max buffer must not be negative")
+	}
+
+	return &Recognizer{
+		primaryName:   primaryName,
+		fallbackName:  fallbackName,
+		newPrimary:    newPrimary,
+		newFallback:   newFallback,
+		retryInterval: retryInterval,
+		maxBuffer:     model.AudioBytes(maxBuffer),
+		audioCh:       audioCh,
+		resultCh:      resultCh,
+	}, nil
+}
+
+// backend is a running core.
+type backend struct {
+	name     string
+	audioCh  chan []byte
+	resultCh chan []*model.Result
+	// errCh receives the result of Start once the core stops.
+	errCh  chan error
+	cancel context.CancelFunc
+}
+
+func (r *Recognizer) start(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	name string,
+	factory model.RecognizerCoreFactory,
+) (*backend, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	b := &backend{
+		name:     name,
+		audioCh:  make(chan []byte, 100),
+		resultCh: make(chan []*model.Result, 10),
+		errCh:    make(chan error, 1),
+		cancel:   cancel,
+	}
+
+	core, err := factory(ctx, b.audioCh, b.resultCh)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create %s recognizer: %w", name, err)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := core.Start(ctx)
+		if err == nil {
+			err = errors.New("recognizer stopped")
+		}
+		b.errCh <- err
+	}()
+
+	return b, nil
+}
+
+// send sends the audio to the backend.
+// It returns false if the backend is stopped.
+func (b *backend) send(ctx context.Context, audio []byte) (bool, error) {
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case err := <-b.errCh:
+		// put the error back to be handled by the main loop.
+		b.errCh <- err
+		return false, nil
+	case b.audioCh <- audio:
+		return true, nil
+	}
+}
+
+func (r *Recognizer) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// active is the backend to which the results are passed.
+	// probe is the primary core started while the fallback is active.
+	var active, fallback, probe *backend
+	// recovered tells that the probe has returned a result.
+	recovered := false
+	// probePending and fallbackPending tell that the cores have interim
+	// results which are not finalized yet.
+	probePending, fallbackPending := false, false
+
+	var buf [][]byte
+	bufLen := 0
+
+	failover := func(cause error) error {
+		slog.Warn(fmt.Sprintf("FailoverRecognizer: %s failed, switching to %s: %v", r.primaryName, r.fallbackName, cause))
+
+		if fallback == nil {
+			b, err := r.start(ctx, &wg, r.fallbackName, r.newFallback)
+			if err != nil {
+				return err
+			}
+			fallback = b
+		}
+		active = fallback
+
+		// resend the audio which is not finalized by the primary core.
+		for _, audio := range buf {
+			if _, err := fallback.send(ctx, audio); err != nil {
+				return err
+			}
+		}
+		buf = nil
+		bufLen = 0
+
+		return nil
+	}
+
+	primary, err := r.start(ctx, &wg, r.primaryName, r.newPrimary)
+	if err != nil {
+		if err := failover(err); err != nil {
+			return err
+		}
+	} else {
+		active = primary
+	}
+
+	// switchBack makes the probe active if both cores are at a pause.
+	switchBack := func() {
+		if probe == nil || !recovered || probePending || fallbackPending {
+			return
+		}
+		slog.Info(fmt.Sprintf("FailoverRecognizer: switched back to %s", r.primaryName))
+		primary = probe
+		probe = nil
+		active = primary
+		recovered = false
+	}
+
+	retryTicker := time.NewTicker(r.retryInterval)
+	defer retryTicker.Stop()
+
+	// nil channels are never selected.
+	resultChOf := func(b *backend) chan []*model.Result {
+		if b == nil {
+			return nil
+		}
+		return b.resultCh
+	}
+	errChOf := func(b *backend) chan error {
+		if b == nil {
+			return nil
+		}
+		return b.errCh
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case audio, ok := <-r.audioCh:
+			if !ok {
+				return errors.New("audio channel closed")
+			}
+
+			if active == primary {
+				buf = append(buf, audio)
+				bufLen += len(audio)
+				for len(buf) > 0 && bufLen > r.maxBuffer {
+					bufLen -= len(buf[0])
+					buf = buf[1:]
+				}
+			}
+
+			if _, err := active.send(ctx, audio); err != nil {
+				return err
+			}
+			if probe != nil {
+				if _, err := probe.send(ctx, audio); err != nil {
+					return err
+				}
+			}
+		case err := <-errChOf(primary):
+			primary.cancel()
+			primary = nil
+			if err := failover(err); err != nil {
+				return err
+			}
+		case err := <-errChOf(fallback):
+			return fmt.Errorf("error occured in %s recognizer: %w", r.fallbackName, err)
+		case err := <-errChOf(probe):
+			slog.Warn(fmt.Sprintf("FailoverRecognizer: %s is not recovered yet: %v", r.primaryName, err))
+			probe.cancel()
+			probe = nil
+			recovered = false
+			probePending = false
+		case <-retryTicker.C:
+			if active != fallback || probe != nil {
+				continue
+			}
+
+			b, err := r.start(ctx, &wg, r.primaryName, r.newPrimary)
+			if err != nil {
+				slog.Warn(fmt.Sprintf("FailoverRecognizer: %s is not recovered yet: %v", r.primaryName, err))
+				continue
+			}
+			probe = b
+			slog.Debug(fmt.Sprintf("FailoverRecognizer: probing %s", r.primaryName))
+		case results := <-resultChOf(probe):
+			// the primary core is healthy because it returns a result.
+			// the result is discarded because the fallback core processes the same audio.
+			if !recovered {
+				slog.Info(fmt.Sprintf("FailoverRecognizer: %s recovered, switching back at the next pause", r.primaryName))
+				recovered = true
+			}
+			probePending = pending(results, probePending)
+			switchBack()
+		case results := <-resultChOf(primary):
+			if active != primary {
+				continue
+			}
+			if err := r.pass(ctx, primary.name, results); err != nil {
+				return err
+			}
+			for _, result := range results {
+				if result.IsFinal {
+					// the audio so far has been finalized.
+					buf = nil
+					bufLen = 0
+				}
+			}
+		case results := <-resultChOf(fallback):
+			if active != fallback {
+				continue
+			}
+			if err := r.pass(ctx, fallback.name, results); err != nil {
+				return err
+			}
+			fallbackPending = pending(results, fallbackPending)
+			switchBack()
+		}
+	}
+}
+
+// pending tells whether the core has an interim result which is not finalized
+// after the results, given whether it had one before them.
+func pending(results []*model.Result, before bool) bool {
+	for _, result := range results {
+		switch {
+		case result.IsFinal:
+			before = false
+		case result.Transcript != "":
+			before = true
+		}
+	}
+	return before
+}
+
+// pass passes the results to the result channel marking the backend.
+func (r *Recognizer) pass(ctx context.Context, name string, results []*model.Result) error {
+	marked := make([]*model.Result, 0, len(results))
+	for _, result := range results {
+		m := *result
+		m.Backend = name
+		// the offset is in the audio since the backend started, not in the audio received.
+		m.End = 0
+		marked = append(marked, &m)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case r.resultCh <- marked:
+		return nil
+	}
+}
@@ -0,0 +1,328 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+func TestNewRecognizer(t *testing.T) {
+	factory := func(
+		context.Context,
+		<-chan []byte,
+		chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		return &model.RecognizerCoreInterfaceMock{}, nil
+	}
+
+	type args struct {
+		primaryName   string
+		newPrimary    model.RecognizerCoreFactory
+		fallbackName  string
+		newFallback   model.RecognizerCoreFactory
+		audioCh       <-chan []byte
+		resultCh      chan<- []*model.Result
+		retryInterval time.Duration
+		maxBuffer     time.Duration
+	}
+	baseArgs := args{
+		primaryName:   "google",
+		newPrimary:    factory,
+		fallbackName:  "vosk",
+		newFallback:   factory,
+		audioCh:       make(chan []byte),
+		resultCh:      make(chan []*model.Result),
+		retryInterval: time.Minute,
+		maxBuffer:     30 * time.Second,
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "success",
+			args: baseArgs,
+		},
+		{
+			name: "empty primary name",
+			args: func() args {
+				a := baseArgs
+				a.primaryName = ""
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "nil primary factory",
+			args: func() args {
+				a := baseArgs
+				a.newPrimary = nil
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "nil fallback factory",
+			args: func() args {
+				a := baseArgs
+				a.newFallback = nil
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "nil audio channel",
+			args: func() args {
+				a := baseArgs
+				a.audioCh = nil
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "nil result channel",
+			args: func() args {
+				a := baseArgs
+				a.resultCh = nil
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "zero retry interval",
+			args: func() args {
+				a := baseArgs
+				a.retryInterval = 0
+				return a
+			}(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRecognizer(
+				tt.args.primaryName,
+				tt.args.newPrimary,
+				tt.args.fallbackName,
+				tt.args.newFallback,
+				tt.args.audioCh,
+				tt.args.resultCh,
+				tt.args.retryInterval,
+				tt.args.maxBuffer,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRecognizer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got == nil {
+				t.Errorf("NewRecognizer() = nil, want non-nil")
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	factory := func(
+		context.Context,
+		<-chan []byte,
+		chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		return &model.RecognizerCoreInterfaceMock{}, nil
+	}
+
+	t.Run("success", func(t *testing.T) {
+		got, err := New(
+			context.Background(),
+			Config{
+				PrimaryName:   "google",
+				NewPrimary:    factory,
+				FallbackName:  "vosk",
+				NewFallback:   factory,
+				RetryInterval: time.Minute,
+				MaxBuffer:     30 * time.Second,
+			},
+			make(chan []byte),
+			make(chan []*model.Result),
+		)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if got == nil {
+			t.Error("New() = nil, want non-nil")
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		got, err := New(context.Background(), Config{}, make(chan []byte), make(chan []*model.Result))
+		if err == nil {
+			t.Error("New() error = nil, want an error")
+		}
+		if got != nil {
+			t.Errorf("New() = %v, want nil", got)
+		}
+	})
+}
+
+// factoryOf returns a factory which returns the given cores in order.
+// createdCh receives the core when it is created.
+func factoryOf(createdCh chan<- *testutil.ScriptedCore, cores ...*testutil.ScriptedCore) model.RecognizerCoreFactory {
+	var mu sync.Mutex
+	return func(
+		ctx context.Context,
+		audioCh <-chan []byte,
+		resultCh chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(cores) == 0 {
+			return nil, errors.New("no more cores")
+		}
+		c := cores[0]
+		cores = cores[1:]
+		if createdCh != nil {
+			createdCh <- c
+		}
+
+		return c.Factory(ctx, audioCh, resultCh)
+	}
+}
+
+func TestRecognizer_Start(t *testing.T) {
+	t.Run("failover and recover", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		audioCh := make(chan []byte)
+		resultCh := make(chan []*model.Result)
+
+		primary1 := testutil.NewScriptedCore()
+		primary2 := testutil.NewScriptedCore()
+		fallback := testutil.NewScriptedCore()
+		primaryCreatedCh := make(chan *testutil.ScriptedCore, 2)
+
+		r := &Recognizer{
+			primaryName:   "primary",
+			fallbackName:  "fallback",
+			newPrimary:    factoryOf(primaryCreatedCh, primary1, primary2),
+			newFallback:   factoryOf(nil, fallback),
+			retryInterval: 10 * time.Millisecond,
+			maxBuffer:     1024,
+			audioCh:       audioCh,
+			resultCh:      resultCh,
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var got error
+		go func() {
+			defer wg.Done()
+			got = r.Start(ctx)
+		}()
+
+		assertReceived := func(c *testutil.ScriptedCore, want string) {
+			t.Helper()
+			if g := string(<-c.ReceivedCh); g != want {
+				t.Errorf("core received %q, want %q", g, want)
+			}
+		}
+		assertResult := func(want []*model.Result) {
+			t.Helper()
+			if diff := cmp.Diff(<-resultCh, want); diff != "" {
+				t.Errorf("result (-got +want):\n%s", diff)
+			}
+		}
+
+		<-primaryCreatedCh
+
+		// the primary is active.
+		audioCh <- []byte("a1")
+		assertReceived(primary1, "a1")
+		primary1.ResultCh <- []*model.Result{{Transcript: "A", IsFinal: true}}
+		assertResult([]*model.Result{{Transcript: "A", IsFinal: true, Backend: "primary"}})
+
+		// a2 is not finalized by the primary, so it is resent to the fallback.
+		audioCh <- []byte("a2")
+		assertReceived(primary1, "a2")
+		primary1.ErrCh <- errors.New("unavailable")
+		assertReceived(fallback, "a2")
+
+		audioCh <- []byte("a3")
+		assertReceived(fallback, "a3")
+		fallback.ResultCh <- []*model.Result{{Transcript: "F", IsFinal: true}}
+		assertResult([]*model.Result{{Transcript: "F", IsFinal: true, Backend: "fallback"}})
+
+		// the primary is restarted and receives the audio with the fallback.
+		<-primaryCreatedCh
+		audioCh <- []byte("a4")
+		assertReceived(fallback, "a4")
+		assertReceived(primary2, "a4")
+
+		// the primary recovers while the fallback is in the middle of an utterance.
+		fallback.ResultCh <- []*model.Result{{Transcript: "G", IsFinal: false}}
+		assertResult([]*model.Result{{Transcript: "G", IsFinal: false, Backend: "fallback"}})
+		// the results of the restarted primary are discarded until the switch.
+		primary2.ResultCh <- []*model.Result{{Transcript: "X", IsFinal: false}}
+		primary2.ResultCh <- []*model.Result{{Transcript: "GX", IsFinal: true}}
+
+		// the utterance across the switch is finalized by the fallback, and
+		// switches back at the pause.
+		fallback.ResultCh <- []*model.Result{{Transcript: "GH", IsFinal: true}}
+		assertResult([]*model.Result{{Transcript: "GH", IsFinal: true, Backend: "fallback"}})
+		primary2.ResultCh <- []*model.Result{{Transcript: "B", IsFinal: true}}
+		assertResult([]*model.Result{{Transcript: "B", IsFinal: true, Backend: "primary"}})
+
+		audioCh <- []byte("a5")
+		assertReceived(primary2, "a5")
+
+		cancel()
+		wg.Wait()
+
+		if !errors.Is(got, context.Canceled) {
+			t.Errorf("Recognizer.Start() error = %v, want %v", got, context.Canceled)
+		}
+		if len(fallback.ReceivedCh) != 0 {
+			t.Errorf("fallback received %q after switching back", <-fallback.ReceivedCh)
+		}
+	})
+
+	t.Run("fallback fails", func(t *testing.T) {
+		audioCh := make(chan []byte)
+		resultCh := make(chan []*model.Result)
+
+		fallback := testutil.NewScriptedCore()
+		r := &Recognizer{
+			primaryName:   "primary",
+			fallbackName:  "fallback",
+			newPrimary:    factoryOf(nil),
+			newFallback:   factoryOf(nil, fallback),
+			retryInterval: time.Hour,
+			audioCh:       audioCh,
+			resultCh:      resultCh,
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var got error
+		go func() {
+			defer wg.Done()
+			got = r.Start(context.Background())
+		}()
+
+		fallback.ErrCh <- errors.New("broken")
+		wg.Wait()
+
+		if got == nil || errors.Is(got, context.Canceled) {
+			t.Errorf("Recognizer.Start() error = %v, want an error", got)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 
 	"cloud.google.com/go/speech/apiv2/speechpb"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
 )
 
 //go:generate moq -rm -out audio_sender_mock.go . AudioSenderInterface
@@ -18,15 +19,23 @@ var _ AudioSenderInterface = &AudioSender{}
 type AudioSender struct {
 	audioCh      <-chan []byte
 	sendStreamCh <-chan speechpb.Speech_StreamingRecognizeClient
+	// offsets tells the receiver the offset of the audio at which each stream started.
+	offsets *streamOffsets
+	// meter accounts the audio sent to the streams. nil if not accounted.
+	meter *Meter
 }
 
 func NewAudioSender(
 	audioCh <-chan []byte,
 	sendStreamCh <-chan speechpb.Speech_StreamingRecognizeClient,
+	offsets *streamOffsets,
+	meter *Meter,
 ) *AudioSender {
 	return &AudioSender{
 		audioCh:      audioCh,
 		sendStreamCh: sendStreamCh,
+		offsets:      offsets,
+		meter:        meter,
 	}
 }
 
@@ -43,6 +52,9 @@ func (s *AudioSender) Start(ctx context.Context) error {
 		}
 	}()
 
+	// sent is the number of bytes of the audio sent to all the streams.
+	sent := 0
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -54,6 +66,7 @@ func (s *AudioSender) Start(ctx context.Context) error {
 			slog.Debug("AudioSender: new stream received")
 
 			// when the new stream is received, close the current stream and switch to the new stream.
+			s.offsets.set(newStream, model.AudioDuration(sent))
 			if err := stream.CloseSend(); err != nil {
 				return fmt.Errorf("failed to close send direction of stream on reconnect: %w", err)
 			}
@@ -64,6 +77,9 @@ func (s *AudioSender) Start(ctx context.Context) error {
 			if !ok {
 				return fmt.Errorf("audio channel is closed")
 			}
+			if err := s.meter.send(stream, len(audio)); err != nil {
+				return err
+			}
 			if err := stream.Send(&speechpb.StreamingRecognizeRequest{
 				StreamingRequest: &speechpb.StreamingRecognizeRequest_Audio{
 					Audio: audio,
@@ -71,6 +87,7 @@ func (s *AudioSender) Start(ctx context.Context) error {
 			}); err != nil {
 				return fmt.Errorf("failed to send audio data: %w", err)
 			}
+			sent += len(audio)
 		}
 	}
 }
@@ -7,20 +7,26 @@ import (
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/speech/apiv2/speechpb"
 	ispeechpb "github.com/hekt/voice-recognition/internal/interfaces/speechpb"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
 )
 
 func TestNewAudioSender(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		audioCh := make(chan []byte)
 		sendStreamCh := make(chan speechpb.Speech_StreamingRecognizeClient, 1)
-		s := NewAudioSender(audioCh, sendStreamCh)
+		offsets := newStreamOffsets()
+		meter, _ := NewMeter(0)
+		s := NewAudioSender(audioCh, sendStreamCh, offsets, meter)
 
 		want := &AudioSender{
 			audioCh:      audioCh,
 			sendStreamCh: sendStreamCh,
+			offsets:      offsets,
+			meter:        meter,
 		}
 		if !reflect.DeepEqual(s, want) {
 			t.Errorf("NewAudioSender() = %v, want %v", s, want)
@@ -116,6 +122,39 @@ func Test_audioSender_Start(t *testing.T) {
 		}
 	})
 
+	t.Run("budget exceeded", func(t *testing.T) {
+		stream := &ispeechpb.Speech_StreamingRecognizeClientMock{
+			SendFunc: func(req *speechpb.StreamingRecognizeRequest) error {
+				return nil
+			},
+			CloseSendFunc: func() error {
+				return nil
+			},
+		}
+		audioCh := make(chan []byte, 2)
+		sendStreamCh := make(chan speechpb.Speech_StreamingRecognizeClient, 1)
+		// the budget allows 1.5 seconds of the audio.
+		meter, err := NewMeter(Cost(1500 * time.Millisecond))
+		if err != nil {
+			t.Fatalf("NewMeter() error = %v", err)
+		}
+		s := NewAudioSender(audioCh, sendStreamCh, nil, meter)
+
+		sendStreamCh <- stream
+		audioCh <- make([]byte, model.AudioBytes(time.Second))
+		audioCh <- make([]byte, model.AudioBytes(time.Second))
+
+		if got := s.Start(context.Background()); !errors.Is(got, ErrBudgetExceeded) {
+			t.Errorf("audioSender.Start() error = %v, want %v", got, ErrBudgetExceeded)
+		}
+		if count := len(stream.SendCalls()); count != 1 {
+			t.Errorf("stream.Send() called %d times, want 1 times", count)
+		}
+		if got := meter.Usage().Sent; got != time.Second {
+			t.Errorf("sent = %v, want %v", got, time.Second)
+		}
+	})
+
 	t.Run("closed stream", func(t *testing.T) {
 		sendStreamCh := make(chan speechpb.Speech_StreamingRecognizeClient, 1)
 		s := &AudioSender{
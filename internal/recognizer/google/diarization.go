@@ -0,0 +1,112 @@
+package google
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"cloud.google.com/go/speech/apiv2/speechpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// Diarization is the config of the speaker diarization, which tells the
+// speaker of each word. It is disabled if MaxSpeakers is 0. Not all the
+// models support it.
+type Diarization struct {
+	MinSpeakers int
+	MaxSpeakers int
+	// Names maps the speaker labels, e.g. "1", to the names. The speaker is
+	// "Speaker <label>" if the label is not mapped.
+	Names map[string]string
+}
+
+func (d Diarization) enabled() bool {
+	return d.MaxSpeakers > 0
+}
+
+// Validate tells whether the numbers of the speakers are valid.
+func (d Diarization) Validate() error {
+	if !d.enabled() {
+		if d.MinSpeakers > 0 {
+			return errors.New("max speakers must be specified with min speakers")
+		}
+		return nil
+	}
+	if d.MinSpeakers < 1 {
+		return errors.New("min speakers must be positive")
+	}
+	if d.MinSpeakers > d.MaxSpeakers {
+		return errors.New("min speakers must not be greater than max speakers")
+	}
+	return nil
+}
+
+// Config returns the diarization config of the recognition. It is nil if
+// the diarization is disabled.
+func (d Diarization) Config() *speechpb.SpeakerDiarizationConfig {
+	if !d.enabled() {
+		return nil
+	}
+	return &speechpb.SpeakerDiarizationConfig{
+		MinSpeakerCount: int32(d.MinSpeakers),
+		MaxSpeakerCount: int32(d.MaxSpeakers),
+	}
+}
+
+// streamingConfig returns the streaming config which overrides the
+// diarization of the recognizer if it is enabled.
+func (d Diarization) streamingConfig() *speechpb.StreamingRecognitionConfig {
+	config := &speechpb.StreamingRecognitionConfig{
+		StreamingFeatures: &speechpb.StreamingRecognitionFeatures{
+			InterimResults: true,
+		},
+	}
+	if d.enabled() {
+		config.Config = &speechpb.RecognitionConfig{
+			Features: &speechpb.RecognitionFeatures{
+				DiarizationConfig: d.Config(),
+			},
+		}
+		config.ConfigMask = &fieldmaskpb.FieldMask{Paths: []string{"features.diarization_config"}}
+	}
+	return config
+}
+
+// speaker returns the name of the speaker who spoke the most words, the one
+// reaching the count first on a tie. It is empty if no word has the speaker label.
+func (d Diarization) speaker(words []*speechpb.WordInfo) string {
+	counts := map[string]int{}
+	var label string
+	for _, word := range words {
+		l := word.GetSpeakerLabel()
+		if l == "" {
+			continue
+		}
+		counts[l]++
+		if counts[l] > counts[label] {
+			label = l
+		}
+	}
+	if label == "" {
+		return ""
+	}
+	if name, ok := d.Names[label]; ok {
+		return name
+	}
+	return "Speaker " + label
+}
+
+// LoadSpeakerNames loads the JSON object mapping the speaker labels to the
+// names, e.g. {"1": "Alice", "2": "Bob"}.
+func LoadSpeakerNames(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read speaker names: %w", err)
+	}
+	var names map[string]string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return nil, fmt.Errorf("failed to parse speaker names: %w", err)
+	}
+	return names, nil
+}
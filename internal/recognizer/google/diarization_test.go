@@ -0,0 +1,89 @@
+package google
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cloud.google.com/go/speech/apiv2/speechpb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiarization_speaker(t *testing.T) {
+	d := Diarization{MinSpeakers: 1, MaxSpeakers: 3, Names: map[string]string{"3": "Carol"}}
+	words := func(labels ...string) []*speechpb.WordInfo {
+		var words []*speechpb.WordInfo
+		for _, label := range labels {
+			words = append(words, &speechpb.WordInfo{Word: "w", SpeakerLabel: label})
+		}
+		return words
+	}
+	tests := []struct {
+		name  string
+		words []*speechpb.WordInfo
+		want  string
+	}{
+		{name: "most words", words: words("1", "2", "2"), want: "Speaker 2"},
+		{name: "tie", words: words("1", "2", "2", "1"), want: "Speaker 2"},
+		{name: "mapped", words: words("3"), want: "Carol"},
+		{name: "no label", words: words("", ""), want: ""},
+		{name: "no words", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.speaker(tt.words); got != tt.want {
+				t.Errorf("Diarization.speaker() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiarization_streamingConfig(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		got := Diarization{}.streamingConfig()
+		if got.GetConfig() != nil || got.GetConfigMask() != nil {
+			t.Errorf("streamingConfig() = %v, want no override", got)
+		}
+		if !got.GetStreamingFeatures().GetInterimResults() {
+			t.Error("streamingConfig() does not enable interim results")
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		got := Diarization{MinSpeakers: 2, MaxSpeakers: 4}.streamingConfig()
+		config := got.GetConfig().GetFeatures().GetDiarizationConfig()
+		if config.GetMinSpeakerCount() != 2 || config.GetMaxSpeakerCount() != 4 {
+			t.Errorf("streamingConfig() diarization = %v, want 2 to 4 speakers", config)
+		}
+		// only the diarization overrides the config of the recognizer.
+		if diff := cmp.Diff(got.GetConfigMask().GetPaths(), []string{"features.diarization_config"}); diff != "" {
+			t.Errorf("streamingConfig() mask (-got +want):\n%s", diff)
+		}
+	})
+}
+
+func TestLoadSpeakerNames(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "speakers.json")
+		if err := os.WriteFile(path, []byte(`{"1": "Alice", "2": "Bob"}`), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		got, err := LoadSpeakerNames(path)
+		if err != nil {
+			t.Fatalf("LoadSpeakerNames() error = %v", err)
+		}
+		if diff := cmp.Diff(got, map[string]string{"1": "Alice", "2": "Bob"}); diff != "" {
+			t.Errorf("LoadSpeakerNames() (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "speakers.json")
+		if err := os.WriteFile(path, []byte(`["Alice"]`), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := LoadSpeakerNames(path); err == nil {
+			t.Error("LoadSpeakerNames() error = nil, want an error")
+		}
+	})
+}
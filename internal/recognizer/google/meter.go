@@ -0,0 +1,181 @@
+package google
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/speech/apiv2/speechpb"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+// ErrBudgetExceeded is returned when sending the audio makes the cost exceed the budget.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Cost returns the cost in dollars of the billed duration.
+func Cost(billed time.Duration) float64 {
+	return billed.Hours() * PricePerHour
+}
+
+// Meter accounts the audio billed by Google. It can be shared by the
+// recognizers of a session, e.g. the ones restarted by the failover backend.
+//
+// The billed duration of a stream is TotalBilledDuration in the metadata of
+// the responses if Google reports it, and the duration of the audio sent to
+// the stream otherwise.
+type Meter struct {
+	// budget is the maximum cost in dollars. 0 means unlimited.
+	budget float64
+
+	mu      sync.Mutex
+	streams map[speechpb.Speech_StreamingRecognizeClient]*streamUsage
+}
+
+type streamUsage struct {
+	sent   time.Duration
+	billed time.Duration
+	// reported is true if Google reported the billed duration.
+	reported bool
+}
+
+// Usage is the audio accounted by the meter.
+type Usage struct {
+	// Sent is the duration of the audio sent to Google.
+	Sent time.Duration
+	// Billed is the duration of the audio billed by Google.
+	Billed time.Duration
+}
+
+// Cost returns the cost in dollars of the usage.
+func (u Usage) Cost() float64 {
+	return Cost(u.Billed)
+}
+
+func NewMeter(budget float64) (*Meter, error) {
+	if budget < 0 {
+		return nil, errors.New("budget must not be negative")
+	}
+
+	return &Meter{
+		budget:  budget,
+		streams: map[speechpb.Speech_StreamingRecognizeClient]*streamUsage{},
+	}, nil
+}
+
+// Budget returns the maximum cost in dollars. 0 means unlimited.
+func (m *Meter) Budget() float64 {
+	return m.budget
+}
+
+// send accounts the bytes of the audio to be sent to the stream.
+// It returns ErrBudgetExceeded without accounting if the projected cost
+// including the audio exceeds the budget. It does nothing on nil.
+func (m *Meter) send(stream speechpb.Speech_StreamingRecognizeClient, bytes int) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d := model.AudioDuration(bytes)
+	if m.budget > 0 {
+		if projected := Cost(m.usage().Billed + d); projected > m.budget {
+			return fmt.Errorf("%w: projected cost $%.4f exceeds $%.4f", ErrBudgetExceeded, projected, m.budget)
+		}
+	}
+
+	u := m.stream(stream)
+	u.sent += d
+	if !u.reported {
+		u.billed = u.sent
+	}
+	return nil
+}
+
+// report records the billed duration of the stream reported by Google.
+// It does nothing on nil.
+func (m *Meter) report(stream speechpb.Speech_StreamingRecognizeClient, billed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u := m.stream(stream)
+	u.billed = billed
+	u.reported = true
+}
+
+// Usage returns the audio accounted so far.
+func (m *Meter) Usage() Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage()
+}
+
+func (m *Meter) usage() Usage {
+	var usage Usage
+	for _, u := range m.streams {
+		usage.Sent += u.sent
+		usage.Billed += u.billed
+	}
+	return usage
+}
+
+func (m *Meter) stream(stream speechpb.Speech_StreamingRecognizeClient) *streamUsage {
+	u, ok := m.streams[stream]
+	if !ok {
+		u = &streamUsage{}
+		m.streams[stream] = u
+	}
+	return u
+}
+
+// LedgerEntry is a line of the ledger file recording the cost of a session.
+type LedgerEntry struct {
+	StartedAt     time.Time `json:"started_at"`
+	EndedAt       time.Time `json:"ended_at"`
+	SentSeconds   float64   `json:"sent_seconds"`
+	BilledSeconds float64   `json:"billed_seconds"`
+	CostDollars   float64   `json:"cost_dollars"`
+	// BudgetDollars is the budget of the session. 0 means unlimited.
+	BudgetDollars float64 `json:"budget_dollars"`
+}
+
+// NewLedgerEntry returns the entry of the session from startedAt to endedAt.
+func (m *Meter) NewLedgerEntry(startedAt, endedAt time.Time) LedgerEntry {
+	usage := m.Usage()
+	return LedgerEntry{
+		StartedAt:     startedAt,
+		EndedAt:       endedAt,
+		SentSeconds:   usage.Sent.Seconds(),
+		BilledSeconds: usage.Billed.Seconds(),
+		CostDollars:   usage.Cost(),
+		BudgetDollars: m.budget,
+	}
+}
+
+// AppendLedger appends the entry to the ledger file as a JSON line.
+func AppendLedger(path string, entry LedgerEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ledger entry: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open ledger file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write ledger file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close ledger file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package google
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	ispeechpb "github.com/hekt/voice-recognition/internal/interfaces/speechpb"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+func TestNewMeter(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m, err := NewMeter(1.5)
+		if err != nil {
+			t.Fatalf("NewMeter() error = %v", err)
+		}
+		if got := m.Budget(); got != 1.5 {
+			t.Errorf("Budget() = %v, want 1.5", got)
+		}
+	})
+
+	t.Run("negative budget", func(t *testing.T) {
+		if _, err := NewMeter(-1); err == nil {
+			t.Error("NewMeter() error = nil, want an error")
+		}
+	})
+}
+
+func TestMeter_Usage(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m, _ := NewMeter(0)
+		stream1 := &ispeechpb.Speech_StreamingRecognizeClientMock{}
+		stream2 := &ispeechpb.Speech_StreamingRecognizeClientMock{}
+
+		// Google reports the billed duration of the first stream rounded up,
+		// and does not report it for the second stream.
+		for range 3 {
+			if err := m.send(stream1, model.AudioBytes(500*time.Millisecond)); err != nil {
+				t.Fatalf("send() error = %v", err)
+			}
+		}
+		m.report(stream1, 2*time.Second)
+		if err := m.send(stream2, model.AudioBytes(time.Second)); err != nil {
+			t.Fatalf("send() error = %v", err)
+		}
+
+		want := Usage{Sent: 2500 * time.Millisecond, Billed: 3 * time.Second}
+		if diff := cmp.Diff(m.Usage(), want); diff != "" {
+			t.Errorf("Usage() mismatch (-got +want):\n%s", diff)
+		}
+		if got, want := m.Usage().Cost(), 3*PricePerHour/3600; got != want {
+			t.Errorf("Cost() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("budget exceeded", func(t *testing.T) {
+		m, _ := NewMeter(Cost(2 * time.Second))
+		stream := &ispeechpb.Speech_StreamingRecognizeClientMock{}
+
+		if err := m.send(stream, model.AudioBytes(time.Second)); err != nil {
+			t.Fatalf("send() error = %v", err)
+		}
+		// the reported duration counts for the projection.
+		m.report(stream, 1500*time.Millisecond)
+		if err := m.send(stream, model.AudioBytes(time.Second)); !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("send() error = %v, want %v", err, ErrBudgetExceeded)
+		}
+		if err := m.send(stream, model.AudioBytes(500*time.Millisecond)); err != nil {
+			t.Errorf("send() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("nil meter", func(t *testing.T) {
+		var m *Meter
+		if err := m.send(&ispeechpb.Speech_StreamingRecognizeClientMock{}, 1); err != nil {
+			t.Errorf("send() error = %v, want nil", err)
+		}
+		m.report(&ispeechpb.Speech_StreamingRecognizeClientMock{}, time.Second)
+	})
+}
+
+func TestAppendLedger(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ledger.jsonl")
+		m, _ := NewMeter(1)
+		if err := m.send(&ispeechpb.Speech_StreamingRecognizeClientMock{}, model.AudioBytes(time.Hour)); err != nil {
+			t.Fatalf("send() error = %v", err)
+		}
+		startedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		entry := m.NewLedgerEntry(startedAt, startedAt.Add(time.Hour))
+
+		for range 2 {
+			if err := AppendLedger(path, entry); err != nil {
+				t.Fatalf("AppendLedger() error = %v", err)
+			}
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read ledger: %v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("ledger has %d lines, want 2", len(lines))
+		}
+		var got LedgerEntry
+		if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+			t.Fatalf("failed to unmarshal ledger entry: %v", err)
+		}
+		want := LedgerEntry{
+			StartedAt:     startedAt,
+			EndedAt:       startedAt.Add(time.Hour),
+			SentSeconds:   3600,
+			BilledSeconds: 3600,
+			CostDollars:   PricePerHour,
+			BudgetDollars: 1,
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("ledger entry mismatch (-got +want):\n%s", diff)
+		}
+	})
+}
@@ -8,12 +8,19 @@ import (
 	"time"
 
 	"cloud.google.com/go/speech/apiv2/speechpb"
+	"github.com/hekt/voice-recognition/internal/clock"
 	myspeech "github.com/hekt/voice-recognition/internal/interfaces/speech"
 	"github.com/hekt/voice-recognition/internal/recognizer/model"
 	"github.com/hekt/voice-recognition/internal/resource"
+	"github.com/hekt/voice-recognition/internal/telemetry"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/status"
 )
 
+// PricePerHour is the price of Speech-to-Text API V2 streaming recognition in dollars
+// per hour of the audio at the time of writing.
+const PricePerHour = 0.96
+
 var _ model.RecognizerCoreInterface = (*Recognizer)(nil)
 
 type Recognizer struct {
@@ -30,6 +37,67 @@ type Recognizer struct {
 
 	audioCh  <-chan []byte
 	resultCh chan<- []*model.Result
+
+	// gauges is the state of the stream exposed while the recognizer runs.
+	gauges *streamGauges
+}
+
+// Config is the config of the Google backend.
+type Config struct {
+	// NewClient creates a client for each recognizer
+	// because the recognizer closes the client when it stops.
+	NewClient         func(ctx context.Context) (myspeech.Client, error)
+	ProjectID         string
+	RecognizerName    string
+	ReconnectInterval time.Duration
+	// Clock measures ReconnectInterval. The system clock is used if nil.
+	Clock clock.Clock
+	// Meter accounts the audio billed by Google. The audio is not accounted if nil.
+	Meter *Meter
+	// Diarization tells the speakers of the results if enabled.
+	Diarization Diarization
+}
+
+// New creates a recognizer from the config.
+func New(
+	ctx context.Context,
+	config Config,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (model.RecognizerCoreInterface, error) {
+	if config.NewClient == nil {
+		return nil, errors.New("client factory must be specified")
+	}
+	c := config.Clock
+	if c == nil {
+		c = clock.Real
+	}
+
+	client, err := config.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create speech client: %w", err)
+	}
+
+	recognizer, err := NewRecognizer(
+		ctx,
+		client,
+		audioCh,
+		resultCh,
+		config.ProjectID,
+		config.RecognizerName,
+		config.ReconnectInterval,
+		c,
+		config.Meter,
+		config.Diarization,
+	)
+	if err != nil {
+		if err := client.Close(); err != nil {
+			slog.Error(fmt.Sprintf("failed to close client: %v", err))
+		}
+		return nil, err
+	}
+
+	return recognizer, nil
 }
 
 func NewRecognizer(
@@ -40,6 +108,9 @@ func NewRecognizer(
 	projectID string,
 	recognizerName string,
 	reconnectInterval time.Duration,
+	clock clock.Clock,
+	meter *Meter,
+	diarization Diarization,
 ) (*Recognizer, error) {
 	if projectID == "" {
 		return nil, errors.New("project ID must be specified")
@@ -47,8 +118,8 @@ func NewRecognizer(
 	if recognizerName == "" {
 		return nil, errors.New("recognizer name must be specified")
 	}
-	if reconnectInterval < time.Minute {
-		return nil, errors.New("reconnect interval must be greater than or equal to 1 minute")
+	if reconnectInterval <= 0 {
+		return nil, errors.New("reconnect interval must be positive")
 	}
 	if client == nil {
 		return nil, errors.New("client must be specified")
@@ -59,21 +130,33 @@ func NewRecognizer(
 	if resultCh == nil {
 		return nil, errors.New("result channel must be specified")
 	}
+	if clock == nil {
+		return nil, errors.New("clock must be specified")
+	}
+	if err := diarization.Validate(); err != nil {
+		return nil, err
+	}
 
 	sendStreamCh := make(chan speechpb.Speech_StreamingRecognizeClient, 1)
 	receiveStreamCh := make(chan speechpb.Speech_StreamingRecognizeClient, 1)
 	responseCh := make(chan *speechpb.StreamingRecognizeResponse, 1)
 
+	offsets := newStreamOffsets()
+	gauges := &streamGauges{}
+
 	streamSupplier := NewStreamSupplier(
 		client,
 		sendStreamCh,
 		receiveStreamCh,
 		resource.RecognizerFullname(projectID, recognizerName),
 		reconnectInterval,
+		clock,
+		diarization,
+		gauges,
 	)
-	audioSender := NewAudioSender(audioCh, sendStreamCh)
-	responseReceiver := NewResponseReceiver(responseCh, receiveStreamCh)
-	responseProcessor := NewResponseProcessor(responseCh, resultCh)
+	audioSender := NewAudioSender(audioCh, sendStreamCh, offsets, meter)
+	responseReceiver := NewResponseReceiver(responseCh, receiveStreamCh, offsets, meter)
+	responseProcessor := NewResponseProcessor(responseCh, resultCh, diarization)
 
 	return &Recognizer{
 		streamSupplier:    streamSupplier,
@@ -83,16 +166,30 @@ func NewRecognizer(
 
 		client: client,
 
-		responseCh:      make(chan *speechpb.StreamingRecognizeResponse),
-		sendStreamCh:    make(chan speechpb.Speech_StreamingRecognizeClient),
-		receiveStreamCh: make(chan speechpb.Speech_StreamingRecognizeClient),
+		responseCh:      responseCh,
+		sendStreamCh:    sendStreamCh,
+		receiveStreamCh: receiveStreamCh,
 
 		audioCh:  audioCh,
 		resultCh: resultCh,
+
+		gauges: gauges,
 	}, nil
 }
 
-func (r *Recognizer) Start(ctx context.Context) error {
+func (r *Recognizer) Start(ctx context.Context) (err error) {
+	removeQueue := telemetry.QueueLength.Add(telemetry.QueueResponse, telemetry.ChannelLength(r.responseCh))
+	defer removeQueue()
+	removeConnected := telemetry.GoogleStreamsConnected.Add(r.gauges.connectedValue)
+	defer removeConnected()
+	removeNextReconnect := telemetry.GoogleNextReconnect.Add(r.gauges.nextReconnectValue)
+	defer removeNextReconnect()
+	defer func() {
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, ErrBudgetExceeded) {
+			telemetry.GoogleErrors.WithLabelValues(status.Code(err).String()).Inc()
+		}
+	}()
+
 	defer func() {
 		close(r.responseCh)
 		close(r.sendStreamCh)
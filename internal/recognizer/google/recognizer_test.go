@@ -9,6 +9,7 @@ import (
 
 	"cloud.google.com/go/speech/apiv2/speechpb"
 	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/clock"
 	myspeech "github.com/hekt/voice-recognition/internal/interfaces/speech"
 	myspeechpb "github.com/hekt/voice-recognition/internal/interfaces/speechpb"
 	"github.com/hekt/voice-recognition/internal/recognizer/model"
@@ -23,6 +24,9 @@ func TestNewRecognizer(t *testing.T) {
 		projectID         string
 		recognizerName    string
 		reconnectInterval time.Duration
+		clock             clock.Clock
+		meter             *Meter
+		diarization       Diarization
 	}
 	baseArgs := args{
 		ctx:               context.Background(),
@@ -32,6 +36,7 @@ func TestNewRecognizer(t *testing.T) {
 		projectID:         "test-project-id",
 		recognizerName:    "test-recognizer-name",
 		reconnectInterval: time.Minute,
+		clock:             clock.Real,
 	}
 	tests := []struct {
 		name    string
@@ -68,6 +73,15 @@ func TestNewRecognizer(t *testing.T) {
 				a.reconnectInterval = time.Second
 				return a
 			}(),
+			wantErr: false,
+		},
+		{
+			name: "zero reconnect interval",
+			args: func() args {
+				a := baseArgs
+				a.reconnectInterval = 0
+				return a
+			}(),
 			wantErr: true,
 		},
 		{
@@ -97,6 +111,42 @@ func TestNewRecognizer(t *testing.T) {
 			}(),
 			wantErr: true,
 		},
+		{
+			name: "nil clock",
+			args: func() args {
+				a := baseArgs
+				a.clock = nil
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "diarization",
+			args: func() args {
+				a := baseArgs
+				a.diarization = Diarization{MinSpeakers: 2, MaxSpeakers: 2}
+				return a
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "min speakers greater than max speakers",
+			args: func() args {
+				a := baseArgs
+				a.diarization = Diarization{MinSpeakers: 3, MaxSpeakers: 2}
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "min speakers without max speakers",
+			args: func() args {
+				a := baseArgs
+				a.diarization = Diarization{MinSpeakers: 2}
+				return a
+			}(),
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -108,6 +158,9 @@ func TestNewRecognizer(t *testing.T) {
 				tt.args.projectID,
 				tt.args.recognizerName,
 				tt.args.reconnectInterval,
+				tt.args.clock,
+				tt.args.meter,
+				tt.args.diarization,
 			)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewRecognizer() error = %v, wantErr %v", err, tt.wantErr)
@@ -144,6 +197,67 @@ func TestNewRecognizer(t *testing.T) {
 	}
 }
 
+func TestNew(t *testing.T) {
+	validConfig := Config{
+		ProjectID:         "test-project-id",
+		RecognizerName:    "test-recognizer-name",
+		ReconnectInterval: time.Minute,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		config := validConfig
+		config.NewClient = func(context.Context) (myspeech.Client, error) {
+			return &myspeech.ClientMock{}, nil
+		}
+		got, err := New(context.Background(), config, make(chan []byte), make(chan []*model.Result))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if got == nil {
+			t.Error("New() = nil, want non-nil")
+		}
+	})
+
+	t.Run("nil client factory", func(t *testing.T) {
+		if _, err := New(context.Background(), validConfig, make(chan []byte), make(chan []*model.Result)); err == nil {
+			t.Error("New() error = nil, want an error")
+		}
+	})
+
+	t.Run("failed to create client", func(t *testing.T) {
+		config := validConfig
+		config.NewClient = func(context.Context) (myspeech.Client, error) {
+			return nil, errors.New("test error")
+		}
+		if _, err := New(context.Background(), config, make(chan []byte), make(chan []*model.Result)); err == nil {
+			t.Error("New() error = nil, want an error")
+		}
+	})
+
+	t.Run("invalid config closes client", func(t *testing.T) {
+		client := &myspeech.ClientMock{
+			CloseFunc: func() error {
+				return nil
+			},
+		}
+		config := validConfig
+		config.ProjectID = ""
+		config.NewClient = func(context.Context) (myspeech.Client, error) {
+			return client, nil
+		}
+		got, err := New(context.Background(), config, make(chan []byte), make(chan []*model.Result))
+		if err == nil {
+			t.Error("New() error = nil, want an error")
+		}
+		if got != nil {
+			t.Errorf("New() = %v, want nil", got)
+		}
+		if len(client.CloseCalls()) != 1 {
+			t.Errorf("client.Close() calls = %d, want 1", len(client.CloseCalls()))
+		}
+	})
+}
+
 func TestRecognizer_Start(t *testing.T) {
 	t.Run("complex test", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -224,9 +338,10 @@ func TestRecognizer_Start(t *testing.T) {
 				}
 			},
 		}
-		audioSender := NewAudioSender(audioCh, sendStreamCh)
-		responseReceiver := NewResponseReceiver(responseCh, receiveStreamCh)
-		responseProcessor := NewResponseProcessor(responseCh, resultCh)
+		offsets := newStreamOffsets()
+		audioSender := NewAudioSender(audioCh, sendStreamCh, offsets, nil)
+		responseReceiver := NewResponseReceiver(responseCh, receiveStreamCh, offsets, nil)
+		responseProcessor := NewResponseProcessor(responseCh, resultCh, Diarization{})
 
 		r := &Recognizer{
 			streamSupplier:    streamSupplier,
@@ -243,6 +358,7 @@ func TestRecognizer_Start(t *testing.T) {
 			receiveStreamCh: receiveStreamCh,
 			audioCh:         audioCh,
 			resultCh:        resultCh,
+			gauges:          &streamGauges{},
 		}
 
 		var wg sync.WaitGroup
@@ -18,15 +18,19 @@ var _ ResponseProcessorInterface = (*ResponseProcessor)(nil)
 type ResponseProcessor struct {
 	responseCh <-chan *speechpb.StreamingRecognizeResponse
 	resultCh   chan<- []*model.Result
+	// diarization names the speakers of the results.
+	diarization Diarization
 }
 
 func NewResponseProcessor(
 	responseCh <-chan *speechpb.StreamingRecognizeResponse,
 	resultCh chan<- []*model.Result,
+	diarization Diarization,
 ) *ResponseProcessor {
 	return &ResponseProcessor{
-		responseCh: responseCh,
-		resultCh:   resultCh,
+		responseCh:  responseCh,
+		resultCh:    resultCh,
+		diarization: diarization,
 	}
 }
 
@@ -50,6 +54,8 @@ func (p *ResponseProcessor) Start(ctx context.Context) error {
 				results = append(results, &model.Result{
 					Transcript: result.Alternatives[0].Transcript,
 					IsFinal:    result.IsFinal,
+					End:        result.ResultEndOffset.AsDuration(),
+					Speaker:    p.diarization.speaker(result.Alternatives[0].Words),
 				})
 			}
 
@@ -57,7 +63,11 @@ func (p *ResponseProcessor) Start(ctx context.Context) error {
 				continue
 			}
 
-			p.resultCh <- results
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case p.resultCh <- results:
+			}
 		}
 	}
 }
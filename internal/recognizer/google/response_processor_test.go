@@ -6,10 +6,12 @@ import (
 	"reflect"
 	"sync"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/speech/apiv2/speechpb"
 	"github.com/google/go-cmp/cmp"
 	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 func TestNewResponseProcessor(t *testing.T) {
@@ -17,10 +19,13 @@ func TestNewResponseProcessor(t *testing.T) {
 		responseCh := make(chan *speechpb.StreamingRecognizeResponse)
 		resultCh := make(chan []*model.Result)
 
-		got := NewResponseProcessor(responseCh, resultCh)
+		diarization := Diarization{MinSpeakers: 1, MaxSpeakers: 2}
+
+		got := NewResponseProcessor(responseCh, resultCh, diarization)
 		want := &ResponseProcessor{
-			responseCh: responseCh,
-			resultCh:   resultCh,
+			responseCh:  responseCh,
+			resultCh:    resultCh,
+			diarization: diarization,
 		}
 
 		if !reflect.DeepEqual(got, want) {
@@ -38,8 +43,9 @@ func Test_ResponseProcessor_Start(t *testing.T) {
 		resultCh := make(chan []*model.Result, 2)
 
 		p := &ResponseProcessor{
-			responseCh: responseCh,
-			resultCh:   resultCh,
+			responseCh:  responseCh,
+			resultCh:    resultCh,
+			diarization: Diarization{MinSpeakers: 1, MaxSpeakers: 2, Names: map[string]string{"2": "Bob"}},
 		}
 
 		var wg sync.WaitGroup
@@ -83,9 +89,16 @@ func Test_ResponseProcessor_Start(t *testing.T) {
 			Results: []*speechpb.StreamingRecognitionResult{
 				{
 					Alternatives: []*speechpb.SpeechRecognitionAlternative{
-						{Transcript: "abcd"},
+						{
+							Transcript: "abcd",
+							Words: []*speechpb.WordInfo{
+								{Word: "ab", SpeakerLabel: "2"},
+								{Word: "cd", SpeakerLabel: "2"},
+							},
+						},
 					},
-					IsFinal: true,
+					IsFinal:         true,
+					ResultEndOffset: durationpb.New(3 * time.Second),
 				},
 				{
 					Alternatives: []*speechpb.SpeechRecognitionAlternative{
@@ -110,7 +123,7 @@ func Test_ResponseProcessor_Start(t *testing.T) {
 				{Transcript: "b", IsFinal: false},
 			},
 			{
-				{Transcript: "abcd", IsFinal: true},
+				{Transcript: "abcd", IsFinal: true, End: 3 * time.Second, Speaker: "Bob"},
 				{Transcript: "x", IsFinal: false},
 			},
 		}
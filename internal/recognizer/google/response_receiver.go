@@ -9,6 +9,7 @@ import (
 	"cloud.google.com/go/speech/apiv2/speechpb"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 //go:generate moq -rm -out response_receiver_mock.go . ResponseReceiverInterface
@@ -21,15 +22,23 @@ var _ ResponseReceiverInterface = (*ResponseReceiver)(nil)
 type ResponseReceiver struct {
 	responseCh      chan<- *speechpb.StreamingRecognizeResponse
 	receiveStreamCh <-chan speechpb.Speech_StreamingRecognizeClient
+	// offsets tells the offset of the audio at which each stream started.
+	offsets *streamOffsets
+	// meter records the billed duration reported in the responses. nil if not accounted.
+	meter *Meter
 }
 
 func NewResponseReceiver(
 	responseCh chan<- *speechpb.StreamingRecognizeResponse,
 	receiveStreamCh <-chan speechpb.Speech_StreamingRecognizeClient,
+	offsets *streamOffsets,
+	meter *Meter,
 ) *ResponseReceiver {
 	return &ResponseReceiver{
 		responseCh:      responseCh,
 		receiveStreamCh: receiveStreamCh,
+		offsets:         offsets,
+		meter:           meter,
 	}
 }
 
@@ -40,6 +49,7 @@ func (r *ResponseReceiver) Start(ctx context.Context) error {
 	if !ok {
 		return fmt.Errorf("failed to get receive stream from channel")
 	}
+	offset := r.offsets.take(stream)
 
 	for {
 		select {
@@ -58,6 +68,7 @@ func (r *ResponseReceiver) Start(ctx context.Context) error {
 						return fmt.Errorf("receive stream channel is closed")
 					}
 					stream = newStream
+					offset = r.offsets.take(stream)
 					slog.Debug("ResponseReceiver: stream switched")
 					continue
 				case <-ctx.Done():
@@ -73,7 +84,25 @@ func (r *ResponseReceiver) Start(ctx context.Context) error {
 				return fmt.Errorf("failed to receive response: %w", err)
 			}
 
-			r.responseCh <- resp
+			if billed := resp.GetMetadata().GetTotalBilledDuration(); billed != nil {
+				r.meter.report(stream, billed.AsDuration())
+			}
+
+			// make the offsets relative to the beginning of the audio rather than the stream.
+			if offset > 0 {
+				for _, result := range resp.Results {
+					if result.ResultEndOffset == nil {
+						continue
+					}
+					result.ResultEndOffset = durationpb.New(result.ResultEndOffset.AsDuration() + offset)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case r.responseCh <- resp:
+			}
 		}
 	}
 }
@@ -5,11 +5,13 @@ import (
 	"io"
 	"reflect"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/speech/apiv2/speechpb"
 	ispeechpb "github.com/hekt/voice-recognition/internal/interfaces/speechpb"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 func TestNewResponseReceiver(t *testing.T) {
@@ -17,10 +19,15 @@ func TestNewResponseReceiver(t *testing.T) {
 		responseCh := make(chan *speechpb.StreamingRecognizeResponse, 1)
 		receiveStreamCh := make(chan speechpb.Speech_StreamingRecognizeClient, 1)
 
-		got := NewResponseReceiver(responseCh, receiveStreamCh)
+		offsets := newStreamOffsets()
+		meter, _ := NewMeter(0)
+
+		got := NewResponseReceiver(responseCh, receiveStreamCh, offsets, meter)
 		want := &ResponseReceiver{
 			responseCh:      responseCh,
 			receiveStreamCh: receiveStreamCh,
+			offsets:         offsets,
+			meter:           meter,
 		}
 
 		if !reflect.DeepEqual(got, want) {
@@ -47,7 +54,14 @@ func Test_responseReceiver_Start(t *testing.T) {
 			},
 		}
 
-		response2 := &speechpb.StreamingRecognizeResponse{}
+		response2 := &speechpb.StreamingRecognizeResponse{
+			Results: []*speechpb.StreamingRecognitionResult{
+				{ResultEndOffset: durationpb.New(time.Second)},
+			},
+			Metadata: &speechpb.RecognitionResponseMetadata{
+				TotalBilledDuration: durationpb.New(2 * time.Second),
+			},
+		}
 		stream2ResponseCh := make(chan *response, 2)
 		stream2ResponseCh <- &response{response2, nil}
 		stream2ResponseCh <- &response{nil, status.Error(codes.Canceled, "canceled")}
@@ -64,9 +78,16 @@ func Test_responseReceiver_Start(t *testing.T) {
 		receiveStreamCh <- stream1
 		receiveStreamCh <- stream2
 
+		// the second stream started after 5 seconds of the audio are sent.
+		offsets := newStreamOffsets()
+		offsets.set(stream2, 5*time.Second)
+		meter, _ := NewMeter(0)
+
 		r := &ResponseReceiver{
 			responseCh:      responseCh,
 			receiveStreamCh: receiveStreamCh,
+			offsets:         offsets,
+			meter:           meter,
 		}
 
 		got := r.Start(context.Background())
@@ -84,6 +105,12 @@ func Test_responseReceiver_Start(t *testing.T) {
 		if got, want := <-responseCh, response2; got != want {
 			t.Errorf("unexpected response: got %v, want %v", got, want)
 		}
+		if got, want := response2.Results[0].ResultEndOffset.AsDuration(), 6*time.Second; got != want {
+			t.Errorf("offset of the second stream = %v, want %v", got, want)
+		}
+		if got, want := meter.Usage().Billed, 2*time.Second; got != want {
+			t.Errorf("billed = %v, want %v", got, want)
+		}
 	})
 
 	t.Run("closed stream", func(t *testing.T) {
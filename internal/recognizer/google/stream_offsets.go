@@ -0,0 +1,48 @@
+package google
+
+import (
+	"sync"
+	"time"
+
+	"cloud.google.com/go/speech/apiv2/speechpb"
+)
+
+// streamOffsets holds the offset of the audio at which each stream started,
+// since the offsets in the responses are relative to the beginning of the stream.
+//
+// The sender sets the offset before sending audio to the stream, and the
+// receiver gets it after the previous stream ends, which happens after the
+// sender closes it on switching to the stream.
+type streamOffsets struct {
+	mu      sync.Mutex
+	offsets map[speechpb.Speech_StreamingRecognizeClient]time.Duration
+}
+
+func newStreamOffsets() *streamOffsets {
+	return &streamOffsets{
+		offsets: map[speechpb.Speech_StreamingRecognizeClient]time.Duration{},
+	}
+}
+
+// set sets the offset at which the stream started. It does nothing on nil.
+func (o *streamOffsets) set(stream speechpb.Speech_StreamingRecognizeClient, offset time.Duration) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.offsets[stream] = offset
+}
+
+// take returns the offset at which the stream started and forgets it.
+// It returns zero for the unknown stream and on nil.
+func (o *streamOffsets) take(stream speechpb.Speech_StreamingRecognizeClient) time.Duration {
+	if o == nil {
+		return 0
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	offset := o.offsets[stream]
+	delete(o.offsets, stream)
+	return offset
+}
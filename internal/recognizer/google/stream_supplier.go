@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/speech/apiv2/speechpb"
+	"github.com/hekt/voice-recognition/internal/clock"
 	"github.com/hekt/voice-recognition/internal/interfaces/speech"
+	"github.com/hekt/voice-recognition/internal/telemetry"
 )
 
 //go:generate moq -rm -out stream_supplier_mock.go . StreamSupplierInterface
@@ -32,6 +35,32 @@ type StreamSupplier struct {
 	recognizerFullName string
 	// supplyInterval is the interval of stream supply.
 	supplyInterval time.Duration
+	// clock measures supplyInterval.
+	clock clock.Clock
+	// diarization overrides the diarization of the recognizer if enabled.
+	diarization Diarization
+	// gauges records the state of the stream. It is not recorded if nil.
+	gauges *streamGauges
+}
+
+// streamGauges are the state of the stream of a recognizer, which is
+// aggregated with the ones of the other recognizers in the metrics.
+type streamGauges struct {
+	connected atomic.Bool
+	// nextReconnect is the Unix time in seconds of the next supply.
+	nextReconnect atomic.Int64
+}
+
+// connectedValue returns 1 once the stream is supplied.
+func (g *streamGauges) connectedValue() float64 {
+	if g.connected.Load() {
+		return 1
+	}
+	return 0
+}
+
+func (g *streamGauges) nextReconnectValue() float64 {
+	return float64(g.nextReconnect.Load())
 }
 
 func NewStreamSupplier(
@@ -40,6 +69,9 @@ func NewStreamSupplier(
 	receiveStreamCh chan<- speechpb.Speech_StreamingRecognizeClient,
 	recognizerFullName string,
 	supplyInterval time.Duration,
+	clock clock.Clock,
+	diarization Diarization,
+	gauges *streamGauges,
 ) *StreamSupplier {
 	return &StreamSupplier{
 		client:             client,
@@ -47,20 +79,23 @@ func NewStreamSupplier(
 		receiveStreamCh:    receiveStreamCh,
 		recognizerFullName: recognizerFullName,
 		supplyInterval:     supplyInterval,
+		clock:              clock,
+		diarization:        diarization,
+		gauges:             gauges,
 	}
 }
 
 func (s *StreamSupplier) Start(ctx context.Context) error {
 	slog.Debug("StreamSupplier: start")
 
-	timer := time.NewTimer(s.supplyInterval)
+	timer := s.clock.NewTimer(s.supplyInterval)
 	defer timer.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-timer.C:
+		case <-timer.C():
 			slog.Debug("StreamSupplier: timer fired")
 
 			newStream, err := s.initializeStream(ctx)
@@ -82,6 +117,8 @@ func (s *StreamSupplier) Start(ctx context.Context) error {
 				return ctx.Err()
 			}
 
+			telemetry.GoogleReconnects.Inc()
+			s.recordConnected()
 			slog.Debug("StreamSupplier: stream supplied")
 		}
 	}
@@ -105,9 +142,19 @@ func (s *StreamSupplier) Supply(ctx context.Context) error {
 		return ctx.Err()
 	}
 
+	s.recordConnected()
 	return nil
 }
 
+// recordConnected records the stream supplied and the time of the next supply.
+func (s *StreamSupplier) recordConnected() {
+	if s.gauges == nil {
+		return
+	}
+	s.gauges.connected.Store(true)
+	s.gauges.nextReconnect.Store(s.clock.Now().Add(s.supplyInterval).Unix())
+}
+
 func (s *StreamSupplier) initializeStream(
 	ctx context.Context,
 ) (speechpb.Speech_StreamingRecognizeClient, error) {
@@ -119,11 +166,7 @@ func (s *StreamSupplier) initializeStream(
 	if err := stream.Send(&speechpb.StreamingRecognizeRequest{
 		Recognizer: s.recognizerFullName,
 		StreamingRequest: &speechpb.StreamingRecognizeRequest_StreamingConfig{
-			StreamingConfig: &speechpb.StreamingRecognitionConfig{
-				StreamingFeatures: &speechpb.StreamingRecognitionFeatures{
-					InterimResults: true,
-				},
-			},
+			StreamingConfig: s.diarization.streamingConfig(),
 		},
 	}); err != nil {
 		return nil, fmt.Errorf("failed to send initial request: %w", err)
@@ -13,6 +13,7 @@ import (
 	"github.com/googleapis/gax-go/v2"
 	ispeech "github.com/hekt/voice-recognition/internal/interfaces/speech"
 	ispeechpb "github.com/hekt/voice-recognition/internal/interfaces/speechpb"
+	"github.com/hekt/voice-recognition/internal/testutil"
 	"google.golang.org/protobuf/testing/protocmp"
 )
 
@@ -23,14 +24,21 @@ func TestNewStreamSupplier(t *testing.T) {
 		receiveStreamCh := make(chan speechpb.Speech_StreamingRecognizeClient)
 		recognizerFullName := "projects/test-project/locations/global/recognizers/test-recognizer"
 		supplyInterval := 5 * time.Minute
+		clock := testutil.NewFakeClock(time.Now())
 
-		got := NewStreamSupplier(client, sendStreamCh, receiveStreamCh, recognizerFullName, supplyInterval)
+		diarization := Diarization{MinSpeakers: 1, MaxSpeakers: 2}
+		gauges := &streamGauges{}
+
+		got := NewStreamSupplier(client, sendStreamCh, receiveStreamCh, recognizerFullName, supplyInterval, clock, diarization, gauges)
 		want := &StreamSupplier{
 			client:             client,
 			sendStreamCh:       sendStreamCh,
 			receiveStreamCh:    receiveStreamCh,
 			recognizerFullName: recognizerFullName,
 			supplyInterval:     supplyInterval,
+			clock:              clock,
+			diarization:        diarization,
+			gauges:             gauges,
 		}
 		if !reflect.DeepEqual(got, want) {
 			t.Errorf("NewStreamSupplier() = %v, want %v", got, want)
@@ -59,28 +67,27 @@ func Test_streamSupplier_Start(t *testing.T) {
 			},
 		}
 
-		// ブロッキングの動作を確認するためにバッファを 1 にしている。
 		// 実際の動作でもバッファは 1 で使う想定。
 		sendStreamCh := make(chan speechpb.Speech_StreamingRecognizeClient, 1)
 		receiveStreamCh := make(chan speechpb.Speech_StreamingRecognizeClient, 1)
 
-		s := &StreamSupplier{
-			client:          client,
-			supplyInterval:  10 * time.Millisecond,
-			sendStreamCh:    sendStreamCh,
-			receiveStreamCh: receiveStreamCh,
-		}
+		clock := testutil.NewFakeClock(time.Now())
+		s := NewStreamSupplier(client, sendStreamCh, receiveStreamCh, "", time.Minute, clock, Diarization{}, nil)
 
-		var wg sync.WaitGroup
-		var got error
-		wg.Add(1)
+		errCh := make(chan error, 1)
 		go func() {
-			defer wg.Done()
-			got = s.Start(ctx)
+			errCh <- s.Start(ctx)
 		}()
 
 		// 2回取り出す
 		for i := 0; i < 2; i++ {
+			// タイマーがセットされるのを待ってから時間を進める
+			clock.BlockUntil(1)
+			if n := clock.Advance(59 * time.Second); n != 0 {
+				t.Errorf("streamSupplier.Start() fires before the interval, attempt = %d", i+1)
+			}
+			clock.Advance(time.Second)
+
 			gotSendStream := <-sendStreamCh
 			if gotSendStream != stream {
 				t.Errorf("streamSupplier.Start() supplies %v, want %v, attempt = %d", gotSendStream, stream, i+1)
@@ -91,21 +98,17 @@ func Test_streamSupplier_Start(t *testing.T) {
 			}
 		}
 
-		// stream を取り出したあとの最後の処理を確実におこなうために待機
-		time.Sleep(100 * time.Millisecond)
+		// 時間を進めない限り次の stream は作られない
+		if len(client.StreamingRecognizeCalls()) != 2 {
+			t.Errorf("streamSupplier.Start() calls StreamingRecognize %d times, want 2 times", len(client.StreamingRecognizeCalls()))
+		}
 
-		// キャンセルしてループを停止させ、goroutine が完了するのを待つ
+		// キャンセルしてループを停止させる
 		cancel()
-		wg.Wait()
 
-		if !errors.Is(got, context.Canceled) {
+		if got := <-errCh; !errors.Is(got, context.Canceled) {
 			t.Errorf("streamSupplier.Start() error = %v, want %v", got, context.Canceled)
 		}
-
-		// バッファが 1 なので、二回取り出したあと三回目を送信して四回目で待機していたはず
-		if len(client.StreamingRecognizeCalls()) != 4 {
-			t.Errorf("streamSupplier.Start() calls StreamingRecognize %d times, want 4 times", len(client.StreamingRecognizeCalls()))
-		}
 	})
 
 	t.Run("initializeStream error", func(t *testing.T) {
@@ -119,11 +122,22 @@ func Test_streamSupplier_Start(t *testing.T) {
 			},
 		}
 
+		clock := testutil.NewFakeClock(time.Now())
 		s := &StreamSupplier{
 			client:         client,
-			supplyInterval: 10 * time.Millisecond,
+			supplyInterval: time.Minute,
+			clock:          clock,
 		}
-		if err := s.Start(context.Background()); err == nil {
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- s.Start(context.Background())
+		}()
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+
+		if err := <-errCh; err == nil {
 			t.Errorf("recognizer.startStreamSupplier() error = %v, wantErr %v", err, true)
 		}
 	})
@@ -149,15 +163,25 @@ func Test_streamSupplier_Supply(t *testing.T) {
 		sendStreamCh := make(chan speechpb.Speech_StreamingRecognizeClient, 1)
 		receiveStreamCh := make(chan speechpb.Speech_StreamingRecognizeClient, 1)
 
+		now := time.Now()
 		s := &StreamSupplier{
 			client:          client,
 			sendStreamCh:    sendStreamCh,
 			receiveStreamCh: receiveStreamCh,
+			supplyInterval:  time.Minute,
+			clock:           testutil.NewFakeClock(now),
+			gauges:          &streamGauges{},
 		}
 
 		if err := s.Supply(context.Background()); err != nil {
 			t.Errorf("streamSupplier.Supply() error = %v, want nil", err)
 		}
+		if got := s.gauges.connectedValue(); got != 1 {
+			t.Errorf("streamSupplier.Supply() sets connected to %v, want 1", got)
+		}
+		if got, want := s.gauges.nextReconnectValue(), float64(now.Add(time.Minute).Unix()); got != want {
+			t.Errorf("streamSupplier.Supply() sets next reconnect to %v, want %v", got, want)
+		}
 		if gotSendStream := <-sendStreamCh; gotSendStream != stream {
 			t.Errorf("streamSupplier.Supply() supplies %v, want %v", gotSendStream, stream)
 		}
@@ -189,6 +213,7 @@ func Test_streamSupplier_Supply(t *testing.T) {
 			client:          client,
 			sendStreamCh:    sendStreamCh,
 			receiveStreamCh: receiveStreamCh,
+			clock:           testutil.NewFakeClock(time.Now()),
 		}
 
 		ctx := context.Background()
@@ -251,6 +276,7 @@ func Test_streamSupplier_Supply(t *testing.T) {
 			client:          client,
 			sendStreamCh:    sendStreamCh,
 			receiveStreamCh: receiveStreamCh,
+			clock:           testutil.NewFakeClock(time.Now()),
 		}
 
 		ctx, cancel := context.WithCancel(context.Background())
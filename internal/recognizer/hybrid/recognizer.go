@@ -0,0 +1,385 @@
+package hybrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"golang.org/x/sync/errgroup"
+)
+
+// drainTimeout is the time to wait for the remaining results of the remote core
+// after the gate is closed.
+const drainTimeout = 5 * time.Second
+
+var _ model.RecognizerCoreInterface = (*Recognizer)(nil)
+
+// Recognizer runs a local core and a remote core on the same audio.
+// Every result of the local core is passed as an interim result to drive the
+// live view, and only the final results of the remote core are passed as final
+// results.
+//
+// When gate is enabled, audio is sent to the remote core only while the local
+// core is recognizing speech. A remote core is created for each speech region
+// and stopped when the region ends.
+type Recognizer struct {
+	newLocal  model.RecognizerCoreFactory
+	newRemote model.RecognizerCoreFactory
+
+	// gate enables sending audio to the remote core only in speech regions.
+	gate bool
+	// preroll is the bytes of audio sent ahead of the detected speech
+	// because the local core detects speech after receiving it.
+	preroll int
+	// hangover is the bytes of audio sent after the last speech detected.
+	hangover int
+	// drainTimeout is the time to keep the remote core after the region ends.
+	drainTimeout time.Duration
+
+	audioCh  <-chan []byte
+	resultCh chan<- []*model.Result
+}
+
+// Config is the config of the hybrid backend.
+type Config struct {
+	NewLocal  model.RecognizerCoreFactory
+	NewRemote model.RecognizerCoreFactory
+	Gate      bool
+	Preroll   time.Duration
+	Hangover  time.Duration
+}
+
+// New creates a recognizer from the config.
+func New(
+	_ context.Context,
+	config Config,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (model.RecognizerCoreInterface, error) {
+	recognizer, err := NewRecognizer(
+		config.NewLocal,
+		config.NewRemote,
+		audioCh,
+		resultCh,
+		config.Gate,
+		config.Preroll,
+		config.Hangover,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return recognizer, nil
+}
+
+func NewRecognizer(
+	newLocal model.RecognizerCoreFactory,
+	newRemote model.RecognizerCoreFactory,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+	gate bool,
+	preroll time.Duration,
+	hangover time.Duration,
+) (*Recognizer, error) {
+	if newLocal == nil {
+		return nil, errors.New("local recognizer factory must be specified")
+	}
+	if newRemote == nil {
+		return nil, errors.New("remote recognizer factory must be specified")
+	}
+	if audioCh == nil {
+		return nil, errors.New("audio channel must be specified")
+	}
+	if resultCh == nil {
+		return nil, errors.New("result channel must be specified")
+	}
+	if preroll < 0 {
+		return nil, errors.New("preroll must not be negative")
+	}
+	if gate && hangover <= 0 {
+		return nil, errors.New("hangover must be positive when gate is enabled")
+	}
+
+	return &Recognizer{
+		newLocal:     newLocal,
+		newRemote:    newRemote,
+		gate:         gate,
+		preroll:      model.AudioBytes(preroll),
+		hangover:     model.AudioBytes(hangover),
+		drainTimeout: drainTimeout,
+		audioCh:      audioCh,
+		resultCh:     resultCh,
+	}, nil
+}
+
+func (r *Recognizer) Start(ctx context.Context) error {
+	localAudioCh := make(chan []byte, 10)
+	localResultCh := make(chan []*model.Result, 10)
+	remoteResultCh := make(chan []*model.Result, 10)
+	activityCh := make(chan struct{}, 1)
+	defer func() {
+		close(localAudioCh)
+		close(localResultCh)
+		close(remoteResultCh)
+		close(activityCh)
+	}()
+
+	local, err := r.newLocal(ctx, localAudioCh, localResultCh)
+	if err != nil {
+		return fmt.Errorf("failed to create local recognizer: %w", err)
+	}
+
+	eg, ctx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		if err := local.Start(ctx); err != nil {
+			return fmt.Errorf("error occured in local recognizer: %w", err)
+		}
+		return nil
+	})
+	eg.Go(func() error {
+		return r.processLocalResults(ctx, localResultCh, activityCh)
+	})
+	eg.Go(func() error {
+		return r.processRemoteResults(ctx, remoteResultCh)
+	})
+	eg.Go(func() error {
+		return r.dispatch(ctx, eg, localAudioCh, remoteResultCh, activityCh)
+	})
+
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// region is a remote core running for a speech region.
+type region struct {
+	audioCh chan []byte
+	ctx     context.Context
+	cancel  context.CancelFunc
+	// drainTimer stops the region. It is nil while the region is open.
+	drainTimer *time.Timer
+}
+
+func (r *Recognizer) dispatch(
+	ctx context.Context,
+	eg *errgroup.Group,
+	localAudioCh chan<- []byte,
+	remoteResultCh chan<- []*model.Result,
+	activityCh <-chan struct{},
+) error {
+	var current *region
+	open := func() error {
+		// reuse the region if it is still draining
+		if current != nil && current.drainTimer != nil && current.drainTimer.Stop() {
+			current.drainTimer = nil
+			slog.Debug("HybridRecognizer: region resumed")
+			return nil
+		}
+
+		rg, err := r.startRegion(ctx, eg, remoteResultCh)
+		if err != nil {
+			return err
+		}
+		current = rg
+		slog.Debug("HybridRecognizer: region opened")
+		return nil
+	}
+	closeRegion := func() {
+		current.drainTimer = time.AfterFunc(r.drainTimeout, current.cancel)
+		slog.Debug("HybridRecognizer: region closed")
+	}
+	send := func(audio []byte) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-current.ctx.Done():
+			return errors.New("remote recognizer stopped unexpectedly")
+		case current.audioCh <- audio:
+			return nil
+		}
+	}
+
+	if !r.gate {
+		if err := open(); err != nil {
+			return err
+		}
+	}
+
+	gateOpened := !r.gate
+	var prerollBuf [][]byte
+	prerollLen := 0
+	// silent is the bytes of audio since the last speech detected.
+	silent := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-activityCh:
+			silent = 0
+			if gateOpened {
+				continue
+			}
+
+			if err := open(); err != nil {
+				return err
+			}
+			gateOpened = true
+			for _, audio := range prerollBuf {
+				if err := send(audio); err != nil {
+					return err
+				}
+			}
+			prerollBuf = nil
+			prerollLen = 0
+		case audio, ok := <-r.audioCh:
+			if !ok {
+				return errors.New("audio channel closed")
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case localAudioCh <- audio:
+			}
+
+			if gateOpened {
+				if err := send(audio); err != nil {
+					return err
+				}
+				if !r.gate {
+					continue
+				}
+
+				silent += len(audio)
+				if silent >= r.hangover {
+					closeRegion()
+					gateOpened = false
+				}
+				continue
+			}
+
+			prerollBuf = append(prerollBuf, audio)
+			prerollLen += len(audio)
+			for len(prerollBuf) > 0 && prerollLen-len(prerollBuf[0]) >= r.preroll {
+				prerollLen -= len(prerollBuf[0])
+				prerollBuf = prerollBuf[1:]
+			}
+		}
+	}
+}
+
+func (r *Recognizer) startRegion(
+	ctx context.Context,
+	eg *errgroup.Group,
+	remoteResultCh chan<- []*model.Result,
+) (*region, error) {
+	regionCtx, cancel := context.WithCancel(ctx)
+	audioCh := make(chan []byte, 100)
+
+	remote, err := r.newRemote(regionCtx, audioCh, remoteResultCh)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create remote recognizer: %w", err)
+	}
+
+	eg.Go(func() error {
+		defer cancel()
+		err := remote.Start(regionCtx)
+		// the region is stopped by the drain timer.
+		if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+			slog.Debug("HybridRecognizer: region stopped")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error occured in remote recognizer: %w", err)
+		}
+		return nil
+	})
+
+	return &region{
+		audioCh: audioCh,
+		ctx:     regionCtx,
+		cancel:  cancel,
+	}, nil
+}
+
+func (r *Recognizer) processLocalResults(
+	ctx context.Context,
+	localResultCh <-chan []*model.Result,
+	activityCh chan<- struct{},
+) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case results, ok := <-localResultCh:
+			if !ok {
+				return errors.New("local result channel closed")
+			}
+
+			// notify the dispatcher that speech is detected without blocking.
+			select {
+			case activityCh <- struct{}{}:
+			default:
+			}
+
+			// the local results are not written even on the shutdown, since
+			// the final results are of the remote core.
+			interims := make([]*model.Result, 0, len(results))
+			for _, result := range results {
+				interims = append(interims, &model.Result{
+					Transcript:  result.Transcript,
+					IsFinal:     false,
+					Provisional: true,
+				})
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case r.resultCh <- interims:
+			}
+		}
+	}
+}
+
+func (r *Recognizer) processRemoteResults(
+	ctx context.Context,
+	remoteResultCh <-chan []*model.Result,
+) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case results, ok := <-remoteResultCh:
+			if !ok {
+				return errors.New("remote result channel closed")
+			}
+
+			finals := make([]*model.Result, 0, len(results))
+			for _, result := range results {
+				if result.IsFinal {
+					// the offset is in the audio passed the gate, not in the audio received.
+					f := *result
+					f.End = 0
+					finals = append(finals, &f)
+				}
+			}
+			if len(finals) == 0 {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case r.resultCh <- finals:
+			}
+		}
+	}
+}
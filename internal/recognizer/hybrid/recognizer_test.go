@@ -0,0 +1,359 @@
+package hybrid
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+func TestNewRecognizer(t *testing.T) {
+	factory := func(
+		context.Context,
+		<-chan []byte,
+		chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		return &model.RecognizerCoreInterfaceMock{}, nil
+	}
+
+	type args struct {
+		newLocal  model.RecognizerCoreFactory
+		newRemote model.RecognizerCoreFactory
+		audioCh   <-chan []byte
+		resultCh  chan<- []*model.Result
+		gate      bool
+		preroll   time.Duration
+		hangover  time.Duration
+	}
+	baseArgs := args{
+		newLocal:  factory,
+		newRemote: factory,
+		audioCh:   make(chan []byte),
+		resultCh:  make(chan []*model.Result),
+		gate:      true,
+		preroll:   time.Second,
+		hangover:  time.Second,
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			args:    baseArgs,
+			wantErr: false,
+		},
+		{
+			name: "nil local factory",
+			args: func() args {
+				a := baseArgs
+				a.newLocal = nil
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "nil remote factory",
+			args: func() args {
+				a := baseArgs
+				a.newRemote = nil
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "nil audio channel",
+			args: func() args {
+				a := baseArgs
+				a.audioCh = nil
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "nil result channel",
+			args: func() args {
+				a := baseArgs
+				a.resultCh = nil
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "negative preroll",
+			args: func() args {
+				a := baseArgs
+				a.preroll = -time.Second
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "zero hangover with gate",
+			args: func() args {
+				a := baseArgs
+				a.hangover = 0
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "zero hangover without gate",
+			args: func() args {
+				a := baseArgs
+				a.gate = false
+				a.hangover = 0
+				return a
+			}(),
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRecognizer(
+				tt.args.newLocal,
+				tt.args.newRemote,
+				tt.args.audioCh,
+				tt.args.resultCh,
+				tt.args.gate,
+				tt.args.preroll,
+				tt.args.hangover,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRecognizer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got == nil {
+				t.Errorf("NewRecognizer() = nil, want non-nil")
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	factory := func(
+		context.Context,
+		<-chan []byte,
+		chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		return &model.RecognizerCoreInterfaceMock{}, nil
+	}
+
+	t.Run("success", func(t *testing.T) {
+		got, err := New(
+			context.Background(),
+			Config{
+				NewLocal:  factory,
+				NewRemote: factory,
+				Gate:      true,
+				Preroll:   time.Second,
+				Hangover:  time.Second,
+			},
+			make(chan []byte),
+			make(chan []*model.Result),
+		)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if got == nil {
+			t.Error("New() = nil, want non-nil")
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		got, err := New(context.Background(), Config{}, make(chan []byte), make(chan []*model.Result))
+		if err == nil {
+			t.Error("New() error = nil, want an error")
+		}
+		if got != nil {
+			t.Errorf("New() = %v, want nil", got)
+		}
+	})
+}
+
+// echoFactory returns a factory of the core which sends the received audio as a result
+// if accept returns true for the audio.
+func echoFactory(
+	accept func([]byte) bool,
+	isFinal bool,
+	receivedCh chan<- []byte,
+	stoppedCh chan<- struct{},
+	calls *int,
+) model.RecognizerCoreFactory {
+	return func(
+		_ context.Context,
+		audioCh <-chan []byte,
+		resultCh chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		*calls++
+		return &model.RecognizerCoreInterfaceMock{
+			StartFunc: func(ctx context.Context) error {
+				defer func() {
+					if stoppedCh != nil {
+						stoppedCh <- struct{}{}
+					}
+				}()
+				for {
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case audio := <-audioCh:
+						if receivedCh != nil {
+							receivedCh <- audio
+						}
+						if !accept(audio) {
+							continue
+						}
+						select {
+						case <-ctx.Done():
+							return ctx.Err()
+						case resultCh <- []*model.Result{{Transcript: string(audio), IsFinal: isFinal}}:
+						}
+					}
+				}
+			},
+		}, nil
+	}
+}
+
+func TestRecognizer_Start(t *testing.T) {
+	isVoice := func(audio []byte) bool {
+		return bytes.HasPrefix(audio, []byte("v"))
+	}
+
+	t.Run("without gate", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		audioCh := make(chan []byte)
+		resultCh := make(chan []*model.Result)
+
+		localCalls, remoteCalls := 0, 0
+		r := &Recognizer{
+			newLocal: echoFactory(isVoice, true, nil, nil, &localCalls),
+			// the remote core returns every audio as a final result
+			newRemote: echoFactory(func([]byte) bool { return true }, true, nil, nil, &remoteCalls),
+			gate:      false,
+			audioCh:   audioCh,
+			resultCh:  resultCh,
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var got error
+		go func() {
+			defer wg.Done()
+			got = r.Start(ctx)
+		}()
+
+		audioCh <- []byte("v1")
+		results := append(<-resultCh, <-resultCh...)
+
+		cancel()
+		wg.Wait()
+
+		if !errors.Is(got, context.Canceled) {
+			t.Errorf("Recognizer.Start() error = %v, want %v", got, context.Canceled)
+		}
+		// the order of the local and remote results is not deterministic.
+		want := map[bool]string{false: "v1", true: "v1"}
+		gotResults := map[bool]string{}
+		for _, result := range results {
+			gotResults[result.IsFinal] = result.Transcript
+		}
+		if diff := cmp.Diff(gotResults, want); diff != "" {
+			t.Errorf("results (-got +want):\n%s", diff)
+		}
+		if localCalls != 1 || remoteCalls != 1 {
+			t.Errorf("factory calls = (%d, %d), want (1, 1)", localCalls, remoteCalls)
+		}
+	})
+
+	t.Run("with gate", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		audioCh := make(chan []byte)
+		resultCh := make(chan []*model.Result, 10)
+		remoteReceivedCh := make(chan []byte, 10)
+		remoteStoppedCh := make(chan struct{}, 1)
+
+		localCalls, remoteCalls := 0, 0
+		r := &Recognizer{
+			newLocal: echoFactory(isVoice, false, nil, nil, &localCalls),
+			newRemote: echoFactory(
+				func([]byte) bool { return false },
+				true,
+				remoteReceivedCh,
+				remoteStoppedCh,
+				&remoteCalls,
+			),
+			gate:         true,
+			preroll:      8,
+			hangover:     8,
+			drainTimeout: 10 * time.Millisecond,
+			audioCh:      audioCh,
+			resultCh:     resultCh,
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var got error
+		go func() {
+			defer wg.Done()
+			got = r.Start(ctx)
+		}()
+
+		// these chunks are buffered, and the oldest one is dropped from preroll.
+		audioCh <- []byte("s000")
+		audioCh <- []byte("s001")
+		// speech is detected and the preroll is sent to the remote core.
+		audioCh <- []byte("v002")
+		for _, want := range []string{"s001", "v002"} {
+			if g := string(<-remoteReceivedCh); g != want {
+				t.Errorf("remote received %q, want %q", g, want)
+			}
+		}
+		// these chunks are sent as hangover and then the gate is closed.
+		audioCh <- []byte("s003")
+		audioCh <- []byte("s004")
+		for _, want := range []string{"s003", "s004"} {
+			if g := string(<-remoteReceivedCh); g != want {
+				t.Errorf("remote received %q, want %q", g, want)
+			}
+		}
+		// this chunk is not sent because the gate is closed.
+		audioCh <- []byte("s005")
+
+		// the remote core is stopped after drain timeout.
+		<-remoteStoppedCh
+
+		cancel()
+		wg.Wait()
+
+		if !errors.Is(got, context.Canceled) {
+			t.Errorf("Recognizer.Start() error = %v, want %v", got, context.Canceled)
+		}
+		if len(remoteReceivedCh) != 0 {
+			t.Errorf("remote received %q, want nothing", <-remoteReceivedCh)
+		}
+		if remoteCalls != 1 {
+			t.Errorf("remote factory calls = %d, want 1", remoteCalls)
+		}
+		if g, w := len(resultCh), 1; g != w {
+			t.Fatalf("len(resultCh) = %d, want %d", g, w)
+		}
+		if diff := cmp.Diff(<-resultCh, []*model.Result{{Transcript: "v002", IsFinal: false, Provisional: true}}); diff != "" {
+			t.Errorf("results (-got +want):\n%s", diff)
+		}
+	})
+}
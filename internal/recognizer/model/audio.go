@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// The audio format is fixed to LINEAR16, 16000Hz, mono throughout the pipeline.
+const (
+	SampleRate     = 16000
+	BytesPerSample = 2
+	BytesPerSecond = SampleRate * BytesPerSample
+)
+
+// AudioDuration returns the playback duration of n bytes of audio.
+func AudioDuration(n int) time.Duration {
+	return time.Duration(n) * time.Second / BytesPerSecond
+}
+
+// AudioBytes returns the number of bytes of audio played in d.
+// The result is rounded down to a sample boundary.
+func AudioBytes(d time.Duration) int {
+	n := int(d * BytesPerSecond / time.Second)
+	return n - n%BytesPerSample
+}
@@ -0,0 +1,79 @@
+package model
+
+import "context"
+
+// Observe wraps the factory so that observe is called with the results of the
+// core built by it before they are passed on.
+func Observe(newCore RecognizerCoreFactory, observe func(results []*Result)) RecognizerCoreFactory {
+	return Transform(newCore, func(results []*Result) []*Result {
+		observe(results)
+		return results
+	})
+}
+
+// Transform wraps the factory so that the results of the core built by it are
+// replaced with the ones returned by transform before they are passed on.
+// Nothing is passed if transform returns no result. The results which the
+// core sends on stop are passed as well. See KeepReceiving.
+func Transform(newCore RecognizerCoreFactory, transform func(results []*Result) []*Result) RecognizerCoreFactory {
+	return func(
+		ctx context.Context,
+		audioCh <-chan []byte,
+		resultCh chan<- []*Result,
+	) (RecognizerCoreInterface, error) {
+		innerResultCh := make(chan []*Result, 1)
+		core, err := newCore(ctx, audioCh, innerResultCh)
+		if err != nil {
+			return nil, err
+		}
+
+		return &transformingCore{
+			core:          core,
+			transform:     transform,
+			resultCh:      resultCh,
+			innerResultCh: innerResultCh,
+		}, nil
+	}
+}
+
+var _ RecognizerCoreInterface = (*transformingCore)(nil)
+
+type transformingCore struct {
+	core      RecognizerCoreInterface
+	transform func(results []*Result) []*Result
+
+	resultCh      chan<- []*Result
+	innerResultCh chan []*Result
+}
+
+func (c *transformingCore) Start(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.core.Start(KeepReceiving(ctx, c.innerResultCh))
+	}()
+
+	for {
+		select {
+		case results := <-c.innerResultCh:
+			c.pass(ctx, results)
+		case err := <-errCh:
+			// the results sent before the core stopped are left in the buffer.
+			for {
+				select {
+				case results := <-c.innerResultCh:
+					c.pass(ctx, results)
+				default:
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (c *transformingCore) pass(ctx context.Context, results []*Result) {
+	transformed := c.transform(results)
+	if len(transformed) == 0 {
+		return
+	}
+	Send(ctx, c.resultCh, transformed)
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTransform(t *testing.T) {
+	t.Run("results sent on stop", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// the inner core sends the result when it stops.
+		newCore := func(
+			_ context.Context,
+			_ <-chan []byte,
+			resultCh chan<- []*Result,
+		) (RecognizerCoreInterface, error) {
+			return &RecognizerCoreInterfaceMock{
+				StartFunc: func(ctx context.Context) error {
+					<-ctx.Done()
+					Send(ctx, resultCh, []*Result{{Transcript: "a", IsFinal: true}})
+					return ctx.Err()
+				},
+			}, nil
+		}
+		transform := func(results []*Result) []*Result {
+			return append(results, &Result{Transcript: "b"})
+		}
+
+		resultCh := make(chan []*Result)
+		core, err := Transform(newCore, transform)(ctx, make(chan []byte), resultCh)
+		if err != nil {
+			t.Fatalf("Transform() error = %v", err)
+		}
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- core.Start(KeepReceiving(ctx, resultCh))
+		}()
+
+		cancel()
+		want := []*Result{{Transcript: "a", IsFinal: true}, {Transcript: "b"}}
+		if diff := cmp.Diff(<-resultCh, want); diff != "" {
+			t.Errorf("results (-got +want):\n%s", diff)
+		}
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("Start() error = %v, want %v", err, context.Canceled)
+		}
+	})
+}
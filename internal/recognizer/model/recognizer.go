@@ -6,3 +6,45 @@ import "context"
 type RecognizerCoreInterface interface {
 	Start(ctx context.Context) error
 }
+
+// RecognizerCoreFactory builds a recognizer core which reads audio from audioCh
+// and writes results to resultCh.
+// It is used by the cores that run other cores internally.
+type RecognizerCoreFactory func(
+	ctx context.Context,
+	audioCh <-chan []byte,
+	resultCh chan<- []*Result,
+) (RecognizerCoreInterface, error)
+
+// RecognizerCoreFactoryBuilder builds a recognizer core factory with its own
+// state, such as a Vosk recognizer or a Google meter, so that the state is not
+// shared with the factories built by other calls.
+// cleanup must be called after the cores built by the factory stop.
+type RecognizerCoreFactoryBuilder func() (newCore RecognizerCoreFactory, cleanup func(), err error)
+
+type keepReceivingKey struct{}
+
+// KeepReceiving returns the context for Start of the core sending to resultCh,
+// telling that the receiver keeps receiving from resultCh until Start returns
+// even after ctx is done, so that the core can pass the results it holds on
+// stop. See Send.
+func KeepReceiving(ctx context.Context, resultCh chan<- []*Result) context.Context {
+	return context.WithValue(ctx, keepReceivingKey{}, resultCh)
+}
+
+// Send sends the results to resultCh. Once ctx is done, it waits for them to
+// be received only if the receiver keeps receiving (see KeepReceiving), and
+// otherwise returns false without sending them.
+func Send(ctx context.Context, resultCh chan<- []*Result, results []*Result) bool {
+	select {
+	case resultCh <- results:
+		return true
+	case <-ctx.Done():
+	}
+
+	if ch, ok := ctx.Value(keepReceivingKey{}).(chan<- []*Result); !ok || ch != resultCh {
+		return false
+	}
+	resultCh <- results
+	return true
+}
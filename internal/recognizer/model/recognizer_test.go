@@ -0,0 +1,42 @@
+package model
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSend(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		resultCh := make(chan []*Result, 1)
+		if !Send(context.Background(), resultCh, []*Result{{Transcript: "a"}}) {
+			t.Error("Send() = false, want true")
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		resultCh := make(chan []*Result)
+		if Send(ctx, resultCh, []*Result{{Transcript: "a"}}) {
+			t.Error("Send() = true, want false")
+		}
+		// the receiver of another channel does not keep receiving from resultCh.
+		if Send(KeepReceiving(ctx, make(chan []*Result)), resultCh, []*Result{{Transcript: "a"}}) {
+			t.Error("Send() = true, want false")
+		}
+	})
+
+	t.Run("stopped and kept receiving", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		resultCh := make(chan []*Result)
+		go func() {
+			<-resultCh
+		}()
+		if !Send(KeepReceiving(ctx, resultCh), resultCh, []*Result{{Transcript: "a"}}) {
+			t.Error("Send() = false, want true")
+		}
+	})
+}
@@ -1,6 +1,43 @@
 package model
 
+import "time"
+
 type Result struct {
 	Transcript string
 	IsFinal    bool
+	// Backend is the name of the backend which produced the result.
+	// It is set only when the backend can change during the session.
+	Backend string
+	// End is the offset of the end of the result from the beginning of the
+	// audio the core received. It is zero if the backend does not tell it.
+	End time.Duration
+	// Speaker is the name of the speaker of the result, e.g. "Speaker 1". It is
+	// empty if the backend does not tell it.
+	Speaker string
+	// Provisional tells that the interim result is a guess of another backend
+	// than the one of the final results, e.g. the local core of the hybrid
+	// backend. It is shown, but not written as a final result on the shutdown.
+	Provisional bool
+}
+
+// Split returns the final results and the interim result after the last of
+// them, into which the interim results are concatenated as they are shown.
+// The interim result is nil if there is none or it is empty. It has the
+// backend of the last interim result, and is provisional if any of them is.
+func Split(results []*Result) (finals []*Result, interim *Result) {
+	var last Result
+	for _, result := range results {
+		if result.IsFinal {
+			finals = append(finals, result)
+			last = Result{}
+			continue
+		}
+		last.Transcript += result.Transcript
+		last.Backend = result.Backend
+		last.Provisional = last.Provisional || result.Provisional
+	}
+	if last.Transcript == "" {
+		return finals, nil
+	}
+	return finals, &last
 }
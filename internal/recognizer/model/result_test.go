@@ -0,0 +1,50 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name        string
+		results     []*Result
+		wantFinals  []*Result
+		wantInterim *Result
+	}{
+		{
+			name: "interim after final",
+			results: []*Result{
+				{Transcript: "a"},
+				{Transcript: "b", IsFinal: true},
+				{Transcript: "c", Backend: "google"},
+				{Transcript: "d", Backend: "vosk", Provisional: true},
+			},
+			wantFinals: []*Result{
+				{Transcript: "b", IsFinal: true},
+			},
+			wantInterim: &Result{Transcript: "cd", Backend: "vosk", Provisional: true},
+		},
+		{
+			name:       "final only",
+			results:    []*Result{{Transcript: "a"}, {Transcript: "b", IsFinal: true}},
+			wantFinals: []*Result{{Transcript: "b", IsFinal: true}},
+		},
+		{
+			name:    "empty interim",
+			results: []*Result{{Transcript: ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finals, interim := Split(tt.results)
+			if diff := cmp.Diff(finals, tt.wantFinals); diff != "" {
+				t.Errorf("finals (-got +want):\n%s", diff)
+			}
+			if diff := cmp.Diff(interim, tt.wantInterim); diff != "" {
+				t.Errorf("interim (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
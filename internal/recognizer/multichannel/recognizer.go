@@ -0,0 +1,339 @@
+package multichannel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+var _ model.RecognizerCoreInterface = (*Recognizer)(nil)
+
+// Recognizer recognizes the audio of interleaved channels, e.g. a stereo
+// recording of a host on the left and a guest on the right. The audio is split
+// into the channels, and each channel is recognized by its own core.
+//
+// The final results of the channels are merged in the order of their end, each
+// labeled with the speaker of the channel. A final result is held until the
+// other channels have nothing to say before it, i.e. they have finalized the
+// audio up to its end or have no interim result, or until the window of audio
+// has passed since its end. The final results held when the recognizer stops
+// are passed without waiting.
+type Recognizer struct {
+	names    []string
+	newCores []model.RecognizerCoreFactory
+	// window is the maximum bytes of audio per channel for which a final
+	// result is held.
+	window int
+
+	audioCh  <-chan []byte
+	resultCh chan<- []*model.Result
+}
+
+// Config is the config of the multichannel backend.
+type Config struct {
+	// Names are the speakers of the channels in the order of the channels.
+	Names []string
+	// NewCores are the factories of the cores of the channels. Each core
+	// receives the mono audio of its channel.
+	NewCores []model.RecognizerCoreFactory
+	Window   time.Duration
+}
+
+// New creates a recognizer from the config.
+func New(
+	_ context.Context,
+	config Config,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (model.RecognizerCoreInterface, error) {
+	recognizer, err := NewRecognizer(
+		config.Names,
+		config.NewCores,
+		audioCh,
+		resultCh,
+		config.Window,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return recognizer, nil
+}
+
+func NewRecognizer(
+	names []string,
+	newCores []model.RecognizerCoreFactory,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+	window time.Duration,
+) (*Recognizer, error) {
+	if len(newCores) < 2 {
+		return nil, errors.New("at least two channels must be specified")
+	}
+	if len(names) != len(newCores) {
+		return nil, errors.New("a speaker name must be specified for each channel")
+	}
+	for i, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("speaker name of channel %d must be specified", i+1)
+		}
+	}
+	for i, newCore := range newCores {
+		if newCore == nil {
+			return nil, fmt.Errorf("recognizer factory of channel %d must be specified", i+1)
+		}
+	}
+	if audioCh == nil {
+		return nil, errors.New("audio channel must be specified")
+	}
+	if resultCh == nil {
+		return nil, errors.New("result channel must be specified")
+	}
+	if window <= 0 {
+		return nil, errors.New("window must be positive")
+	}
+
+	return &Recognizer{
+		names:    names,
+		newCores: newCores,
+		window:   model.AudioBytes(window),
+		audioCh:  audioCh,
+		resultCh: resultCh,
+	}, nil
+}
+
+// channel is the running core of a channel.
+type channel struct {
+	name     string
+	audioCh  chan []byte
+	resultCh chan []*model.Result
+	// finalized is the bytes of audio finalized by the core.
+	finalized int
+	// interim is the current interim result of the core.
+	interim string
+}
+
+// held is a final result waiting for the other channels.
+type held struct {
+	result  *model.Result
+	channel int
+	// end is the bytes of audio per channel at the end of the result.
+	end int
+}
+
+func (r *Recognizer) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	errCh := make(chan error, len(r.newCores))
+	channels := make([]*channel, len(r.newCores))
+	for i, newCore := range r.newCores {
+		c := &channel{
+			name:     r.names[i],
+			audioCh:  make(chan []byte, 100),
+			resultCh: make(chan []*model.Result, 10),
+		}
+		core, err := newCore(ctx, c.audioCh, c.resultCh)
+		if err != nil {
+			return fmt.Errorf("failed to create recognizer of %s: %w", c.name, err)
+		}
+		channels[i] = c
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := core.Start(ctx)
+			if err == nil {
+				err = errors.New("recognizer stopped")
+			}
+			errCh <- fmt.Errorf("error occured in recognizer of %s: %w", c.name, err)
+		}()
+	}
+
+	// merged is the results of the channels labeled with the index.
+	type labeled struct {
+		channel int
+		results []*model.Result
+	}
+	merged := make(chan labeled)
+	for i, c := range channels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case results := <-c.resultCh:
+					select {
+					case <-ctx.Done():
+						return
+					case merged <- labeled{channel: i, results: results}:
+					}
+				}
+			}
+		}()
+	}
+
+	var (
+		// position is the bytes of audio per channel sent to the cores.
+		position int
+		// rest is the bytes of the incomplete frame left by the last audio.
+		rest    []byte
+		pending []held
+		// interim is the last interim result passed.
+		interim string
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.flush(ctx, channels, nil, pending)
+			return ctx.Err()
+		case err := <-errCh:
+			return err
+		case audio, ok := <-r.audioCh:
+			if !ok {
+				return errors.New("audio channel closed")
+			}
+
+			var split [][]byte
+			split, rest = deinterleave(append(rest, audio...), len(channels))
+			for i, c := range channels {
+				select {
+				case <-ctx.Done():
+					r.flush(ctx, channels, nil, pending)
+					return ctx.Err()
+				case c.audioCh <- split[i]:
+				}
+			}
+			position += len(split[0])
+		case l := <-merged:
+			c := channels[l.channel]
+			c.interim = ""
+			for _, result := range l.results {
+				if !result.IsFinal {
+					c.interim += result.Transcript
+					continue
+				}
+				end := position
+				if result.End > 0 {
+					end = model.AudioBytes(result.End)
+				}
+				c.finalized = max(c.finalized, end)
+
+				final := *result
+				final.Speaker = c.name
+				final.End = model.AudioDuration(end)
+				pending = append(pending, held{result: &final, channel: l.channel, end: end})
+			}
+			// results of the cores may arrive out of order.
+			sort.SliceStable(pending, func(i, j int) bool {
+				return pending[i].end < pending[j].end
+			})
+		}
+
+		var finals []*model.Result
+		finals, pending = r.release(channels, pending, position)
+		results := finals
+		// the interim result is passed only when it changes.
+		if i := r.interim(channels, pending); i != "" && (len(finals) > 0 || i != interim) {
+			results = append(results, &model.Result{Transcript: i})
+			interim = i
+		}
+		if len(results) == 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			r.flush(ctx, channels, finals, pending)
+			return ctx.Err()
+		case r.resultCh <- results:
+		}
+	}
+}
+
+// release returns the final results which the other channels have nothing to
+// say before, and the rest.
+func (r *Recognizer) release(channels []*channel, pending []held, position int) ([]*model.Result, []held) {
+	var results []*model.Result
+	for len(pending) > 0 {
+		h := pending[0]
+		if position-h.end < r.window {
+			waiting := false
+			for i, c := range channels {
+				if i != h.channel && c.interim != "" && c.finalized < h.end {
+					waiting = true
+					break
+				}
+			}
+			if waiting {
+				break
+			}
+		}
+		results = append(results, h.result)
+		pending = pending[1:]
+	}
+	return results, pending
+}
+
+// flush passes the final results and the held ones without waiting for the
+// other channels when the recognizer stops, so that they are written as final
+// results rather than as a part of the interim result flushed on shutdown.
+// They are lost if the receiver does not keep receiving. See model.Send.
+func (r *Recognizer) flush(ctx context.Context, channels []*channel, finals []*model.Result, pending []held) {
+	results := finals
+	for _, h := range pending {
+		results = append(results, h.result)
+	}
+	if len(results) == 0 {
+		return
+	}
+	if i := r.interim(channels, nil); i != "" {
+		results = append(results, &model.Result{Transcript: i})
+	}
+	model.Send(ctx, r.resultCh, results)
+}
+
+// interim returns the interim result showing the held final results and the
+// interim results of the channels, each prefixed with the speaker.
+func (r *Recognizer) interim(channels []*channel, pending []held) string {
+	var parts []string
+	for _, h := range pending {
+		parts = append(parts, h.result.Speaker+": "+h.result.Transcript)
+	}
+	for _, c := range channels {
+		if c.interim != "" {
+			parts = append(parts, c.name+": "+c.interim)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// deinterleave splits the interleaved audio of n channels into the audio of
+// each channel. The bytes of the incomplete frame at the end are returned as
+// the rest.
+func deinterleave(audio []byte, n int) (split [][]byte, rest []byte) {
+	frame := n * model.BytesPerSample
+	frames := len(audio) / frame
+	split = make([][]byte, n)
+	for i := range split {
+		split[i] = make([]byte, 0, frames*model.BytesPerSample)
+	}
+	for f := range frames {
+		for i := range split {
+			offset := f*frame + i*model.BytesPerSample
+			split[i] = append(split[i], audio[offset:offset+model.BytesPerSample]...)
+		}
+	}
+	return split, append([]byte(nil), audio[frames*frame:]...)
+}
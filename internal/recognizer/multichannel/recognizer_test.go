@@ -0,0 +1,449 @@
+package multichannel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/recognizer"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+// writerFunc is an io.Writer of the function.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestNewRecognizer(t *testing.T) {
+	factory := func(
+		context.Context,
+		<-chan []byte,
+		chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		return &model.RecognizerCoreInterfaceMock{}, nil
+	}
+
+	type args struct {
+		names    []string
+		newCores []model.RecognizerCoreFactory
+		audioCh  <-chan []byte
+		resultCh chan<- []*model.Result
+		window   time.Duration
+	}
+	baseArgs := args{
+		names:    []string{"Host", "Guest"},
+		newCores: []model.RecognizerCoreFactory{factory, factory},
+		audioCh:  make(chan []byte),
+		resultCh: make(chan []*model.Result),
+		window:   5 * time.Second,
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "success",
+			args: baseArgs,
+		},
+		{
+			name: "single channel",
+			args: func() args {
+				a := baseArgs
+				a.names = []string{"Host"}
+				a.newCores = []model.RecognizerCoreFactory{factory}
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "missing name",
+			args: func() args {
+				a := baseArgs
+				a.names = []string{"Host"}
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "empty name",
+			args: func() args {
+				a := baseArgs
+				a.names = []string{"Host", ""}
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "nil factory",
+			args: func() args {
+				a := baseArgs
+				a.newCores = []model.RecognizerCoreFactory{factory, nil}
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "nil audio channel",
+			args: func() args {
+				a := baseArgs
+				a.audioCh = nil
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "nil result channel",
+			args: func() args {
+				a := baseArgs
+				a.resultCh = nil
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "zero window",
+			args: func() args {
+				a := baseArgs
+				a.window = 0
+				return a
+			}(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRecognizer(
+				tt.args.names,
+				tt.args.newCores,
+				tt.args.audioCh,
+				tt.args.resultCh,
+				tt.args.window,
+			)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRecognizer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got == nil {
+				t.Errorf("NewRecognizer() = nil, want non-nil")
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	factory := func(
+		context.Context,
+		<-chan []byte,
+		chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		return &model.RecognizerCoreInterfaceMock{}, nil
+	}
+
+	t.Run("success", func(t *testing.T) {
+		got, err := New(
+			context.Background(),
+			Config{
+				Names:    []string{"Host", "Guest"},
+				NewCores: []model.RecognizerCoreFactory{factory, factory},
+				Window:   5 * time.Second,
+			},
+			make(chan []byte),
+			make(chan []*model.Result),
+		)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if got == nil {
+			t.Error("New() = nil, want non-nil")
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		got, err := New(context.Background(), Config{}, make(chan []byte), make(chan []*model.Result))
+		if err == nil {
+			t.Error("New() error = nil, want an error")
+		}
+		if got != nil {
+			t.Errorf("New() = %v, want nil", got)
+		}
+	})
+}
+
+func TestDeinterleave(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		split, rest := deinterleave([]byte("L1R1L2R2L3"), 2)
+
+		want := [][]byte{[]byte("L1L2"), []byte("R1R2")}
+		if diff := cmp.Diff(split, want); diff != "" {
+			t.Errorf("deinterleave() split (-got +want):\n%s", diff)
+		}
+		if string(rest) != "L3" {
+			t.Errorf("deinterleave() rest = %q, want %q", rest, "L3")
+		}
+	})
+}
+
+func TestRecognizer_Start(t *testing.T) {
+	t.Run("merge in order", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		audioCh := make(chan []byte)
+		resultCh := make(chan []*model.Result)
+		host := testutil.NewScriptedCore()
+		guest := testutil.NewScriptedCore()
+		r := &Recognizer{
+			names:    []string{"Host", "Guest"},
+			newCores: []model.RecognizerCoreFactory{host.Factory, guest.Factory},
+			window:   model.AudioBytes(time.Second),
+			audioCh:  audioCh,
+			resultCh: resultCh,
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var got error
+		go func() {
+			defer wg.Done()
+			got = r.Start(ctx)
+		}()
+
+		assertReceived := func(c *testutil.ScriptedCore, want string) {
+			t.Helper()
+			if g := string(<-c.ReceivedCh); g != want {
+				t.Errorf("core received %q, want %q", g, want)
+			}
+		}
+		assertResult := func(want []*model.Result) {
+			t.Helper()
+			if diff := cmp.Diff(<-resultCh, want); diff != "" {
+				t.Errorf("result (-got +want):\n%s", diff)
+			}
+		}
+
+		// the incomplete frame is sent with the next audio.
+		audioCh <- []byte("L1R1L")
+		assertReceived(host, "L1")
+		assertReceived(guest, "R1")
+		audioCh <- []byte("2R2")
+		assertReceived(host, "L2")
+		assertReceived(guest, "R2")
+
+		guest.ResultCh <- []*model.Result{{Transcript: "g"}}
+		assertResult([]*model.Result{{Transcript: "Guest: g"}})
+
+		// the final result of the host is held while the guest is speaking before it.
+		host.ResultCh <- []*model.Result{{Transcript: "h", IsFinal: true, End: 3 * time.Second}}
+		assertResult([]*model.Result{{Transcript: "Host: h Guest: g"}})
+
+		// the guest finalizes before the host.
+		guest.ResultCh <- []*model.Result{{Transcript: "G", IsFinal: true, End: 2 * time.Second}}
+		assertResult([]*model.Result{
+			{Transcript: "G", IsFinal: true, End: 2 * time.Second, Speaker: "Guest"},
+			{Transcript: "h", IsFinal: true, End: 3 * time.Second, Speaker: "Host"},
+		})
+
+		// the final result is passed at once if the other channel is silent.
+		host.ResultCh <- []*model.Result{{Transcript: "H", IsFinal: true, End: 4 * time.Second}}
+		assertResult([]*model.Result{{Transcript: "H", IsFinal: true, End: 4 * time.Second, Speaker: "Host"}})
+
+		cancel()
+		wg.Wait()
+
+		if !errors.Is(got, context.Canceled) {
+			t.Errorf("Recognizer.Start() error = %v, want %v", got, context.Canceled)
+		}
+	})
+
+	t.Run("window passed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		audioCh := make(chan []byte)
+		resultCh := make(chan []*model.Result)
+		host := testutil.NewScriptedCore()
+		guest := testutil.NewScriptedCore()
+		r := &Recognizer{
+			names:    []string{"Host", "Guest"},
+			newCores: []model.RecognizerCoreFactory{host.Factory, guest.Factory},
+			window:   4,
+			audioCh:  audioCh,
+			resultCh: resultCh,
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = r.Start(ctx)
+		}()
+
+		audioCh <- []byte("L1R1")
+		<-host.ReceivedCh
+		<-guest.ReceivedCh
+		guest.ResultCh <- []*model.Result{{Transcript: "g"}}
+		<-resultCh
+		// the end is the audio sent so far if the core does not tell it.
+		host.ResultCh <- []*model.Result{{Transcript: "h", IsFinal: true}}
+		<-resultCh
+
+		audioCh <- []byte("L2R2L3R3")
+		<-host.ReceivedCh
+		<-guest.ReceivedCh
+		want := []*model.Result{
+			{Transcript: "h", IsFinal: true, End: model.AudioDuration(2), Speaker: "Host"},
+			{Transcript: "Guest: g"},
+		}
+		if diff := cmp.Diff(<-resultCh, want); diff != "" {
+			t.Errorf("result (-got +want):\n%s", diff)
+		}
+
+		cancel()
+		wg.Wait()
+	})
+
+	t.Run("held on stop", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		resultCh := make(chan []*model.Result)
+		host := testutil.NewScriptedCore()
+		guest := testutil.NewScriptedCore()
+		r := &Recognizer{
+			names:    []string{"Host", "Guest"},
+			newCores: []model.RecognizerCoreFactory{host.Factory, guest.Factory},
+			window:   model.AudioBytes(time.Second),
+			audioCh:  make(chan []byte),
+			resultCh: resultCh,
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// the held final result is passed on stop since the results are
+			// received until the recognizer stops.
+			_ = r.Start(model.KeepReceiving(ctx, resultCh))
+		}()
+
+		guest.ResultCh <- []*model.Result{{Transcript: "g"}}
+		<-resultCh
+		host.ResultCh <- []*model.Result{{Transcript: "h", IsFinal: true, End: time.Second}}
+		if diff := cmp.Diff(<-resultCh, []*model.Result{{Transcript: "Host: h Guest: g"}}); diff != "" {
+			t.Errorf("result (-got +want):\n%s", diff)
+		}
+
+		cancel()
+		want := []*model.Result{
+			{Transcript: "h", IsFinal: true, End: time.Second, Speaker: "Host"},
+			{Transcript: "Guest: g"},
+		}
+		if diff := cmp.Diff(<-resultCh, want); diff != "" {
+			t.Errorf("result (-got +want):\n%s", diff)
+		}
+		wg.Wait()
+	})
+
+	t.Run("held on stop of pipeline", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		host := testutil.NewScriptedCore()
+		guest := testutil.NewScriptedCore()
+		newCore := func(
+			_ context.Context,
+			audioCh <-chan []byte,
+			resultCh chan<- []*model.Result,
+		) (model.RecognizerCoreInterface, error) {
+			return &Recognizer{
+				names:    []string{"Host", "Guest"},
+				newCores: []model.RecognizerCoreFactory{host.Factory, guest.Factory},
+				window:   model.AudioBytes(time.Second),
+				audioCh:  audioCh,
+				resultCh: resultCh,
+			}, nil
+		}
+
+		// heldCh is closed when the held final result is shown as the interim result.
+		heldCh := make(chan struct{})
+		var once sync.Once
+		interimWriter := writerFunc(func(p []byte) (int, error) {
+			if bytes.Contains(p, []byte("Host: h")) {
+				once.Do(func() { close(heldCh) })
+			}
+			return len(p), nil
+		})
+		resultWriter := &bytes.Buffer{}
+		reader := testutil.NewChannelReader()
+		// the results pass through a wrapper as the ones of the app do.
+		pipeline, err := recognizer.NewPipeline(ctx, model.Observe(newCore, func([]*model.Result) {}), recognizer.PipelineConfig{
+			BufferSize:      1024,
+			InactiveTimeout: time.Minute,
+			AudioReader:     reader,
+			ResultWriter:    resultWriter,
+			InterimWriter:   interimWriter,
+		})
+		if err != nil {
+			t.Fatalf("NewPipeline() error = %v", err)
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- pipeline.Start(ctx)
+		}()
+
+		guest.ResultCh <- []*model.Result{{Transcript: "g"}}
+		host.ResultCh <- []*model.Result{{Transcript: "h", IsFinal: true, End: time.Second}}
+		<-heldCh
+
+		cancel()
+		// the audio reader is blocked until the reader ends.
+		close(reader.EOFCh)
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("Start() error = %v, want %v", err, context.Canceled)
+		}
+		// the held final result is written as a final result before the
+		// interim result flushed on shutdown.
+		if diff := cmp.Diff(resultWriter.String(), "\nHost: h\nGuest: g"); diff != "" {
+			t.Errorf("written results (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("channel fails", func(t *testing.T) {
+		host := testutil.NewScriptedCore()
+		guest := testutil.NewScriptedCore()
+		r := &Recognizer{
+			names:    []string{"Host", "Guest"},
+			newCores: []model.RecognizerCoreFactory{host.Factory, guest.Factory},
+			window:   4,
+			audioCh:  make(chan []byte),
+			resultCh: make(chan []*model.Result),
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var got error
+		go func() {
+			defer wg.Done()
+			got = r.Start(context.Background())
+		}()
+
+		guest.ErrCh <- errors.New("broken")
+		wg.Wait()
+
+		if got == nil || errors.Is(got, context.Canceled) {
+			t.Errorf("Recognizer.Start() error = %v, want an error", got)
+		}
+	})
+}
@@ -0,0 +1,313 @@
+package recognizer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/audio"
+	"github.com/hekt/voice-recognition/internal/fakespeech"
+	"github.com/hekt/voice-recognition/internal/file"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the pipeline tests")
+
+// TestPipeline runs the whole pipeline from the audio to the output file with a
+// scripted backend, and compares the output file and the interim stream with
+// the golden files in testdata/pipeline. Run with -update to regenerate them.
+func TestPipeline(t *testing.T) {
+	tests := []pipelineCase{
+		{
+			name:       "continuous",
+			audio:      "testdata/pipeline/silence.wav",
+			script:     "testdata/pipeline/script.jsonl",
+			bufferSize: 4096,
+		},
+		{
+			name:              "reconnect",
+			audio:             "testdata/pipeline/silence.wav",
+			script:            "testdata/pipeline/script.jsonl",
+			bufferSize:        4096,
+			reconnectInterval: time.Second,
+		},
+		{
+			name:             "inactive",
+			audio:            "testdata/pipeline/silence.wav",
+			script:           "testdata/pipeline/script.jsonl",
+			bufferSize:       4096,
+			stopByInactivity: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, interim := runPipeline(t, tt)
+
+			assertGolden(t, filepath.Join("testdata/pipeline", tt.name+".output.golden"), output)
+			assertGolden(t, filepath.Join("testdata/pipeline", tt.name+".interim.golden"), interim)
+		})
+	}
+}
+
+type pipelineCase struct {
+	name string
+	// audio is the WAV file fed to the pipeline.
+	audio string
+	// script is the script played by the backend. See fakespeech.ParseScript.
+	script     string
+	bufferSize int
+	// reconnectInterval is the interval of the clock to reconnect the backend.
+	// 0 means no reconnection.
+	reconnectInterval time.Duration
+	// stopByInactivity stops the pipeline by the inactive timeout instead of cancellation.
+	stopByInactivity bool
+}
+
+// inactiveTimeout is the inactive timeout of the pipeline measured by the fake clock.
+const inactiveTimeout = time.Minute
+
+// runPipeline feeds the audio to the pipeline chunk by chunk and stops it by
+// cancellation or inactivity after the audio ends. The clock advances by the
+// duration of each chunk, and the next chunk is fed after the results of the
+// chunk are written so that the outputs are deterministic.
+// It returns the content of the output file and the interim writes quoted line by line.
+func runPipeline(t *testing.T, tc pipelineCase) (output []byte, interim []byte) {
+	t.Helper()
+
+	wav, err := os.ReadFile(tc.audio)
+	if err != nil {
+		t.Fatalf("failed to read audio: %v", err)
+	}
+	pcm, err := audio.DecodeWAV(wav)
+	if err != nil {
+		t.Fatalf("failed to decode audio: %v", err)
+	}
+	script, err := fakespeech.LoadScript(tc.script)
+	if err != nil {
+		t.Fatalf("failed to load script: %v", err)
+	}
+
+	clock := testutil.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	ackCh := make(chan int)
+	newCore := func(
+		_ context.Context,
+		audioCh <-chan []byte,
+		resultCh chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		return &scriptedCore{
+			audioCh:           audioCh,
+			resultCh:          resultCh,
+			clock:             clock,
+			reconnectInterval: tc.reconnectInterval,
+			player:            fakespeech.NewPlayer(script),
+			ackCh:             ackCh,
+		}, nil
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "output.txt")
+	outputFile, err := file.OpenFileWriter(outputPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("failed to open output: %v", err)
+	}
+	defer outputFile.Close()
+	writtenCh := make(chan struct{}, 1024)
+	interimBuf := &bytes.Buffer{}
+	reader := testutil.NewChannelReader()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, err := NewPipeline(ctx, newCore, PipelineConfig{
+		BufferSize:      tc.bufferSize,
+		InactiveTimeout: inactiveTimeout,
+		Clock:           clock,
+		AudioReader:     reader,
+		ResultWriter: &countingWriter{
+			Writer:    outputFile,
+			WrittenCh: writtenCh,
+		},
+		InterimWriter: &countingWriter{
+			Writer:    &quotingWriter{Writer: interimBuf},
+			WrittenCh: writtenCh,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPipeline() error = %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- r.Start(ctx)
+	}()
+
+	for offset := 0; offset < len(pcm); offset += tc.bufferSize {
+		chunk := pcm[offset:min(offset+tc.bufferSize, len(pcm))]
+		clock.Advance(model.AudioDuration(len(chunk)))
+		reader.BufCh <- chunk
+
+		n := receive(t, ackCh)
+		for range n {
+			receive(t, writtenCh)
+		}
+	}
+	close(reader.EOFCh)
+
+	if tc.stopByInactivity {
+		// the monitor may reset the timer by the last write after the clock
+		// advances, so advance until it stops.
+		timeout := time.After(5 * time.Second)
+	loop:
+		for {
+			clock.Advance(inactiveTimeout)
+			select {
+			case err := <-errCh:
+				if err == nil || errors.Is(err, context.Canceled) {
+					t.Fatalf("Start() error = %v, want inactive error", err)
+				}
+				break loop
+			case <-time.After(10 * time.Millisecond):
+			case <-timeout:
+				t.Fatal("pipeline did not stop")
+			}
+		}
+	} else {
+		// stop as ctrl-c does, which writes the pending interim result to the output file.
+		cancel()
+		if err := receive(t, errCh); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Start() error = %v, want context canceled", err)
+		}
+	}
+
+	output, err = os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	return output, interimBuf.Bytes()
+}
+
+// receive receives a value from ch, or fails if the pipeline is stuck.
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the pipeline")
+		var zero T
+		return zero
+	}
+}
+
+// assertGolden compares got with the golden file, or updates the file with -update.
+func assertGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file, run with -update to create it: %v", err)
+	}
+	if diff := cmp.Diff(string(got), string(want)); diff != "" {
+		t.Errorf("%s mismatch (-got +want):\n%s", path, diff)
+	}
+}
+
+var _ model.RecognizerCoreInterface = (*scriptedCore)(nil)
+
+// scriptedCore plays the script along the audio as the Google backend does.
+// When reconnectInterval of the clock has passed, it reconnects before
+// recognizing the next audio, which finalizes the utterance heard so far.
+// It sends each result separately and tells the number of the writes of them
+// to ackCh for each audio chunk.
+type scriptedCore struct {
+	audioCh           <-chan []byte
+	resultCh          chan<- []*model.Result
+	clock             *testutil.FakeClock
+	reconnectInterval time.Duration
+	player            *fakespeech.Player
+	ackCh             chan<- int
+}
+
+func (c *scriptedCore) Start(ctx context.Context) error {
+	connectedAt := c.clock.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case audio, ok := <-c.audioCh:
+			if !ok {
+				return errors.New("audio channel is closed")
+			}
+
+			var events []fakespeech.Event
+			if c.reconnectInterval > 0 && c.clock.Now().Sub(connectedAt) >= c.reconnectInterval {
+				events = append(events, c.player.Finalize()...)
+				connectedAt = c.clock.Now()
+			}
+			events = append(events, c.player.Advance(model.AudioDuration(len(audio)))...)
+
+			for _, e := range events {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case c.resultCh <- []*model.Result{{Transcript: e.Text, IsFinal: e.IsFinal}}:
+				}
+			}
+
+			// the final results are written to both the output file and the terminal.
+			writes := len(events)
+			for _, e := range events {
+				if e.IsFinal {
+					writes++
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case c.ackCh <- writes:
+			}
+		}
+	}
+}
+
+// countingWriter notifies each write to WrittenCh.
+type countingWriter struct {
+	Writer    io.Writer
+	WrittenCh chan<- struct{}
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	defer func() {
+		w.WrittenCh <- struct{}{}
+	}()
+	return w.Writer.Write(p)
+}
+
+// quotingWriter writes each write as a quoted line to keep the escape sequences readable.
+type quotingWriter struct {
+	Writer io.Writer
+}
+
+func (w *quotingWriter) Write(p []byte) (int, error) {
+	if _, err := fmt.Fprintf(w.Writer, "%q\n", p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -3,12 +3,17 @@ package recognizer
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"time"
+
+	"github.com/hekt/voice-recognition/internal/clock"
 )
 
 //go:generate moq -rm -out process_monitor_mock.go . ProcessMonitorInterface
 type ProcessMonitorInterface interface {
 	Start(context.Context) error
+	// Deadline returns the time when the monitor times out. It is zero before Start.
+	Deadline() time.Time
 }
 
 var _ ProcessMonitorInterface = &ProcessMonitor{}
@@ -16,20 +21,27 @@ var _ ProcessMonitorInterface = &ProcessMonitor{}
 type ProcessMonitor struct {
 	processCh       <-chan struct{}
 	timeoutDuration time.Duration
+	clock           clock.Clock
+
+	// deadline is the unix time in nanoseconds when the monitor times out.
+	deadline atomic.Int64
 }
 
 func NewProcessMonitor(
 	processCh <-chan struct{},
 	timeoutDuration time.Duration,
+	clock clock.Clock,
 ) *ProcessMonitor {
 	return &ProcessMonitor{
 		processCh:       processCh,
 		timeoutDuration: timeoutDuration,
+		clock:           clock,
 	}
 }
 
 func (m *ProcessMonitor) Start(ctx context.Context) error {
-	timer := time.NewTimer(m.timeoutDuration)
+	m.extend()
+	timer := m.clock.NewTimer(m.timeoutDuration)
 	defer timer.Stop()
 
 	for {
@@ -37,12 +49,22 @@ func (m *ProcessMonitor) Start(ctx context.Context) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-m.processCh:
-			if !timer.Stop() {
-				<-timer.C
-			}
 			timer.Reset(m.timeoutDuration)
-		case <-timer.C:
+			m.extend()
+		case <-timer.C():
 			return errors.New("inactive for a long time")
 		}
 	}
 }
+
+func (m *ProcessMonitor) Deadline() time.Time {
+	deadline := m.deadline.Load()
+	if deadline == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, deadline)
+}
+
+func (m *ProcessMonitor) extend() {
+	m.deadline.Store(m.clock.Now().Add(m.timeoutDuration).UnixNano())
+}
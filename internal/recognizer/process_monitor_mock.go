@@ -6,6 +6,7 @@ package recognizer
 import (
 	"context"
 	"sync"
+	"time"
 )
 
 // Ensure, that ProcessMonitorInterfaceMock does implement ProcessMonitorInterface.
@@ -18,6 +19,9 @@ var _ ProcessMonitorInterface = &ProcessMonitorInterfaceMock{}
 //
 //		// make and configure a mocked ProcessMonitorInterface
 //		mockedProcessMonitorInterface := &ProcessMonitorInterfaceMock{
+//			DeadlineFunc: func() time.Time {
+//				panic("mock out the Deadline method")
+//			},
 //			StartFunc: func(contextMoqParam context.Context) error {
 //				panic("mock out the Start method")
 //			},
@@ -28,18 +32,52 @@ var _ ProcessMonitorInterface = &ProcessMonitorInterfaceMock{}
 //
 //	}
 type ProcessMonitorInterfaceMock struct {
+	// DeadlineFunc mocks the Deadline method.
+	DeadlineFunc func() time.Time
+
 	// StartFunc mocks the Start method.
 	StartFunc func(contextMoqParam context.Context) error
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// Deadline holds details about calls to the Deadline method.
+		Deadline []struct {
+		}
 		// Start holds details about calls to the Start method.
 		Start []struct {
 			// ContextMoqParam is the contextMoqParam argument value.
 			ContextMoqParam context.Context
 		}
 	}
-	lockStart sync.RWMutex
+	lockDeadline sync.RWMutex
+	lockStart    sync.RWMutex
+}
+
+// Deadline calls DeadlineFunc.
+func (mock *ProcessMonitorInterfaceMock) Deadline() time.Time {
+	if mock.DeadlineFunc == nil {
+		panic("ProcessMonitorInterfaceMock.DeadlineFunc: method is nil but ProcessMonitorInterface.Deadline was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockDeadline.Lock()
+	mock.calls.Deadline = append(mock.calls.Deadline, callInfo)
+	mock.lockDeadline.Unlock()
+	return mock.DeadlineFunc()
+}
+
+// DeadlineCalls gets all the calls that were made to Deadline.
+// Check the length with:
+//
+//	len(mockedProcessMonitorInterface.DeadlineCalls())
+func (mock *ProcessMonitorInterfaceMock) DeadlineCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockDeadline.RLock()
+	calls = mock.calls.Deadline
+	mock.lockDeadline.RUnlock()
+	return calls
 }
 
 // Start calls StartFunc.
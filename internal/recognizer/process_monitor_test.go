@@ -4,61 +4,73 @@ import (
 	"context"
 	"errors"
 	"reflect"
-	"sync"
 	"testing"
 	"time"
+
+	"github.com/hekt/voice-recognition/internal/testutil"
 )
 
 func TestNewProcessMonitor(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		processCh := make(chan struct{})
 		timeoutDuration := 1 * time.Second
+		clock := testutil.NewFakeClock(time.Now())
 		want := &ProcessMonitor{
 			processCh:       processCh,
 			timeoutDuration: timeoutDuration,
+			clock:           clock,
 		}
 
-		if got := NewProcessMonitor(processCh, timeoutDuration); !reflect.DeepEqual(got, want) {
+		if got := NewProcessMonitor(processCh, timeoutDuration, clock); !reflect.DeepEqual(got, want) {
 			t.Errorf("NewProcessMonitor() = %v, want %v", got, want)
 		}
 	})
 }
 
 func TestProcessMonitor_Start(t *testing.T) {
+	wantMsg := "inactive for a long time"
+
 	t.Run("timeout", func(t *testing.T) {
 		processCh := make(chan struct{})
-		timeoutDuration := time.Microsecond
-		m := NewProcessMonitor(processCh, timeoutDuration)
+		clock := testutil.NewFakeClock(time.Now())
+		m := NewProcessMonitor(processCh, 10*time.Second, clock)
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		wantMsg := "inactive for a long time"
-		if got := m.Start(ctx); got != nil && got.Error() != wantMsg {
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Start(ctx)
+		}()
+
+		clock.BlockUntil(1)
+		if n := clock.Advance(9 * time.Second); n != 0 {
+			t.Errorf("timer fired before the timeout")
+		}
+		if n := clock.Advance(time.Second); n != 1 {
+			t.Errorf("timer did not fire at the timeout")
+		}
+
+		if got := <-errCh; got == nil || got.Error() != wantMsg {
 			t.Errorf("ProcessMonitor.Start() = %v, want %v", got, wantMsg)
 		}
 	})
 
 	t.Run("canceled by others", func(t *testing.T) {
 		processCh := make(chan struct{})
-		timeoutDuration := time.Hour
-		m := NewProcessMonitor(processCh, timeoutDuration)
+		m := NewProcessMonitor(processCh, time.Hour, testutil.NewFakeClock(time.Now()))
 
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		var wg sync.WaitGroup
-		wg.Add(1)
-		var got error
+		errCh := make(chan error, 1)
 		go func() {
-			defer wg.Done()
-			got = m.Start(ctx)
+			errCh <- m.Start(ctx)
 		}()
 
 		cancel()
-		wg.Wait()
 
-		if !errors.Is(got, context.Canceled) {
+		if got := <-errCh; !errors.Is(got, context.Canceled) {
 			t.Errorf("ProcessMonitor.Start() = %v, want %v", got, context.Canceled)
 		}
 	})
@@ -68,50 +80,41 @@ func TestProcessMonitor_Start(t *testing.T) {
 		defer cancel()
 
 		processCh := make(chan struct{})
-		m := &ProcessMonitor{
-			processCh:       processCh,
-			timeoutDuration: 100 * time.Millisecond,
-		}
+		start := time.Now()
+		clock := testutil.NewFakeClock(start)
+		m := NewProcessMonitor(processCh, 10*time.Second, clock)
 
-		var wg sync.WaitGroup
-		wg.Add(1)
-		var got error
-		go func() {
-			defer wg.Done()
-			got = m.Start(ctx)
-		}()
+		if got := m.Deadline(); !got.IsZero() {
+			t.Errorf("ProcessMonitor.Deadline() before Start = %v, want zero", got)
+		}
 
-		// extend timeout every 50ms
-		processCtx, processCancel := context.WithCancel(context.Background())
-		defer processCancel()
-		ticker := time.NewTicker(50 * time.Millisecond)
-		wg.Add(1)
+		errCh := make(chan error, 1)
 		go func() {
-			defer wg.Done()
-			select {
-			case <-processCtx.Done():
-				return
-			case <-ticker.C:
-				processCh <- struct{}{}
-			}
+			errCh <- m.Start(ctx)
 		}()
 
-		// If the timeout has not occurred after waiting for 150ms, the timeout has been extended.
-		time.Sleep(150 * time.Millisecond)
-		select {
-		case <-ctx.Done():
-			t.Fatalf("unexpected timeout")
-		default:
+		clock.BlockUntil(1)
+		if got, want := m.Deadline(), start.Add(10*time.Second); !got.Equal(want) {
+			t.Errorf("ProcessMonitor.Deadline() = %v, want %v", got, want)
 		}
+		clock.Advance(9 * time.Second)
 
-		// Stop extending timeout
-		processCancel()
+		// the second send is received after the timer is reset by the first one.
+		processCh <- struct{}{}
+		processCh <- struct{}{}
 
-		wg.Wait()
+		if n := clock.Advance(9 * time.Second); n != 0 {
+			t.Errorf("timer fired before the extended timeout")
+		}
+		if n := clock.Advance(time.Second); n != 1 {
+			t.Errorf("timer did not fire at the extended timeout")
+		}
 
-		wantMsg := "inactive for a long time"
-		if got != nil && got.Error() != wantMsg {
+		if got := <-errCh; got == nil || got.Error() != wantMsg {
 			t.Errorf("ProcessMonitor.Start() = %v, want %v", got, wantMsg)
 		}
+		if got, want := m.Deadline(), start.Add(19*time.Second); !got.Equal(want) {
+			t.Errorf("ProcessMonitor.Deadline() = %v, want %v", got, want)
+		}
 	})
 }
@@ -12,12 +12,9 @@ import (
 
 	"golang.org/x/sync/errgroup"
 
-	myspeech "github.com/hekt/voice-recognition/internal/interfaces/speech"
-	myvosk "github.com/hekt/voice-recognition/internal/interfaces/vosk"
-	"github.com/hekt/voice-recognition/internal/punctuator"
-	"github.com/hekt/voice-recognition/internal/recognizer/google"
+	"github.com/hekt/voice-recognition/internal/clock"
 	"github.com/hekt/voice-recognition/internal/recognizer/model"
-	"github.com/hekt/voice-recognition/internal/recognizer/vosk"
+	"github.com/hekt/voice-recognition/internal/telemetry"
 )
 
 type Recognizer struct {
@@ -29,33 +26,55 @@ type Recognizer struct {
 	audioCh   chan []byte
 	resultCh  chan []*model.Result
 	processCh chan struct{}
+	// coreDone is closed when the recognizer core stops.
+	coreDone chan struct{}
 }
 
-func New(
+// PipelineConfig is the config of the pipeline around the recognizer core.
+type PipelineConfig struct {
+	// BufferSize is the size of the audio chunk read at once.
+	BufferSize int
+	// InactiveTimeout is the duration to stop the pipeline when nothing is written.
+	InactiveTimeout time.Duration
+	// Clock measures InactiveTimeout. The system clock is used if nil.
+	Clock clock.Clock
+
+	AudioReader  io.Reader
+	ResultWriter io.Writer
+	// InterimWriter renders the interim result with the final results. See TerminalWriter.
+	InterimWriter io.Writer
+	// SegmentWriter writes the final results with their metadata. It is optional.
+	SegmentWriter SegmentWriterInterface
+	// InterimJournal records the raw interim result, which is written to
+	// ResultWriter only on the clean shutdown, to recover it after a crash.
+	// The provisional one is recorded as empty, since it is not written.
+	// It is optional.
+	InterimJournal io.Writer
+}
+
+// NewPipeline creates a recognizer which reads audio, recognizes it by the core
+// built by newCore, and writes the results.
+func NewPipeline(
 	ctx context.Context,
-	client myspeech.Client,
-	projectID string,
-	recognizerName string,
-	reconnectInterval time.Duration,
-	bufferSize int,
-	inactiveTimeout time.Duration,
-	ioAudioReader io.Reader,
-	ioResultWriter io.Writer,
-	ioInterimWriter io.Writer,
+	newCore model.RecognizerCoreFactory,
+	config PipelineConfig,
 ) (*Recognizer, error) {
-	if bufferSize < 1024 {
+	if newCore == nil {
+		return nil, errors.New("recognizer factory must be specified")
+	}
+	if config.BufferSize < 1024 {
 		return nil, errors.New("buffer size must be greater than or equal to 1024")
 	}
-	if inactiveTimeout == 0 {
+	if config.InactiveTimeout == 0 {
 		return nil, errors.New("inactive timeout must be specified")
 	}
-	if ioAudioReader == nil {
+	if config.AudioReader == nil {
 		return nil, errors.New("audio reader must be specified")
 	}
-	if ioResultWriter == nil {
+	if config.ResultWriter == nil {
 		return nil, errors.New("result writer must be specified")
 	}
-	if ioInterimWriter == nil {
+	if config.InterimWriter == nil {
 		return nil, errors.New("interim writer must be specified")
 	}
 
@@ -63,33 +82,34 @@ func New(
 	audioCh := make(chan []byte, 10)
 	resultCh := make(chan []*model.Result, 10)
 	processCh := make(chan struct{}, 1)
+	coreDone := make(chan struct{})
 
-	recognizer, err := google.NewRecognizer(
-		ctx,
-		client,
-		audioCh,
-		resultCh,
-		projectID,
-		recognizerName,
-		reconnectInterval,
-	)
+	recognizer, err := newCore(ctx, audioCh, resultCh)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create google recognizer: %w", err)
+		return nil, fmt.Errorf("failed to create recognizer core: %w", err)
 	}
 
-	audioReader := NewAudioReceiver(ioAudioReader, audioCh, bufferSize)
+	audioReader := NewAudioReceiver(config.AudioReader, audioCh, config.BufferSize)
+	terminal := NewTerminalWriter(config.InterimWriter)
 	resultWriter := NewResultWriter(
 		resultCh,
 		&NotifyingWriter{
-			Writer:   &DecoratedResultWriter{Writer: ioResultWriter},
+			Writer:   io.MultiWriter(&DecoratedResultWriter{Writer: config.ResultWriter}, terminal.Final()),
 			NotifyCh: processCh,
 		},
 		&NotifyingWriter{
-			Writer:   &DecoratedInterimWriter{Writer: ioInterimWriter},
+			Writer:   terminal.Interim(),
 			NotifyCh: processCh,
 		},
+		config.SegmentWriter,
+		config.InterimJournal,
+		coreDone,
 	)
-	processMonitor := NewProcessMonitor(processCh, inactiveTimeout)
+	c := config.Clock
+	if c == nil {
+		c = clock.Real
+	}
+	processMonitor := NewProcessMonitor(processCh, config.InactiveTimeout, c)
 
 	return &Recognizer{
 		recognizer:     recognizer,
@@ -100,58 +120,14 @@ func New(
 		audioCh:   audioCh,
 		resultCh:  resultCh,
 		processCh: processCh,
+		coreDone:  coreDone,
 	}, nil
 }
 
-func NewVoskRecognizer(
-	voskRecognizer myvosk.VoskRecognizer,
-	punctuator punctuator.PunctuatorInterface,
-	bufferSize int,
-	inactiveTimeout time.Duration,
-	ioAudioReader io.Reader,
-	ioResultWriter io.Writer,
-	ioInterimWriter io.Writer,
-) (*Recognizer, error) {
-	if bufferSize < 1024 {
-		return nil, errors.New("buffer size must be greater than or equal to 1024")
-	}
-	if inactiveTimeout == 0 {
-		return nil, errors.New("inactive timeout must be specified")
-	}
-
-	audioCh := make(chan []byte, 10)
-	resultCh := make(chan []*model.Result, 10)
-	processCh := make(chan struct{}, 1)
-
-	recognizer, err := vosk.NewRecognizer(voskRecognizer, punctuator, audioCh, resultCh)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create vosk recognizer: %w", err)
-	}
-
-	audioReader := NewAudioReceiver(ioAudioReader, audioCh, bufferSize)
-	resultWriter := NewResultWriter(
-		resultCh,
-		&NotifyingWriter{
-			Writer:   &DecoratedResultWriter{Writer: ioResultWriter},
-			NotifyCh: processCh,
-		},
-		&NotifyingWriter{
-			Writer:   &DecoratedInterimWriter{Writer: ioInterimWriter},
-			NotifyCh: processCh,
-		},
-	)
-	processMonitor := NewProcessMonitor(processCh, inactiveTimeout)
-
-	return &Recognizer{
-		recognizer:     recognizer,
-		audioReader:    audioReader,
-		resultWriter:   resultWriter,
-		processMonitor: processMonitor,
-
-		audioCh:   audioCh,
-		resultCh:  resultCh,
-		processCh: processCh,
-	}, nil
+// InactiveDeadline returns the time when the pipeline stops unless something is
+// written. It is zero before Start.
+func (r *Recognizer) InactiveDeadline() time.Time {
+	return r.processMonitor.Deadline()
 }
 
 func (r *Recognizer) Start(ctx context.Context) error {
@@ -163,6 +139,11 @@ func (r *Recognizer) Start(ctx context.Context) error {
 		close(r.processCh)
 	}()
 
+	removeAudioQueue := telemetry.QueueLength.Add(telemetry.QueueAudio, telemetry.ChannelLength(r.audioCh))
+	defer removeAudioQueue()
+	removeResultQueue := telemetry.QueueLength.Add(telemetry.QueueResult, telemetry.ChannelLength(r.resultCh))
+	defer removeResultQueue()
+
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
 	defer stop()
 
@@ -175,7 +156,10 @@ func (r *Recognizer) Start(ctx context.Context) error {
 		return nil
 	})
 	eg.Go(func() error {
-		if err := r.recognizer.Start(ctx); err != nil {
+		// the result writer receives the results until the core stops, so that
+		// the core can pass the results it holds on stop.
+		defer close(r.coreDone)
+		if err := r.recognizer.Start(model.KeepReceiving(ctx, r.resultCh)); err != nil {
 			return fmt.Errorf("error occured in recognizer: %w", err)
 		}
 		return nil
@@ -4,40 +4,36 @@ import (
 	"bytes"
 	"context"
 	"errors"
-	"io"
 	"sync"
 	"testing"
 	"time"
 
-	myspeech "github.com/hekt/voice-recognition/internal/interfaces/speech"
-	myvosk "github.com/hekt/voice-recognition/internal/interfaces/vosk"
-	"github.com/hekt/voice-recognition/internal/punctuator"
 	"github.com/hekt/voice-recognition/internal/recognizer/model"
 	"github.com/hekt/voice-recognition/internal/testutil"
 )
 
-func Test_New(t *testing.T) {
+func TestNewPipeline(t *testing.T) {
+	coreFactory := func(
+		context.Context,
+		<-chan []byte,
+		chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		return &model.RecognizerCoreInterfaceMock{}, nil
+	}
+
 	type args struct {
-		client            myspeech.Client
-		projectID         string
-		recognizerName    string
-		reconnectInterval time.Duration
-		bufferSize        int
-		inactiveTimeout   time.Duration
-		audioReader       io.Reader
-		resultWriter      io.Writer
-		interimWriter     io.Writer
+		newCore model.RecognizerCoreFactory
+		config  PipelineConfig
 	}
 	validArgs := args{
-		client:            &myspeech.ClientMock{},
-		projectID:         "test-project-id",
-		recognizerName:    "test-recognizer-name",
-		reconnectInterval: time.Minute,
-		bufferSize:        1024,
-		inactiveTimeout:   time.Minute,
-		audioReader:       &bytes.Buffer{},
-		resultWriter:      &bytes.Buffer{},
-		interimWriter:     &bytes.Buffer{},
+		newCore: coreFactory,
+		config: PipelineConfig{
+			BufferSize:      1024,
+			InactiveTimeout: time.Minute,
+			AudioReader:     &bytes.Buffer{},
+			ResultWriter:    &bytes.Buffer{},
+			InterimWriter:   &bytes.Buffer{},
+		},
 	}
 	tests := []struct {
 		name    string
@@ -50,119 +46,70 @@ func Test_New(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "invalid buffer size",
+			name: "invalid recognizer factory",
 			args: func() args {
 				a := validArgs
-				a.bufferSize = 0
+				a.newCore = nil
 				return a
 			}(),
 			wantErr: true,
 		},
 		{
-			name: "invalid inactive timeout",
+			name: "failed to create recognizer core",
 			args: func() args {
 				a := validArgs
-				a.inactiveTimeout = 0
+				a.newCore = func(
+					context.Context,
+					<-chan []byte,
+					chan<- []*model.Result,
+				) (model.RecognizerCoreInterface, error) {
+					return nil, errors.New("test error")
+				}
 				return a
 			}(),
 			wantErr: true,
 		},
 		{
-			name: "invalid audio reader",
+			name: "invalid buffer size",
 			args: func() args {
 				a := validArgs
-				a.audioReader = nil
+				a.config.BufferSize = 0
 				return a
 			}(),
 			wantErr: true,
 		},
 		{
-			name: "invalid result writer",
+			name: "invalid inactive timeout",
 			args: func() args {
 				a := validArgs
-				a.resultWriter = nil
+				a.config.InactiveTimeout = 0
 				return a
 			}(),
 			wantErr: true,
 		},
 		{
-			name: "invalid interim writer",
+			name: "invalid audio reader",
 			args: func() args {
 				a := validArgs
-				a.interimWriter = nil
+				a.config.AudioReader = nil
 				return a
 			}(),
 			wantErr: true,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			ctx := context.Background()
-			got, err := New(
-				ctx,
-				tt.args.client,
-				tt.args.projectID,
-				tt.args.recognizerName,
-				tt.args.reconnectInterval,
-				tt.args.bufferSize,
-				tt.args.inactiveTimeout,
-				tt.args.audioReader,
-				tt.args.resultWriter,
-				tt.args.interimWriter,
-			)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !tt.wantErr && got == nil {
-				t.Errorf("New() = %v, want non-nil", got)
-			}
-		})
-	}
-}
-
-func TestNewVoskRecognizer(t *testing.T) {
-	type args struct {
-		voskRecognizer  myvosk.VoskRecognizer
-		punctuator      punctuator.PunctuatorInterface
-		bufferSize      int
-		inactiveTimeout time.Duration
-		ioAudioReader   io.Reader
-		ioResultWriter  io.Writer
-		ioInterimWriter io.Writer
-	}
-	baseArgs := args{
-		voskRecognizer:  &myvosk.VoskRecognizerMock{},
-		punctuator:      &punctuator.PunctuatorInterfaceMock{},
-		bufferSize:      1024,
-		inactiveTimeout: time.Minute,
-		ioAudioReader:   &bytes.Buffer{},
-		ioResultWriter:  &bytes.Buffer{},
-		ioInterimWriter: &bytes.Buffer{},
-	}
-	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
-	}{
-		{
-			name: "success",
-			args: baseArgs,
-		},
 		{
-			name: "invalid buffer size",
+			name: "invalid result writer",
 			args: func() args {
-				a := baseArgs
-				a.bufferSize = 0
+				a := validArgs
+				a.config.ResultWriter = nil
 				return a
 			}(),
 			wantErr: true,
 		},
 		{
-			name: "invalid inactive timeout",
+			name: "invalid interim writer",
 			args: func() args {
-				a := baseArgs
-				a.inactiveTimeout = 0
+				a := validArgs
+				a.config.InterimWriter = nil
 				return a
 			}(),
 			wantErr: true,
@@ -170,24 +117,13 @@ func TestNewVoskRecognizer(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewVoskRecognizer(
-				tt.args.voskRecognizer,
-				tt.args.punctuator,
-				tt.args.bufferSize,
-				tt.args.inactiveTimeout,
-				tt.args.ioAudioReader,
-				tt.args.ioResultWriter,
-				tt.args.ioInterimWriter,
-			)
+			got, err := NewPipeline(context.Background(), tt.args.newCore, tt.args.config)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("NewVoskRecognizer() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("NewPipeline() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if err != nil {
-				return
-			}
-			if got == nil {
-				t.Errorf("NewVoskRecognizer() = %v, want non-nil", got)
+			if !tt.wantErr && got == nil {
+				t.Errorf("NewPipeline() = %v, want non-nil", got)
 			}
 		})
 	}
@@ -277,6 +213,7 @@ func Test_Recognizer_Start(t *testing.T) {
 				audioCh:        tt.fields.audioCh,
 				resultCh:       tt.fields.resultCh,
 				processCh:      tt.fields.processCh,
+				coreDone:       make(chan struct{}),
 			}
 			if err := r.Start(context.Background()); (err != nil) != tt.wantErr {
 				t.Errorf("recognizer.Start() error = %v, wantErr %v", err, tt.wantErr)
@@ -298,6 +235,7 @@ func Test_Recognizer_Start(t *testing.T) {
 		audioCh := make(chan []byte)
 		resultCh := make(chan []*model.Result)
 		processCh := make(chan struct{}, 3)
+		coreDone := make(chan struct{})
 
 		// workers
 		recognizer := &model.RecognizerCoreInterfaceMock{
@@ -334,6 +272,9 @@ func Test_Recognizer_Start(t *testing.T) {
 				Writer:   ioInterimWriter,
 				NotifyCh: processCh,
 			},
+			nil,
+			nil,
+			coreDone,
 		)
 		processMonitor := &ProcessMonitorInterfaceMock{
 			StartFunc: func(context.Context) error {
@@ -349,6 +290,7 @@ func Test_Recognizer_Start(t *testing.T) {
 			audioCh:        audioCh,
 			resultCh:       resultCh,
 			processCh:      processCh,
+			coreDone:       coreDone,
 		}
 
 		var wg sync.WaitGroup
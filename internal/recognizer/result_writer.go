@@ -1,7 +1,6 @@
 package recognizer
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -21,22 +20,36 @@ type ResultWriter struct {
 	resultCh      <-chan []*model.Result
 	resultWriter  io.Writer
 	interimWriter io.Writer
+	// segmentWriter writes the final results with their metadata. nil if not written.
+	segmentWriter SegmentWriterInterface
+	// interimJournal records the interim result to be written on the
+	// shutdown, which is empty if it is provisional. nil if not recorded.
+	interimJournal io.Writer
+	// coreDone is closed when the core sending the results stops. The results
+	// are received until then on stop. nil if only the buffered ones are.
+	coreDone <-chan struct{}
+	turns    Turns
 }
 
 func NewResultWriter(
 	resultCh <-chan []*model.Result,
 	resultWriter io.Writer,
 	interimWriter io.Writer,
+	segmentWriter SegmentWriterInterface,
+	interimJournal io.Writer,
+	coreDone <-chan struct{},
 ) *ResultWriter {
 	return &ResultWriter{
-		resultCh:      resultCh,
-		resultWriter:  resultWriter,
-		interimWriter: interimWriter,
+		resultCh:       resultCh,
+		resultWriter:   resultWriter,
+		interimWriter:  interimWriter,
+		segmentWriter:  segmentWriter,
+		interimJournal: interimJournal,
+		coreDone:       coreDone,
 	}
 }
 
 func (w *ResultWriter) Start(ctx context.Context) error {
-	buf := bytes.Buffer{}
 	var interimResult []byte
 	defer func() {
 		if len(interimResult) == 0 {
@@ -45,40 +58,129 @@ func (w *ResultWriter) Start(ctx context.Context) error {
 		if _, err := w.resultWriter.Write(interimResult); err != nil {
 			slog.Error(fmt.Sprintf("failed to write interim result: %v", err))
 		}
+		if err := w.writeSegment(&model.Result{Transcript: string(interimResult), IsFinal: true}); err != nil {
+			slog.Error(err.Error())
+		}
 		slog.Debug("ResponseProcessor: interim result written")
 	}()
 
+	write := func(results []*model.Result) error {
+		finals, interim := model.Split(results)
+		for _, result := range finals {
+			if _, err := w.resultWriter.Write([]byte(w.turns.Transcript(result))); err != nil {
+				return fmt.Errorf("failed to write result: %w", err)
+			}
+			w.turns.Advance(result)
+			if err := w.writeSegment(result); err != nil {
+				return err
+			}
+			interimResult = nil
+		}
+
+		if interim == nil {
+			return nil
+		}
+
+		interimResult = []byte(interim.Transcript)
+		if interim.Provisional {
+			interimResult = nil
+		}
+		if w.interimJournal != nil {
+			if _, err := w.interimJournal.Write(interimResult); err != nil {
+				return fmt.Errorf("failed to record interim result: %w", err)
+			}
+		}
+		if _, err := w.interimWriter.Write([]byte(interim.Transcript)); err != nil {
+			return fmt.Errorf("failed to write interim result: %w", err)
+		}
+		return nil
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			// the results sent on stop, e.g. the final results held by the
+			// multichannel backend, are written before the interim result.
+			// They are received until the core stops, and then the rest left
+			// in the buffer.
+			for done := w.coreDone == nil; !done; {
+				select {
+				case results, ok := <-w.resultCh:
+					if !ok {
+						return ctx.Err()
+					}
+					if err := write(results); err != nil {
+						return err
+					}
+				case <-w.coreDone:
+					done = true
+				}
+			}
+			for {
+				select {
+				case results, ok := <-w.resultCh:
+					if !ok {
+						return ctx.Err()
+					}
+					if err := write(results); err != nil {
+						return err
+					}
+				default:
+					return ctx.Err()
+				}
+			}
 		case results, ok := <-w.resultCh:
 			if !ok {
 				return fmt.Errorf("result channel is closed")
 			}
+			if err := write(results); err != nil {
+				return err
+			}
+		}
+	}
+}
 
-			buf.Reset()
-			for _, result := range results {
-				if !result.IsFinal {
-					buf.WriteString(result.Transcript)
-					continue
-				}
+func (w *ResultWriter) writeSegment(result *model.Result) error {
+	if w.segmentWriter == nil {
+		return nil
+	}
+	if err := w.segmentWriter.WriteSegment(result); err != nil {
+		return fmt.Errorf("failed to write segment: %w", err)
+	}
+	return nil
+}
 
-				if _, err := w.resultWriter.Write([]byte(result.Transcript)); err != nil {
-					return fmt.Errorf("failed to write result: %w", err)
-				}
-				interimResult = nil
-				buf.Reset()
-			}
+// FinalTranscript returns the transcript of the final result.
+// It is prefixed with the backend if the result is marked.
+func FinalTranscript(result *model.Result) string {
+	return withBackend(result, result.Transcript)
+}
 
-			if buf.Len() == 0 {
-				continue
-			}
+func withBackend(result *model.Result, text string) string {
+	if result.Backend == "" {
+		return text
+	}
+	return fmt.Sprintf("[%s] %s", result.Backend, text)
+}
 
-			interimResult = buf.Bytes()
-			if _, err := w.interimWriter.Write(interimResult); err != nil {
-				return fmt.Errorf("failed to write interim result: %w", err)
-			}
-		}
+// Turns tells the turns of the speakers in the final results, where the
+// speaker changes. The zero value is ready to use.
+type Turns struct {
+	// speaker is the speaker of the last result written.
+	speaker string
+}
+
+// Transcript returns FinalTranscript of the result, with the speaker like
+// "Speaker 1: " after the backend if the result starts a turn. Advance must
+// be called with the result when it is written.
+func (t *Turns) Transcript(result *model.Result) string {
+	if result.Speaker == "" || result.Speaker == t.speaker {
+		return FinalTranscript(result)
 	}
+	return withBackend(result, result.Speaker+": "+result.Transcript)
+}
+
+// Advance moves to the turn of the result written.
+func (t *Turns) Advance(result *model.Result) {
+	t.speaker = result.Speaker
 }
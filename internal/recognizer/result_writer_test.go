@@ -17,12 +17,14 @@ func TestNewResultWriter(t *testing.T) {
 		resultCh := make(chan []*model.Result)
 		resultWriter := &bytes.Buffer{}
 		interimWriter := &bytes.Buffer{}
+		segmentWriter := &SegmentWriterInterfaceMock{}
 		want := &ResultWriter{
 			resultCh:      resultCh,
 			resultWriter:  resultWriter,
 			interimWriter: interimWriter,
+			segmentWriter: segmentWriter,
 		}
-		got := NewResultWriter(resultCh, resultWriter, interimWriter)
+		got := NewResultWriter(resultCh, resultWriter, interimWriter, segmentWriter, nil, nil)
 		if !reflect.DeepEqual(got, want) {
 			t.Errorf("NewResultWriter() = %v, want %v", got, want)
 		}
@@ -75,6 +77,233 @@ func TestResultWriter_Start(t *testing.T) {
 		}
 	})
 
+	t.Run("provisional interim result", func(t *testing.T) {
+		resultCh := make(chan []*model.Result)
+		resultWriter := &bytes.Buffer{}
+		interimWriter := &bytes.Buffer{}
+		interimJournal := &recordingWriter{}
+		w := &ResultWriter{
+			resultCh:       resultCh,
+			resultWriter:   resultWriter,
+			interimWriter:  interimWriter,
+			interimJournal: interimJournal,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var got error
+		go func() {
+			defer wg.Done()
+			got = w.Start(ctx)
+		}()
+
+		resultCh <- []*model.Result{{Transcript: "a"}}
+		resultCh <- []*model.Result{{Transcript: "ab", IsFinal: true}}
+		resultCh <- []*model.Result{{Transcript: "c", Provisional: true}}
+
+		cancel()
+		wg.Wait()
+
+		if !errors.Is(got, context.Canceled) {
+			t.Errorf("unexpected error: %v", got)
+		}
+		// the provisional interim result is shown, but not written on the shutdown.
+		if diff := cmp.Diff(resultWriter.String(), "ab"); diff != "" {
+			t.Errorf("unexpected result: (-got +want)\n%s", diff)
+		}
+		if diff := cmp.Diff(interimWriter.String(), "ac"); diff != "" {
+			t.Errorf("unexpected interim: (-got +want)\n%s", diff)
+		}
+		if diff := cmp.Diff(interimJournal.writes, []string{"a", ""}); diff != "" {
+			t.Errorf("unexpected journal: (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("results sent on stop", func(t *testing.T) {
+		resultCh := make(chan []*model.Result, 2)
+		resultWriter := &bytes.Buffer{}
+		w := &ResultWriter{
+			resultCh:      resultCh,
+			resultWriter:  resultWriter,
+			interimWriter: &bytes.Buffer{},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		resultCh <- []*model.Result{{Transcript: "a b"}}
+		resultCh <- []*model.Result{
+			{Transcript: "a", IsFinal: true},
+			{Transcript: "b"},
+		}
+
+		if err := w.Start(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected error: %v", err)
+		}
+		// the results are written before the interim result is flushed.
+		if diff := cmp.Diff(resultWriter.String(), "ab"); diff != "" {
+			t.Errorf("unexpected result: (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("results sent until core stops", func(t *testing.T) {
+		resultCh := make(chan []*model.Result)
+		coreDone := make(chan struct{})
+		resultWriter := &bytes.Buffer{}
+		w := &ResultWriter{
+			resultCh:      resultCh,
+			resultWriter:  resultWriter,
+			interimWriter: &bytes.Buffer{},
+			coreDone:      coreDone,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- w.Start(ctx)
+		}()
+
+		// the core passes the results on stop.
+		resultCh <- []*model.Result{{Transcript: "a", IsFinal: true}}
+		resultCh <- []*model.Result{{Transcript: "b"}}
+		close(coreDone)
+
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(resultWriter.String(), "ab"); diff != "" {
+			t.Errorf("unexpected result: (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("marked with backend", func(t *testing.T) {
+		resultCh := make(chan []*model.Result)
+		resultWriter := &bytes.Buffer{}
+		interimWriter := &bytes.Buffer{}
+		w := &ResultWriter{
+			resultCh:      resultCh,
+			resultWriter:  resultWriter,
+			interimWriter: interimWriter,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var got error
+		go func() {
+			defer wg.Done()
+			got = w.Start(ctx)
+		}()
+
+		resultCh <- []*model.Result{
+			{Transcript: "a", IsFinal: false, Backend: "google"},
+		}
+		resultCh <- []*model.Result{
+			{Transcript: "abc", IsFinal: true, Backend: "google"},
+		}
+
+		cancel()
+		wg.Wait()
+
+		if !errors.Is(got, context.Canceled) {
+			t.Errorf("unexpected error: %v", got)
+		}
+		if diff := cmp.Diff(resultWriter.String(), "[google] abc"); diff != "" {
+			t.Errorf("unexpected result: (-got +want)\n%s", diff)
+		}
+		if diff := cmp.Diff(interimWriter.String(), "a"); diff != "" {
+			t.Errorf("unexpected interim: (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("prefixed with speaker at turns", func(t *testing.T) {
+		resultCh := make(chan []*model.Result)
+		resultWriter := &bytes.Buffer{}
+		w := &ResultWriter{
+			resultCh:      resultCh,
+			resultWriter:  resultWriter,
+			interimWriter: &bytes.Buffer{},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var got error
+		go func() {
+			defer wg.Done()
+			got = w.Start(ctx)
+		}()
+
+		resultCh <- []*model.Result{
+			{Transcript: "a", IsFinal: true, Speaker: "Speaker 1"},
+			{Transcript: "b", IsFinal: true, Speaker: "Speaker 1"},
+		}
+		resultCh <- []*model.Result{
+			{Transcript: "c", IsFinal: true, Backend: "google", Speaker: "Alice"},
+		}
+
+		cancel()
+		wg.Wait()
+
+		if !errors.Is(got, context.Canceled) {
+			t.Errorf("unexpected error: %v", got)
+		}
+		if diff := cmp.Diff(resultWriter.String(), "Speaker 1: ab[google] Alice: c"); diff != "" {
+			t.Errorf("unexpected result: (-got +want)\n%s", diff)
+		}
+	})
+
+	t.Run("with segment writer", func(t *testing.T) {
+		resultCh := make(chan []*model.Result)
+		var segments []string
+		w := &ResultWriter{
+			resultCh:      resultCh,
+			resultWriter:  &bytes.Buffer{},
+			interimWriter: &bytes.Buffer{},
+			segmentWriter: &SegmentWriterInterfaceMock{
+				WriteSegmentFunc: func(result *model.Result) error {
+					segments = append(segments, result.Transcript)
+					return nil
+				},
+			},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var got error
+		go func() {
+			defer wg.Done()
+			got = w.Start(ctx)
+		}()
+
+		resultCh <- []*model.Result{
+			{Transcript: "a", IsFinal: false},
+		}
+		resultCh <- []*model.Result{
+			{Transcript: "abc", IsFinal: true},
+		}
+		resultCh <- []*model.Result{
+			{Transcript: "d", IsFinal: false},
+		}
+
+		cancel()
+		wg.Wait()
+
+		if !errors.Is(got, context.Canceled) {
+			t.Errorf("unexpected error: %v", got)
+		}
+		// the pending interim result is written as a segment on shutdown.
+		if diff := cmp.Diff(segments, []string{"abc", "d"}); diff != "" {
+			t.Errorf("unexpected segments: (-got +want)\n%s", diff)
+		}
+	})
+
 	t.Run("result channel is closed", func(t *testing.T) {
 		resultCh := make(chan []*model.Result)
 		resultWriter := &bytes.Buffer{}
@@ -93,3 +322,13 @@ func TestResultWriter_Start(t *testing.T) {
 		}
 	})
 }
+
+// recordingWriter records each write.
+type recordingWriter struct {
+	writes []string
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
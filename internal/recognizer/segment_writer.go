@@ -0,0 +1,75 @@
+package recognizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+//go:generate moq -rm -out segment_writer_mock.go . SegmentWriterInterface
+type SegmentWriterInterface interface {
+	// WriteSegment writes the final result.
+	WriteSegment(result *model.Result) error
+}
+
+var _ SegmentWriterInterface = (*JSONSegmentWriter)(nil)
+
+// Segment is a final result linked to the position in the saved audio.
+type Segment struct {
+	Text    string `json:"text"`
+	Backend string `json:"backend,omitempty"`
+	// Audio is the path of the saved audio.
+	Audio string `json:"audio"`
+	// StartSample and EndSample are the range of the segment in the samples of the audio.
+	// The segment starts at the end of the previous one.
+	StartSample int64 `json:"start_sample"`
+	EndSample   int64 `json:"end_sample"`
+}
+
+// JSONSegmentWriter writes the final results as JSON lines of Segment.
+type JSONSegmentWriter struct {
+	writer    io.Writer
+	audioPath string
+	// position returns the number of the samples saved so far. It is the end of
+	// the segment if the backend does not tell it.
+	position func() int64
+
+	lastEnd int64
+}
+
+func NewJSONSegmentWriter(writer io.Writer, audioPath string, position func() int64) *JSONSegmentWriter {
+	return &JSONSegmentWriter{
+		writer:    writer,
+		audioPath: audioPath,
+		position:  position,
+	}
+}
+
+func (w *JSONSegmentWriter) WriteSegment(result *model.Result) error {
+	end := w.position()
+	if result.End > 0 {
+		end = int64(model.AudioBytes(result.End) / model.BytesPerSample)
+	}
+	// the end told by the backend can be before the end of the previous one
+	// when the backend is switched.
+	start := min(w.lastEnd, end)
+
+	line, err := json.Marshal(Segment{
+		Text:        result.Transcript,
+		Backend:     result.Backend,
+		Audio:       w.audioPath,
+		StartSample: start,
+		EndSample:   end,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal segment: %w", err)
+	}
+	if _, err := w.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write segment: %w", err)
+	}
+
+	w.lastEnd = end
+	return nil
+}
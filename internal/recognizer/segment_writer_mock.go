@@ -0,0 +1,75 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package recognizer
+
+import (
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"sync"
+)
+
+// Ensure, that SegmentWriterInterfaceMock does implement SegmentWriterInterface.
+// If this is not the case, regenerate this file with moq.
+var _ SegmentWriterInterface = &SegmentWriterInterfaceMock{}
+
+// SegmentWriterInterfaceMock is a mock implementation of SegmentWriterInterface.
+//
+//	func TestSomethingThatUsesSegmentWriterInterface(t *testing.T) {
+//
+//		// make and configure a mocked SegmentWriterInterface
+//		mockedSegmentWriterInterface := &SegmentWriterInterfaceMock{
+//			WriteSegmentFunc: func(result *model.Result) error {
+//				panic("mock out the WriteSegment method")
+//			},
+//		}
+//
+//		// use mockedSegmentWriterInterface in code that requires SegmentWriterInterface
+//		// and then make assertions.
+//
+//	}
+type SegmentWriterInterfaceMock struct {
+	// WriteSegmentFunc mocks the WriteSegment method.
+	WriteSegmentFunc func(result *model.Result) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// WriteSegment holds details about calls to the WriteSegment method.
+		WriteSegment []struct {
+			// Result is the result argument value.
+			Result *model.Result
+		}
+	}
+	lockWriteSegment sync.RWMutex
+}
+
+// WriteSegment calls WriteSegmentFunc.
+func (mock *SegmentWriterInterfaceMock) WriteSegment(result *model.Result) error {
+	if mock.WriteSegmentFunc == nil {
+		panic("SegmentWriterInterfaceMock.WriteSegmentFunc: method is nil but SegmentWriterInterface.WriteSegment was just called")
+	}
+	callInfo := struct {
+		Result *model.Result
+	}{
+		Result: result,
+	}
+	mock.lockWriteSegment.Lock()
+	mock.calls.WriteSegment = append(mock.calls.WriteSegment, callInfo)
+	mock.lockWriteSegment.Unlock()
+	return mock.WriteSegmentFunc(result)
+}
+
+// WriteSegmentCalls gets all the calls that were made to WriteSegment.
+// Check the length with:
+//
+//	len(mockedSegmentWriterInterface.WriteSegmentCalls())
+func (mock *SegmentWriterInterfaceMock) WriteSegmentCalls() []struct {
+	Result *model.Result
+} {
+	var calls []struct {
+		Result *model.Result
+	}
+	mock.lockWriteSegment.RLock()
+	calls = mock.calls.WriteSegment
+	mock.lockWriteSegment.RUnlock()
+	return calls
+}
@@ -0,0 +1,54 @@
+package recognizer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+func TestJSONSegmentWriter_WriteSegment(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		position := int64(0)
+		w := NewJSONSegmentWriter(buf, "audio.wav", func() int64 { return position })
+
+		// the first end is told by the backend, and the second is the position of the saved audio.
+		position = 20000
+		if err := w.WriteSegment(&model.Result{Transcript: "a", IsFinal: true, End: time.Second}); err != nil {
+			t.Fatalf("WriteSegment() error = %v", err)
+		}
+		position = 40000
+		if err := w.WriteSegment(&model.Result{Transcript: "b", IsFinal: true, Backend: "vosk"}); err != nil {
+			t.Fatalf("WriteSegment() error = %v", err)
+		}
+
+		want := `{"text":"a","audio":"audio.wav","start_sample":0,"end_sample":16000}
+{"text":"b","backend":"vosk","audio":"audio.wav","start_sample":16000,"end_sample":40000}
+`
+		if diff := cmp.Diff(buf.String(), want); diff != "" {
+			t.Errorf("WriteSegment() mismatch (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("end before the previous one", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		w := NewJSONSegmentWriter(buf, "audio.wav", func() int64 { return 32000 })
+
+		if err := w.WriteSegment(&model.Result{Transcript: "a", IsFinal: true}); err != nil {
+			t.Fatalf("WriteSegment() error = %v", err)
+		}
+		if err := w.WriteSegment(&model.Result{Transcript: "b", IsFinal: true, End: time.Second}); err != nil {
+			t.Fatalf("WriteSegment() error = %v", err)
+		}
+
+		want := `{"text":"a","audio":"audio.wav","start_sample":0,"end_sample":32000}
+{"text":"b","audio":"audio.wav","start_sample":16000,"end_sample":16000}
+`
+		if diff := cmp.Diff(buf.String(), want); diff != "" {
+			t.Errorf("WriteSegment() mismatch (-got +want):\n%s", diff)
+		}
+	})
+}
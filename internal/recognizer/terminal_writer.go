@@ -0,0 +1,143 @@
+package recognizer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+	"golang.org/x/text/width"
+)
+
+// TerminalWriter renders the results on the terminal without wiping its
+// scrollback. The final results scroll up as lines, and the current interim
+// result is redrawn in place on the bottom lines, which are counted by
+// wrapping it by the width of the terminal.
+//
+// When the output is not a terminal, each update is written as a plain line
+// prefixed with "interim: " or "final: " without escape sequences.
+type TerminalWriter struct {
+	writer io.Writer
+	// width returns the width of the terminal in columns, or 0 if unknown.
+	// It is nil if the output is not a terminal.
+	width func() int
+
+	buf bytes.Buffer
+	// interim is the interim result drawn.
+	interim string
+	// lines is the number of the lines which the interim result drawn occupies.
+	lines int
+}
+
+// NewTerminalWriter creates a writer rendering on w. w is taken as a terminal
+// if it is a file of a terminal.
+func NewTerminalWriter(w io.Writer) *TerminalWriter {
+	t := &TerminalWriter{writer: w}
+	if f, ok := w.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		t.width = func() int {
+			// the size is taken on each draw to follow the resize.
+			width, _, err := term.GetSize(int(f.Fd()))
+			if err != nil {
+				return 0
+			}
+			return width
+		}
+	}
+	return t
+}
+
+// Interim returns the writer of the interim results.
+func (w *TerminalWriter) Interim() io.Writer {
+	return writerFunc(w.writeInterim)
+}
+
+// Final returns the writer of the final results.
+func (w *TerminalWriter) Final() io.Writer {
+	return writerFunc(w.writeFinal)
+}
+
+func (w *TerminalWriter) writeInterim(p []byte) (int, error) {
+	text := string(p)
+	if text == w.interim {
+		return len(p), nil
+	}
+
+	w.buf.Reset()
+	if w.width == nil {
+		fmt.Fprintf(&w.buf, "interim: %s\n", text)
+	} else {
+		w.erase()
+		w.buf.Write(greenColor)
+		w.buf.WriteString(text)
+		w.buf.Write(resetColor)
+		w.lines = countLines(text, w.width())
+	}
+	w.interim = text
+
+	if _, err := w.writer.Write(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *TerminalWriter) writeFinal(p []byte) (int, error) {
+	w.buf.Reset()
+	if w.width == nil {
+		fmt.Fprintf(&w.buf, "final: %s\n", p)
+	} else {
+		w.erase()
+		w.buf.Write(p)
+		w.buf.Write(newLine)
+		w.lines = 0
+	}
+	w.interim = ""
+
+	if _, err := w.writer.Write(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// erase writes the sequences to erase the interim result drawn, leaving the cursor where it began.
+func (w *TerminalWriter) erase() {
+	if w.lines == 0 {
+		return
+	}
+	w.buf.WriteString("\r")
+	if w.lines > 1 {
+		fmt.Fprintf(&w.buf, "\033[%dA", w.lines-1)
+	}
+	w.buf.Write(eraseBelow)
+}
+
+// countLines returns the number of the lines which text occupies on the
+// terminal of the width. The wide characters take two columns. The width
+// 0 means no wrapping.
+func countLines(text string, columns int) int {
+	lines, col := 1, 0
+	for _, r := range text {
+		if r == '\n' {
+			lines++
+			col = 0
+			continue
+		}
+		rw := 1
+		switch width.LookupRune(r).Kind() {
+		case width.EastAsianWide, width.EastAsianFullwidth:
+			rw = 2
+		}
+		if columns > 0 && col+rw > columns {
+			lines++
+			col = 0
+		}
+		col += rw
+	}
+	return lines
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
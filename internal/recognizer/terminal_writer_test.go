@@ -0,0 +1,117 @@
+package recognizer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTerminalWriter(t *testing.T) {
+	type write struct {
+		final bool
+		text  string
+	}
+	tests := []struct {
+		name string
+		// width is the width of the terminal. -1 means not a terminal.
+		width  int
+		writes []write
+		want   string
+	}{
+		{
+			name:  "not a terminal",
+			width: -1,
+			writes: []write{
+				{text: "こ"},
+				{text: "こん"},
+				{text: "こん"},
+				{final: true, text: "こんにちは"},
+			},
+			want: "interim: こ\n" +
+				"interim: こん\n" +
+				"final: こんにちは\n",
+		},
+		{
+			name:  "terminal",
+			width: 80,
+			writes: []write{
+				{text: "こ"},
+				{text: "こん"},
+				{final: true, text: "こんにちは"},
+				{text: "今"},
+			},
+			want: "\033[32mこ\033[0m" +
+				"\r\033[J\033[32mこん\033[0m" +
+				"\r\033[Jこんにちは\n" +
+				"\033[32m今\033[0m",
+		},
+		{
+			name:  "wrapped",
+			width: 4,
+			writes: []write{
+				// 6 columns in 2 lines.
+				{text: "あいう"},
+				{text: "あいうえ"},
+				// 4 columns in a line.
+				{text: "ab"},
+				{text: "abcd"},
+				{final: true, text: "abcd"},
+			},
+			want: "\033[32mあいう\033[0m" +
+				"\r\033[1A\033[J\033[32mあいうえ\033[0m" +
+				"\r\033[1A\033[J\033[32mab\033[0m" +
+				"\r\033[J\033[32mabcd\033[0m" +
+				"\r\033[Jabcd\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w := NewTerminalWriter(buf)
+			if tt.width >= 0 {
+				w.width = func() int { return tt.width }
+			}
+
+			for _, write := range tt.writes {
+				writer := w.Interim()
+				if write.final {
+					writer = w.Final()
+				}
+				n, err := writer.Write([]byte(write.text))
+				if err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+				if n != len(write.text) {
+					t.Errorf("Write() = %d, want %d", n, len(write.text))
+				}
+			}
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("written %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_countLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		columns int
+		want    int
+	}{
+		{name: "empty", text: "", columns: 10, want: 1},
+		{name: "fits", text: "abcd", columns: 4, want: 1},
+		{name: "wrapped", text: "abcde", columns: 4, want: 2},
+		{name: "wide characters", text: "あいう", columns: 4, want: 2},
+		{name: "wide character at the edge", text: "abcあ", columns: 4, want: 2},
+		{name: "newline", text: "ab\ncd", columns: 4, want: 2},
+		{name: "unknown width", text: "abcdefgh", columns: 0, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countLines(tt.text, tt.columns); got != tt.want {
+				t.Errorf("countLines() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -20,6 +20,26 @@ type Recognizer struct {
 	resultCh   chan<- []*model.Result
 }
 
+// Config is the config of the Vosk backend.
+type Config struct {
+	Recognizer myvosk.VoskRecognizer
+	Punctuator punctuator.PunctuatorInterface
+}
+
+// New creates a recognizer from the config.
+func New(
+	_ context.Context,
+	config Config,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (model.RecognizerCoreInterface, error) {
+	recognizer, err := NewRecognizer(config.Recognizer, config.Punctuator, audioCh, resultCh)
+	if err != nil {
+		return nil, err
+	}
+	return recognizer, nil
+}
+
 func NewRecognizer(
 	recognizer myvosk.VoskRecognizer,
 	punctuator punctuator.PunctuatorInterface,
@@ -48,6 +68,9 @@ func NewRecognizer(
 }
 
 func (r *Recognizer) Start(ctx context.Context) error {
+	// received is the number of bytes of the audio received.
+	received := 0
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -58,6 +81,7 @@ func (r *Recognizer) Start(ctx context.Context) error {
 			}
 
 			n := r.recognizer.AcceptWaveform(audio)
+			received += len(audio)
 
 			var results []*model.Result
 			if n == 0 {
@@ -88,7 +112,7 @@ func (r *Recognizer) Start(ctx context.Context) error {
 					return fmt.Errorf("failed to punctuate: %w", err)
 				}
 				results = []*model.Result{
-					{Transcript: punctuated, IsFinal: true},
+					{Transcript: punctuated, IsFinal: true, End: model.AudioDuration(received)},
 				}
 			}
 
@@ -59,6 +59,36 @@ func TestNewRecognizer(t *testing.T) {
 	}
 }
 
+func TestNew(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		got, err := New(
+			context.Background(),
+			Config{
+				Recognizer: &myvosk.VoskRecognizerMock{},
+				Punctuator: &punctuator.PunctuatorInterfaceMock{},
+			},
+			make(chan []byte),
+			make(chan []*model.Result),
+		)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if got == nil {
+			t.Error("New() = nil, want non-nil")
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		got, err := New(context.Background(), Config{}, make(chan []byte), make(chan []*model.Result))
+		if err == nil {
+			t.Error("New() error = nil, want an error")
+		}
+		if got != nil {
+			t.Errorf("New() = %v, want nil", got)
+		}
+	})
+}
+
 func TestRecognizer_Start(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -122,7 +152,8 @@ func TestRecognizer_Start(t *testing.T) {
 		}
 		wantResults := [][]*model.Result{
 			{{Transcript: "p_hello", IsFinal: false}},
-			{{Transcript: "p_world", IsFinal: true}},
+			// the end is the 10 bytes of "hello" and "world".
+			{{Transcript: "p_world", IsFinal: true, End: model.AudioDuration(10)}},
 		}
 		for _, want := range wantResults {
 			got := <-resultCh
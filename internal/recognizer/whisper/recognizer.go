@@ -0,0 +1,226 @@
+package whisper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+var _ model.RecognizerCoreInterface = (*Recognizer)(nil)
+
+// Recognizer transcribes the audio by Whisper in overlapping windows.
+//
+// The window starts at the end of the last final result and grows as the audio
+// is received. Each time step of audio is received, the whole window is
+// transcribed. The leading segments which are the same as the previous
+// transcription are agreed and passed as final results, and the window is
+// moved to the end of them. The rest is passed as an interim result.
+type Recognizer struct {
+	transcriber TranscriberInterface
+
+	// step is the bytes of audio received to start the next transcription.
+	step int
+	// maxWindow is the bytes of the window to finalize the segments without agreement.
+	maxWindow int
+
+	audioCh  <-chan []byte
+	resultCh chan<- []*model.Result
+}
+
+// Config is the config of the Whisper backend.
+type Config struct {
+	// URL is the endpoint of the transcription,
+	// e.g. http://127.0.0.1:8080/inference for whisper.cpp server.
+	URL       string
+	Model     string
+	Language  string
+	Timeout   time.Duration
+	Step      time.Duration
+	MaxWindow time.Duration
+}
+
+// New creates a recognizer from the config.
+func New(
+	_ context.Context,
+	config Config,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (model.RecognizerCoreInterface, error) {
+	transcriber, err := NewHTTPTranscriber(
+		&http.Client{Timeout: config.Timeout},
+		config.URL,
+		config.Model,
+		config.Language,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transcriber: %w", err)
+	}
+
+	recognizer, err := NewRecognizer(transcriber, audioCh, resultCh, config.Step, config.MaxWindow)
+	if err != nil {
+		return nil, err
+	}
+	return recognizer, nil
+}
+
+func NewRecognizer(
+	transcriber TranscriberInterface,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+	step time.Duration,
+	maxWindow time.Duration,
+) (*Recognizer, error) {
+	if transcriber == nil {
+		return nil, errors.New("transcriber must be specified")
+	}
+	if audioCh == nil {
+		return nil, errors.New("audio channel must be specified")
+	}
+	if resultCh == nil {
+		return nil, errors.New("result channel must be specified")
+	}
+	if step <= 0 {
+		return nil, errors.New("step must be positive")
+	}
+	if maxWindow < step {
+		return nil, errors.New("max window must be greater than or equal to step")
+	}
+
+	return &Recognizer{
+		transcriber: transcriber,
+		step:        model.AudioBytes(step),
+		maxWindow:   model.AudioBytes(maxWindow),
+		audioCh:     audioCh,
+		resultCh:    resultCh,
+	}, nil
+}
+
+type transcription struct {
+	segments []Segment
+	// size is the bytes of the transcribed window.
+	size int
+	err  error
+}
+
+func (r *Recognizer) Start(ctx context.Context) error {
+	// the transcription runs in background to keep receiving audio.
+	doneCh := make(chan transcription, 1)
+	running := false
+
+	var window []byte
+	// pending is the bytes of audio received since the last transcription started.
+	pending := 0
+	// prev is the segments not agreed in the last transcription.
+	var prev []Segment
+
+	transcribe := func() {
+		running = true
+		pending = 0
+		pcm := append([]byte(nil), window...)
+		go func() {
+			segments, err := r.transcriber.Transcribe(ctx, pcm)
+			doneCh <- transcription{segments: segments, size: len(pcm), err: err}
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case audio, ok := <-r.audioCh:
+			if !ok {
+				return errors.New("audio channel closed")
+			}
+
+			window = append(window, audio...)
+			pending += len(audio)
+			if !running && pending >= r.step {
+				transcribe()
+			}
+		case t := <-doneCh:
+			running = false
+			if t.err != nil {
+				return fmt.Errorf("failed to transcribe: %w", t.err)
+			}
+
+			n := agree(prev, t.segments)
+			if t.size >= r.maxWindow {
+				slog.Debug("WhisperRecognizer: window is full")
+				n = len(t.segments)
+			}
+
+			finalized := finalizedBytes(t.segments, n, t.size, r.step)
+			window = window[finalized:]
+			prev = t.segments[n:]
+
+			if results := buildResults(t.segments[:n], prev); len(results) > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case r.resultCh <- results:
+				}
+			}
+
+			if pending >= r.step {
+				transcribe()
+			}
+		}
+	}
+}
+
+// agree returns the number of the leading segments which are the same in the
+// previous and current transcriptions.
+// The last segment is not agreed because it may be cut off by the end of the window.
+func agree(prev, cur []Segment) int {
+	n := 0
+	for n < len(cur)-1 && n < len(prev) {
+		if strings.TrimSpace(cur[n].Text) != strings.TrimSpace(prev[n].Text) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// finalizedBytes returns the bytes of the window to be dropped after the first n
+// segments are finalized.
+func finalizedBytes(segments []Segment, n int, size int, step int) int {
+	if n == 0 {
+		if len(segments) == 0 && size >= step {
+			// no speech in the window. keep only the last step in case speech begins there.
+			return size - step
+		}
+		return 0
+	}
+	if n == len(segments) {
+		return size
+	}
+
+	end := model.AudioBytes(time.Duration(segments[n-1].End * float64(time.Second)))
+	return min(max(end, 0), size)
+}
+
+func buildResults(finals []Segment, interims []Segment) []*model.Result {
+	results := make([]*model.Result, 0, len(finals)+1)
+	for _, s := range finals {
+		if t := strings.TrimSpace(s.Text); t != "" {
+			results = append(results, &model.Result{Transcript: t, IsFinal: true})
+		}
+	}
+
+	var b strings.Builder
+	for _, s := range interims {
+		b.WriteString(s.Text)
+	}
+	if t := strings.TrimSpace(b.String()); t != "" {
+		results = append(results, &model.Result{Transcript: t, IsFinal: false})
+	}
+
+	return results
+}
@@ -0,0 +1,311 @@
+package whisper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+func TestNewRecognizer(t *testing.T) {
+	type args struct {
+		transcriber TranscriberInterface
+		audioCh     <-chan []byte
+		resultCh    chan<- []*model.Result
+		step        time.Duration
+		maxWindow   time.Duration
+	}
+	baseArgs := args{
+		transcriber: &TranscriberInterfaceMock{},
+		audioCh:     make(chan []byte),
+		resultCh:    make(chan []*model.Result),
+		step:        time.Second,
+		maxWindow:   20 * time.Second,
+	}
+	tests := []struct {
+		name    string
+		args    func() args
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			args:    func() args { return baseArgs },
+			wantErr: false,
+		},
+		{
+			name: "nil transcriber",
+			args: func() args {
+				a := baseArgs
+				a.transcriber = nil
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "nil audio channel",
+			args: func() args {
+				a := baseArgs
+				a.audioCh = nil
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "nil result channel",
+			args: func() args {
+				a := baseArgs
+				a.resultCh = nil
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero step",
+			args: func() args {
+				a := baseArgs
+				a.step = 0
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "max window less than step",
+			args: func() args {
+				a := baseArgs
+				a.maxWindow = a.step / 2
+				return a
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.args()
+			got, err := NewRecognizer(a.transcriber, a.audioCh, a.resultCh, a.step, a.maxWindow)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRecognizer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got == nil {
+				t.Errorf("NewRecognizer() = nil, want non-nil")
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		got, err := New(
+			context.Background(),
+			Config{
+				URL:       "http://127.0.0.1:8080/inference",
+				Language:  "ja",
+				Timeout:   time.Minute,
+				Step:      time.Second,
+				MaxWindow: 20 * time.Second,
+			},
+			make(chan []byte),
+			make(chan []*model.Result),
+		)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if got == nil {
+			t.Error("New() = nil, want non-nil")
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		got, err := New(context.Background(), Config{}, make(chan []byte), make(chan []*model.Result))
+		if err == nil {
+			t.Error("New() error = nil, want an error")
+		}
+		if got != nil {
+			t.Errorf("New() = %v, want nil", got)
+		}
+	})
+}
+
+func TestRecognizer_Start(t *testing.T) {
+	// 4 bytes of audio is 2 samples.
+	chunk := 4
+	end := func(chunks int) float64 {
+		return model.AudioDuration(chunk * chunks).Seconds()
+	}
+
+	t.Run("success", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		hypotheses := [][]Segment{
+			{{End: end(1), Text: " A"}, {End: end(2), Text: " B"}},
+			{{End: end(1), Text: " A"}, {End: end(2), Text: " B2"}, {End: end(2), Text: " C"}},
+			{{End: end(1), Text: " B2"}, {End: end(2), Text: " D"}},
+		}
+		transcriber := &TranscriberInterfaceMock{}
+		transcriber.TranscribeFunc = func(ctx context.Context, pcm []byte) ([]Segment, error) {
+			return hypotheses[len(transcriber.TranscribeCalls())-1], nil
+		}
+		audioCh := make(chan []byte)
+		resultCh := make(chan []*model.Result)
+
+		r := &Recognizer{
+			transcriber: transcriber,
+			step:        chunk,
+			maxWindow:   chunk * 100,
+			audioCh:     audioCh,
+			resultCh:    resultCh,
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var err error
+		go func() {
+			defer wg.Done()
+			err = r.Start(ctx)
+		}()
+
+		var gotResults [][]*model.Result
+		for _, audio := range []string{"aaaa", "bbbb", "cccc"} {
+			audioCh <- []byte(audio)
+			gotResults = append(gotResults, <-resultCh)
+		}
+
+		cancel()
+		wg.Wait()
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Recognizer.Start() error = %v, want %v", err, context.Canceled)
+		}
+		wantResults := [][]*model.Result{
+			{{Transcript: "A B", IsFinal: false}},
+			{{Transcript: "A", IsFinal: true}, {Transcript: "B2 C", IsFinal: false}},
+			{{Transcript: "B2", IsFinal: true}, {Transcript: "D", IsFinal: false}},
+		}
+		if diff := cmp.Diff(gotResults, wantResults); diff != "" {
+			t.Errorf("unexpected results (-got +want):\n%s", diff)
+		}
+
+		var gotWindows []string
+		for _, c := range transcriber.TranscribeCalls() {
+			gotWindows = append(gotWindows, string(c.Pcm))
+		}
+		wantWindows := []string{"aaaa", "aaaabbbb", "bbbbcccc"}
+		if diff := cmp.Diff(gotWindows, wantWindows); diff != "" {
+			t.Errorf("unexpected windows (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("window is full", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		transcriber := &TranscriberInterfaceMock{
+			TranscribeFunc: func(ctx context.Context, pcm []byte) ([]Segment, error) {
+				return []Segment{{End: end(1), Text: "A"}, {End: end(2), Text: "B"}}, nil
+			},
+		}
+		audioCh := make(chan []byte)
+		resultCh := make(chan []*model.Result)
+
+		r := &Recognizer{
+			transcriber: transcriber,
+			step:        chunk * 2,
+			maxWindow:   chunk * 2,
+			audioCh:     audioCh,
+			resultCh:    resultCh,
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var err error
+		go func() {
+			defer wg.Done()
+			err = r.Start(ctx)
+		}()
+
+		audioCh <- []byte("aaaabbbb")
+		got := <-resultCh
+
+		cancel()
+		wg.Wait()
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Recognizer.Start() error = %v, want %v", err, context.Canceled)
+		}
+		want := []*model.Result{
+			{Transcript: "A", IsFinal: true},
+			{Transcript: "B", IsFinal: true},
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("unexpected results (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("transcription error", func(t *testing.T) {
+		transcriber := &TranscriberInterfaceMock{
+			TranscribeFunc: func(ctx context.Context, pcm []byte) ([]Segment, error) {
+				return nil, errors.New("error")
+			},
+		}
+		audioCh := make(chan []byte, 1)
+		r := &Recognizer{
+			transcriber: transcriber,
+			step:        chunk,
+			maxWindow:   chunk,
+			audioCh:     audioCh,
+			resultCh:    make(chan []*model.Result),
+		}
+
+		audioCh <- []byte("aaaa")
+		if err := r.Start(context.Background()); err == nil {
+			t.Error("Recognizer.Start() error = nil, want an error")
+		}
+	})
+}
+
+func Test_agree(t *testing.T) {
+	tests := []struct {
+		name string
+		prev []Segment
+		cur  []Segment
+		want int
+	}{
+		{
+			name: "no previous",
+			prev: nil,
+			cur:  []Segment{{Text: "A"}, {Text: "B"}},
+			want: 0,
+		},
+		{
+			name: "leading segments agreed",
+			prev: []Segment{{Text: "A"}, {Text: "B"}, {Text: "C"}},
+			cur:  []Segment{{Text: " A"}, {Text: "B "}, {Text: "X"}, {Text: "D"}},
+			want: 2,
+		},
+		{
+			name: "last segment is not agreed",
+			prev: []Segment{{Text: "A"}, {Text: "B"}},
+			cur:  []Segment{{Text: "A"}, {Text: "B"}},
+			want: 1,
+		},
+		{
+			name: "first segment differs",
+			prev: []Segment{{Text: "A"}, {Text: "B"}},
+			cur:  []Segment{{Text: "X"}, {Text: "B"}, {Text: "C"}},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := agree(tt.prev, tt.cur); got != tt.want {
+				t.Errorf("agree() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package whisper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/hekt/voice-recognition/internal/audio"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+// Segment is a part of a transcription.
+// Start and End are the offsets in seconds from the beginning of the audio.
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+//go:generate moq -rm -out transcriber_mock.go . TranscriberInterface
+type TranscriberInterface interface {
+	// Transcribe transcribes the LINEAR16 audio into segments.
+	Transcribe(ctx context.Context, pcm []byte) ([]Segment, error)
+}
+
+var _ TranscriberInterface = (*HTTPTranscriber)(nil)
+
+// HTTPTranscriber transcribes audio by a whisper.cpp server or an
+// OpenAI-compatible /v1/audio/transcriptions endpoint.
+type HTTPTranscriber struct {
+	client   *http.Client
+	url      string
+	model    string
+	language string
+}
+
+func NewHTTPTranscriber(
+	client *http.Client,
+	url string,
+	model string,
+	language string,
+) (*HTTPTranscriber, error) {
+	if client == nil {
+		return nil, errors.New("http client must be specified")
+	}
+	if url == "" {
+		return nil, errors.New("url must be specified")
+	}
+
+	return &HTTPTranscriber{
+		client:   client,
+		url:      url,
+		model:    model,
+		language: language,
+	}, nil
+}
+
+type transcriptionResponse struct {
+	Text     string    `json:"text"`
+	Segments []Segment `json:"segments"`
+}
+
+func (t *HTTPTranscriber) Transcribe(ctx context.Context, pcm []byte) ([]Segment, error) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	fw, err := w.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := fw.Write(audio.EncodeWAV(pcm)); err != nil {
+		return nil, fmt.Errorf("failed to write audio: %w", err)
+	}
+	fields := map[string]string{
+		"response_format": "verbose_json",
+		"temperature":     "0",
+		"model":           t.model,
+		"language":        t.language,
+	}
+	for k, v := range fields {
+		if v == "" {
+			continue
+		}
+		if err := w.WriteField(k, v); err != nil {
+			return nil, fmt.Errorf("failed to write field %s: %w", k, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+
+	var tr transcriptionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// some servers return only the text.
+	if len(tr.Segments) == 0 && tr.Text != "" {
+		return []Segment{{
+			Start: 0,
+			End:   model.AudioDuration(len(pcm)).Seconds(),
+			Text:  tr.Text,
+		}}, nil
+	}
+
+	return tr.Segments, nil
+}
@@ -0,0 +1,81 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package whisper
+
+import (
+	"context"
+	"sync"
+)
+
+// Ensure, that TranscriberInterfaceMock does implement TranscriberInterface.
+// If this is not the case, regenerate this file with moq.
+var _ TranscriberInterface = &TranscriberInterfaceMock{}
+
+// TranscriberInterfaceMock is a mock implementation of TranscriberInterface.
+//
+//	func TestSomethingThatUsesTranscriberInterface(t *testing.T) {
+//
+//		// make and configure a mocked TranscriberInterface
+//		mockedTranscriberInterface := &TranscriberInterfaceMock{
+//			TranscribeFunc: func(ctx context.Context, pcm []byte) ([]Segment, error) {
+//				panic("mock out the Transcribe method")
+//			},
+//		}
+//
+//		// use mockedTranscriberInterface in code that requires TranscriberInterface
+//		// and then make assertions.
+//
+//	}
+type TranscriberInterfaceMock struct {
+	// TranscribeFunc mocks the Transcribe method.
+	TranscribeFunc func(ctx context.Context, pcm []byte) ([]Segment, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Transcribe holds details about calls to the Transcribe method.
+		Transcribe []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Pcm is the pcm argument value.
+			Pcm []byte
+		}
+	}
+	lockTranscribe sync.RWMutex
+}
+
+// Transcribe calls TranscribeFunc.
+func (mock *TranscriberInterfaceMock) Transcribe(ctx context.Context, pcm []byte) ([]Segment, error) {
+	if mock.TranscribeFunc == nil {
+		panic("TranscriberInterfaceMock.TranscribeFunc: method is nil but TranscriberInterface.Transcribe was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Pcm []byte
+	}{
+		Ctx: ctx,
+		Pcm: pcm,
+	}
+	mock.lockTranscribe.Lock()
+	mock.calls.Transcribe = append(mock.calls.Transcribe, callInfo)
+	mock.lockTranscribe.Unlock()
+	return mock.TranscribeFunc(ctx, pcm)
+}
+
+// TranscribeCalls gets all the calls that were made to Transcribe.
+// Check the length with:
+//
+//	len(mockedTranscriberInterface.TranscribeCalls())
+func (mock *TranscriberInterfaceMock) TranscribeCalls() []struct {
+	Ctx context.Context
+	Pcm []byte
+} {
+	var calls []struct {
+		Ctx context.Context
+		Pcm []byte
+	}
+	mock.lockTranscribe.RLock()
+	calls = mock.calls.Transcribe
+	mock.lockTranscribe.RUnlock()
+	return calls
+}
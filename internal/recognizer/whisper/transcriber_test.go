@@ -0,0 +1,164 @@
+package whisper
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/audio"
+)
+
+func TestNewHTTPTranscriber(t *testing.T) {
+	type args struct {
+		client   *http.Client
+		url      string
+		model    string
+		language string
+	}
+	baseArgs := args{
+		client:   http.DefaultClient,
+		url:      "http://127.0.0.1:8080/inference",
+		model:    "",
+		language: "ja",
+	}
+	tests := []struct {
+		name    string
+		args    func() args
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			args:    func() args { return baseArgs },
+			wantErr: false,
+		},
+		{
+			name: "nil client",
+			args: func() args {
+				a := baseArgs
+				a.client = nil
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty url",
+			args: func() args {
+				a := baseArgs
+				a.url = ""
+				return a
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.args()
+			got, err := NewHTTPTranscriber(a.client, a.url, a.model, a.language)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewHTTPTranscriber() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got == nil {
+				t.Errorf("NewHTTPTranscriber() = nil, want non-nil")
+			}
+		})
+	}
+}
+
+func TestHTTPTranscriber_Transcribe(t *testing.T) {
+	pcm := bytes.Repeat([]byte{1, 2}, 16000)
+
+	tests := []struct {
+		name       string
+		status     int
+		response   string
+		wantFields map[string]string
+		want       []Segment
+		wantErr    bool
+	}{
+		{
+			name:     "success",
+			status:   http.StatusOK,
+			response: `{"text":"こんにちは。世界","segments":[{"start":0,"end":0.5,"text":"こんにちは。"},{"start":0.5,"end":1,"text":"世界"}]}`,
+			wantFields: map[string]string{
+				"response_format": "verbose_json",
+				"temperature":     "0",
+				"model":           "whisper-1",
+				"language":        "ja",
+			},
+			want: []Segment{
+				{Start: 0, End: 0.5, Text: "こんにちは。"},
+				{Start: 0.5, End: 1, Text: "世界"},
+			},
+			wantErr: false,
+		},
+		{
+			name:     "text only",
+			status:   http.StatusOK,
+			response: `{"text":"こんにちは"}`,
+			want:     []Segment{{Start: 0, End: 1, Text: "こんにちは"}},
+			wantErr:  false,
+		},
+		{
+			name:     "error status",
+			status:   http.StatusInternalServerError,
+			response: `failed`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid json",
+			status:   http.StatusOK,
+			response: `{"text":`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseMultipartForm(1 << 20); err != nil {
+					t.Errorf("failed to parse form: %v", err)
+				}
+				for k, want := range tt.wantFields {
+					if got := r.FormValue(k); got != want {
+						t.Errorf("field %s = %q, want %q", k, got, want)
+					}
+				}
+				f, _, err := r.FormFile("file")
+				if err != nil {
+					t.Errorf("failed to get file: %v", err)
+				} else {
+					got, _ := io.ReadAll(f)
+					if !bytes.Equal(got, audio.EncodeWAV(pcm)) {
+						t.Errorf("file is not the WAV of the audio")
+					}
+				}
+
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			transcriber := &HTTPTranscriber{
+				client:   server.Client(),
+				url:      server.URL,
+				model:    "whisper-1",
+				language: "ja",
+			}
+			got, err := transcriber.Transcribe(context.Background(), pcm)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HTTPTranscriber.Transcribe() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("unexpected segments (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
@@ -6,29 +6,12 @@ import (
 )
 
 var (
-	clearScreen = []byte("\033[H\033[2J")
-	greenColor  = []byte("\033[32m")
-	resetColor  = []byte("\033[0m")
-	newLine     = []byte("\n")
+	eraseBelow = []byte("\033[J")
+	greenColor = []byte("\033[32m")
+	resetColor = []byte("\033[0m")
+	newLine    = []byte("\n")
 )
 
-var _ io.Writer = (*DecoratedInterimWriter)(nil)
-
-type DecoratedInterimWriter struct {
-	Writer io.Writer
-	buf    bytes.Buffer
-}
-
-func (w *DecoratedInterimWriter) Write(p []byte) (n int, err error) {
-	w.buf.Reset()
-	w.buf.Write(clearScreen)
-	w.buf.Write(greenColor)
-	w.buf.Write(p)
-	w.buf.Write(resetColor)
-
-	return w.Writer.Write(w.buf.Bytes())
-}
-
 var _ io.Writer = (*DecoratedResultWriter)(nil)
 
 type DecoratedResultWriter struct {
@@ -41,7 +24,11 @@ func (w *DecoratedResultWriter) Write(p []byte) (n int, err error) {
 	w.buf.Write(newLine)
 	w.buf.Write(p)
 
-	return w.Writer.Write(w.buf.Bytes())
+	// the decoration is not counted, so that it works in io.MultiWriter.
+	if _, err := w.Writer.Write(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
 }
 
 var _ io.Writer = (*NotifyingWriter)(nil)
@@ -53,7 +40,13 @@ type NotifyingWriter struct {
 
 func (w *NotifyingWriter) Write(p []byte) (n int, err error) {
 	defer func() {
-		w.NotifyCh <- struct{}{}
+		// the notification is dropped if the previous one is not received yet,
+		// since it only tells that something is written. blocking here hangs
+		// the pending result written on shutdown after the receiver stopped.
+		select {
+		case w.NotifyCh <- struct{}{}:
+		default:
+		}
 	}()
 	return w.Writer.Write(p)
 }
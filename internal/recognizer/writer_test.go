@@ -6,12 +6,12 @@ import (
 	"testing"
 )
 
-func TestDecoratedInterimWriter_Write(t *testing.T) {
-	wantFormat := "\033[H\033[2J" + "\033[32m" + "%s" + "\033[0m"
+func TestDecoratedResultWriter_Write(t *testing.T) {
+	wantFormat := "\n%s"
 
 	t.Run("write once", func(t *testing.T) {
 		buf := &bytes.Buffer{}
-		w := &DecoratedInterimWriter{Writer: buf}
+		w := &DecoratedResultWriter{Writer: buf}
 		if _, err := w.Write([]byte("test")); err != nil {
 			t.Errorf("Write() error = %v, wantErr %v", err, false)
 		}
@@ -24,7 +24,7 @@ func TestDecoratedInterimWriter_Write(t *testing.T) {
 
 	t.Run("write twice", func(t *testing.T) {
 		buf := &bytes.Buffer{}
-		w := &DecoratedInterimWriter{Writer: buf}
+		w := &DecoratedResultWriter{Writer: buf}
 		if _, err := w.Write([]byte("test1")); err != nil {
 			t.Errorf("Write() error = %v, wantErr %v", err, false)
 		}
@@ -38,43 +38,33 @@ func TestDecoratedInterimWriter_Write(t *testing.T) {
 		}
 	})
 }
-func TestDecoratedResultWriter_Write(t *testing.T) {
-	wantFormat := "\n%s"
-
-	t.Run("write once", func(t *testing.T) {
-		buf := &bytes.Buffer{}
-		w := &DecoratedResultWriter{Writer: buf}
-		if _, err := w.Write([]byte("test")); err != nil {
-			t.Errorf("Write() error = %v, wantErr %v", err, false)
-		}
 
-		want := fmt.Sprintf(wantFormat, "test")
-		if got := buf.String(); got != want {
-			t.Errorf("Write() writes %v, want %v", got, want)
+func TestNotifyingWriter_Write(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		writer := &bytes.Buffer{}
+		notifyCh := make(chan struct{}, 2)
+		w := &NotifyingWriter{
+			Writer:   writer,
+			NotifyCh: notifyCh,
 		}
-	})
 
-	t.Run("write twice", func(t *testing.T) {
-		buf := &bytes.Buffer{}
-		w := &DecoratedResultWriter{Writer: buf}
 		if _, err := w.Write([]byte("test1")); err != nil {
 			t.Errorf("Write() error = %v, wantErr %v", err, false)
 		}
 		if _, err := w.Write([]byte("test2")); err != nil {
 			t.Errorf("Write() error = %v, wantErr %v", err, false)
 		}
-
-		want := fmt.Sprintf(wantFormat, "test1") + fmt.Sprintf(wantFormat, "test2")
-		if got := buf.String(); got != want {
+		if got, want := writer.String(), "test1test2"; got != want {
 			t.Errorf("Write() writes %v, want %v", got, want)
 		}
+		if got, want := len(notifyCh), 2; got != want {
+			t.Errorf("NotifyCh length %v, want %v", got, want)
+		}
 	})
-}
 
-func TestNotifyingWriter_Write(t *testing.T) {
-	t.Run("success", func(t *testing.T) {
+	t.Run("does not block when notification is pending", func(t *testing.T) {
 		writer := &bytes.Buffer{}
-		notifyCh := make(chan struct{}, 2)
+		notifyCh := make(chan struct{}, 1)
 		w := &NotifyingWriter{
 			Writer:   writer,
 			NotifyCh: notifyCh,
@@ -89,7 +79,7 @@ func TestNotifyingWriter_Write(t *testing.T) {
 		if got, want := writer.String(), "test1test2"; got != want {
 			t.Errorf("Write() writes %v, want %v", got, want)
 		}
-		if got, want := len(notifyCh), 2; got != want {
+		if got, want := len(notifyCh), 1; got != want {
 			t.Errorf("NotifyCh length %v, want %v", got, want)
 		}
 	})
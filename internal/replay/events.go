@@ -0,0 +1,77 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Event is a disturbance injected at the position of the audio.
+type Event struct {
+	// At is the offset from the beginning of the recorded audio.
+	At time.Duration
+	// Gap is the duration of the silence inserted, e.g. to test the inactive timeout.
+	Gap time.Duration
+	// Stall is the duration to deliver nothing as a stuck capture does,
+	// e.g. to test the reconnection while the audio stops.
+	Stall time.Duration
+}
+
+// LoadEvents reads the events from the file. See ParseEvents for the format.
+func LoadEvents(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open events: %w", err)
+	}
+	defer f.Close()
+
+	return ParseEvents(f)
+}
+
+// ParseEvents parses the events which have an event in JSON for each line,
+// e.g. {"at": 10, "gap": 5} or {"at": 20, "stall": 70}.
+// The durations are in seconds and the events must be in order of at.
+func ParseEvents(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var e struct {
+			At    float64 `json:"at"`
+			Gap   float64 `json:"gap"`
+			Stall float64 `json:"stall"`
+		}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", n, err)
+		}
+
+		event := Event{
+			At:    time.Duration(e.At * float64(time.Second)),
+			Gap:   time.Duration(e.Gap * float64(time.Second)),
+			Stall: time.Duration(e.Stall * float64(time.Second)),
+		}
+		if event.At < 0 || event.Gap < 0 || event.Stall < 0 {
+			return nil, fmt.Errorf("negative duration in line %d", n)
+		}
+		if event.Gap == 0 && event.Stall == 0 {
+			return nil, fmt.Errorf("gap or stall must be specified in line %d", n)
+		}
+		if len(events) > 0 && event.At < events[len(events)-1].At {
+			return nil, fmt.Errorf("event in line %d is before the previous one", n)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+
+	return events, nil
+}
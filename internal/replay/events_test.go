@@ -0,0 +1,67 @@
+package replay
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  string
+		want    []Event
+		wantErr bool
+	}{
+		{
+			name: "success",
+			events: `{"at": 1.5, "gap": 5}
+# comment
+
+{"at": 1.5, "stall": 70}
+{"at": 3, "gap": 1, "stall": 2}
+`,
+			want: []Event{
+				{At: 1500 * time.Millisecond, Gap: 5 * time.Second},
+				{At: 1500 * time.Millisecond, Stall: 70 * time.Second},
+				{At: 3 * time.Second, Gap: time.Second, Stall: 2 * time.Second},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "no gap and stall",
+			events:  `{"at": 1}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative duration",
+			events:  `{"at": 1, "gap": -1}`,
+			wantErr: true,
+		},
+		{
+			name: "out of order",
+			events: `{"at": 2, "gap": 1}
+{"at": 1, "gap": 1}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			events:  `{"at": 0`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEvents(strings.NewReader(tt.events))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseEvents() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("ParseEvents() (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
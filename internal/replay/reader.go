@@ -0,0 +1,143 @@
+// Package replay feeds recorded audio to the pipeline with the pacing of a live capture.
+package replay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/clock"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+var _ io.Reader = (*PacedReader)(nil)
+
+// PacedReader reads the LINEAR16 audio at the speed relative to real time as
+// a live capture delivers it, injecting the events of the script.
+type PacedReader struct {
+	ctx    context.Context
+	reader io.Reader
+	// speed is the speed relative to real time. 0 means as fast as possible.
+	speed  float64
+	events []Event
+	clock  clock.Clock
+
+	// start is the time when the audio at the beginning is due. It is shifted by the stalls.
+	start   time.Time
+	started bool
+	// read is the bytes of the audio read from the reader.
+	read int
+	// delivered is the bytes delivered including the silence of the gaps.
+	delivered int
+	// silence is the bytes of the silence of the gap left to deliver.
+	silence int
+}
+
+// NewPacedReader creates a reader which paces r. Read returns the error of
+// ctx when it is done while waiting.
+func NewPacedReader(
+	ctx context.Context,
+	r io.Reader,
+	speed float64,
+	events []Event,
+	clock clock.Clock,
+) (*PacedReader, error) {
+	if r == nil {
+		return nil, errors.New("reader must be specified")
+	}
+	if speed < 0 {
+		return nil, errors.New("speed must not be negative")
+	}
+	if clock == nil {
+		return nil, errors.New("clock must be specified")
+	}
+
+	return &PacedReader{
+		ctx:    ctx,
+		reader: r,
+		speed:  speed,
+		events: events,
+		clock:  clock,
+	}, nil
+}
+
+func (r *PacedReader) Read(p []byte) (int, error) {
+	if !r.started {
+		r.start = r.clock.Now()
+		r.started = true
+	}
+
+	if err := r.applyEvents(); err != nil {
+		return 0, err
+	}
+	if err := r.wait(); err != nil {
+		return 0, err
+	}
+
+	// keep the samples aligned for the backends.
+	size := len(p) - len(p)%model.BytesPerSample
+	if size == 0 {
+		return 0, errors.New("buffer is smaller than a sample")
+	}
+
+	if r.silence > 0 {
+		n := min(size, r.silence)
+		clear(p[:n])
+		r.silence -= n
+		r.delivered += n
+		return n, nil
+	}
+
+	// stop at the next event to inject it at the exact position.
+	if len(r.events) > 0 {
+		size = min(size, model.AudioBytes(r.events[0].At)-r.read)
+	}
+	n, err := r.reader.Read(p[:size])
+	r.read += n
+	r.delivered += n
+	return n, err
+}
+
+// applyEvents applies the events which have come at the position of the audio read.
+func (r *PacedReader) applyEvents() error {
+	for len(r.events) > 0 && model.AudioBytes(r.events[0].At) <= r.read {
+		e := r.events[0]
+		r.events = r.events[1:]
+
+		r.silence += model.AudioBytes(e.Gap)
+		if e.Stall > 0 {
+			if err := r.sleep(e.Stall); err != nil {
+				return err
+			}
+			r.start = r.start.Add(e.Stall)
+		}
+	}
+	return nil
+}
+
+// wait waits until the audio delivered so far is due.
+func (r *PacedReader) wait() error {
+	if r.speed == 0 {
+		return nil
+	}
+
+	due := r.start.Add(time.Duration(float64(model.AudioDuration(r.delivered)) / r.speed))
+	return r.sleep(due.Sub(r.clock.Now()))
+}
+
+func (r *PacedReader) sleep(d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := r.clock.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-r.ctx.Done():
+		return r.ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
@@ -0,0 +1,192 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/clock"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+func TestNewPacedReader(t *testing.T) {
+	type args struct {
+		r     io.Reader
+		speed float64
+		clock clock.Clock
+	}
+	baseArgs := args{
+		r:     &bytes.Buffer{},
+		speed: 1,
+		clock: testutil.NewFakeClock(time.Now()),
+	}
+	tests := []struct {
+		name    string
+		args    func() args
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			args:    func() args { return baseArgs },
+			wantErr: false,
+		},
+		{
+			name: "max speed",
+			args: func() args {
+				a := baseArgs
+				a.speed = 0
+				return a
+			},
+			wantErr: false,
+		},
+		{
+			name: "nil reader",
+			args: func() args {
+				a := baseArgs
+				a.r = nil
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative speed",
+			args: func() args {
+				a := baseArgs
+				a.speed = -1
+				return a
+			},
+			wantErr: true,
+		},
+		{
+			name: "nil clock",
+			args: func() args {
+				a := baseArgs
+				a.clock = nil
+				return a
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.args()
+			_, err := NewPacedReader(context.Background(), a.r, a.speed, nil, a.clock)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPacedReader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPacedReader_Read(t *testing.T) {
+	second := model.AudioBytes(time.Second)
+
+	t.Run("paced", func(t *testing.T) {
+		clock := testutil.NewFakeClock(time.Now())
+		audio := bytes.Repeat([]byte{1}, 2*second)
+		r, err := NewPacedReader(context.Background(), bytes.NewReader(audio), 2, nil, clock)
+		if err != nil {
+			t.Fatalf("NewPacedReader() error = %v", err)
+		}
+
+		// the first second is due immediately.
+		buf := make([]byte, second)
+		if n, err := r.Read(buf); n != second || err != nil {
+			t.Fatalf("Read() = %d, %v, want %d, nil", n, err, second)
+		}
+
+		// the next second is due in 0.5 seconds at double speed.
+		readCh := readAsync(r, buf)
+		clock.BlockUntil(1)
+		if n := clock.Advance(499 * time.Millisecond); n != 0 {
+			t.Error("read before the audio is due")
+		}
+		clock.Advance(time.Millisecond)
+		if got := <-readCh; got.n != second || got.err != nil {
+			t.Errorf("Read() = %d, %v, want %d, nil", got.n, got.err, second)
+		}
+	})
+
+	t.Run("gap", func(t *testing.T) {
+		clock := testutil.NewFakeClock(time.Now())
+		audio := []byte{1, 1, 2, 2}
+		events := []Event{{At: model.AudioDuration(2), Gap: model.AudioDuration(4)}}
+		r, err := NewPacedReader(context.Background(), bytes.NewReader(audio), 0, events, clock)
+		if err != nil {
+			t.Fatalf("NewPacedReader() error = %v", err)
+		}
+
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+		if want := []byte{1, 1, 0, 0, 0, 0, 2, 2}; !bytes.Equal(got, want) {
+			t.Errorf("ReadAll() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("stall", func(t *testing.T) {
+		clock := testutil.NewFakeClock(time.Now())
+		audio := bytes.Repeat([]byte{1}, 2*second)
+		events := []Event{{At: time.Second, Stall: time.Minute}}
+		r, err := NewPacedReader(context.Background(), bytes.NewReader(audio), 1, events, clock)
+		if err != nil {
+			t.Fatalf("NewPacedReader() error = %v", err)
+		}
+
+		buf := make([]byte, 2*second)
+		// stops at the event.
+		if n, err := r.Read(buf); n != second || err != nil {
+			t.Fatalf("Read() = %d, %v, want %d, nil", n, err, second)
+		}
+
+		readCh := readAsync(r, buf)
+		clock.BlockUntil(1)
+		if n := clock.Advance(time.Minute); n != 1 {
+			t.Error("stall did not end")
+		}
+		// the audio after the stall is due a second after the stall ends.
+		clock.BlockUntil(1)
+		if n := clock.Advance(time.Second); n != 1 {
+			t.Error("read before the audio is due")
+		}
+		if got := <-readCh; got.n != second || got.err != nil {
+			t.Errorf("Read() = %d, %v, want %d, nil", got.n, got.err, second)
+		}
+	})
+
+	t.Run("canceled while waiting", func(t *testing.T) {
+		clock := testutil.NewFakeClock(time.Now())
+		ctx, cancel := context.WithCancel(context.Background())
+		events := []Event{{At: 0, Stall: time.Minute}}
+		r, err := NewPacedReader(ctx, bytes.NewReader(make([]byte, second)), 1, events, clock)
+		if err != nil {
+			t.Fatalf("NewPacedReader() error = %v", err)
+		}
+
+		readCh := readAsync(r, make([]byte, second))
+		clock.BlockUntil(1)
+		cancel()
+		if got := <-readCh; !errors.Is(got.err, context.Canceled) {
+			t.Errorf("Read() error = %v, want %v", got.err, context.Canceled)
+		}
+	})
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+func readAsync(r io.Reader, buf []byte) <-chan readResult {
+	ch := make(chan readResult, 1)
+	go func() {
+		n, err := r.Read(buf)
+		ch <- readResult{n, err}
+	}()
+	return ch
+}
@@ -21,6 +21,8 @@ type CreateRecognizerArgs struct {
 	Model          string
 	LanguageCode   string
 	PhraseSet      string
+	// Diarization is the default speaker diarization. nil if it is disabled.
+	Diarization *speechpb.SpeakerDiarizationConfig
 }
 
 type DeleteRecognizerArgs struct {
@@ -71,6 +73,7 @@ func (m *recognizerManager) Create(ctx context.Context, args CreateRecognizerArg
 				},
 				Features: &speechpb.RecognitionFeatures{
 					EnableAutomaticPunctuation: true,
+					DiarizationConfig:          args.Diarization,
 				},
 				Adaptation: &speechpb.SpeechAdaptation{
 					PhraseSets: phraseSets,
@@ -66,6 +66,10 @@ func Test_recognizerManager_Create(t *testing.T) {
 					Model:          "model",
 					LanguageCode:   "language-code",
 					PhraseSet:      "phrase-set",
+					Diarization: &speechpb.SpeakerDiarizationConfig{
+						MinSpeakerCount: 2,
+						MaxSpeakerCount: 3,
+					},
 				},
 			},
 			wantErr: false,
@@ -0,0 +1,399 @@
+// Package server serves the recognition of multiple sessions, e.g. meeting
+// rooms, over HTTP. Each session runs its own pipeline, receives the audio
+// and pushes the results on a WebSocket.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/file"
+	"github.com/hekt/voice-recognition/internal/postprocess"
+	"github.com/hekt/voice-recognition/internal/recognizer"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"golang.org/x/net/websocket"
+)
+
+// Config is the config of the server.
+type Config struct {
+	// Backends are the builders of the backends which the sessions select by
+	// name. Each session builds its own backend so that the state of the
+	// backend, such as the words kept by Vosk, is not mixed with the others.
+	Backends map[string]model.RecognizerCoreFactoryBuilder
+	// DefaultBackend is the backend of the sessions which do not select one.
+	DefaultBackend string
+	// PostProcess is the resources of the processors which the sessions select by name.
+	PostProcess postprocess.Options
+	// DefaultPostProcess is the processors of the sessions which do not select them.
+	DefaultPostProcess []string
+	// CheckPostProcess returns an error if the processors cannot be used with
+	// the backend. It is optional.
+	CheckPostProcess func(backend string, names []string) error
+	// MaxSessions is the maximum number of the running sessions.
+	MaxSessions int
+	// MaxDuration is the maximum duration of a session. 0 means unlimited.
+	MaxDuration time.Duration
+	// OutputDir is the directory to write the final results of each session to <id>.txt.
+	OutputDir string
+
+	// BufferSize and InactiveTimeout are passed to the pipeline of each session.
+	BufferSize      int
+	InactiveTimeout time.Duration
+}
+
+// Server manages the sessions and serves the API.
+type Server struct {
+	config Config
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	// reserved is the number of the sessions whose backend is being built,
+	// which are counted in MaxSessions.
+	reserved int
+}
+
+func NewServer(config Config) (*Server, error) {
+	if len(config.Backends) == 0 {
+		return nil, errors.New("backends must be specified")
+	}
+	if _, ok := config.Backends[config.DefaultBackend]; !ok {
+		return nil, fmt.Errorf("default backend %q is not in the backends", config.DefaultBackend)
+	}
+	if _, err := postprocess.Build(config.DefaultPostProcess, config.PostProcess); err != nil {
+		return nil, fmt.Errorf("invalid default post-processing: %w", err)
+	}
+	if config.CheckPostProcess != nil {
+		if err := config.CheckPostProcess(config.DefaultBackend, config.DefaultPostProcess); err != nil {
+			return nil, fmt.Errorf("invalid default post-processing: %w", err)
+		}
+	}
+	if config.MaxSessions <= 0 {
+		return nil, errors.New("max sessions must be positive")
+	}
+	if config.MaxDuration < 0 {
+		return nil, errors.New("max duration must not be negative")
+	}
+	if config.OutputDir == "" {
+		return nil, errors.New("output directory must be specified")
+	}
+	if err := os.MkdirAll(config.OutputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		config:   config,
+		ctx:      ctx,
+		cancel:   cancel,
+		sessions: map[string]*session{},
+	}, nil
+}
+
+// Handler returns the handler of the API:
+//
+//   - POST /sessions creates a session. The body is {"backend": "vosk", "postprocess": ["fillers"]},
+//     whose fields can be omitted.
+//   - GET /sessions lists the sessions.
+//   - GET /sessions/{id} returns the session.
+//   - DELETE /sessions/{id} stops the session.
+//   - GET /sessions/{id}/ws is the WebSocket which receives the audio in
+//     binary messages and pushes the events as JSON.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /sessions", s.handleCreate)
+	mux.HandleFunc("GET /sessions", s.handleList)
+	mux.HandleFunc("GET /sessions/{id}", s.handleGet)
+	mux.HandleFunc("DELETE /sessions/{id}", s.handleStop)
+	mux.HandleFunc("GET /sessions/{id}/ws", s.handleWebSocket)
+	return mux
+}
+
+// Close stops all sessions and waits for them.
+func (s *Server) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+type createRequest struct {
+	Backend     string   `json:"backend"`
+	PostProcess []string `json:"postprocess"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+var (
+	errTooManySessions = errors.New("too many sessions")
+	errUnknownBackend  = errors.New("unknown backend")
+	errPostProcess     = errors.New("invalid post-processing")
+)
+
+// Create starts a session with the backend and the processors of the results.
+// The default backend is used if it is empty, and so are the default
+// processors if they are nil.
+func (s *Server) Create(backend string, postProcess []string) (SessionInfo, error) {
+	if backend == "" {
+		backend = s.config.DefaultBackend
+	}
+	newBackend, ok := s.config.Backends[backend]
+	if !ok {
+		return SessionInfo{}, fmt.Errorf("%w %q", errUnknownBackend, backend)
+	}
+	if postProcess == nil {
+		postProcess = s.config.DefaultPostProcess
+	}
+	if s.config.CheckPostProcess != nil {
+		if err := s.config.CheckPostProcess(backend, postProcess); err != nil {
+			return SessionInfo{}, fmt.Errorf("%w: %w", errPostProcess, err)
+		}
+	}
+	chain, err := postprocess.Build(postProcess, s.config.PostProcess)
+	if err != nil {
+		return SessionInfo{}, fmt.Errorf("%w: %w", errPostProcess, err)
+	}
+	if err := s.reserve(); err != nil {
+		return SessionInfo{}, err
+	}
+	// the backend is built out of the lock since it may load a model.
+	newCore, cleanup, err := newBackend()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reserved--
+	if err != nil {
+		return SessionInfo{}, fmt.Errorf("failed to build backend %s: %w", backend, err)
+	}
+	if s.ctx.Err() != nil {
+		cleanup()
+		return SessionInfo{}, errors.New("server is closed")
+	}
+
+	id, err := newID()
+	if err != nil {
+		cleanup()
+		return SessionInfo{}, err
+	}
+	info := SessionInfo{
+		ID:          id,
+		Backend:     backend,
+		PostProcess: postProcess,
+		CreatedAt:   time.Now(),
+		Transcript:  filepath.Join(s.config.OutputDir, id+".txt"),
+		WebSocket:   "/sessions/" + id + "/ws",
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if s.config.MaxDuration > 0 {
+		ctx, cancel = context.WithTimeout(s.ctx, s.config.MaxDuration)
+	} else {
+		ctx, cancel = context.WithCancel(s.ctx)
+	}
+	sess := newSession(info, cancel)
+
+	transcript, err := file.OpenFileWriter(info.Transcript, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.FileMode(0o644))
+	if err != nil {
+		cancel()
+		cleanup()
+		return SessionInfo{}, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	pipeline, err := recognizer.NewPipeline(ctx, sess.wrap(chain.Wrap(newCore)), recognizer.PipelineConfig{
+		BufferSize:      s.config.BufferSize,
+		InactiveTimeout: s.config.InactiveTimeout,
+		AudioReader:     sess.audioReader,
+		ResultWriter:    transcript,
+		InterimWriter:   io.Discard,
+	})
+	if err != nil {
+		cancel()
+		transcript.Close()
+		cleanup()
+		return SessionInfo{}, fmt.Errorf("failed to create recognizer: %w", err)
+	}
+
+	s.sessions[id] = sess
+	s.wg.Add(1)
+	go s.run(ctx, sess, pipeline, transcript, cleanup)
+
+	slog.Info(fmt.Sprintf("Server: session %s started with %s", id, backend))
+	return info, nil
+}
+
+// reserve reserves a slot of MaxSessions for the session being created.
+func (s *Server) reserve() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return errors.New("server is closed")
+	}
+	if len(s.sessions)+s.reserved >= s.config.MaxSessions {
+		return fmt.Errorf("%w, the maximum is %d", errTooManySessions, s.config.MaxSessions)
+	}
+	s.reserved++
+	return nil
+}
+
+// run runs the pipeline of the session until it stops. cleanup releases the
+// backend of the session after that.
+func (s *Server) run(
+	ctx context.Context,
+	sess *session,
+	pipeline *recognizer.Recognizer,
+	transcript *file.FileWriter,
+	cleanup func(),
+) {
+	defer s.wg.Done()
+	defer close(sess.done)
+	defer sess.cancel()
+	defer cleanup()
+	defer func() {
+		if err := transcript.Close(); err != nil {
+			slog.Error(fmt.Sprintf("Server: failed to close transcript of session %s: %v", sess.info.ID, err))
+		}
+	}()
+
+	err := pipeline.Start(ctx)
+	switch {
+	case errors.Is(err, context.Canceled):
+		err = nil
+	case errors.Is(err, context.DeadlineExceeded):
+		err = errors.New("session exceeded the maximum duration")
+	}
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Server: session %s stopped: %v", sess.info.ID, err))
+	} else {
+		slog.Info(fmt.Sprintf("Server: session %s stopped", sess.info.ID))
+	}
+
+	s.mu.Lock()
+	delete(s.sessions, sess.info.ID)
+	s.mu.Unlock()
+
+	sess.end(err)
+}
+
+// List returns the running sessions in the order of creation.
+func (s *Server) List() []SessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]SessionInfo, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		infos = append(infos, sess.info)
+	}
+	slices.SortFunc(infos, func(a, b SessionInfo) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return infos
+}
+
+func (s *Server) session(id string) (*session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	return sess, ok
+}
+
+// Stop stops the session and waits for it. It returns false if the session is not running.
+func (s *Server) Stop(id string) bool {
+	sess, ok := s.session(id)
+	if !ok {
+		return false
+	}
+	sess.cancel()
+	<-sess.done
+	return true
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	info, err := s.Create(req.Backend, req.PostProcess)
+	switch {
+	case errors.Is(err, errUnknownBackend), errors.Is(err, errPostProcess):
+		writeError(w, http.StatusBadRequest, err)
+	case errors.Is(err, errTooManySessions):
+		writeError(w, http.StatusTooManyRequests, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		writeJSON(w, http.StatusCreated, info)
+	}
+}
+
+func (s *Server) handleList(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.List())
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("session not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, sess.info)
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if !s.Stop(r.PathValue("id")) {
+		writeError(w, http.StatusNotFound, errors.New("session not found"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("session not found"))
+		return
+	}
+	websocket.Server{Handler: sess.serve}.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug(fmt.Sprintf("Server: failed to write response: %v", err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
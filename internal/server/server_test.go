@@ -0,0 +1,430 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/postprocess"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"golang.org/x/net/websocket"
+)
+
+// newEchoCore returns the factory of the core which returns the audio as an
+// interim result and a final result.
+func newEchoCore(
+	_ context.Context,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (model.RecognizerCoreInterface, error) {
+	return &model.RecognizerCoreInterfaceMock{
+		StartFunc: func(ctx context.Context) error {
+			for {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case audio := <-audioCh:
+					for _, results := range [][]*model.Result{
+						{{Transcript: string(audio), Backend: "echo"}},
+						{{Transcript: string(audio), IsFinal: true, Backend: "echo"}},
+					} {
+						select {
+						case <-ctx.Done():
+							return ctx.Err()
+						case resultCh <- results:
+						}
+					}
+				}
+			}
+		},
+	}, nil
+}
+
+// staticBackend returns a builder which always builds newCore.
+func staticBackend(newCore model.RecognizerCoreFactory) model.RecognizerCoreFactoryBuilder {
+	return func() (model.RecognizerCoreFactory, func(), error) {
+		return newCore, func() {}, nil
+	}
+}
+
+// newConcatBackend returns a builder of the backend which returns all audio
+// received so far as a final result. The audio is kept in the backend, so the
+// results would be mixed if the sessions shared the backend. cleaned is
+// closed when every backend built is cleaned up.
+func newConcatBackend(n int) (builder model.RecognizerCoreFactoryBuilder, cleaned <-chan struct{}) {
+	var mu sync.Mutex
+	done := make(chan struct{})
+	return func() (model.RecognizerCoreFactory, func(), error) {
+		var received []byte
+		newCore := func(
+			_ context.Context,
+			audioCh <-chan []byte,
+			resultCh chan<- []*model.Result,
+		) (model.RecognizerCoreInterface, error) {
+			return &model.RecognizerCoreInterfaceMock{
+				StartFunc: func(ctx context.Context) error {
+					for {
+						select {
+						case <-ctx.Done():
+							return ctx.Err()
+						case audio := <-audioCh:
+							received = append(received, audio...)
+							select {
+							case <-ctx.Done():
+								return ctx.Err()
+							case resultCh <- []*model.Result{{Transcript: string(received), IsFinal: true}}:
+							}
+						}
+					}
+				},
+			}, nil
+		}
+		return newCore, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			n--
+			if n == 0 {
+				close(done)
+			}
+		}, nil
+	}, done
+}
+
+func newTestConfig(t *testing.T) Config {
+	return Config{
+		Backends:        map[string]model.RecognizerCoreFactoryBuilder{"echo": staticBackend(newEchoCore)},
+		DefaultBackend:  "echo",
+		MaxSessions:     1,
+		OutputDir:       t.TempDir(),
+		BufferSize:      1024,
+		InactiveTimeout: time.Minute,
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			modify:  func(c *Config) {},
+			wantErr: false,
+		},
+		{
+			name:    "no backends",
+			modify:  func(c *Config) { c.Backends = nil },
+			wantErr: true,
+		},
+		{
+			name:    "unknown default backend",
+			modify:  func(c *Config) { c.DefaultBackend = "unknown" },
+			wantErr: true,
+		},
+		{
+			name:    "unknown default post-processing",
+			modify:  func(c *Config) { c.DefaultPostProcess = []string{"unknown"} },
+			wantErr: true,
+		},
+		{
+			name: "default post-processing rejected",
+			modify: func(c *Config) {
+				c.DefaultPostProcess = []string{"whitespace"}
+				c.CheckPostProcess = func(string, []string) error { return errors.New("rejected") }
+			},
+			wantErr: true,
+		},
+		{
+			name:    "no max sessions",
+			modify:  func(c *Config) { c.MaxSessions = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "negative max duration",
+			modify:  func(c *Config) { c.MaxDuration = -time.Second },
+			wantErr: true,
+		},
+		{
+			name:    "no output directory",
+			modify:  func(c *Config) { c.OutputDir = "" },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(t)
+			tt.modify(&config)
+			s, err := NewServer(config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewServer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if s != nil {
+				s.Close()
+			}
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		s, err := NewServer(newTestConfig(t))
+		if err != nil {
+			t.Fatalf("NewServer() error = %v", err)
+		}
+		defer s.Close()
+		ts := httptest.NewServer(s.Handler())
+		defer ts.Close()
+
+		var info SessionInfo
+		request(t, http.MethodPost, ts.URL+"/sessions", `{"backend": "echo"}`, http.StatusCreated, &info)
+		if info.Backend != "echo" || info.WebSocket != "/sessions/"+info.ID+"/ws" {
+			t.Errorf("unexpected session: %+v", info)
+		}
+
+		var list []SessionInfo
+		request(t, http.MethodGet, ts.URL+"/sessions", "", http.StatusOK, &list)
+		if diff := cmp.Diff(list, []SessionInfo{info}); diff != "" {
+			t.Errorf("list (-got +want):\n%s", diff)
+		}
+		var got SessionInfo
+		request(t, http.MethodGet, ts.URL+"/sessions/"+info.ID, "", http.StatusOK, &got)
+		if diff := cmp.Diff(got, info); diff != "" {
+			t.Errorf("get (-got +want):\n%s", diff)
+		}
+
+		// the limits.
+		request(t, http.MethodPost, ts.URL+"/sessions", "", http.StatusTooManyRequests, nil)
+		request(t, http.MethodPost, ts.URL+"/sessions", `{"backend": "unknown"}`, http.StatusBadRequest, nil)
+
+		ws := dial(t, ts, info.WebSocket)
+		defer ws.Close()
+		if err := websocket.Message.Send(ws, []byte("hello")); err != nil {
+			t.Fatalf("failed to send audio: %v", err)
+		}
+		wantEvents := []Event{
+			{Type: EventInterim, Text: "hello", Backend: "echo"},
+			{Type: EventFinal, Text: "hello", Backend: "echo"},
+		}
+		if diff := cmp.Diff(receive(t, ws, 2), wantEvents); diff != "" {
+			t.Errorf("events (-got +want):\n%s", diff)
+		}
+
+		request(t, http.MethodDelete, ts.URL+"/sessions/"+info.ID, "", http.StatusNoContent, nil)
+		if diff := cmp.Diff(receive(t, ws, 1), []Event{{Type: EventEnd}}); diff != "" {
+			t.Errorf("events (-got +want):\n%s", diff)
+		}
+		request(t, http.MethodGet, ts.URL+"/sessions/"+info.ID, "", http.StatusNotFound, nil)
+		request(t, http.MethodDelete, ts.URL+"/sessions/"+info.ID, "", http.StatusNotFound, nil)
+
+		transcript, err := os.ReadFile(info.Transcript)
+		if err != nil {
+			t.Fatalf("failed to read transcript: %v", err)
+		}
+		if got, want := string(transcript), "\n[echo] hello"; got != want {
+			t.Errorf("transcript = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("post-processing", func(t *testing.T) {
+		config := newTestConfig(t)
+		config.PostProcess = postprocess.Options{Dictionary: map[string]string{"hello": "こんにちは"}}
+		config.CheckPostProcess = func(_ string, names []string) error {
+			if slices.Contains(names, postprocess.NamePunctuation) {
+				return errors.New("punctuated by the backend")
+			}
+			return nil
+		}
+		s, err := NewServer(config)
+		if err != nil {
+			t.Fatalf("NewServer() error = %v", err)
+		}
+		defer s.Close()
+		ts := httptest.NewServer(s.Handler())
+		defer ts.Close()
+
+		request(t, http.MethodPost, ts.URL+"/sessions", `{"postprocess": ["unknown"]}`, http.StatusBadRequest, nil)
+		request(t, http.MethodPost, ts.URL+"/sessions", `{"postprocess": ["punctuation"]}`, http.StatusBadRequest, nil)
+		var info SessionInfo
+		request(t, http.MethodPost, ts.URL+"/sessions", `{"postprocess": ["dictionary", "whitespace"]}`, http.StatusCreated, &info)
+		if diff := cmp.Diff(info.PostProcess, []string{"dictionary", "whitespace"}); diff != "" {
+			t.Errorf("post-processing (-got +want):\n%s", diff)
+		}
+
+		ws := dial(t, ts, info.WebSocket)
+		defer ws.Close()
+		if err := websocket.Message.Send(ws, []byte(" hello ")); err != nil {
+			t.Fatalf("failed to send audio: %v", err)
+		}
+		wantEvents := []Event{
+			{Type: EventInterim, Text: "こんにちは", Backend: "echo"},
+			{Type: EventFinal, Text: "こんにちは", Backend: "echo"},
+		}
+		if diff := cmp.Diff(receive(t, ws, 2), wantEvents); diff != "" {
+			t.Errorf("events (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("backend for each session", func(t *testing.T) {
+		newBackend, cleaned := newConcatBackend(2)
+		config := newTestConfig(t)
+		config.Backends["concat"] = newBackend
+		config.MaxSessions = 2
+		s, err := NewServer(config)
+		if err != nil {
+			t.Fatalf("NewServer() error = %v", err)
+		}
+		defer s.Close()
+		ts := httptest.NewServer(s.Handler())
+		defer ts.Close()
+
+		var a, b SessionInfo
+		request(t, http.MethodPost, ts.URL+"/sessions", `{"backend": "concat"}`, http.StatusCreated, &a)
+		request(t, http.MethodPost, ts.URL+"/sessions", `{"backend": "concat"}`, http.StatusCreated, &b)
+		wsA := dial(t, ts, a.WebSocket)
+		defer wsA.Close()
+		wsB := dial(t, ts, b.WebSocket)
+		defer wsB.Close()
+
+		for _, step := range []struct {
+			ws    *websocket.Conn
+			audio string
+			want  string
+		}{
+			{ws: wsA, audio: "a", want: "a"},
+			{ws: wsB, audio: "b", want: "b"},
+			{ws: wsA, audio: "c", want: "ac"},
+		} {
+			if err := websocket.Message.Send(step.ws, []byte(step.audio)); err != nil {
+				t.Fatalf("failed to send audio: %v", err)
+			}
+			if diff := cmp.Diff(receive(t, step.ws, 1), []Event{{Type: EventFinal, Text: step.want}}); diff != "" {
+				t.Errorf("events (-got +want):\n%s", diff)
+			}
+		}
+
+		request(t, http.MethodDelete, ts.URL+"/sessions/"+a.ID, "", http.StatusNoContent, nil)
+		request(t, http.MethodDelete, ts.URL+"/sessions/"+b.ID, "", http.StatusNoContent, nil)
+		select {
+		case <-cleaned:
+		case <-time.After(5 * time.Second):
+			t.Error("backends are not cleaned up after the sessions stop")
+		}
+	})
+
+	t.Run("slot reserved while building backend", func(t *testing.T) {
+		buildingCh := make(chan struct{})
+		failCh := make(chan struct{})
+		config := newTestConfig(t)
+		config.Backends["slow"] = func() (model.RecognizerCoreFactory, func(), error) {
+			close(buildingCh)
+			<-failCh
+			return nil, nil, errors.New("broken model")
+		}
+		s, err := NewServer(config)
+		if err != nil {
+			t.Fatalf("NewServer() error = %v", err)
+		}
+		defer s.Close()
+
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := s.Create("slow", nil)
+			errCh <- err
+		}()
+		<-buildingCh
+
+		if _, err := s.Create("echo", nil); !errors.Is(err, errTooManySessions) {
+			t.Errorf("Create() error = %v, want %v", err, errTooManySessions)
+		}
+		close(failCh)
+		if err := <-errCh; err == nil {
+			t.Error("Create() error = nil, want the build error")
+		}
+		// the slot is released by the failure.
+		if _, err := s.Create("echo", nil); err != nil {
+			t.Errorf("Create() error = %v", err)
+		}
+	})
+
+	t.Run("max duration", func(t *testing.T) {
+		config := newTestConfig(t)
+		config.MaxDuration = 100 * time.Millisecond
+		s, err := NewServer(config)
+		if err != nil {
+			t.Fatalf("NewServer() error = %v", err)
+		}
+		defer s.Close()
+		ts := httptest.NewServer(s.Handler())
+		defer ts.Close()
+
+		var info SessionInfo
+		request(t, http.MethodPost, ts.URL+"/sessions", "", http.StatusCreated, &info)
+		ws := dial(t, ts, info.WebSocket)
+		defer ws.Close()
+
+		want := []Event{{Type: EventEnd, Error: "session exceeded the maximum duration"}}
+		if diff := cmp.Diff(receive(t, ws, 1), want); diff != "" {
+			t.Errorf("events (-got +want):\n%s", diff)
+		}
+	})
+}
+
+// request sends the request and decodes the response into v if it is not nil.
+func request(t *testing.T, method, url, body string, wantStatus int, v any) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to %s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s status = %d, want %d", method, url, resp.StatusCode, wantStatus)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+}
+
+func dial(t *testing.T, ts *httptest.Server, path string) *websocket.Conn {
+	t.Helper()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+path, "", ts.URL)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	return ws
+}
+
+// receive receives n events or fails after a while.
+func receive(t *testing.T, ws *websocket.Conn, n int) []Event {
+	t.Helper()
+
+	if err := ws.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+	events := make([]Event, n)
+	for i := range events {
+		if err := websocket.JSON.Receive(ws, &events[i]); err != nil {
+			t.Fatalf("failed to receive event: %v", err)
+		}
+	}
+	return events
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/broadcast"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	EventInterim = "interim"
+	EventFinal   = "final"
+	// EventEnd is sent when the session stops. Error is set if it stopped by an error.
+	EventEnd = "end"
+)
+
+// Event is a message pushed to the WebSocket clients of a session as JSON.
+type Event struct {
+	Type    string `json:"type"`
+	Text    string `json:"text,omitempty"`
+	Backend string `json:"backend,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SessionInfo is the session in the responses of the API.
+type SessionInfo struct {
+	ID      string `json:"id"`
+	Backend string `json:"backend"`
+	// PostProcess is the names of the processors of the results.
+	PostProcess []string  `json:"postprocess,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Transcript is the path of the file of the final results.
+	Transcript string `json:"transcript"`
+	// WebSocket is the path of the WebSocket endpoint.
+	WebSocket string `json:"websocket"`
+}
+
+var errSessionStopped = errors.New("session stopped")
+
+// session is a recognition pipeline and its clients.
+type session struct {
+	info   SessionInfo
+	cancel context.CancelFunc
+	// done is closed when the pipeline stops.
+	done chan struct{}
+
+	// the pipeline reads the audio written by the clients through the pipe.
+	audioReader *io.PipeReader
+	audioWriter *io.PipeWriter
+
+	events *broadcast.Broadcaster[Event]
+}
+
+func newSession(info SessionInfo, cancel context.CancelFunc) *session {
+	r, w := io.Pipe()
+	return &session{
+		info:        info,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		audioReader: r,
+		audioWriter: w,
+		events:      broadcast.New[Event](fmt.Sprintf("Server: client of session %s", info.ID)),
+	}
+}
+
+// subscribe returns the channel of the events, which is closed when the session ends.
+func (s *session) subscribe() (events <-chan Event, unsubscribe func()) {
+	return s.events.Subscribe()
+}
+
+// publish sends the results to the subscribers. See model.Split for the interim result.
+func (s *session) publish(results []*model.Result) {
+	finals, interim := model.Split(results)
+	for _, result := range finals {
+		s.events.Send(Event{Type: EventFinal, Text: result.Transcript, Backend: result.Backend})
+	}
+	if interim != nil {
+		s.events.Send(Event{Type: EventInterim, Text: interim.Transcript, Backend: interim.Backend})
+	}
+}
+
+// end sends the end event to the subscribers and closes their channels.
+func (s *session) end(err error) {
+	e := Event{Type: EventEnd}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	s.events.Send(e)
+	s.events.Close()
+}
+
+// wrap wraps the factory so that the core built by it publishes the results
+// to the subscribers and closes the audio when it stops.
+func (s *session) wrap(newCore model.RecognizerCoreFactory) model.RecognizerCoreFactory {
+	return model.Observe(func(
+		ctx context.Context,
+		audioCh <-chan []byte,
+		resultCh chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		core, err := newCore(ctx, audioCh, resultCh)
+		if err != nil {
+			return nil, err
+		}
+		return &closingCore{core: core, audioReader: s.audioReader}, nil
+	}, s.publish)
+}
+
+var _ model.RecognizerCoreInterface = (*closingCore)(nil)
+
+// closingCore closes the audio of the session when the core stops.
+type closingCore struct {
+	core        model.RecognizerCoreInterface
+	audioReader *io.PipeReader
+}
+
+func (c *closingCore) Start(ctx context.Context) error {
+	// the audio reader of the pipeline is blocked on the pipe until it is closed.
+	defer c.audioReader.CloseWithError(errSessionStopped)
+	return c.core.Start(ctx)
+}
+
+// message is a WebSocket message with its frame type.
+type message struct {
+	payloadType byte
+	data        []byte
+}
+
+// messageCodec receives a message keeping its frame type to tell the audio from text.
+var messageCodec = websocket.Codec{
+	Unmarshal: func(data []byte, payloadType byte, v any) error {
+		m, ok := v.(*message)
+		if !ok {
+			return fmt.Errorf("unexpected type %T", v)
+		}
+		m.payloadType = payloadType
+		m.data = data
+		return nil
+	},
+}
+
+// serve receives the audio in the binary messages from the client and
+// pushes the events to it until either of them ends.
+func (s *session) serve(ws *websocket.Conn) {
+	defer ws.Close()
+	remote := ws.Request().RemoteAddr
+
+	events, unsubscribe := s.subscribe()
+	defer unsubscribe()
+
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			var m message
+			if err := messageCodec.Receive(ws, &m); err != nil {
+				if err != io.EOF {
+					slog.Debug(fmt.Sprintf("Server: failed to receive from %s: %v", remote, err))
+				}
+				return
+			}
+			if m.payloadType != websocket.BinaryFrame {
+				slog.Debug(fmt.Sprintf("Server: text message from %s ignored", remote))
+				continue
+			}
+			if _, err := s.audioWriter.Write(m.data); err != nil {
+				return
+			}
+		}
+	}()
+
+	slog.Info(fmt.Sprintf("Server: client %s connected to session %s", remote, s.info.ID))
+	defer slog.Info(fmt.Sprintf("Server: client %s disconnected from session %s", remote, s.info.ID))
+
+	for {
+		select {
+		case <-gone:
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := websocket.JSON.Send(ws, e); err != nil {
+				slog.Debug(fmt.Sprintf("Server: failed to send to %s: %v", remote, err))
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/clock"
+	"github.com/hekt/voice-recognition/internal/recognizer"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+const (
+	// maxBatchBackoff is the maximum backoff of the retries of BatchWebhook.
+	maxBatchBackoff = time.Minute
+	// shutdownTimeout is the timeout of the last post on shutdown.
+	shutdownTimeout = 5 * time.Second
+)
+
+var _ recognizer.SegmentWriterInterface = (*BatchWebhook)(nil)
+
+// BatchWebhook posts the final results to the URL in batches as JSON arrays
+// of Record. The results are queued in a file until they are posted, so that
+// nothing is lost while the endpoint is down, even across the restarts.
+// WriteSegment only queues the result, and Start posts them.
+type BatchWebhook struct {
+	url    string
+	client *http.Client
+	// batchSize is the maximum number of the results in a post. The results
+	// are posted when as many are queued or interval passes.
+	batchSize int
+	interval  time.Duration
+	// backoff is the first backoff of the retries, which doubles up to maxBatchBackoff.
+	backoff time.Duration
+	clock   clock.Clock
+
+	timeline *timeline
+	queue    *diskQueue
+	// notifyCh tells that a batch is full.
+	notifyCh chan struct{}
+}
+
+func NewBatchWebhook(
+	url string,
+	queuePath string,
+	batchSize int,
+	interval time.Duration,
+	backoff time.Duration,
+	client *http.Client,
+	clock clock.Clock,
+) (*BatchWebhook, error) {
+	if url == "" {
+		return nil, errors.New("url must be specified")
+	}
+	if queuePath == "" {
+		return nil, errors.New("queue path must be specified")
+	}
+	if batchSize <= 0 {
+		return nil, errors.New("batch size must be positive")
+	}
+	if interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+	if backoff <= 0 {
+		return nil, errors.New("backoff must be positive")
+	}
+	queue, err := openQueue(queuePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BatchWebhook{
+		url:       url,
+		client:    client,
+		batchSize: batchSize,
+		interval:  interval,
+		backoff:   backoff,
+		clock:     clock,
+		timeline:  newTimeline(clock),
+		queue:     queue,
+		notifyCh:  make(chan struct{}, 1),
+	}, nil
+}
+
+// WriteSegment queues the result.
+func (w *BatchWebhook) WriteSegment(result *model.Result) error {
+	record, end := w.timeline.record(result)
+	if err := w.queue.push(record); err != nil {
+		return err
+	}
+	w.timeline.advance(end)
+
+	if w.queue.len() >= w.batchSize {
+		select {
+		case w.notifyCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Start posts the queued results until ctx is done. The results left are
+// posted once more on shutdown, and kept in the queue if it fails.
+func (w *BatchWebhook) Start(ctx context.Context) error {
+	timer := w.clock.NewTimer(w.interval)
+	defer timer.Stop()
+
+	backoff := w.backoff
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+			defer cancel()
+			if err := w.flush(shutdownCtx); err != nil {
+				slog.Warn(fmt.Sprintf("Webhook: %d results are left in the queue: %v", w.queue.len(), err))
+			}
+			return ctx.Err()
+		case <-w.notifyCh:
+			// the full batch waits for the backoff.
+			if failing {
+				continue
+			}
+		case <-timer.C():
+		}
+
+		if err := w.flush(ctx); err != nil {
+			slog.Warn(fmt.Sprintf("Webhook: failed to post results, retrying in %s: %v", backoff, err))
+			timer.Reset(backoff)
+			backoff = min(backoff*2, maxBatchBackoff)
+			failing = true
+			continue
+		}
+		timer.Reset(w.interval)
+		backoff = w.backoff
+		failing = false
+	}
+}
+
+// flush posts all the queued results in batches.
+func (w *BatchWebhook) flush(ctx context.Context) error {
+	for {
+		batch := w.queue.peek(w.batchSize)
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := w.post(ctx, batch); err != nil {
+			return err
+		}
+		if err := w.queue.remove(len(batch)); err != nil {
+			return err
+		}
+		slog.Debug(fmt.Sprintf("Webhook: %d results posted", len(batch)))
+	}
+}
+
+func (w *BatchWebhook) post(ctx context.Context, batch []json.RawMessage) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post batch: %w", err)
+	}
+	defer resp.Body.Close()
+	// drains the body to reuse the connection.
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+// newBatchServer returns the server which sends the texts of the posted
+// batches to the channel. It fails the first posts as many times as failures.
+func newBatchServer(t *testing.T, failures int32) (*httptest.Server, <-chan []string) {
+	t.Helper()
+
+	var failed atomic.Int32
+	batches := make(chan []string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failed.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var records []Record
+		if err := json.NewDecoder(r.Body).Decode(&records); err != nil {
+			t.Errorf("failed to decode batch: %v", err)
+		}
+		var texts []string
+		for _, record := range records {
+			texts = append(texts, record.Text)
+		}
+		batches <- texts
+	}))
+	t.Cleanup(ts.Close)
+	return ts, batches
+}
+
+func writeTexts(t *testing.T, w *BatchWebhook, texts ...string) {
+	t.Helper()
+	for _, text := range texts {
+		if err := w.WriteSegment(&model.Result{Transcript: text, IsFinal: true}); err != nil {
+			t.Fatalf("BatchWebhook.WriteSegment() error = %v", err)
+		}
+	}
+}
+
+// advanceUntilPosted advances the clock by step until a batch is posted,
+// since the timer may be reset after the clock is advanced.
+func advanceUntilPosted(t *testing.T, clock *testutil.FakeClock, step time.Duration, batches <-chan []string) []string {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		clock.Advance(step)
+		select {
+		case got := <-batches:
+			return got
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("no batch is posted")
+	return nil
+}
+
+func TestBatchWebhook_Start(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ts, batches := newBatchServer(t, 0)
+		clock := testutil.NewFakeClock(testTime)
+		w, err := NewBatchWebhook(ts.URL, filepath.Join(t.TempDir(), "queue.jsonl"), 2, time.Minute, time.Second, ts.Client(), clock)
+		if err != nil {
+			t.Fatalf("NewBatchWebhook() error = %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- w.Start(ctx)
+		}()
+
+		// the full batch is posted at once.
+		writeTexts(t, w, "a", "b")
+		if got := <-batches; len(got) != 2 || got[0] != "a" || got[1] != "b" {
+			t.Errorf("posted %v, want [a b]", got)
+		}
+
+		// the rest is posted after the interval.
+		writeTexts(t, w, "c")
+		if got := advanceUntilPosted(t, clock, time.Minute, batches); len(got) != 1 || got[0] != "c" {
+			t.Errorf("posted %v, want [c]", got)
+		}
+
+		cancel()
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("BatchWebhook.Start() error = %v, want context canceled", err)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		ts, batches := newBatchServer(t, 2)
+		clock := testutil.NewFakeClock(testTime)
+		w, err := NewBatchWebhook(ts.URL, filepath.Join(t.TempDir(), "queue.jsonl"), 1, time.Hour, time.Second, ts.Client(), clock)
+		if err != nil {
+			t.Fatalf("NewBatchWebhook() error = %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			_ = w.Start(ctx)
+		}()
+
+		writeTexts(t, w, "a")
+		// the backoffs of 1s and 2s pass long before the interval.
+		if got := advanceUntilPosted(t, clock, time.Second, batches); len(got) != 1 || got[0] != "a" {
+			t.Errorf("posted %v, want [a]", got)
+		}
+	})
+
+	t.Run("kept in queue", func(t *testing.T) {
+		queuePath := filepath.Join(t.TempDir(), "queue.jsonl")
+		down, _ := newBatchServer(t, 100)
+		clock := testutil.NewFakeClock(testTime)
+		w, err := NewBatchWebhook(down.URL, queuePath, 10, time.Minute, time.Second, down.Client(), clock)
+		if err != nil {
+			t.Fatalf("NewBatchWebhook() error = %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- w.Start(ctx)
+		}()
+		writeTexts(t, w, "a")
+		cancel()
+		<-errCh
+
+		// the next process posts the results left.
+		ts, batches := newBatchServer(t, 0)
+		next, err := NewBatchWebhook(ts.URL, queuePath, 10, time.Minute, time.Second, ts.Client(), clock)
+		if err != nil {
+			t.Fatalf("NewBatchWebhook() error = %v", err)
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			_ = next.Start(ctx)
+		}()
+		if got := advanceUntilPosted(t, clock, time.Minute, batches); len(got) != 1 || got[0] != "a" {
+			t.Errorf("posted %v, want [a]", got)
+		}
+	})
+}
+
+func TestNewBatchWebhook(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name      string
+		url       string
+		queuePath string
+		batchSize int
+		interval  time.Duration
+		backoff   time.Duration
+		wantErr   bool
+	}{
+		{name: "success", url: "http://example.com", queuePath: filepath.Join(dir, "q"), batchSize: 1, interval: time.Second, backoff: time.Second},
+		{name: "no url", queuePath: filepath.Join(dir, "q"), batchSize: 1, interval: time.Second, backoff: time.Second, wantErr: true},
+		{name: "no queue", url: "http://example.com", batchSize: 1, interval: time.Second, backoff: time.Second, wantErr: true},
+		{name: "no batch size", url: "http://example.com", queuePath: filepath.Join(dir, "q"), interval: time.Second, backoff: time.Second, wantErr: true},
+		{name: "no interval", url: "http://example.com", queuePath: filepath.Join(dir, "q"), batchSize: 1, backoff: time.Second, wantErr: true},
+		{name: "no backoff", url: "http://example.com", queuePath: filepath.Join(dir, "q"), batchSize: 1, interval: time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewBatchWebhook(tt.url, tt.queuePath, tt.batchSize, tt.interval, tt.backoff, http.DefaultClient, testutil.NewFakeClock(testTime))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewBatchWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
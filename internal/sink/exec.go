@@ -0,0 +1,52 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/clock"
+	"github.com/hekt/voice-recognition/internal/recognizer"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+// execTimeout is the timeout of the command run for a final result.
+const execTimeout = 30 * time.Second
+
+var _ recognizer.SegmentWriterInterface = (*ExecSink)(nil)
+
+// ExecSink runs the command by sh for each final result with the JSON of
+// Record on stdin. The results wait for the command to exit.
+type ExecSink struct {
+	command  string
+	timeline *timeline
+}
+
+func NewExecSink(command string, clock clock.Clock) *ExecSink {
+	return &ExecSink{command: command, timeline: newTimeline(clock)}
+}
+
+func (s *ExecSink) WriteSegment(result *model.Result) error {
+	record, end := s.timeline.record(result)
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.command)
+	cmd.Stdin = bytes.NewReader(append(line, '\n'))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run %q: %w: %s", s.command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	s.timeline.advance(end)
+	return nil
+}
@@ -0,0 +1,40 @@
+package sink
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+func TestExecSink_WriteSegment(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out.json")
+		s := NewExecSink("cat > "+path, testutil.NewFakeClock(testTime))
+
+		if err := s.WriteSegment(&model.Result{Transcript: "a", IsFinal: true}); err != nil {
+			t.Fatalf("ExecSink.WriteSegment() error = %v", err)
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		want := `{"text":"a","start":0,"end":0,"time":"2024-01-02T15:04:05Z"}` + "\n"
+		if string(got) != want {
+			t.Errorf("ExecSink.WriteSegment() passes %q, want %q", got, want)
+		}
+	})
+
+	t.Run("command fails", func(t *testing.T) {
+		s := NewExecSink("echo oops >&2; exit 3", testutil.NewFakeClock(testTime))
+
+		err := s.WriteSegment(&model.Result{Transcript: "a", IsFinal: true})
+		if err == nil || !strings.Contains(err.Error(), "oops") {
+			t.Errorf("ExecSink.WriteSegment() error = %v, want the error with stderr", err)
+		}
+	})
+}
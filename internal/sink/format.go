@@ -0,0 +1,154 @@
+// Package sink writes the final results to several outputs at once, each in
+// its own format and with its own error policy. The sinks are the segment
+// writers of the pipeline.
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/clock"
+	"github.com/hekt/voice-recognition/internal/recognizer"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+var (
+	_ recognizer.SegmentWriterInterface = (*TextSink)(nil)
+	_ recognizer.SegmentWriterInterface = (*JSONLSink)(nil)
+	_ recognizer.SegmentWriterInterface = (*SRTSink)(nil)
+)
+
+// Record is a final result written by JSONLSink and WebhookSink.
+type Record struct {
+	Text    string `json:"text"`
+	Backend string `json:"backend,omitempty"`
+	Speaker string `json:"speaker,omitempty"`
+	// Start and End are the offsets in seconds from the beginning of the session.
+	// The record starts at the end of the previous one.
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	// Time is the time when the result is written.
+	Time time.Time `json:"time"`
+}
+
+// timeline tells the span of the final results from the beginning of the session.
+type timeline struct {
+	clock clock.Clock
+	start time.Time
+
+	lastEnd time.Duration
+}
+
+func newTimeline(clock clock.Clock) *timeline {
+	return &timeline{clock: clock, start: clock.Now()}
+}
+
+// span returns the span of the result. The end is the time elapsed if the
+// backend does not tell it. advance must be called with the end when the
+// result is written, so that a failed result is retried with the same start.
+func (t *timeline) span(result *model.Result) (start, end time.Duration) {
+	end = t.clock.Now().Sub(t.start)
+	if result.End > 0 {
+		end = result.End
+	}
+	// the end told by the backend can be before the end of the previous one
+	// when the backend is switched.
+	return min(t.lastEnd, end), end
+}
+
+func (t *timeline) advance(end time.Duration) {
+	t.lastEnd = end
+}
+
+func (t *timeline) record(result *model.Result) (Record, time.Duration) {
+	start, end := t.span(result)
+	return Record{
+		Text:    result.Transcript,
+		Backend: result.Backend,
+		Speaker: result.Speaker,
+		Start:   start.Seconds(),
+		End:     end.Seconds(),
+		Time:    t.clock.Now(),
+	}, end
+}
+
+// TextSink writes the final results as plain text in the same way as the output file.
+type TextSink struct {
+	writer io.Writer
+	turns  recognizer.Turns
+}
+
+func NewTextSink(writer io.Writer) *TextSink {
+	return &TextSink{writer: writer}
+}
+
+func (s *TextSink) WriteSegment(result *model.Result) error {
+	if _, err := io.WriteString(s.writer, "\n"+s.turns.Transcript(result)); err != nil {
+		return fmt.Errorf("failed to write text: %w", err)
+	}
+	s.turns.Advance(result)
+	return nil
+}
+
+// JSONLSink writes the final results as JSON lines of Record.
+type JSONLSink struct {
+	writer   io.Writer
+	timeline *timeline
+}
+
+func NewJSONLSink(writer io.Writer, clock clock.Clock) *JSONLSink {
+	return &JSONLSink{writer: writer, timeline: newTimeline(clock)}
+}
+
+func (s *JSONLSink) WriteSegment(result *model.Result) error {
+	record, end := s.timeline.record(result)
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+	if _, err := s.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	s.timeline.advance(end)
+	return nil
+}
+
+// SRTSink writes the final results as the cues of SubRip subtitles.
+type SRTSink struct {
+	writer   io.Writer
+	timeline *timeline
+	turns    recognizer.Turns
+
+	index int
+}
+
+func NewSRTSink(writer io.Writer, clock clock.Clock) *SRTSink {
+	return &SRTSink{writer: writer, timeline: newTimeline(clock)}
+}
+
+func (s *SRTSink) WriteSegment(result *model.Result) error {
+	start, end := s.timeline.span(result)
+	cue := fmt.Sprintf(
+		"%d\n%s --> %s\n%s\n\n",
+		s.index+1,
+		srtTimestamp(start),
+		srtTimestamp(end),
+		s.turns.Transcript(result),
+	)
+	if _, err := io.WriteString(s.writer, cue); err != nil {
+		return fmt.Errorf("failed to write cue: %w", err)
+	}
+	// the number is not consumed by the failed cue so that the retried one has it.
+	s.index++
+	s.timeline.advance(end)
+	s.turns.Advance(result)
+	return nil
+}
+
+// srtTimestamp formats d as hh:mm:ss,mmm.
+func srtTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
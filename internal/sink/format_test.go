@@ -0,0 +1,102 @@
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+var testTime = time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+func TestTextSink_WriteSegment(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var buf bytes.Buffer
+		s := NewTextSink(&buf)
+
+		for _, result := range []*model.Result{
+			{Transcript: "a", IsFinal: true},
+			{Transcript: "b", IsFinal: true, Backend: "vosk"},
+			{Transcript: "c", IsFinal: true, Speaker: "Speaker 1"},
+			{Transcript: "d", IsFinal: true, Speaker: "Speaker 1"},
+			{Transcript: "e", IsFinal: true, Speaker: "Speaker 2"},
+		} {
+			if err := s.WriteSegment(result); err != nil {
+				t.Fatalf("TextSink.WriteSegment() error = %v", err)
+			}
+		}
+
+		// the speaker is prefixed at the turns.
+		if got, want := buf.String(), "\na\n[vosk] b\nSpeaker 1: c\nd\nSpeaker 2: e"; got != want {
+			t.Errorf("TextSink.WriteSegment() writes %q, want %q", got, want)
+		}
+	})
+}
+
+func TestJSONLSink_WriteSegment(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var buf bytes.Buffer
+		clock := testutil.NewFakeClock(testTime)
+		s := NewJSONLSink(&buf, clock)
+
+		clock.Advance(2 * time.Second)
+		if err := s.WriteSegment(&model.Result{Transcript: "a", IsFinal: true}); err != nil {
+			t.Fatalf("JSONLSink.WriteSegment() error = %v", err)
+		}
+		// the end told by the backend is preferred.
+		if err := s.WriteSegment(&model.Result{Transcript: "b", IsFinal: true, Backend: "google", End: 3500 * time.Millisecond, Speaker: "Alice"}); err != nil {
+			t.Fatalf("JSONLSink.WriteSegment() error = %v", err)
+		}
+
+		want := `{"text":"a","start":0,"end":2,"time":"2024-01-02T15:04:07Z"}
+{"text":"b","backend":"google","speaker":"Alice","start":2,"end":3.5,"time":"2024-01-02T15:04:07Z"}
+`
+		if got := buf.String(); got != want {
+			t.Errorf("JSONLSink.WriteSegment() writes %q, want %q", got, want)
+		}
+	})
+}
+
+func TestSRTSink_WriteSegment(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		w := &failingWriter{failures: 1}
+		clock := testutil.NewFakeClock(testTime)
+		s := NewSRTSink(w, clock)
+
+		clock.Advance(1500 * time.Millisecond)
+		if err := s.WriteSegment(&model.Result{Transcript: "a", IsFinal: true}); err != nil {
+			t.Fatalf("SRTSink.WriteSegment() error = %v", err)
+		}
+		result := &model.Result{Transcript: "b", IsFinal: true, End: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, Speaker: "Alice"}
+		// the failed cue is retried with the same number, start and speaker.
+		if err := s.WriteSegment(result); err == nil {
+			t.Fatal("SRTSink.WriteSegment() error = nil, want error")
+		}
+		if err := s.WriteSegment(result); err != nil {
+			t.Fatalf("SRTSink.WriteSegment() error = %v", err)
+		}
+
+		want := "1\n00:00:00,000 --> 00:00:01,500\na\n\n" +
+			"2\n00:00:01,500 --> 01:02:03,004\nAlice: b\n\n"
+		if got := w.buf.String(); got != want {
+			t.Errorf("SRTSink.WriteSegment() writes %q, want %q", got, want)
+		}
+	})
+}
+
+// failingWriter fails the writes after the first one as many times as failures.
+type failingWriter struct {
+	buf      bytes.Buffer
+	failures int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.buf.Len() > 0 && w.failures > 0 {
+		w.failures--
+		return 0, errors.New("test")
+	}
+	return w.buf.Write(p)
+}
@@ -0,0 +1,88 @@
+package sink
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/clock"
+	"github.com/hekt/voice-recognition/internal/recognizer"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+// Policy is what to do when a sink fails to write a result.
+type Policy string
+
+const (
+	// PolicyFail fails the session.
+	PolicyFail Policy = "fail"
+	// PolicyRetry retries with the exponential backoff, and fails the session
+	// if the retries run out. The session waits for the retries.
+	PolicyRetry Policy = "retry"
+	// PolicyDrop drops the result with a warning.
+	PolicyDrop Policy = "drop"
+)
+
+var _ recognizer.SegmentWriterInterface = (*policySink)(nil)
+
+// policySink applies the policy to the errors of the sink.
+type policySink struct {
+	name   string
+	sink   recognizer.SegmentWriterInterface
+	policy Policy
+
+	// retries and backoff are used by PolicyRetry. The backoff doubles on each retry.
+	retries int
+	backoff time.Duration
+	clock   clock.Clock
+}
+
+// WithPolicy applies the policy to the errors of the sink. name is shown in
+// the errors and the logs. Retries and Backoff of options are used by PolicyRetry.
+func WithPolicy(
+	name string,
+	sink recognizer.SegmentWriterInterface,
+	policy Policy,
+	options Options,
+) recognizer.SegmentWriterInterface {
+	c := options.Clock
+	if c == nil {
+		c = clock.Real
+	}
+	return &policySink{
+		name:    name,
+		sink:    sink,
+		policy:  policy,
+		retries: options.Retries,
+		backoff: options.Backoff,
+		clock:   c,
+	}
+}
+
+func (s *policySink) WriteSegment(result *model.Result) error {
+	err := s.sink.WriteSegment(result)
+	if err == nil {
+		return nil
+	}
+
+	switch s.policy {
+	case PolicyDrop:
+		slog.Warn(fmt.Sprintf("Sink: result dropped by %s: %v", s.name, err))
+		return nil
+	case PolicyRetry:
+		backoff := s.backoff
+		for i := 0; i < s.retries; i++ {
+			slog.Warn(fmt.Sprintf("Sink: failed to write to %s, retrying in %s: %v", s.name, backoff, err))
+			timer := s.clock.NewTimer(backoff)
+			<-timer.C()
+			backoff *= 2
+
+			if err = s.sink.WriteSegment(result); err == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("failed to write to %s after %d retries: %w", s.name, s.retries, err)
+	default:
+		return fmt.Errorf("failed to write to %s: %w", s.name, err)
+	}
+}
@@ -0,0 +1,101 @@
+package sink
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+func Test_policySink_WriteSegment(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    Policy
+		failures  int
+		wantErr   bool
+		wantCalls int
+	}{
+		{
+			name:      "success",
+			policy:    PolicyFail,
+			failures:  0,
+			wantErr:   false,
+			wantCalls: 1,
+		},
+		{
+			name:      "fail",
+			policy:    PolicyFail,
+			failures:  1,
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:      "drop",
+			policy:    PolicyDrop,
+			failures:  1,
+			wantErr:   false,
+			wantCalls: 1,
+		},
+		{
+			name:      "retry",
+			policy:    PolicyRetry,
+			failures:  2,
+			wantErr:   false,
+			wantCalls: 3,
+		},
+		{
+			name:      "retries run out",
+			policy:    PolicyRetry,
+			failures:  3,
+			wantErr:   true,
+			wantCalls: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures := tt.failures
+			sink := &recognizer.SegmentWriterInterfaceMock{
+				WriteSegmentFunc: func(_ *model.Result) error {
+					if failures > 0 {
+						failures--
+						return errors.New("test")
+					}
+					return nil
+				},
+			}
+			clock := testutil.NewFakeClock(testTime)
+			s := &policySink{
+				name:    "test",
+				sink:    sink,
+				policy:  tt.policy,
+				retries: 2,
+				backoff: time.Second,
+				clock:   clock,
+			}
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- s.WriteSegment(&model.Result{Transcript: "a", IsFinal: true})
+			}()
+
+			// the backoff doubles.
+			for _, backoff := range []time.Duration{time.Second, 2 * time.Second}[:min(tt.wantCalls-1, 2)] {
+				clock.BlockUntil(1)
+				if n := clock.Advance(backoff - time.Millisecond); n != 0 {
+					t.Errorf("retried before the backoff %s", backoff)
+				}
+				clock.Advance(time.Millisecond)
+			}
+
+			if err := <-errCh; (err != nil) != tt.wantErr {
+				t.Errorf("policySink.WriteSegment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := len(sink.WriteSegmentCalls()); got != tt.wantCalls {
+				t.Errorf("policySink.WriteSegment() writes %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
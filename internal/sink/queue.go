@@ -0,0 +1,118 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// diskQueue is a queue of JSON values kept in a file as JSON lines, so that
+// the values are not lost when the process stops.
+type diskQueue struct {
+	path string
+
+	mu     sync.Mutex
+	values []json.RawMessage
+}
+
+// openQueue opens the queue in the file, which keeps the values left by the
+// previous process. The broken lines, e.g. written partially on a crash, are dropped.
+func openQueue(path string) (*diskQueue, error) {
+	q := &diskQueue{path: path}
+
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read queue: %w", err)
+	}
+	broken := len(b) > 0 && b[len(b)-1] != '\n'
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			slog.Warn(fmt.Sprintf("Queue: broken line in %s dropped: %s", path, line))
+			broken = true
+			continue
+		}
+		q.values = append(q.values, line)
+	}
+	// the values pushed later must not be appended to the broken line.
+	if broken {
+		if err := q.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	if len(q.values) > 0 {
+		slog.Info(fmt.Sprintf("Queue: %d values left in %s", len(q.values), path))
+	}
+	return q, nil
+}
+
+// push appends the value to the file and the queue.
+func (q *diskQueue) push(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	f, err := os.OpenFile(q.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.FileMode(0o644))
+	if err != nil {
+		return fmt.Errorf("failed to open queue: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write queue: %w", err)
+	}
+
+	q.values = append(q.values, line)
+	return nil
+}
+
+// peek returns the first n values at most.
+func (q *diskQueue) peek(n int) []json.RawMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]json.RawMessage{}, q.values[:min(n, len(q.values))]...)
+}
+
+// remove removes the first n values and rewrites the file with the rest.
+func (q *diskQueue) remove(n int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.values = q.values[n:]
+	return q.rewrite()
+}
+
+// rewrite writes the values to the file. The caller must hold mu.
+func (q *diskQueue) rewrite() error {
+	var buf bytes.Buffer
+	for _, v := range q.values {
+		buf.Write(v)
+		buf.WriteByte('\n')
+	}
+	// the file is replaced at once so that a crash does not leave it half written.
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), os.FileMode(0o644)); err != nil {
+		return fmt.Errorf("failed to write queue: %w", err)
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return fmt.Errorf("failed to replace queue: %w", err)
+	}
+	return nil
+}
+
+func (q *diskQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.values)
+}
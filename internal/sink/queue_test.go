@@ -0,0 +1,63 @@
+package sink
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_diskQueue(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "queue.jsonl")
+		q, err := openQueue(path)
+		if err != nil {
+			t.Fatalf("openQueue() error = %v", err)
+		}
+		for _, v := range []string{"a", "b", "c"} {
+			if err := q.push(v); err != nil {
+				t.Fatalf("diskQueue.push() error = %v", err)
+			}
+		}
+		if diff := cmp.Diff(q.peek(2), []json.RawMessage{[]byte(`"a"`), []byte(`"b"`)}); diff != "" {
+			t.Errorf("diskQueue.peek() (-got +want):\n%s", diff)
+		}
+		if err := q.remove(2); err != nil {
+			t.Fatalf("diskQueue.remove() error = %v", err)
+		}
+
+		// the rest is kept in the file.
+		reopened, err := openQueue(path)
+		if err != nil {
+			t.Fatalf("openQueue() error = %v", err)
+		}
+		if diff := cmp.Diff(reopened.peek(2), []json.RawMessage{[]byte(`"c"`)}); diff != "" {
+			t.Errorf("diskQueue.peek() after reopen (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("broken line", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "queue.jsonl")
+		if err := os.WriteFile(path, []byte("\"a\"\n{\"text\":"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		q, err := openQueue(path)
+		if err != nil {
+			t.Fatalf("openQueue() error = %v", err)
+		}
+		if err := q.push("b"); err != nil {
+			t.Fatalf("diskQueue.push() error = %v", err)
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		if want := "\"a\"\n\"b\"\n"; string(got) != want {
+			t.Errorf("queue file = %q, want %q", got, want)
+		}
+	})
+}
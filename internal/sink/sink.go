@@ -0,0 +1,138 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/clock"
+	"github.com/hekt/voice-recognition/internal/file"
+	"github.com/hekt/voice-recognition/internal/recognizer"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+// webhookTimeout is the timeout of the posts to the webhooks by default.
+const webhookTimeout = 10 * time.Second
+
+var _ recognizer.SegmentWriterInterface = (*FanOut)(nil)
+
+// FanOut writes the final results to all the sinks.
+type FanOut struct {
+	sinks []recognizer.SegmentWriterInterface
+}
+
+func NewFanOut(sinks ...recognizer.SegmentWriterInterface) *FanOut {
+	return &FanOut{sinks: sinks}
+}
+
+// WriteSegment writes the result to all the sinks even if some of them fail,
+// and returns their errors joined.
+func (f *FanOut) WriteSegment(result *model.Result) error {
+	var errs []error
+	for _, s := range f.sinks {
+		if err := s.WriteSegment(result); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Options are the options of the sinks opened by Open.
+type Options struct {
+	// Retries and Backoff are used by PolicyRetry.
+	Retries int
+	Backoff time.Duration
+	Clock   clock.Clock
+	// Client posts the results to the webhooks.
+	Client *http.Client
+	// Files holds the files of the sinks to reopen and close them together.
+	// The files are left open until the exit if nil.
+	Files *file.Group
+}
+
+// Open opens the sink of the spec "<format>[+<policy>]:<target>", e.g.
+// "jsonl:session.jsonl" or "webhook+retry:https://example.com/hook".
+// The format is text, jsonl or srt with the path of the file, or webhook
+// with the URL. The policy is fail if omitted. The SRT file is truncated
+// since its cues are numbered from 1, and the others are appended.
+func Open(spec string, options Options) (recognizer.SegmentWriterInterface, error) {
+	head, target, ok := strings.Cut(spec, ":")
+	if !ok || target == "" {
+		return nil, fmt.Errorf("invalid sink %q, <format>[+<policy>]:<target> is expected", spec)
+	}
+	format, policy, ok := strings.Cut(head, "+")
+	if !ok {
+		policy = string(PolicyFail)
+	}
+	switch Policy(policy) {
+	case PolicyFail, PolicyRetry, PolicyDrop:
+	default:
+		return nil, fmt.Errorf("unknown policy %q of sink %q", policy, spec)
+	}
+	if options.Retries < 0 {
+		return nil, errors.New("retries must not be negative")
+	}
+	c := options.Clock
+	if c == nil {
+		c = clock.Real
+	}
+
+	var s recognizer.SegmentWriterInterface
+	switch format {
+	case "text":
+		w, err := openFile(target, 0, options.Files)
+		if err != nil {
+			return nil, err
+		}
+		s = NewTextSink(w)
+	case "jsonl":
+		w, err := openFile(target, 0, options.Files)
+		if err != nil {
+			return nil, err
+		}
+		s = NewJSONLSink(w, c)
+	case "srt":
+		w, err := openFile(target, os.O_TRUNC, options.Files)
+		if err != nil {
+			return nil, err
+		}
+		s = NewSRTSink(w, c)
+	case "webhook":
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q", target)
+		}
+		client := options.Client
+		if client == nil {
+			client = &http.Client{Timeout: webhookTimeout}
+		}
+		s = NewWebhookSink(target, client, c)
+	default:
+		return nil, fmt.Errorf("unknown format %q of sink %q", format, spec)
+	}
+
+	return WithPolicy(format+":"+target, s, Policy(policy), Options{
+		Retries: options.Retries,
+		Backoff: options.Backoff,
+		Clock:   c,
+	}), nil
+}
+
+// openFile creates the file early, making it easier to use with tools like
+// `tail -f`, and returns the writer appending to it. The file is added to
+// files if not nil.
+func openFile(path string, flag int, files *file.Group) (*file.FileWriter, error) {
+	open := file.OpenFileWriter
+	if files != nil {
+		open = files.Open
+	}
+	w, err := open(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY|flag, os.FileMode(0o644))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sink file: %w", err)
+	}
+	return w, nil
+}
@@ -0,0 +1,90 @@
+package sink
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hekt/voice-recognition/internal/file"
+	"github.com/hekt/voice-recognition/internal/recognizer"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+func TestFanOut_WriteSegment(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ok := &recognizer.SegmentWriterInterfaceMock{
+			WriteSegmentFunc: func(_ *model.Result) error { return nil },
+		}
+		failing := &recognizer.SegmentWriterInterfaceMock{
+			WriteSegmentFunc: func(_ *model.Result) error { return errors.New("test") },
+		}
+		f := NewFanOut(failing, ok)
+
+		// the failure of a sink does not stop the others.
+		if err := f.WriteSegment(&model.Result{Transcript: "a", IsFinal: true}); err == nil {
+			t.Error("FanOut.WriteSegment() error = nil, want error")
+		}
+		if len(failing.WriteSegmentCalls()) != 1 || len(ok.WriteSegmentCalls()) != 1 {
+			t.Errorf("FanOut.WriteSegment() writes %d and %d times, want once each",
+				len(failing.WriteSegmentCalls()), len(ok.WriteSegmentCalls()))
+		}
+	})
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	srt := filepath.Join(dir, "session.srt")
+	if err := os.WriteFile(srt, []byte("old"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	jsonl := filepath.Join(dir, "session.jsonl")
+	if err := os.WriteFile(jsonl, []byte("old\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		spec       string
+		wantPolicy Policy
+		wantErr    bool
+	}{
+		{name: "text", spec: "text:" + filepath.Join(dir, "output.txt"), wantPolicy: PolicyFail},
+		{name: "jsonl", spec: "jsonl+drop:" + jsonl, wantPolicy: PolicyDrop},
+		{name: "srt", spec: "srt+retry:" + srt, wantPolicy: PolicyRetry},
+		{name: "webhook", spec: "webhook+retry:https://example.com/hook?a=b", wantPolicy: PolicyRetry},
+		{name: "no target", spec: "text:", wantErr: true},
+		{name: "no format", spec: "output.txt", wantErr: true},
+		{name: "unknown format", spec: "csv:output.csv", wantErr: true},
+		{name: "unknown policy", spec: "text+ignore:output.txt", wantErr: true},
+		{name: "invalid webhook", spec: "webhook:example.com/hook", wantErr: true},
+		{name: "missing directory", spec: "text:" + filepath.Join(dir, "missing", "output.txt"), wantErr: true},
+	}
+	files := file.NewGroup()
+	defer files.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Open(tt.spec, Options{Files: files})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if policy := got.(*policySink).policy; policy != tt.wantPolicy {
+				t.Errorf("Open() policy = %v, want %v", policy, tt.wantPolicy)
+			}
+		})
+	}
+
+	// the SRT file is truncated and the others are appended.
+	for path, want := range map[string]string{srt: "", jsonl: "old\n"} {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		if string(b) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(path), b, want)
+		}
+	}
+}
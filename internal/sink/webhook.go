@@ -0,0 +1,48 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/hekt/voice-recognition/internal/clock"
+	"github.com/hekt/voice-recognition/internal/recognizer"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+var _ recognizer.SegmentWriterInterface = (*WebhookSink)(nil)
+
+// WebhookSink posts each final result to the URL as JSON of Record.
+type WebhookSink struct {
+	url      string
+	client   *http.Client
+	timeline *timeline
+}
+
+func NewWebhookSink(url string, client *http.Client, clock clock.Clock) *WebhookSink {
+	return &WebhookSink{url: url, client: client, timeline: newTimeline(clock)}
+}
+
+func (s *WebhookSink) WriteSegment(result *model.Result) error {
+	record, end := s.timeline.record(result)
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post record: %w", err)
+	}
+	defer resp.Body.Close()
+	// drains the body to reuse the connection.
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	s.timeline.advance(end)
+	return nil
+}
@@ -0,0 +1,50 @@
+package sink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+func TestWebhookSink_WriteSegment(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var got Record
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Errorf("failed to decode record: %v", err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer ts.Close()
+
+		s := NewWebhookSink(ts.URL, ts.Client(), testutil.NewFakeClock(testTime))
+		if err := s.WriteSegment(&model.Result{Transcript: "a", IsFinal: true, Backend: "vosk"}); err != nil {
+			t.Fatalf("WebhookSink.WriteSegment() error = %v", err)
+		}
+
+		want := Record{Text: "a", Backend: "vosk", Time: testTime}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("posted record (-got +want):\n%s", diff)
+		}
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer ts.Close()
+
+		s := NewWebhookSink(ts.URL, ts.Client(), testutil.NewFakeClock(testTime))
+		if err := s.WriteSegment(&model.Result{Transcript: "a", IsFinal: true}); err == nil {
+			t.Error("WebhookSink.WriteSegment() error = nil, want error")
+		}
+	})
+}
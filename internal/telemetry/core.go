@@ -0,0 +1,147 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"golang.org/x/sync/errgroup"
+)
+
+// InstrumentCore instruments the cores built by the factory with the metrics
+// of Default. See CoreMetrics.Instrument.
+func InstrumentCore(backend string, newCore model.RecognizerCoreFactory) model.RecognizerCoreFactory {
+	return Core.Instrument(backend, newCore)
+}
+
+// Instrument wraps the factory so that the core built by it counts the
+// audio sent to it and the results returned by it with the backend label.
+func (m *CoreMetrics) Instrument(backend string, newCore model.RecognizerCoreFactory) model.RecognizerCoreFactory {
+	return func(
+		ctx context.Context,
+		audioCh <-chan []byte,
+		resultCh chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		c := &instrumentedCore{
+			metrics:      m,
+			backend:      backend,
+			audioCh:      audioCh,
+			innerAudioCh: make(chan []byte, 1),
+		}
+		return model.Observe(func(
+			ctx context.Context,
+			_ <-chan []byte,
+			resultCh chan<- []*model.Result,
+		) (model.RecognizerCoreInterface, error) {
+			core, err := newCore(ctx, c.innerAudioCh, resultCh)
+			if err != nil {
+				return nil, err
+			}
+			c.core = core
+			return c, nil
+		}, c.observe)(ctx, audioCh, resultCh)
+	}
+}
+
+var _ model.RecognizerCoreInterface = (*instrumentedCore)(nil)
+
+// instrumentedCore counts the audio sent to the core. The results are counted
+// by observe.
+type instrumentedCore struct {
+	metrics *CoreMetrics
+	backend string
+	core    model.RecognizerCoreInterface
+
+	audioCh      <-chan []byte
+	innerAudioCh chan []byte
+
+	mu sync.Mutex
+	// sent is the duration of the audio sent to the core.
+	sent time.Duration
+	// marks are the times when the audio was sent to the core for the latency.
+	marks []mark
+}
+
+// mark is the time when the audio until end was sent.
+type mark struct {
+	end time.Duration
+	at  time.Time
+}
+
+func (c *instrumentedCore) Start(ctx context.Context) error {
+	eg, ctx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		return c.core.Start(ctx)
+	})
+	eg.Go(func() error {
+		return c.forwardAudio(ctx)
+	})
+
+	return eg.Wait()
+}
+
+func (c *instrumentedCore) forwardAudio(ctx context.Context) error {
+	sentSeconds := c.metrics.AudioSentSeconds.WithLabelValues(c.backend)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case audio, ok := <-c.audioCh:
+			if !ok {
+				return errors.New("audio channel closed")
+			}
+
+			d := model.AudioDuration(len(audio))
+			c.mu.Lock()
+			c.sent += d
+			c.marks = append(c.marks, mark{end: c.sent, at: c.metrics.now()})
+			c.mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case c.innerAudioCh <- audio:
+			}
+			sentSeconds.Add(d.Seconds())
+		}
+	}
+}
+
+func (c *instrumentedCore) observe(results []*model.Result) {
+	for _, result := range results {
+		if !result.IsFinal {
+			c.metrics.ResultsTotal.WithLabelValues(c.backend, ResultInterim).Inc()
+			continue
+		}
+		c.metrics.ResultsTotal.WithLabelValues(c.backend, ResultFinal).Inc()
+		if l, ok := c.latency(result.End); ok {
+			c.metrics.FinalLatency.WithLabelValues(c.backend).Observe(l.Seconds())
+		}
+	}
+}
+
+// latency returns the duration since the audio at end was sent.
+// It returns false if end is unknown.
+func (c *instrumentedCore) latency(end time.Duration) (time.Duration, bool) {
+	if end <= 0 {
+		return 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the marks before end are no longer needed since the results are in order.
+	i := 0
+	for i < len(c.marks) && c.marks[i].end < end {
+		i++
+	}
+	c.marks = c.marks[i:]
+	if len(c.marks) == 0 {
+		return 0, false
+	}
+	return c.metrics.now().Sub(c.marks[0].at), true
+}
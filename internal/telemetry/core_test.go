@@ -0,0 +1,128 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCoreMetrics_Instrument(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		const backend = "instrument-test"
+
+		// the inner core returns an interim result and a final result ending at
+		// the audio received so far for each audio.
+		var innerAudioCh <-chan []byte
+		var innerResultCh chan<- []*model.Result
+		newCore := func(
+			_ context.Context,
+			audioCh <-chan []byte,
+			resultCh chan<- []*model.Result,
+		) (model.RecognizerCoreInterface, error) {
+			innerAudioCh = audioCh
+			innerResultCh = resultCh
+			return &model.RecognizerCoreInterfaceMock{
+				StartFunc: func(ctx context.Context) error {
+					var received time.Duration
+					for {
+						select {
+						case <-ctx.Done():
+							return ctx.Err()
+						case audio := <-innerAudioCh:
+							received += model.AudioDuration(len(audio))
+							innerResultCh <- []*model.Result{
+								{Transcript: "a"},
+								{Transcript: "a", IsFinal: true, End: received},
+							}
+						}
+					}
+				},
+			}, nil
+		}
+
+		audioCh := make(chan []byte)
+		resultCh := make(chan []*model.Result)
+		registry := prometheus.NewRegistry()
+		metrics := NewCoreMetrics(registry)
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		metrics.now = func() time.Time {
+			now = now.Add(500 * time.Millisecond)
+			return now
+		}
+		core, err := metrics.Instrument(backend, newCore)(context.Background(), audioCh, resultCh)
+		if err != nil {
+			t.Fatalf("CoreMetrics.Instrument() error = %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- core.Start(ctx)
+		}()
+
+		audioCh <- make([]byte, 32000)
+		if got := <-resultCh; len(got) != 2 || got[1].End != time.Second {
+			t.Errorf("unexpected results: %v", got)
+		}
+		cancel()
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("Start() error = %v, want context canceled", err)
+		}
+
+		want := `# HELP voice_recognition_audio_sent_seconds_total Seconds of the audio sent to the backend.
+# TYPE voice_recognition_audio_sent_seconds_total counter
+voice_recognition_audio_sent_seconds_total{backend="instrument-test"} 1
+# HELP voice_recognition_final_latency_seconds Seconds from the audio at the end of a final result sent to the backend until the result is returned.
+# TYPE voice_recognition_final_latency_seconds histogram
+voice_recognition_final_latency_seconds_bucket{backend="instrument-test",le="0.1"} 0
+voice_recognition_final_latency_seconds_bucket{backend="instrument-test",le="0.25"} 0
+voice_recognition_final_latency_seconds_bucket{backend="instrument-test",le="0.5"} 1
+voice_recognition_final_latency_seconds_bucket{backend="instrument-test",le="1"} 1
+voice_recognition_final_latency_seconds_bucket{backend="instrument-test",le="2"} 1
+voice_recognition_final_latency_seconds_bucket{backend="instrument-test",le="5"} 1
+voice_recognition_final_latency_seconds_bucket{backend="instrument-test",le="10"} 1
+voice_recognition_final_latency_seconds_bucket{backend="instrument-test",le="30"} 1
+voice_recognition_final_latency_seconds_bucket{backend="instrument-test",le="+Inf"} 1
+voice_recognition_final_latency_seconds_sum{backend="instrument-test"} 0.5
+voice_recognition_final_latency_seconds_count{backend="instrument-test"} 1
+# HELP voice_recognition_results_total Number of the results returned by the backend.
+# TYPE voice_recognition_results_total counter
+voice_recognition_results_total{backend="instrument-test",type="final"} 1
+voice_recognition_results_total{backend="instrument-test",type="interim"} 1
+`
+		if err := testutil.GatherAndCompare(registry, strings.NewReader(want)); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestInstrumentedCore_latency(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &instrumentedCore{
+		metrics: &CoreMetrics{now: func() time.Time { return now }},
+		marks: []mark{
+			{end: time.Second, at: now.Add(-3 * time.Second)},
+			{end: 2 * time.Second, at: now.Add(-2 * time.Second)},
+		},
+	}
+
+	if _, ok := c.latency(0); ok {
+		t.Error("latency(0) is reported, want unknown")
+	}
+	if got, ok := c.latency(1500 * time.Millisecond); !ok || got != 2*time.Second {
+		t.Errorf("latency(1.5s) = %v, %v, want 2s", got, ok)
+	}
+	if got, ok := c.latency(1500 * time.Millisecond); !ok || got != 2*time.Second {
+		t.Errorf("latency(1.5s) again = %v, %v, want 2s", got, ok)
+	}
+	if _, ok := c.latency(3 * time.Second); ok {
+		t.Error("latency(3s) is reported, want unknown since the audio is not sent yet")
+	}
+}
@@ -0,0 +1,141 @@
+package telemetry
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// funcSet is the functions computing the values of a gauge.
+type funcSet struct {
+	funcs map[int]func() float64
+	seq   int
+}
+
+// add adds f and returns the id to delete it with.
+func (s *funcSet) add(f func() float64) int {
+	if s.funcs == nil {
+		s.funcs = map[int]func() float64{}
+	}
+	s.seq++
+	s.funcs[s.seq] = f
+	return s.seq
+}
+
+func (s *funcSet) values() []float64 {
+	values := make([]float64, 0, len(s.funcs))
+	for _, f := range s.funcs {
+		values = append(values, f())
+	}
+	return values
+}
+
+var _ prometheus.Collector = (*GaugeFuncs)(nil)
+
+// GaugeFuncs is a gauge whose value is aggregated by aggregate from the values
+// computed by the functions added to it on each scrape. It is used for the
+// states of the cores running at the same time.
+type GaugeFuncs struct {
+	desc      *prometheus.Desc
+	aggregate func(values []float64) float64
+
+	mu  sync.Mutex
+	set funcSet
+}
+
+func NewGaugeFuncs(name, help string, aggregate func(values []float64) float64) *GaugeFuncs {
+	return &GaugeFuncs{
+		desc:      prometheus.NewDesc(name, help, nil, nil),
+		aggregate: aggregate,
+	}
+}
+
+// Add adds the function computing a value to aggregate.
+// remove must be called when the value is no longer available.
+func (g *GaugeFuncs) Add(f func() float64) (remove func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := g.set.add(f)
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		delete(g.set.funcs, id)
+	}
+}
+
+// Value returns the aggregated value. It is 0 while no function is added.
+func (g *GaugeFuncs) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.set.funcs) == 0 {
+		return 0
+	}
+	return g.aggregate(g.set.values())
+}
+
+func (g *GaugeFuncs) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+func (g *GaugeFuncs) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, g.Value())
+}
+
+var _ prometheus.Collector = (*GaugeFuncVec)(nil)
+
+// GaugeFuncVec is a gauge partitioned by a label whose values are the sums of
+// the values computed by the functions added to it on each scrape.
+type GaugeFuncVec struct {
+	desc *prometheus.Desc
+
+	mu   sync.Mutex
+	sets map[string]*funcSet
+}
+
+func NewGaugeFuncVec(name, help, labelName string) *GaugeFuncVec {
+	return &GaugeFuncVec{
+		desc: prometheus.NewDesc(name, help, []string{labelName}, nil),
+		sets: map[string]*funcSet{},
+	}
+}
+
+// Add adds the function computing the value of the label value.
+// The values of the functions added with the same label value are summed.
+// remove must be called when the value is no longer available.
+func (v *GaugeFuncVec) Add(labelValue string, f func() float64) (remove func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	set, ok := v.sets[labelValue]
+	if !ok {
+		set = &funcSet{}
+		v.sets[labelValue] = set
+	}
+	id := set.add(f)
+
+	return func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+
+		delete(set.funcs, id)
+		if len(set.funcs) == 0 {
+			delete(v.sets, labelValue)
+		}
+	}
+}
+
+func (v *GaugeFuncVec) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.desc
+}
+
+func (v *GaugeFuncVec) Collect(ch chan<- prometheus.Metric) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for labelValue, set := range v.sets {
+		ch <- prometheus.MustNewConstMetric(v.desc, prometheus.GaugeValue, sum(set.values()), labelValue)
+	}
+}
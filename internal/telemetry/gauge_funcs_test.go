@@ -0,0 +1,59 @@
+package telemetry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGaugeFuncs_Collect(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		g := NewGaugeFuncs("a", "Gauge aggregated.", func(values []float64) float64 {
+			return float64(len(values))
+		})
+		g.Add(func() float64 { return 1 })
+		g.Add(func() float64 { return 1 })
+		g.Add(func() float64 { return 1 })()
+
+		want := `# HELP a Gauge aggregated.
+# TYPE a gauge
+a 2
+`
+		if err := testutil.CollectAndCompare(g, strings.NewReader(want)); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("no function", func(t *testing.T) {
+		g := NewGaugeFuncs("a", "Gauge aggregated.", earliest)
+		g.Add(func() float64 { return 1 })()
+
+		want := `# HELP a Gauge aggregated.
+# TYPE a gauge
+a 0
+`
+		if err := testutil.CollectAndCompare(g, strings.NewReader(want)); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestGaugeFuncVec_Collect(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		v := NewGaugeFuncVec("a", "Gauge with a label.", "queue")
+		v.Add("audio", func() float64 { return 1 })
+		v.Add("audio", func() float64 { return 2 })
+		v.Add("result", func() float64 { return 4 })
+		v.Add("response", func() float64 { return 8 })()
+
+		want := `# HELP a Gauge with a label.
+# TYPE a gauge
+a{queue="audio"} 3
+a{queue="result"} 4
+`
+		if err := testutil.CollectAndCompare(v, strings.NewReader(want)); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+// Package telemetry collects the metrics of the recognizer and exposes them in
+// the Prometheus text format.
+package telemetry
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Default is the registry of the metrics below.
+var Default = prometheus.NewRegistry()
+
+// Handler returns the handler serving the metrics of Default for Prometheus to scrape.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{})
+}
+
+// Result types of the ResultsTotal label of CoreMetrics.
+const (
+	ResultInterim = "interim"
+	ResultFinal   = "final"
+)
+
+// Queue names of the QueueLength label.
+const (
+	QueueAudio    = "audio"
+	QueueResult   = "result"
+	QueueResponse = "response"
+)
+
+var (
+	AudioReadBytes = promauto.With(Default).NewCounter(prometheus.CounterOpts{
+		Name: "voice_recognition_audio_read_bytes_total",
+		Help: "Bytes of the audio read from the input.",
+	})
+	QueueLength = register(NewGaugeFuncVec(
+		"voice_recognition_queue_length",
+		"Number of the items waiting in the channel.",
+		"queue",
+	))
+	GoogleReconnects = promauto.With(Default).NewCounter(prometheus.CounterOpts{
+		Name: "voice_recognition_google_reconnects_total",
+		Help: "Number of the reconnections of the Google stream.",
+	})
+	// GoogleStreamsConnected and GoogleNextReconnect aggregate the states of
+	// the streams of the Google recognizers running at the same time, e.g. the
+	// ones of the channels of the multichannel backend.
+	GoogleStreamsConnected = register(NewGaugeFuncs(
+		"voice_recognition_google_streams_connected",
+		"Number of the open Google streams.",
+		sum,
+	))
+	GoogleNextReconnect = register(NewGaugeFuncs(
+		"voice_recognition_google_next_reconnect_timestamp_seconds",
+		"Unix time in seconds when a Google stream is reconnected next, the earliest of the streams.",
+		earliest,
+	))
+	// GoogleEstimatedCost sums the costs of the meters of the Google backends
+	// running at the same time.
+	GoogleEstimatedCost = register(NewGaugeFuncs(
+		"voice_recognition_google_estimated_cost_dollars",
+		"Estimated cost in dollars of the audio billed by Google.",
+		sum,
+	))
+	GoogleErrors = promauto.With(Default).NewCounterVec(prometheus.CounterOpts{
+		Name: "voice_recognition_google_errors_total",
+		Help: "Number of the errors of the Google backend by gRPC code.",
+	}, []string{"code"})
+)
+
+// register registers c in Default. It panics on a duplicate name because
+// metrics are defined statically.
+func register[C prometheus.Collector](c C) C {
+	Default.MustRegister(c)
+	return c
+}
+
+// Core is the metrics of the cores instrumented by InstrumentCore.
+var Core = NewCoreMetrics(Default)
+
+// CoreMetrics are the metrics of the cores instrumented by Instrument.
+type CoreMetrics struct {
+	AudioSentSeconds *prometheus.CounterVec
+	ResultsTotal     *prometheus.CounterVec
+	FinalLatency     *prometheus.HistogramVec
+
+	now func() time.Time
+}
+
+// NewCoreMetrics registers the metrics of the cores in the registry.
+func NewCoreMetrics(r prometheus.Registerer) *CoreMetrics {
+	f := promauto.With(r)
+	return &CoreMetrics{
+		AudioSentSeconds: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "voice_recognition_audio_sent_seconds_total",
+			Help: "Seconds of the audio sent to the backend.",
+		}, []string{"backend"}),
+		ResultsTotal: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "voice_recognition_results_total",
+			Help: "Number of the results returned by the backend.",
+		}, []string{"backend", "type"}),
+		FinalLatency: f.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "voice_recognition_final_latency_seconds",
+			Help:    "Seconds from the audio at the end of a final result sent to the backend until the result is returned.",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+		}, []string{"backend"}),
+		now: time.Now,
+	}
+}
+
+func sum(values []float64) float64 {
+	var s float64
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
+
+// earliest returns the minimum of the values ignoring 0, which is a time not set yet.
+func earliest(values []float64) float64 {
+	var m float64
+	for _, v := range values {
+		if v > 0 && (m == 0 || v < m) {
+			m = v
+		}
+	}
+	return m
+}
+
+// ChannelLength returns the function which reports the length of ch for QueueLength.
+func ChannelLength[T any](ch chan T) func() float64 {
+	return func() float64 {
+		return float64(len(ch))
+	}
+}
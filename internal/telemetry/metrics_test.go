@@ -0,0 +1,56 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+			t.Errorf("Content-Type = %q", got)
+		}
+		if got := rec.Body.String(); !strings.Contains(got, "# TYPE voice_recognition_audio_read_bytes_total counter\n") {
+			t.Errorf("body = %q, want voice_recognition_audio_read_bytes_total", got)
+		}
+	})
+}
+
+func Test_earliest(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{
+			name:   "minimum",
+			values: []float64{30, 10, 20},
+			want:   10,
+		},
+		{
+			name:   "not set",
+			values: []float64{0, 20, 0},
+			want:   20,
+		},
+		{
+			name:   "none set",
+			values: []float64{0, 0},
+			want:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := earliest(tt.values); got != tt.want {
+				t.Errorf("earliest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
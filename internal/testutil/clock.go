@@ -0,0 +1,138 @@
+package testutil
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/clock"
+)
+
+var _ clock.Clock = (*FakeClock)(nil)
+
+// FakeClock is a clock which moves only by Advance.
+// The timers fire in Advance when their deadlines have come.
+type FakeClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) clock.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{
+		clock:    c,
+		ch:       make(chan time.Time, 1),
+		deadline: c.now.Add(d),
+		active:   true,
+	}
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves the clock by d and fires the timers whose deadlines have come
+// in order of the deadlines. It returns the number of the timers fired.
+func (c *FakeClock) Advance(d time.Duration) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	var fired []*fakeTimer
+	for _, t := range c.timers {
+		if t.active && !t.deadline.After(c.now) {
+			fired = append(fired, t)
+		}
+	}
+	sort.SliceStable(fired, func(i, j int) bool {
+		return fired[i].deadline.Before(fired[j].deadline)
+	})
+	for _, t := range fired {
+		t.active = false
+		t.ch <- t.deadline
+	}
+	if len(fired) > 0 {
+		c.cond.Broadcast()
+	}
+
+	return len(fired)
+}
+
+// BlockUntil blocks until n timers are active, which is used to advance the
+// clock after the component under test has set its timers.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.activeTimers() < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *FakeClock) activeTimers() int {
+	n := 0
+	for _, t := range c.timers {
+		if t.active {
+			n++
+		}
+	}
+	return n
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	ch       chan time.Time
+	deadline time.Time
+	active   bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	wasActive := t.active
+	t.active = false
+	t.drain()
+	t.clock.cond.Broadcast()
+	return wasActive
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	wasActive := t.active
+	t.active = true
+	t.deadline = t.clock.now.Add(d)
+	t.drain()
+	t.clock.cond.Broadcast()
+	return wasActive
+}
+
+// drain discards the time not received yet as time.Timer does since Go 1.23.
+func (t *fakeTimer) drain() {
+	select {
+	case <-t.ch:
+	default:
+	}
+}
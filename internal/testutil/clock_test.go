@@ -0,0 +1,75 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("fire in order of deadlines", func(t *testing.T) {
+		c := NewFakeClock(start)
+		late := c.NewTimer(2 * time.Second)
+		early := c.NewTimer(time.Second)
+
+		if n := c.Advance(999 * time.Millisecond); n != 0 {
+			t.Errorf("Advance() = %d, want 0", n)
+		}
+		if n := c.Advance(1001 * time.Millisecond); n != 2 {
+			t.Errorf("Advance() = %d, want 2", n)
+		}
+		if got, want := <-early.C(), start.Add(time.Second); !got.Equal(want) {
+			t.Errorf("early timer fired at %v, want %v", got, want)
+		}
+		if got, want := <-late.C(), start.Add(2*time.Second); !got.Equal(want) {
+			t.Errorf("late timer fired at %v, want %v", got, want)
+		}
+		if got, want := c.Now(), start.Add(2*time.Second); !got.Equal(want) {
+			t.Errorf("Now() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		c := NewFakeClock(start)
+		timer := c.NewTimer(time.Second)
+
+		if !timer.Stop() {
+			t.Error("Stop() = false, want true for active timer")
+		}
+		if n := c.Advance(time.Second); n != 0 {
+			t.Errorf("Advance() = %d, want 0", n)
+		}
+		if timer.Stop() {
+			t.Error("Stop() = true, want false for stopped timer")
+		}
+	})
+
+	t.Run("reset discards the fired time", func(t *testing.T) {
+		c := NewFakeClock(start)
+		timer := c.NewTimer(time.Second)
+		c.Advance(time.Second)
+
+		if timer.Reset(time.Second) {
+			t.Error("Reset() = true, want false for fired timer")
+		}
+		select {
+		case <-timer.C():
+			t.Error("received the time fired before Reset")
+		default:
+		}
+		if n := c.Advance(time.Second); n != 1 {
+			t.Errorf("Advance() = %d, want 1", n)
+		}
+	})
+
+	t.Run("block until timers are set", func(t *testing.T) {
+		c := NewFakeClock(start)
+		go c.NewTimer(time.Second)
+
+		c.BlockUntil(1)
+		if n := c.Advance(time.Second); n != 1 {
+			t.Errorf("Advance() = %d, want 1", n)
+		}
+	})
+}
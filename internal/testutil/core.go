@@ -0,0 +1,52 @@
+package testutil
+
+import (
+	"context"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+// ScriptedCore is a recognizer core controlled by the test. It passes the
+// audio it receives to ReceivedCh, sends the results from ResultCh, and
+// returns the error from ErrCh.
+type ScriptedCore struct {
+	ReceivedCh chan []byte
+	ResultCh   chan []*model.Result
+	ErrCh      chan error
+}
+
+func NewScriptedCore() *ScriptedCore {
+	return &ScriptedCore{
+		ReceivedCh: make(chan []byte, 10),
+		ResultCh:   make(chan []*model.Result),
+		ErrCh:      make(chan error),
+	}
+}
+
+// Factory is the model.RecognizerCoreFactory of the core.
+func (c *ScriptedCore) Factory(
+	_ context.Context,
+	audioCh <-chan []byte,
+	resultCh chan<- []*model.Result,
+) (model.RecognizerCoreInterface, error) {
+	return &model.RecognizerCoreInterfaceMock{
+		StartFunc: func(ctx context.Context) error {
+			for {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case err := <-c.ErrCh:
+					return err
+				case audio := <-audioCh:
+					c.ReceivedCh <- audio
+				case results := <-c.ResultCh:
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case resultCh <- results:
+					}
+				}
+			}
+		},
+	}, nil
+}
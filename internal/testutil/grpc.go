@@ -19,11 +19,21 @@ import (
 func MockSpeechClient(t *testing.T, ctx context.Context, mockServer speechpb.SpeechServer) myspeech.Client {
 	t.Helper()
 
+	return NewSpeechClient(t, ctx, func(s *grpc.Server) {
+		speechpb.RegisterSpeechServer(s, mockServer)
+	})
+}
+
+// NewSpeechClient returns a client connected to the in-memory server with the
+// services registered by register.
+func NewSpeechClient(t *testing.T, ctx context.Context, register func(s *grpc.Server)) myspeech.Client {
+	t.Helper()
+
 	l := bufconn.Listen(1024 * 1024)
 	t.Cleanup(func() { l.Close() })
 
 	s := grpc.NewServer()
-	speechpb.RegisterSpeechServer(s, mockServer)
+	register(s)
 
 	go s.Serve(l)
 	t.Cleanup(func() { s.Stop() })
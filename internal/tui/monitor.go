@@ -0,0 +1,306 @@
+// Package tui shows a recognition session on a full screen of the terminal:
+// the transcript, the input level, the state of the stream and the cost.
+package tui
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/clock"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/text/width"
+)
+
+// floorDBFS is the lowest level shown by the meter.
+const floorDBFS = -60.0
+
+// markBuffer is the number of the markers kept until the core writes them.
+const markBuffer = 8
+
+// Status is the state of the session outside the monitor, polled on each redraw.
+type Status struct {
+	// Streaming is true if the backend streams to Google, which reconnects the stream periodically.
+	Streaming     bool
+	Connected     bool
+	NextReconnect time.Time
+	// InactiveDeadline is the time when the session stops unless something is recognized.
+	InactiveDeadline time.Time
+	// Cost is the estimated cost in dollars.
+	Cost float64
+}
+
+// Monitor keeps the transcript and the input level of the session, and
+// pauses the audio and inserts the markers on the keys.
+type Monitor struct {
+	clock  clock.Clock
+	start  time.Time
+	markCh chan string
+	paused atomic.Bool
+
+	mu sync.Mutex
+	// finals are the final results so far.
+	finals []string
+	// interim is the current interim result. Empty if none.
+	interim string
+	// level is the level of the latest audio in dBFS.
+	level   float64
+	markers int
+}
+
+func NewMonitor(clock clock.Clock) *Monitor {
+	return &Monitor{
+		clock:  clock,
+		start:  clock.Now(),
+		markCh: make(chan string, markBuffer),
+		level:  math.Inf(-1),
+	}
+}
+
+// Publish records the results. See model.Split for the interim result.
+func (m *Monitor) Publish(results []*model.Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	finals, interim := model.Split(results)
+	for _, result := range finals {
+		m.finals = append(m.finals, result.Transcript)
+		m.interim = ""
+	}
+	if interim != nil {
+		m.interim = interim.Transcript
+	}
+}
+
+// TogglePause pauses or resumes sending the audio to the core.
+func (m *Monitor) TogglePause() {
+	paused := !m.paused.Load()
+	m.paused.Store(paused)
+	if paused {
+		slog.Info("Monitor: paused")
+	} else {
+		slog.Info("Monitor: resumed")
+	}
+}
+
+func (m *Monitor) Paused() bool {
+	return m.paused.Load()
+}
+
+// Mark inserts a marker with the current time into the transcript.
+func (m *Monitor) Mark() {
+	m.mu.Lock()
+	m.markers++
+	text := fmt.Sprintf("--- marker %d %s ---", m.markers, m.clock.Now().Format("15:04:05"))
+	m.mu.Unlock()
+
+	select {
+	case m.markCh <- text:
+	default:
+		slog.Warn(fmt.Sprintf("Monitor: too many markers, %q is dropped", text))
+	}
+}
+
+// Input returns the reader of the audio from r, S16LE, which measures the
+// level and drops the audio while paused.
+func (m *Monitor) Input(r io.Reader) io.Reader {
+	return &inputReader{monitor: m, reader: r}
+}
+
+type inputReader struct {
+	monitor *Monitor
+	reader  io.Reader
+}
+
+func (r *inputReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.reader.Read(p)
+		if n > 0 {
+			r.monitor.setLevel(levelDBFS(p[:n]))
+		}
+		// the audio keeps being read while paused so that the source is not blocked.
+		if r.monitor.Paused() {
+			n = 0
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+func (m *Monitor) setLevel(level float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.level = level
+}
+
+// levelDBFS returns the RMS level of the S16LE samples in dBFS.
+func levelDBFS(b []byte) float64 {
+	samples := len(b) / 2
+	if samples == 0 {
+		return math.Inf(-1)
+	}
+	var sum float64
+	for i := 0; i < samples; i++ {
+		s := float64(int16(binary.LittleEndian.Uint16(b[i*2:])))
+		sum += s * s
+	}
+	return 20 * math.Log10(math.Sqrt(sum/float64(samples))/32768)
+}
+
+// Wrap wraps the factory so that the core built by it records the results to
+// the monitor and writes the markers as the final results.
+func (m *Monitor) Wrap(newCore model.RecognizerCoreFactory) model.RecognizerCoreFactory {
+	return model.Observe(func(
+		ctx context.Context,
+		audioCh <-chan []byte,
+		resultCh chan<- []*model.Result,
+	) (model.RecognizerCoreInterface, error) {
+		core, err := newCore(ctx, audioCh, resultCh)
+		if err != nil {
+			return nil, err
+		}
+		return &markingCore{monitor: m, core: core, resultCh: resultCh}, nil
+	}, m.Publish)
+}
+
+var _ model.RecognizerCoreInterface = (*markingCore)(nil)
+
+// markingCore writes the markers along with the results of the core.
+type markingCore struct {
+	monitor  *Monitor
+	core     model.RecognizerCoreInterface
+	resultCh chan<- []*model.Result
+}
+
+func (c *markingCore) Start(ctx context.Context) error {
+	eg, ctx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		return c.core.Start(ctx)
+	})
+	eg.Go(func() error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case text := <-c.monitor.markCh:
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case c.resultCh <- []*model.Result{{Transcript: text, IsFinal: true}}:
+				}
+			}
+		}
+	})
+
+	return eg.Wait()
+}
+
+// Render returns the lines of the screen of the size.
+func (m *Monitor) Render(columns, rows int, status Status) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	state := "\033[31m● REC\033[0m"
+	if m.Paused() {
+		state = "\033[33m❚❚ PAUSED\033[0m"
+	}
+	header := []string{
+		fmt.Sprintf("%s  %s", state, formatDuration(now.Sub(m.start))),
+	}
+	if status.Streaming {
+		stream := "disconnected"
+		if status.Connected {
+			stream = "connected, reconnect in " + formatDuration(status.NextReconnect.Sub(now))
+		}
+		header = append(header, "stream    "+stream)
+	}
+	if !status.InactiveDeadline.IsZero() {
+		header = append(header, "inactive  stops in "+formatDuration(status.InactiveDeadline.Sub(now)))
+	}
+	header = append(header,
+		fmt.Sprintf("cost      $%.4f", status.Cost),
+		"level     "+levelMeter(m.level, columns-10),
+	)
+	separator := strings.Repeat("-", columns)
+	footer := []string{separator, "p: pause/resume  m: marker  q: quit"}
+	header = append(header, separator)
+
+	var transcript []string
+	for _, final := range m.finals {
+		transcript = append(transcript, wrap(final, columns)...)
+	}
+	if m.interim != "" {
+		for _, line := range wrap(m.interim, columns) {
+			transcript = append(transcript, "\033[32m"+line+"\033[0m")
+		}
+	}
+	// the latest lines are shown.
+	height := max(rows-len(header)-len(footer), 0)
+	transcript = transcript[max(len(transcript)-height, 0):]
+	for len(transcript) < height {
+		transcript = append(transcript, "")
+	}
+
+	lines := append(header, transcript...)
+	lines = append(lines, footer...)
+	return lines[:min(len(lines), rows)]
+}
+
+// formatDuration formats d as h:mm:ss. It is 0:00:00 if d is negative.
+func formatDuration(d time.Duration) string {
+	d = max(d, 0).Truncate(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+// levelMeter returns the bar of the level from floorDBFS to 0 within the columns.
+func levelMeter(level float64, columns int) string {
+	label := "  -inf dBFS"
+	if !math.IsInf(level, -1) {
+		label = fmt.Sprintf(" %5.1f dBFS", level)
+	}
+	size := columns - len(label) - 2
+	if size <= 0 {
+		return strings.TrimSpace(label)
+	}
+	filled := int(math.Round((max(level, floorDBFS) - floorDBFS) / -floorDBFS * float64(size)))
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", size-filled) + "]" + label
+}
+
+// wrap splits the text into the lines of the columns. The wide characters take 2 columns.
+func wrap(text string, columns int) []string {
+	var lines []string
+	var line strings.Builder
+	col := 0
+	for _, r := range text {
+		if r == '\n' {
+			lines = append(lines, line.String())
+			line.Reset()
+			col = 0
+			continue
+		}
+		rw := 1
+		switch width.LookupRune(r).Kind() {
+		case width.EastAsianWide, width.EastAsianFullwidth:
+			rw = 2
+		}
+		if columns > 0 && col+rw > columns {
+			lines = append(lines, line.String())
+			line.Reset()
+			col = 0
+		}
+		line.WriteRune(r)
+		col += rw
+	}
+	return append(lines, line.String())
+}
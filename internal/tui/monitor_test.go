@@ -0,0 +1,249 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+var testTime = time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+func TestMonitor_Render(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(m *Monitor, clock *testutil.FakeClock) Status
+		rows    int
+		want    []string
+	}{
+		{
+			name: "success",
+			prepare: func(m *Monitor, clock *testutil.FakeClock) Status {
+				clock.Advance(65 * time.Second)
+				m.setLevel(-30)
+				m.Publish([]*model.Result{
+					{Transcript: "a", IsFinal: true},
+					{Transcript: "b", IsFinal: true},
+				})
+				m.Publish([]*model.Result{
+					{Transcript: strings.Repeat("あ", 25), IsFinal: true},
+					{Transcript: "c"},
+				})
+				return Status{
+					Streaming:        true,
+					Connected:        true,
+					NextReconnect:    clock.Now().Add(4 * time.Minute),
+					InactiveDeadline: clock.Now().Add(30 * time.Second),
+					Cost:             0.0123,
+				}
+			},
+			rows: 12,
+			want: []string{
+				"\033[31m● REC\033[0m  0:01:05",
+				"stream    connected, reconnect in 0:04:00",
+				"inactive  stops in 0:00:30",
+				"cost      $0.0123",
+				"level     [#########........] -30.0 dBFS",
+				strings.Repeat("-", 40),
+				// the oldest line is scrolled out.
+				"b",
+				strings.Repeat("あ", 20),
+				strings.Repeat("あ", 5),
+				"\033[32mc\033[0m",
+				strings.Repeat("-", 40),
+				"p: pause/resume  m: marker  q: quit",
+			},
+		},
+		{
+			name: "paused without stream",
+			prepare: func(m *Monitor, _ *testutil.FakeClock) Status {
+				m.TogglePause()
+				return Status{Streaming: true}
+			},
+			rows: 9,
+			want: []string{
+				"\033[33m❚❚ PAUSED\033[0m  0:00:00",
+				"stream    disconnected",
+				"cost      $0.0000",
+				"level     [.................]  -inf dBFS",
+				strings.Repeat("-", 40),
+				"",
+				"",
+				strings.Repeat("-", 40),
+				"p: pause/resume  m: marker  q: quit",
+			},
+		},
+		{
+			name: "too small",
+			prepare: func(_ *Monitor, _ *testutil.FakeClock) Status {
+				return Status{}
+			},
+			rows: 2,
+			want: []string{
+				"\033[31m● REC\033[0m  0:00:00",
+				"cost      $0.0000",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := testutil.NewFakeClock(testTime)
+			m := NewMonitor(clock)
+			status := tt.prepare(m, clock)
+
+			if diff := cmp.Diff(m.Render(40, tt.rows, status), tt.want); diff != "" {
+				t.Errorf("Monitor.Render() (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMonitor_Input(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m := NewMonitor(testutil.NewFakeClock(testTime))
+		audio := samples(16384, -16384)
+
+		got, err := io.ReadAll(m.Input(bytes.NewReader(audio)))
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if !bytes.Equal(got, audio) {
+			t.Errorf("read %v, want %v", got, audio)
+		}
+		if math.Abs(m.level-(-6.02)) > 0.01 {
+			t.Errorf("level = %v, want -6.02", m.level)
+		}
+	})
+
+	t.Run("paused", func(t *testing.T) {
+		m := NewMonitor(testutil.NewFakeClock(testTime))
+		m.TogglePause()
+
+		got, err := io.ReadAll(m.Input(bytes.NewReader(samples(32767))))
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("read %v while paused, want nothing", got)
+		}
+		// the level is measured even while paused.
+		if math.Abs(m.level) > 0.01 {
+			t.Errorf("level = %v, want 0", m.level)
+		}
+	})
+}
+
+func Test_levelDBFS(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+		want float64
+	}{
+		{name: "full scale", b: samples(-32768), want: 0},
+		{name: "half", b: samples(16384, -16384), want: -6.02},
+		{name: "silence", b: samples(0, 0), want: math.Inf(-1)},
+		{name: "empty", b: []byte{1}, want: math.Inf(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := levelDBFS(tt.b)
+			if math.IsInf(tt.want, -1) {
+				if !math.IsInf(got, -1) {
+					t.Errorf("levelDBFS() = %v, want %v", got, tt.want)
+				}
+				return
+			}
+			if math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("levelDBFS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitor_Wrap(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		newCore := func(
+			_ context.Context,
+			_ <-chan []byte,
+			resultCh chan<- []*model.Result,
+		) (model.RecognizerCoreInterface, error) {
+			return &model.RecognizerCoreInterfaceMock{
+				StartFunc: func(ctx context.Context) error {
+					resultCh <- []*model.Result{{Transcript: "a", IsFinal: true}}
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			}, nil
+		}
+
+		m := NewMonitor(testutil.NewFakeClock(testTime))
+		resultCh := make(chan []*model.Result)
+		core, err := m.Wrap(newCore)(context.Background(), make(chan []byte), resultCh)
+		if err != nil {
+			t.Fatalf("Wrap() error = %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- core.Start(ctx)
+		}()
+
+		if diff := cmp.Diff(<-resultCh, []*model.Result{{Transcript: "a", IsFinal: true}}); diff != "" {
+			t.Errorf("results (-got +want):\n%s", diff)
+		}
+		m.Mark()
+		want := []*model.Result{{Transcript: "--- marker 1 15:04:05 ---", IsFinal: true}}
+		if diff := cmp.Diff(<-resultCh, want); diff != "" {
+			t.Errorf("results (-got +want):\n%s", diff)
+		}
+		if diff := cmp.Diff(m.finals, []string{"a", "--- marker 1 15:04:05 ---"}); diff != "" {
+			t.Errorf("finals (-got +want):\n%s", diff)
+		}
+
+		cancel()
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("Start() error = %v, want context canceled", err)
+		}
+	})
+}
+
+func Test_wrap(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		columns int
+		want    []string
+	}{
+		{name: "fits", text: "abc", columns: 3, want: []string{"abc"}},
+		{name: "wraps", text: "abcd", columns: 3, want: []string{"abc", "d"}},
+		{name: "wide", text: "あいう", columns: 5, want: []string{"あい", "う"}},
+		{name: "newline", text: "a\nb", columns: 3, want: []string{"a", "b"}},
+		{name: "empty", text: "", columns: 3, want: []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(wrap(tt.text, tt.columns), tt.want); diff != "" {
+				t.Errorf("wrap() (-got +want):\n%s", diff)
+			}
+		})
+	}
+}
+
+// samples returns the S16LE bytes of the samples.
+func samples(s ...int16) []byte {
+	b := make([]byte, len(s)*2)
+	for i, v := range s {
+		binary.LittleEndian.PutUint16(b[i*2:], uint16(v))
+	}
+	return b
+}
@@ -0,0 +1,108 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// refreshInterval is the interval to redraw the screen.
+const refreshInterval = 200 * time.Millisecond
+
+const (
+	// enterScreen switches to the alternate screen and hides the cursor.
+	enterScreen = "\033[?1049h\033[?25l"
+	// leaveScreen restores the screen and the cursor.
+	leaveScreen = "\033[?25h\033[?1049l"
+
+	keyCtrlC = 0x03
+)
+
+// Run draws the monitor on tty, which must be in raw mode, until ctx is done.
+// The keys read from tty pause or resume the audio (p), insert a marker (m)
+// and call quit (q or Ctrl-C). size returns the size of tty, and status is
+// polled on each redraw.
+func (m *Monitor) Run(
+	ctx context.Context,
+	tty io.ReadWriter,
+	size func() (columns, rows int, err error),
+	status func() Status,
+	quit func(),
+) error {
+	if _, err := io.WriteString(tty, enterScreen); err != nil {
+		return fmt.Errorf("failed to enter screen: %w", err)
+	}
+	defer func() {
+		if _, err := io.WriteString(tty, leaveScreen); err != nil {
+			slog.Error(fmt.Sprintf("Monitor: failed to leave screen: %v", err))
+		}
+	}()
+
+	keyCh := make(chan byte)
+	go readKeys(ctx, tty, keyCh)
+
+	timer := m.clock.NewTimer(refreshInterval)
+	defer timer.Stop()
+
+	for {
+		if err := m.draw(tty, size, status()); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case key := <-keyCh:
+			switch key {
+			case 'p', 'P':
+				m.TogglePause()
+			case 'm', 'M':
+				m.Mark()
+			case 'q', 'Q', keyCtrlC:
+				slog.Info("Monitor: quit")
+				quit()
+			}
+		case <-timer.C():
+			timer.Reset(refreshInterval)
+		}
+	}
+}
+
+func (m *Monitor) draw(w io.Writer, size func() (int, int, error), status Status) error {
+	columns, rows, err := size()
+	if err != nil {
+		slog.Debug(fmt.Sprintf("Monitor: failed to get terminal size: %v", err))
+		columns, rows = 80, 24
+	}
+
+	// the lines are overwritten in place to avoid flickering. \r is needed in raw mode.
+	frame := "\033[H" + strings.Join(m.Render(columns, rows, status), "\033[K\r\n") + "\033[K\033[J"
+	if _, err := io.WriteString(w, frame); err != nil {
+		return fmt.Errorf("failed to draw screen: %w", err)
+	}
+	return nil
+}
+
+// readKeys sends the keys read from r until it fails or ctx is done.
+func readKeys(ctx context.Context, r io.Reader, keyCh chan<- byte) {
+	buf := make([]byte, 16)
+	for {
+		n, err := r.Read(buf)
+		for _, key := range buf[:n] {
+			select {
+			case <-ctx.Done():
+				return
+			case keyCh <- key:
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				slog.Debug(fmt.Sprintf("Monitor: failed to read keys: %v", err))
+			}
+			return
+		}
+	}
+}
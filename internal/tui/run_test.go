@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hekt/voice-recognition/internal/testutil"
+)
+
+func TestMonitor_Run(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		m := NewMonitor(testutil.NewFakeClock(testTime))
+
+		keys, keyWriter := io.Pipe()
+		defer keyWriter.Close()
+		var screen bytes.Buffer
+		tty := struct {
+			io.Reader
+			io.Writer
+		}{keys, &screen}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		quitCh := make(chan struct{})
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- m.Run(
+				ctx,
+				tty,
+				func() (int, int, error) { return 40, 10, nil },
+				func() Status { return Status{} },
+				func() { close(quitCh) },
+			)
+		}()
+
+		// the keys are handled in order, so p is handled when q quits.
+		if _, err := keyWriter.Write([]byte("pq")); err != nil {
+			t.Fatalf("failed to write keys: %v", err)
+		}
+		<-quitCh
+		if !m.Paused() {
+			t.Error("Monitor.Run() does not pause on p")
+		}
+
+		cancel()
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("Monitor.Run() error = %v, want context canceled", err)
+		}
+
+		got := screen.String()
+		if !strings.HasPrefix(got, enterScreen) || !strings.HasSuffix(got, leaveScreen) {
+			t.Errorf("Monitor.Run() does not restore the screen: %q", got)
+		}
+		if !strings.Contains(got, "PAUSED") {
+			t.Errorf("Monitor.Run() does not draw the pause: %q", got)
+		}
+	})
+}
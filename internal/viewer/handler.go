@@ -0,0 +1,97 @@
+package viewer
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// keepAliveInterval is the interval of the comments sent to keep the idle stream open through proxies.
+const keepAliveInterval = 15 * time.Second
+
+// NewHandler returns the handler serving the page on /, the events of the
+// hub on /events, and the transcript file on /transcript. transcript returns
+// the path of the file, which changes when it is rotated.
+func NewHandler(hub *Hub, transcript func() string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if _, err := w.Write(indexHTML); err != nil {
+			slog.Debug(fmt.Sprintf("Viewer: failed to write page: %v", err))
+		}
+	})
+	mux.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		serveEvents(w, r, hub)
+	})
+	mux.HandleFunc("GET /transcript", func(w http.ResponseWriter, r *http.Request) {
+		path := transcript()
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(path)))
+		http.ServeFile(w, r, path)
+	})
+	return mux
+}
+
+// serveEvents streams the transcript so far and the following events until
+// the browser disconnects or the hub is closed.
+func serveEvents(w http.ResponseWriter, r *http.Request, hub *Hub) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	history, events, unsubscribe := hub.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for _, e := range history {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+		slog.Debug(fmt.Sprintf("Viewer: failed to write event: %v", err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package viewer
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+func TestNewHandler(t *testing.T) {
+	transcript := filepath.Join(t.TempDir(), "output.txt")
+	if err := os.WriteFile(transcript, []byte("\nこんにちは"), 0o644); err != nil {
+		t.Fatalf("failed to write transcript: %v", err)
+	}
+	hub := NewHub()
+	ts := httptest.NewServer(NewHandler(hub, func() string { return transcript }))
+	defer ts.Close()
+
+	t.Run("page", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/")
+		if err != nil {
+			t.Fatalf("failed to get page: %v", err)
+		}
+		defer resp.Body.Close()
+		if got := resp.Header.Get("Content-Type"); got != "text/html; charset=utf-8" {
+			t.Errorf("Content-Type = %q", got)
+		}
+	})
+
+	t.Run("transcript", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/transcript")
+		if err != nil {
+			t.Fatalf("failed to get transcript: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "\nこんにちは" {
+			t.Errorf("transcript = %q", body)
+		}
+		if got, want := resp.Header.Get("Content-Disposition"), `attachment; filename="output.txt"`; got != want {
+			t.Errorf("Content-Disposition = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("events", func(t *testing.T) {
+		hub.Publish([]*model.Result{{Transcript: "a", IsFinal: true}})
+
+		resp, err := http.Get(ts.URL + "/events")
+		if err != nil {
+			t.Fatalf("failed to get events: %v", err)
+		}
+		defer resp.Body.Close()
+		if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+			t.Errorf("Content-Type = %q", got)
+		}
+
+		lines := make(chan string)
+		go func() {
+			defer close(lines)
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				if line := scanner.Text(); strings.HasPrefix(line, "data: ") {
+					lines <- line
+				}
+			}
+		}()
+		next := func() string {
+			select {
+			case line := <-lines:
+				return line
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out receiving event")
+				return ""
+			}
+		}
+
+		if got, want := next(), `data: {"type":"final","text":"a"}`; got != want {
+			t.Errorf("history = %q, want %q", got, want)
+		}
+		hub.Publish([]*model.Result{{Transcript: "b"}})
+		if got, want := next(), `data: {"type":"interim","text":"b"}`; got != want {
+			t.Errorf("event = %q, want %q", got, want)
+		}
+
+		// the stream ends when the hub is closed.
+		hub.Close()
+		for range lines {
+		}
+	})
+}
@@ -0,0 +1,82 @@
+// Package viewer serves the live transcript of a session to browsers with Server-Sent Events.
+package viewer
+
+import (
+	"sync"
+
+	"github.com/hekt/voice-recognition/internal/broadcast"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+const (
+	EventInterim = "interim"
+	EventFinal   = "final"
+)
+
+// Event is a result pushed to the browsers as JSON.
+type Event struct {
+	Type    string `json:"type"`
+	Text    string `json:"text"`
+	Backend string `json:"backend,omitempty"`
+}
+
+// Hub keeps the transcript of the session and broadcasts the results to the
+// browsers. A browser disconnected for falling behind catches up with the
+// history when it reconnects.
+type Hub struct {
+	events *broadcast.Broadcaster[Event]
+
+	mu sync.Mutex
+	// finals are the final results so far, sent to the browsers on connect.
+	finals []Event
+	// interim is the current interim result. Text is empty if none.
+	interim Event
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		events: broadcast.New[Event]("Viewer: browser"),
+	}
+}
+
+// Publish broadcasts the results. See model.Split for the interim result.
+func (h *Hub) Publish(results []*model.Result) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	finals, interim := model.Split(results)
+	for _, result := range finals {
+		final := Event{Type: EventFinal, Text: result.Transcript, Backend: result.Backend}
+		h.finals = append(h.finals, final)
+		h.interim = Event{}
+		h.events.Send(final)
+	}
+	if interim != nil {
+		h.interim = Event{Type: EventInterim, Text: interim.Transcript, Backend: interim.Backend}
+		h.events.Send(h.interim)
+	}
+}
+
+// subscribe returns the transcript so far and the channel of the following
+// events, which is closed when the hub is closed.
+func (h *Hub) subscribe() (history []Event, events <-chan Event, unsubscribe func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	history = append([]Event{}, h.finals...)
+	if h.interim.Text != "" {
+		history = append(history, h.interim)
+	}
+	events, unsubscribe = h.events.Subscribe()
+	return history, events, unsubscribe
+}
+
+// Close disconnects the browsers.
+func (h *Hub) Close() {
+	h.events.Close()
+}
+
+// Wrap wraps the factory so that the core built by it publishes the results to the hub.
+func (h *Hub) Wrap(newCore model.RecognizerCoreFactory) model.RecognizerCoreFactory {
+	return model.Observe(newCore, h.Publish)
+}
@@ -0,0 +1,116 @@
+package viewer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hekt/voice-recognition/internal/broadcast"
+	"github.com/hekt/voice-recognition/internal/recognizer/model"
+)
+
+func TestHub_Publish(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		hub := NewHub()
+		hub.Publish([]*model.Result{
+			{Transcript: "a", IsFinal: true, Backend: "google"},
+			{Transcript: "b"},
+			{Transcript: "c"},
+		})
+
+		history, events, unsubscribe := hub.subscribe()
+		defer unsubscribe()
+		wantHistory := []Event{
+			{Type: EventFinal, Text: "a", Backend: "google"},
+			{Type: EventInterim, Text: "bc"},
+		}
+		if diff := cmp.Diff(history, wantHistory); diff != "" {
+			t.Errorf("history (-got +want):\n%s", diff)
+		}
+
+		hub.Publish([]*model.Result{{Transcript: "bcd", IsFinal: true}})
+		if diff := cmp.Diff(<-events, Event{Type: EventFinal, Text: "bcd"}); diff != "" {
+			t.Errorf("event (-got +want):\n%s", diff)
+		}
+
+		// the interim is replaced by the final.
+		history, _, unsubscribe2 := hub.subscribe()
+		defer unsubscribe2()
+		wantHistory = []Event{
+			{Type: EventFinal, Text: "a", Backend: "google"},
+			{Type: EventFinal, Text: "bcd"},
+		}
+		if diff := cmp.Diff(history, wantHistory); diff != "" {
+			t.Errorf("history (-got +want):\n%s", diff)
+		}
+
+		hub.Close()
+		if _, ok := <-events; ok {
+			t.Error("events are not closed")
+		}
+	})
+
+	t.Run("slow subscriber", func(t *testing.T) {
+		hub := NewHub()
+		_, events, unsubscribe := hub.subscribe()
+		defer unsubscribe()
+
+		for range broadcast.Buffer + 1 {
+			hub.Publish([]*model.Result{{Transcript: "a"}})
+		}
+
+		n := 0
+		for range events {
+			n++
+		}
+		if n != broadcast.Buffer {
+			t.Errorf("received %d events, want %d", n, broadcast.Buffer)
+		}
+	})
+}
+
+func TestHub_Wrap(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		newCore := func(
+			_ context.Context,
+			_ <-chan []byte,
+			resultCh chan<- []*model.Result,
+		) (model.RecognizerCoreInterface, error) {
+			return &model.RecognizerCoreInterfaceMock{
+				StartFunc: func(ctx context.Context) error {
+					resultCh <- []*model.Result{{Transcript: "a", IsFinal: true}}
+					<-ctx.Done()
+					return ctx.Err()
+				},
+			}, nil
+		}
+
+		hub := NewHub()
+		resultCh := make(chan []*model.Result)
+		core, err := hub.Wrap(newCore)(context.Background(), make(chan []byte), resultCh)
+		if err != nil {
+			t.Fatalf("Wrap() error = %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- core.Start(ctx)
+		}()
+
+		if got := <-resultCh; len(got) != 1 || got[0].Transcript != "a" {
+			t.Errorf("unexpected results: %v", got)
+		}
+		history, _, unsubscribe := hub.subscribe()
+		defer unsubscribe()
+		if diff := cmp.Diff(history, []Event{{Type: EventFinal, Text: "a"}}); diff != "" {
+			t.Errorf("history (-got +want):\n%s", diff)
+		}
+
+		cancel()
+		if err := <-errCh; !errors.Is(err, context.Canceled) {
+			t.Errorf("Start() error = %v, want context canceled", err)
+		}
+	})
+}